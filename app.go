@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 
+	"DentistApp/backup"
 	"DentistApp/handlers"
 	"DentistApp/models"
+	"DentistApp/pdfdoc"
+	"DentistApp/reminders"
 )
 
 // App struct
@@ -18,16 +21,33 @@ type App struct {
 	sessionHandler        *handlers.SessionHandler
 	invoiceHandler        *handlers.InvoiceHandler
 	expenseCategoryHandler *handlers.ExpenseCategoryHandler
+	expenseHandler        *handlers.ExpenseHandler
 	workTypeHandler       *handlers.WorkTypeHandler
 	colorShadeHandler     *handlers.ColorShadeHandler
 	dentalLabHandler      *handlers.DentalLabHandler
 	labOrderHandler      *handlers.LabOrderHandler
 	licenseService        *handlers.LicenseService
 	authHandler           *handlers.AuthHandler
+	auditHandler          *handlers.AuditHandler
+	backupHandler         *handlers.BackupHandler
+	backupManager         *backup.Manager
+	backupScheduler       *backup.Scheduler
+	settingsHandler       *handlers.SettingsHandler
+	chairHandler          *handlers.ChairHandler
+	scheduleHandler       *handlers.ScheduleHandler
+	reminderHandler       *handlers.ReminderHandler
+	reminderScheduler     *reminders.Scheduler
+	waitlistHandler       *handlers.WaitlistHandler
+	toothChartHandler     *handlers.ToothChartHandler
+	treatmentPlanHandler  *handlers.TreatmentPlanHandler
+	perioHandler          *handlers.PerioHandler
+	clinicalNoteHandler   *handlers.ClinicalNoteHandler
+	prescriptionHandler   *handlers.PrescriptionHandler
+	medicalAlertHandler   *handlers.MedicalAlertHandler
 }
 
 // NewApp creates a new App application struct
-func NewApp(patientHandler *handlers.PatientHandler, appointmentHandler *handlers.AppointmentHandler, paymentHandler *handlers.PaymentHandler, procedureHandler *handlers.ProcedureHandler, sessionHandler *handlers.SessionHandler, invoiceHandler *handlers.InvoiceHandler, expenseCategoryHandler *handlers.ExpenseCategoryHandler, workTypeHandler *handlers.WorkTypeHandler, colorShadeHandler *handlers.ColorShadeHandler, dentalLabHandler *handlers.DentalLabHandler, labOrderHandler *handlers.LabOrderHandler, authHandler *handlers.AuthHandler) *App {
+func NewApp(patientHandler *handlers.PatientHandler, appointmentHandler *handlers.AppointmentHandler, paymentHandler *handlers.PaymentHandler, procedureHandler *handlers.ProcedureHandler, sessionHandler *handlers.SessionHandler, invoiceHandler *handlers.InvoiceHandler, expenseCategoryHandler *handlers.ExpenseCategoryHandler, expenseHandler *handlers.ExpenseHandler, workTypeHandler *handlers.WorkTypeHandler, colorShadeHandler *handlers.ColorShadeHandler, dentalLabHandler *handlers.DentalLabHandler, labOrderHandler *handlers.LabOrderHandler, authHandler *handlers.AuthHandler, auditHandler *handlers.AuditHandler, backupHandler *handlers.BackupHandler, backupManager *backup.Manager, backupScheduler *backup.Scheduler, settingsHandler *handlers.SettingsHandler, chairHandler *handlers.ChairHandler, scheduleHandler *handlers.ScheduleHandler, reminderHandler *handlers.ReminderHandler, reminderScheduler *reminders.Scheduler, waitlistHandler *handlers.WaitlistHandler, toothChartHandler *handlers.ToothChartHandler, treatmentPlanHandler *handlers.TreatmentPlanHandler, perioHandler *handlers.PerioHandler, clinicalNoteHandler *handlers.ClinicalNoteHandler, prescriptionHandler *handlers.PrescriptionHandler, medicalAlertHandler *handlers.MedicalAlertHandler) *App {
 	return &App{
 		patientHandler:        patientHandler,
 		appointmentHandler:    appointmentHandler,
@@ -36,12 +56,29 @@ func NewApp(patientHandler *handlers.PatientHandler, appointmentHandler *handler
 		sessionHandler:        sessionHandler,
 		invoiceHandler:        invoiceHandler,
 		expenseCategoryHandler: expenseCategoryHandler,
+		expenseHandler:        expenseHandler,
 		workTypeHandler:       workTypeHandler,
 		colorShadeHandler:     colorShadeHandler,
 		dentalLabHandler:      dentalLabHandler,
 		labOrderHandler:       labOrderHandler,
 		licenseService:        handlers.NewLicenseService(),
 		authHandler:           authHandler,
+		auditHandler:          auditHandler,
+		backupHandler:         backupHandler,
+		backupManager:         backupManager,
+		backupScheduler:       backupScheduler,
+		settingsHandler:       settingsHandler,
+		chairHandler:          chairHandler,
+		scheduleHandler:       scheduleHandler,
+		reminderHandler:       reminderHandler,
+		reminderScheduler:     reminderScheduler,
+		waitlistHandler:       waitlistHandler,
+		toothChartHandler:     toothChartHandler,
+		treatmentPlanHandler:  treatmentPlanHandler,
+		perioHandler:          perioHandler,
+		clinicalNoteHandler:   clinicalNoteHandler,
+		prescriptionHandler:   prescriptionHandler,
+		medicalAlertHandler:   medicalAlertHandler,
 	}
 }
 
@@ -49,10 +86,14 @@ func NewApp(patientHandler *handlers.PatientHandler, appointmentHandler *handler
 // so we can call the runtime methods
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
+	a.backupScheduler.Start()
+	a.reminderScheduler.Start()
 }
 
 // shutdown is called when the app is shutting down
 func (a *App) shutdown(ctx context.Context) {
+	a.backupScheduler.Stop()
+	a.reminderScheduler.Stop()
 	// a.db.Close() // The database is closed in main.go
 }
 
@@ -76,6 +117,48 @@ func (a *App) checkLicense(licenseKey string) error {
 	return nil
 }
 
+// currentUser validates the license and resolves the session token to the acting user
+func (a *App) currentUser(sessionToken, licenseKey string) (*models.User, error) {
+	if err := a.checkLicense(licenseKey); err != nil {
+		return nil, err
+	}
+	return a.authHandler.ValidateSession(sessionToken)
+}
+
+// authorize resolves the acting user and checks that their role grants the permission
+func (a *App) authorize(sessionToken, licenseKey string, permission models.Permission) (*models.User, error) {
+	user, err := a.currentUser(sessionToken, licenseKey)
+	if err != nil {
+		return nil, err
+	}
+	if user.MustChangePassword {
+		return nil, handlers.ErrPasswordChangeRequired
+	}
+	if err := handlers.CheckPermission(user, permission); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// checkSeats returns an error if the license's seat limit leaves no room for another active user
+func (a *App) checkSeats(licenseKey string) error {
+	info, err := a.licenseService.ValidateLicense(licenseKey)
+	if err != nil {
+		return err
+	}
+	if info.Seats == 0 {
+		return nil
+	}
+	active, err := a.authHandler.CountActiveUsers()
+	if err != nil {
+		return err
+	}
+	if active >= info.Seats {
+		return fmt.Errorf("your license allows %d active users; deactivate a user or upgrade the license", info.Seats)
+	}
+	return nil
+}
+
 // Authentication Methods
 
 // Login authenticates a user and returns a session token
@@ -92,78 +175,161 @@ func (a *App) Login(username, password, licenseKey string) (*models.LoginRespons
 	return a.authHandler.Login(username, password)
 }
 
+// Logout revokes the session token
+func (a *App) Logout(sessionToken string) error {
+	return a.authHandler.Logout(sessionToken)
+}
+
+// ValidateSession checks a stored session token and returns its user
+func (a *App) ValidateSession(sessionToken, licenseKey string) (*models.User, error) {
+	return a.currentUser(sessionToken, licenseKey)
+}
+
 // CreateUser creates a new user (admin only)
-func (a *App) CreateUser(userForm models.UserForm, createdByID int, licenseKey string) (int64, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) CreateUser(userForm models.UserForm, sessionToken, licenseKey string) (int64, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermUserManage)
+	if err != nil {
+		return 0, err
+	}
+	if err := a.checkSeats(licenseKey); err != nil {
 		return 0, err
 	}
-	return a.authHandler.CreateUser(userForm, createdByID)
+	return a.authHandler.CreateUser(userForm, user.ID)
 }
 
-// GetCurrentUser returns the current user by ID
-func (a *App) GetCurrentUser(userID int, licenseKey string) (*models.User, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+// GetCurrentUser returns the user that owns the session token
+func (a *App) GetCurrentUser(sessionToken, licenseKey string) (*models.User, error) {
+	user, err := a.currentUser(sessionToken, licenseKey)
+	if err != nil {
 		return nil, err
 	}
-	return a.authHandler.GetUserByID(userID)
+	return a.authHandler.GetUserByID(user.ID)
 }
 
-// GetAllUsers returns all users (admin only)
-func (a *App) GetAllUsers(licenseKey string) ([]models.User, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+// GetPermissions returns the permissions granted to the session's user
+func (a *App) GetPermissions(sessionToken, licenseKey string) ([]string, error) {
+	user, err := a.currentUser(sessionToken, licenseKey)
+	if err != nil {
+		return nil, err
+	}
+	return handlers.PermissionsForRole(user.Role), nil
+}
+
+// GetAllUsers returns all users; every role may list staff, e.g. for the dentist filters
+func (a *App) GetAllUsers(sessionToken, licenseKey string) ([]models.User, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermUserView); err != nil {
 		return nil, err
 	}
 	return a.authHandler.GetAllUsers()
 }
 
+// UpdateUser changes a user's username and role (admin only)
+func (a *App) UpdateUser(id int, userForm models.UserUpdateForm, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermUserManage)
+	if err != nil {
+		return err
+	}
+	return a.authHandler.UpdateUser(id, userForm, user.ID)
+}
+
+// DeactivateUser disables a user's login while keeping their history (admin only)
+func (a *App) DeactivateUser(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermUserManage)
+	if err != nil {
+		return err
+	}
+	return a.authHandler.DeactivateUser(id, user.ID)
+}
+
+// ReactivateUser re-enables a deactivated user (admin only)
+func (a *App) ReactivateUser(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermUserManage)
+	if err != nil {
+		return err
+	}
+	if err := a.checkSeats(licenseKey); err != nil {
+		return err
+	}
+	return a.authHandler.ReactivateUser(id, user.ID)
+}
+
+// ResetPassword sets a temporary password the user must change on next login (admin only)
+func (a *App) ResetPassword(id int, newPassword string, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermUserManage)
+	if err != nil {
+		return err
+	}
+	return a.authHandler.ResetPassword(id, newPassword, user.ID)
+}
+
+// ChangePassword changes the session user's own password. It is allowed while a password change is required.
+func (a *App) ChangePassword(form models.ChangePasswordForm, sessionToken, licenseKey string) error {
+	user, err := a.currentUser(sessionToken, licenseKey)
+	if err != nil {
+		return err
+	}
+	return a.authHandler.ChangePassword(user.ID, sessionToken, form.OldPassword, form.NewPassword)
+}
+
 // Patient Management Methods
 
 // AddPatient adds a new patient
-func (a *App) AddPatient(patient models.PatientForm, licenseKey string) (int64, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) AddPatient(patient models.PatientForm, sessionToken, licenseKey string) (int64, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermPatientCreate)
+	if err != nil {
 		return 0, err
 	}
-	return a.patientHandler.AddPatient(patient)
+	return a.patientHandler.AddPatient(patient, user.ID)
 }
 
 // GetPatients returns all patients
-func (a *App) GetPatients(licenseKey string) ([]models.Patient, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) GetPatients(sessionToken, licenseKey string) ([]models.Patient, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermPatientView); err != nil {
 		return nil, err
 	}
 	return a.patientHandler.GetPatients()
 }
 
 // GetPatient returns a specific patient by ID
-func (a *App) GetPatient(id int, licenseKey string) (models.Patient, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) GetPatient(id int, sessionToken, licenseKey string) (models.Patient, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermPatientView); err != nil {
 		return models.Patient{}, err
 	}
 	return a.patientHandler.GetPatient(id)
 }
 
 // UpdatePatient updates an existing patient
-func (a *App) UpdatePatient(patient models.Patient, licenseKey string) error {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) UpdatePatient(patient models.Patient, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermPatientUpdate)
+	if err != nil {
 		return err
 	}
-	return a.patientHandler.UpdatePatient(patient)
+	return a.patientHandler.UpdatePatient(patient, user.ID)
 }
 
 // DeletePatient deletes a patient
-func (a *App) DeletePatient(id int, licenseKey string) error {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) DeletePatient(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermPatientDelete)
+	if err != nil {
 		return err
 	}
-	return a.patientHandler.DeletePatient(id)
+	return a.patientHandler.DeletePatient(id, user.ID)
 }
 
-// SearchPatients searches patients by name or phone
-func (a *App) SearchPatients(searchTerm string, licenseKey string) ([]models.Patient, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+// SearchPatients searches patients by name or phone, optionally within an age band
+func (a *App) SearchPatients(searchTerm string, filters models.PatientFilters, sessionToken, licenseKey string) ([]models.Patient, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermPatientView); err != nil {
+		return nil, err
+	}
+	return a.patientHandler.SearchPatients(searchTerm, filters)
+}
+
+// GetUpcomingBirthdays returns the patients with a birthday between from and to (YYYY-MM-DD)
+func (a *App) GetUpcomingBirthdays(from, to string, sessionToken, licenseKey string) ([]models.PatientBirthday, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermPatientView); err != nil {
 		return nil, err
 	}
-	return a.patientHandler.SearchPatients(searchTerm)
+	return a.patientHandler.GetUpcomingBirthdays(from, to)
 }
 
 // Greet returns a greeting for the given name
@@ -175,7 +341,7 @@ func (a *App) Greet(name string, licenseKey string) string {
 }
 
 // OpenPatientFolder opens the patient's folder in the system file explorer
-func (a *App) OpenPatientFolder(id int, licenseKey string) error {
+func (a *App) OpenPatientFolder(id int, sessionToken, licenseKey string) error {
 	fmt.Printf("[APP] OpenPatientFolder called - Patient ID: %d\n", id)
 	fmt.Printf("[APP] License key length: %d\n", len(licenseKey))
 	fmt.Printf("[APP] License key (first 20 chars): %s...\n",
@@ -186,12 +352,12 @@ func (a *App) OpenPatientFolder(id int, licenseKey string) error {
 			return licenseKey
 		}())
 
-	if err := a.checkLicense(licenseKey); err != nil {
-		fmt.Printf("[APP] License validation FAILED: %v\n", err)
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermPatientView); err != nil {
+		fmt.Printf("[APP] Authorization FAILED: %v\n", err)
 		return err
 	}
 
-	fmt.Printf("[APP] License validation PASSED, calling handler...\n")
+	fmt.Printf("[APP] Authorization PASSED, calling handler...\n")
 	err := a.patientHandler.OpenPatientFolder(id)
 	if err != nil {
 		fmt.Printf("[APP] Handler returned ERROR: %v\n", err)
@@ -202,120 +368,337 @@ func (a *App) OpenPatientFolder(id int, licenseKey string) error {
 }
 
 // DeleteAllPatients deletes all patients
-func (a *App) DeleteAllPatients(licenseKey string) error {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) DeleteAllPatients(sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermPatientDeleteAll)
+	if err != nil {
 		return err
 	}
-	return a.patientHandler.DeleteAllPatients()
+	return a.patientHandler.DeleteAllPatients(user.ID)
 }
 
 // Appointment Management Methods
 
-func (a *App) AddAppointment(appt models.Appointment, licenseKey string) (int64, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) AddAppointment(appt models.Appointment, sessionToken, licenseKey string) (int64, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentManage)
+	if err != nil {
 		return 0, err
 	}
-	return a.appointmentHandler.AddAppointment(appt)
+	return a.appointmentHandler.AddAppointment(appt, user.ID)
 }
 
-func (a *App) GetAppointments(licenseKey string) ([]models.Appointment, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) GetAppointments(sessionToken, licenseKey string) ([]models.Appointment, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentView); err != nil {
 		return nil, err
 	}
 	return a.appointmentHandler.GetAppointments()
 }
 
-func (a *App) GetAppointment(id int, licenseKey string) (models.Appointment, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+// GetAppointmentsInRange returns the appointments between two dates (YYYY-MM-DD, inclusive), optionally
+// for one dentist or chair (0 for all)
+func (a *App) GetAppointmentsInRange(from, to string, dentistID, chairID int, sessionToken, licenseKey string) ([]models.Appointment, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentView); err != nil {
+		return nil, err
+	}
+	return a.appointmentHandler.GetAppointmentsInRange(from, to, dentistID, chairID)
+}
+
+// GetAppointmentDaySummary returns per-day appointment counts and booked minutes for the month view
+func (a *App) GetAppointmentDaySummary(from, to string, dentistID, chairID int, sessionToken, licenseKey string) ([]models.AppointmentDaySummary, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentView); err != nil {
+		return nil, err
+	}
+	return a.appointmentHandler.GetAppointmentDaySummary(from, to, dentistID, chairID)
+}
+
+// ExportAppointmentsICS saves the appointments from through to, optionally for one dentist, as an
+// iCalendar file in the exports folder and returns its path
+func (a *App) ExportAppointmentsICS(from, to string, dentistID int, sessionToken, licenseKey string) (string, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentView); err != nil {
+		return "", err
+	}
+	clinic, err := a.clinicBranding(licenseKey)
+	if err != nil {
+		return "", err
+	}
+	return a.appointmentHandler.ExportAppointmentsICS(from, to, dentistID, clinic.Name)
+}
+
+// ImportAppointmentsICS books the events of an iCalendar file for existing patients and reports the rest
+func (a *App) ImportAppointmentsICS(content string, sessionToken, licenseKey string) (models.CalendarImportResult, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentManage)
+	if err != nil {
+		return models.CalendarImportResult{}, err
+	}
+	return a.appointmentHandler.ImportAppointmentsICS(content, user.ID)
+}
+
+func (a *App) GetAppointment(id int, sessionToken, licenseKey string) (models.Appointment, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentView); err != nil {
 		return models.Appointment{}, err
 	}
 	return a.appointmentHandler.GetAppointment(id)
 }
 
-func (a *App) UpdateAppointment(appt models.Appointment, licenseKey string) error {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) UpdateAppointment(appt models.Appointment, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentManage)
+	if err != nil {
 		return err
 	}
-	return a.appointmentHandler.UpdateAppointment(appt)
+	return a.appointmentHandler.UpdateAppointment(appt, user.ID)
 }
 
-func (a *App) DeleteAppointment(id int, licenseKey string) error {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) DeleteAppointment(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentManage)
+	if err != nil {
+		return err
+	}
+	return a.appointmentHandler.DeleteAppointment(id, user.ID)
+}
+
+// SetAppointmentStatus moves an appointment through its lifecycle (confirmed, checked in, completed, cancelled, ...)
+func (a *App) SetAppointmentStatus(id int, status, reason string, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentManage)
+	if err != nil {
+		return err
+	}
+	return a.appointmentHandler.SetAppointmentStatus(id, status, reason, user.ID)
+}
+
+// SessionFormForAppointment returns a new session form filled from an appointment
+func (a *App) SessionFormForAppointment(id int, sessionToken, licenseKey string) (models.SessionForm, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSessionCreate)
+	if err != nil {
+		return models.SessionForm{}, err
+	}
+	return a.appointmentHandler.SessionFormForAppointment(id, user.ID)
+}
+
+// CreateAppointmentSeries books a recurring series of appointments from an RRULE
+func (a *App) CreateAppointmentSeries(form models.AppointmentSeriesForm, sessionToken, licenseKey string) (models.AppointmentSeriesResult, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentManage)
+	if err != nil {
+		return models.AppointmentSeriesResult{}, err
+	}
+	return a.appointmentHandler.CreateAppointmentSeries(form, user.ID)
+}
+
+// UpdateAppointmentInSeries updates a visit and, depending on scope ("this", "following", "series"), the rest of its series
+func (a *App) UpdateAppointmentInSeries(appt models.Appointment, scope string, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentManage)
+	if err != nil {
+		return err
+	}
+	return a.appointmentHandler.UpdateAppointmentInSeries(appt, scope, user.ID)
+}
+
+// CancelAppointmentInSeries cancels a visit and, depending on scope, the rest of its series
+func (a *App) CancelAppointmentInSeries(id int, scope, reason string, sessionToken, licenseKey string) (int, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentManage)
+	if err != nil {
+		return 0, err
+	}
+	return a.appointmentHandler.CancelAppointmentInSeries(id, scope, reason, user.ID)
+}
+
+// Schedule Methods
+
+// GetWorkingHours returns a dentist's weekly working hours
+func (a *App) GetWorkingHours(userID int, sessionToken, licenseKey string) ([]models.WorkingHours, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentView); err != nil {
+		return nil, err
+	}
+	return a.scheduleHandler.GetWorkingHours(userID)
+}
+
+// SetWorkingHours replaces a dentist's weekly working hours (admin only)
+func (a *App) SetWorkingHours(userID int, hours []models.WorkingHours, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSettingsManage)
+	if err != nil {
+		return err
+	}
+	return a.scheduleHandler.SetWorkingHours(userID, hours, user.ID)
+}
+
+// GetClinicHolidays returns the days the clinic is closed
+func (a *App) GetClinicHolidays(sessionToken, licenseKey string) ([]models.ClinicHoliday, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentView); err != nil {
+		return nil, err
+	}
+	return a.scheduleHandler.GetClinicHolidays()
+}
+
+// AddClinicHoliday closes the clinic on a day (admin only)
+func (a *App) AddClinicHoliday(holiday models.ClinicHoliday, sessionToken, licenseKey string) (int64, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSettingsManage)
+	if err != nil {
+		return 0, err
+	}
+	return a.scheduleHandler.AddClinicHoliday(holiday, user.ID)
+}
+
+// DeleteClinicHoliday removes a clinic holiday (admin only)
+func (a *App) DeleteClinicHoliday(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSettingsManage)
+	if err != nil {
+		return err
+	}
+	return a.scheduleHandler.DeleteClinicHoliday(id, user.ID)
+}
+
+// GetTimeOff returns dentists' time off; userID 0 returns everyone's
+func (a *App) GetTimeOff(userID int, sessionToken, licenseKey string) ([]models.TimeOff, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentView); err != nil {
+		return nil, err
+	}
+	return a.scheduleHandler.GetTimeOff(userID)
+}
+
+// AddTimeOff records a period a dentist is unavailable (admin only)
+func (a *App) AddTimeOff(entry models.TimeOff, sessionToken, licenseKey string) (int64, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSettingsManage)
+	if err != nil {
+		return 0, err
+	}
+	return a.scheduleHandler.AddTimeOff(entry, user.ID)
+}
+
+// DeleteTimeOff removes a time off entry (admin only)
+func (a *App) DeleteTimeOff(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSettingsManage)
+	if err != nil {
+		return err
+	}
+	return a.scheduleHandler.DeleteTimeOff(id, user.ID)
+}
+
+// FindFreeSlots returns open slots of the given length in a dentist's schedule between two dates
+func (a *App) FindFreeSlots(dentistID, duration int, from, to string, sessionToken, licenseKey string) ([]models.FreeSlot, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentView); err != nil {
+		return nil, err
+	}
+	return a.scheduleHandler.FindFreeSlots(dentistID, duration, from, to)
+}
+
+// Chair Methods
+
+// GetChairs returns all treatment chairs
+func (a *App) GetChairs(sessionToken, licenseKey string) ([]models.Chair, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentView); err != nil {
+		return nil, err
+	}
+	return a.chairHandler.GetChairs()
+}
+
+// GetDentists returns the active dentists that appointments can be assigned to
+func (a *App) GetDentists(sessionToken, licenseKey string) ([]models.User, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentView); err != nil {
+		return nil, err
+	}
+	return a.authHandler.GetDentists()
+}
+
+// CreateChair adds a treatment chair (admin only)
+func (a *App) CreateChair(chair models.ChairForm, sessionToken, licenseKey string) (int64, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSettingsManage)
+	if err != nil {
+		return 0, err
+	}
+	return a.chairHandler.CreateChair(chair, user.ID)
+}
+
+// UpdateChair renames or (de)activates a treatment chair (admin only)
+func (a *App) UpdateChair(id int, chair models.ChairForm, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSettingsManage)
+	if err != nil {
 		return err
 	}
-	return a.appointmentHandler.DeleteAppointment(id)
+	return a.chairHandler.UpdateChair(id, chair, user.ID)
+}
+
+// DeleteChair deletes a treatment chair that has no appointments (admin only)
+func (a *App) DeleteChair(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSettingsManage)
+	if err != nil {
+		return err
+	}
+	return a.chairHandler.DeleteChair(id, user.ID)
 }
 
 // Payment Management Methods
 
-func (a *App) AddPayment(payment models.Payment, licenseKey string) (int64, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) AddPayment(payment models.Payment, sessionToken, licenseKey string) (int64, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermPaymentCreate)
+	if err != nil {
 		return 0, err
 	}
-	return a.paymentHandler.AddPayment(payment)
+	return a.paymentHandler.AddPayment(payment, user.ID)
 }
 
-func (a *App) GetPaymentsForPatient(patientID int, licenseKey string) ([]models.Payment, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) GetPaymentsForPatient(patientID int, sessionToken, licenseKey string) ([]models.Payment, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermPaymentView); err != nil {
 		return nil, err
 	}
 	return a.paymentHandler.GetPaymentsForPatient(patientID)
 }
 
-func (a *App) GetLastPaymentForPatient(patientID int, licenseKey string) (*models.Payment, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) GetLastPaymentForPatient(patientID int, sessionToken, licenseKey string) (*models.Payment, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermPaymentView); err != nil {
 		return nil, err
 	}
 	return a.paymentHandler.GetLastPaymentForPatient(patientID)
 }
 
-func (a *App) UpdateTotalRequired(patientID int, total int, licenseKey string) error {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) UpdateTotalRequired(patientID int, total int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermPaymentUpdate)
+	if err != nil {
 		return err
 	}
-	return a.paymentHandler.UpdateTotalRequired(patientID, total)
+	return a.paymentHandler.UpdateTotalRequired(patientID, total, user.ID)
 }
 
 // GetPatientBalance returns the total required, total paid, and remaining for a patient
-func (a *App) GetPatientBalance(patientID int, licenseKey string) (*handlers.PatientBalance, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) GetPatientBalance(patientID int, sessionToken, licenseKey string) (*handlers.PatientBalance, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermPaymentView); err != nil {
 		return nil, err
 	}
 	return a.paymentHandler.GetPatientBalance(patientID)
 }
 
 // DeletePayment deletes a payment by ID
-func (a *App) DeletePayment(paymentID int, licenseKey string) error {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) DeletePayment(paymentID int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermPaymentDelete)
+	if err != nil {
 		return err
 	}
-	return a.paymentHandler.DeletePayment(paymentID)
+	return a.paymentHandler.DeletePayment(paymentID, user.ID)
 }
 
 // UpdatePayment updates a payment by ID
-func (a *App) UpdatePayment(payment models.Payment, licenseKey string) error {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) UpdatePayment(payment models.Payment, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermPaymentUpdate)
+	if err != nil {
 		return err
 	}
-	return a.paymentHandler.UpdatePayment(payment)
+	return a.paymentHandler.UpdatePayment(payment, user.ID)
 }
 
 // Dental Procedure Management Methods
 
-func (a *App) CreateProcedure(procedure models.ProcedureForm, licenseKey string) (int64, error) {
+func (a *App) CreateProcedure(procedure models.ProcedureForm, sessionToken, licenseKey string) (int64, error) {
 	fmt.Printf("[App] CreateProcedure called with name: %s, price: %d\n", procedure.Name, procedure.Price)
-	if err := a.checkLicense(licenseKey); err != nil {
-		fmt.Printf("[App] License check failed: %v\n", err)
+	user, err := a.authorize(sessionToken, licenseKey, models.PermProcedureManage)
+	if err != nil {
+		fmt.Printf("[App] Authorization failed: %v\n", err)
 		return 0, err
 	}
-	fmt.Println("[App] License check passed, calling procedureHandler.CreateProcedure")
-	return a.procedureHandler.CreateProcedure(procedure)
+	fmt.Println("[App] Authorization passed, calling procedureHandler.CreateProcedure")
+	return a.procedureHandler.CreateProcedure(procedure, user.ID)
 }
 
-func (a *App) GetProcedures(licenseKey string) ([]models.Procedure, error) {
+func (a *App) GetProcedures(sessionToken, licenseKey string) ([]models.Procedure, error) {
 	// fmt.Printf("[App] GetProcedures() called with license key length: %d\n", len(licenseKey))
 
-	if err := a.checkLicense(licenseKey); err != nil {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermProcedureView); err != nil {
 		// fmt.Printf("[App] GetProcedures() - License check FAILED: %v\n", err)
 		return nil, err
 	}
@@ -331,186 +714,311 @@ func (a *App) GetProcedures(licenseKey string) ([]models.Procedure, error) {
 }
 
 // GetProceduresPaginated returns paginated procedures
-func (a *App) GetProceduresPaginated(page, pageSize int, licenseKey string) (*models.ProceduresResponse, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) GetProceduresPaginated(page, pageSize int, sessionToken, licenseKey string) (*models.ProceduresResponse, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermProcedureView); err != nil {
 		return nil, err
 	}
 	return a.procedureHandler.GetProceduresPaginated(page, pageSize)
 }
 
-func (a *App) UpdateProcedure(procedure models.Procedure, licenseKey string) error {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) UpdateProcedure(procedure models.Procedure, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermProcedureManage)
+	if err != nil {
 		return err
 	}
-	return a.procedureHandler.UpdateProcedure(procedure)
+	return a.procedureHandler.UpdateProcedure(procedure, user.ID)
 }
 
-func (a *App) DeleteProcedure(id int, licenseKey string) error {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) DeleteProcedure(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermProcedureManage)
+	if err != nil {
 		return err
 	}
-	return a.procedureHandler.DeleteProcedure(id)
+	return a.procedureHandler.DeleteProcedure(id, user.ID)
 }
 
 // Expense Category Management Methods
 
 // CreateExpenseCategory creates a new expense category
-func (a *App) CreateExpenseCategory(category models.ExpenseCategoryForm, userID int, licenseKey string) (int64, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) CreateExpenseCategory(category models.ExpenseCategoryForm, sessionToken, licenseKey string) (int64, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermExpenseCategoryManage)
+	if err != nil {
 		return 0, err
 	}
-	return a.expenseCategoryHandler.CreateExpenseCategory(category, userID)
+	return a.expenseCategoryHandler.CreateExpenseCategory(category, user.ID)
 }
 
 // GetExpenseCategories returns all active expense categories
-func (a *App) GetExpenseCategories(licenseKey string) ([]models.ExpenseCategory, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) GetExpenseCategories(sessionToken, licenseKey string) ([]models.ExpenseCategory, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermExpenseView); err != nil {
 		return nil, err
 	}
 	return a.expenseCategoryHandler.GetExpenseCategories()
 }
 
 // GetExpenseCategoriesPaginated returns paginated expense categories
-func (a *App) GetExpenseCategoriesPaginated(page, pageSize int, licenseKey string) (*models.ExpenseCategoriesResponse, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) GetExpenseCategoriesPaginated(page, pageSize int, sessionToken, licenseKey string) (*models.ExpenseCategoriesResponse, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermExpenseView); err != nil {
 		return nil, err
 	}
 	return a.expenseCategoryHandler.GetExpenseCategoriesPaginated(page, pageSize)
 }
 
 // UpdateExpenseCategory updates an expense category
-func (a *App) UpdateExpenseCategory(id int, category models.ExpenseCategoryForm, userID int, licenseKey string) error {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) UpdateExpenseCategory(id int, category models.ExpenseCategoryForm, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermExpenseCategoryManage)
+	if err != nil {
 		return err
 	}
-	return a.expenseCategoryHandler.UpdateExpenseCategory(id, category, userID)
+	return a.expenseCategoryHandler.UpdateExpenseCategory(id, category, user.ID)
 }
 
 // DeleteExpenseCategory deletes an expense category (soft delete)
-func (a *App) DeleteExpenseCategory(id int, licenseKey string) error {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) DeleteExpenseCategory(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermExpenseCategoryManage)
+	if err != nil {
 		return err
 	}
-	return a.expenseCategoryHandler.DeleteExpenseCategory(id)
+	return a.expenseCategoryHandler.DeleteExpenseCategory(id, user.ID)
 }
 
 // PermanentlyDeleteExpenseCategory permanently deletes an expense category (hard delete)
-func (a *App) PermanentlyDeleteExpenseCategory(id int, licenseKey string) error {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) PermanentlyDeleteExpenseCategory(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermExpenseCategoryDeletePermanent)
+	if err != nil {
 		return err
 	}
-	return a.expenseCategoryHandler.PermanentlyDeleteExpenseCategory(id)
+	return a.expenseCategoryHandler.PermanentlyDeleteExpenseCategory(id, user.ID)
 }
 
-// Session Management Methods
+// Expense Management Methods
 
-// CreateSession creates a new session
-func (a *App) CreateSession(session models.SessionForm, licenseKey string) (int64, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+// CreateExpense records a new clinic expense
+func (a *App) CreateExpense(expense models.ExpenseForm, sessionToken, licenseKey string) (int64, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermExpenseCreate)
+	if err != nil {
 		return 0, err
 	}
-	return a.sessionHandler.CreateSession(session)
+	return a.expenseHandler.CreateExpense(expense, user.ID)
 }
 
-// GetSessions returns paginated sessions
-func (a *App) GetSessions(page int, filters models.SessionFilters, licenseKey string) (models.SessionsResponse, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
-		return models.SessionsResponse{}, err
+// GetExpense returns a specific expense by id
+func (a *App) GetExpense(id int, sessionToken, licenseKey string) (*models.Expense, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermExpenseView); err != nil {
+		return nil, err
 	}
-	// Convert empty filters to nil for backward compatibility
-	var filterPtr *models.SessionFilters
-	if filters.PatientID != nil || filters.Status != nil || filters.DentistID != nil ||
-		filters.DateFrom != nil || filters.DateTo != nil || len(filters.ProcedureIDs) > 0 {
+	return a.expenseHandler.GetExpense(id)
+}
+
+// GetExpensesPaginated returns paginated expenses filtered by category, date range, vendor and payment status
+func (a *App) GetExpensesPaginated(page, pageSize int, filters models.ExpenseFilters, sessionToken, licenseKey string) (*models.ExpensesResponse, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermExpenseView); err != nil {
+		return nil, err
+	}
+	// Convert empty filters to nil
+	var filterPtr *models.ExpenseFilters
+	if filters.CategoryID != nil || filters.DateFrom != nil || filters.DateTo != nil ||
+		filters.VendorName != nil || filters.PaymentStatus != nil {
 		filterPtr = &filters
 	}
-	return a.sessionHandler.GetSessions(page, filterPtr)
+	return a.expenseHandler.GetExpensesPaginated(page, pageSize, filterPtr)
 }
 
-// GetSession returns a specific session by ID
-func (a *App) GetSession(id int, licenseKey string) (models.Session, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
-		return models.Session{}, err
+// UpdateExpense updates an expense
+func (a *App) UpdateExpense(id int, expense models.ExpenseForm, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermExpenseUpdate)
+	if err != nil {
+		return err
 	}
-	return a.sessionHandler.GetSession(id)
+	return a.expenseHandler.UpdateExpense(id, expense, user.ID)
 }
 
-// UpdateSession updates an existing session
-func (a *App) UpdateSession(session models.Session, items []models.SessionItemForm, licenseKey string) error {
-	if err := a.checkLicense(licenseKey); err != nil {
+// DeleteExpense deletes an expense and its payments
+func (a *App) DeleteExpense(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermExpenseDelete)
+	if err != nil {
 		return err
 	}
-	return a.sessionHandler.UpdateSession(session, items)
+	return a.expenseHandler.DeleteExpense(id, user.ID)
 }
 
-// DeleteSession deletes a session
-func (a *App) DeleteSession(id int, licenseKey string) error {
-	if err := a.checkLicense(licenseKey); err != nil {
-		return err
+// GetExpensePaymentDetails returns expense payment summary and history
+func (a *App) GetExpensePaymentDetails(expenseID int, sessionToken, licenseKey string) (*models.ExpensePaymentDetails, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermExpenseView); err != nil {
+		return nil, err
 	}
-	return a.sessionHandler.DeleteSession(id)
+	return a.expenseHandler.GetExpensePaymentDetails(expenseID)
+}
+
+// CreateExpensePayment records a (partial) payment for an expense
+func (a *App) CreateExpensePayment(expenseID int, amount int, paymentDate string, paymentMethod string, note string, sessionToken, licenseKey string) (*models.ExpensePaymentDetails, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermExpenseApprove)
+	if err != nil {
+		return nil, err
+	}
+	return a.expenseHandler.CreateExpensePayment(expenseID, amount, paymentDate, paymentMethod, note, user.ID)
+}
+
+// DeleteExpensePayment deletes an expense payment and returns the updated expense payment details
+func (a *App) DeleteExpensePayment(paymentID int, sessionToken, licenseKey string) (*models.ExpensePaymentDetails, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermExpenseApprove)
+	if err != nil {
+		return nil, err
+	}
+	return a.expenseHandler.DeleteExpensePayment(paymentID, user.ID)
+}
+
+// Session Management Methods
+
+// CreateSession creates a new session
+func (a *App) CreateSession(session models.SessionForm, sessionToken, licenseKey string) (int64, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSessionCreate)
+	if err != nil {
+		return 0, err
+	}
+	return a.sessionHandler.CreateSession(session, user.ID)
+}
+
+// GetSessions returns paginated sessions
+func (a *App) GetSessions(page int, filters models.SessionFilters, sessionToken, licenseKey string) (models.SessionsResponse, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermSessionView); err != nil {
+		return models.SessionsResponse{}, err
+	}
+	// Convert empty filters to nil for backward compatibility
+	var filterPtr *models.SessionFilters
+	if filters.PatientID != nil || filters.Status != nil || filters.DentistID != nil ||
+		filters.DateFrom != nil || filters.DateTo != nil || len(filters.ProcedureIDs) > 0 {
+		filterPtr = &filters
+	}
+	return a.sessionHandler.GetSessions(page, filterPtr)
+}
+
+// GetSession returns a specific session by ID
+func (a *App) GetSession(id int, sessionToken, licenseKey string) (models.Session, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermSessionView); err != nil {
+		return models.Session{}, err
+	}
+	return a.sessionHandler.GetSession(id)
+}
+
+// UpdateSession updates an existing session
+func (a *App) UpdateSession(session models.Session, items []models.SessionItemForm, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSessionUpdate)
+	if err != nil {
+		return err
+	}
+	return a.sessionHandler.UpdateSession(session, items, user.ID)
+}
+
+// DeleteSession deletes a session
+func (a *App) DeleteSession(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSessionDelete)
+	if err != nil {
+		return err
+	}
+	return a.sessionHandler.DeleteSession(id, user.ID)
 }
 
 // Invoice Management Methods
 
 // CreateInvoice creates an invoice from a session
-func (a *App) CreateInvoice(sessionID int, licenseKey string) (*models.Invoice, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) CreateInvoice(sessionID int, sessionToken, licenseKey string) (*models.Invoice, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermInvoiceCreate)
+	if err != nil {
 		return nil, err
 	}
-	return a.invoiceHandler.CreateInvoice(sessionID)
+	return a.invoiceHandler.CreateInvoice(sessionID, user.ID)
 }
 
 // GetInvoiceBySession gets an invoice by session ID
-func (a *App) GetInvoiceBySession(sessionID int, licenseKey string) (*models.Invoice, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) GetInvoiceBySession(sessionID int, sessionToken, licenseKey string) (*models.Invoice, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermInvoiceView); err != nil {
 		return nil, err
 	}
 	return a.invoiceHandler.GetInvoiceBySession(sessionID)
 }
 
 // PreviewInvoice returns preview data for invoice confirmation
-func (a *App) PreviewInvoice(sessionID int, licenseKey string) (*models.InvoicePreview, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) PreviewInvoice(sessionID int, sessionToken, licenseKey string) (*models.InvoicePreview, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermInvoiceView); err != nil {
 		return nil, err
 	}
 	return a.invoiceHandler.PreviewInvoice(sessionID)
 }
 
 // GetInvoiceOverview returns aggregated invoice stats for today, this week, and this month
-func (a *App) GetInvoiceOverview(licenseKey string) (*models.InvoiceOverview, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) GetInvoiceOverview(sessionToken, licenseKey string) (*models.InvoiceOverview, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermInvoiceView); err != nil {
 		return nil, err
 	}
 	return a.invoiceHandler.GetInvoiceOverview()
 }
 
 // GetInvoices returns paginated invoices for the financials dashboard
-func (a *App) GetInvoices(page int, pageSize int, licenseKey string) (*models.InvoiceListResponse, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) GetInvoices(page int, pageSize int, sessionToken, licenseKey string) (*models.InvoiceListResponse, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermInvoiceView); err != nil {
 		return nil, err
 	}
 	return a.invoiceHandler.GetInvoices(page, pageSize)
 }
 
+// ExportInvoicePDF saves a printable PDF of the invoice in the patient's folder and returns its path
+func (a *App) ExportInvoicePDF(invoiceID int, sessionToken, licenseKey string) (string, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermInvoiceView); err != nil {
+		return "", err
+	}
+	clinic, err := a.clinicBranding(licenseKey)
+	if err != nil {
+		return "", err
+	}
+	return a.invoiceHandler.ExportInvoicePDF(invoiceID, clinic)
+}
+
+// clinicBranding returns the header and footer printed on invoices and prescriptions. The clinic
+// name falls back to the one on the license when none is set.
+func (a *App) clinicBranding(licenseKey string) (pdfdoc.Clinic, error) {
+	settings, err := a.settingsHandler.GetClinicSettings()
+	if err != nil {
+		return pdfdoc.Clinic{}, err
+	}
+	clinic := pdfdoc.Clinic{
+		Name:      settings.Name,
+		Address:   settings.Address,
+		Phone:     settings.Phone,
+		TaxNumber: settings.TaxNumber,
+		Logo:      settings.Logo,
+		LogoType:  settings.LogoType,
+		Currency:  settings.CurrencySymbol,
+		Footer:    settings.InvoiceFooter,
+	}
+	if clinic.Name == "" {
+		if info, err := a.licenseService.ValidateLicense(licenseKey); err == nil {
+			clinic.Name = info.Clinic
+		}
+	}
+	return clinic, nil
+}
+
 // GetInvoicePaymentDetails returns invoice payment summary and history
-func (a *App) GetInvoicePaymentDetails(invoiceID int, licenseKey string) (*models.InvoicePaymentDetails, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) GetInvoicePaymentDetails(invoiceID int, sessionToken, licenseKey string) (*models.InvoicePaymentDetails, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermPaymentView); err != nil {
 		return nil, err
 	}
 	return a.invoiceHandler.GetInvoicePaymentDetails(invoiceID)
 }
 
 // CreateInvoicePayment records a payment for an invoice
-func (a *App) CreateInvoicePayment(invoiceID int, amount int, paymentDate string, note string, licenseKey string) (*models.InvoicePaymentDetails, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) CreateInvoicePayment(invoiceID int, amount int, paymentDate string, note string, sessionToken, licenseKey string) (*models.InvoicePaymentDetails, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermPaymentCreate)
+	if err != nil {
 		return nil, err
 	}
-	return a.invoiceHandler.CreatePayment(invoiceID, amount, paymentDate, note)
+	return a.invoiceHandler.CreatePayment(invoiceID, amount, paymentDate, note, user.ID)
 }
 
 // GetInvoicePayments returns paginated payments linked to invoices
-func (a *App) GetInvoicePayments(page int, pageSize int, licenseKey string) (*models.PaymentListResponse, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) GetInvoicePayments(page int, pageSize int, sessionToken, licenseKey string) (*models.PaymentListResponse, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermPaymentView); err != nil {
 		return nil, err
 	}
 	return a.paymentHandler.GetInvoicePayments(page, pageSize)
@@ -519,135 +1027,755 @@ func (a *App) GetInvoicePayments(page int, pageSize int, licenseKey string) (*mo
 // Work Type Management Methods
 
 // CreateWorkType creates a new work type
-func (a *App) CreateWorkType(workType models.WorkTypeForm, userID int, licenseKey string) (int64, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) CreateWorkType(workType models.WorkTypeForm, sessionToken, licenseKey string) (int64, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermLabManage)
+	if err != nil {
 		return 0, err
 	}
-	return a.workTypeHandler.CreateWorkType(workType, userID)
+	return a.workTypeHandler.CreateWorkType(workType, user.ID)
 }
 
 // GetWorkTypesPaginated returns paginated work types
-func (a *App) GetWorkTypesPaginated(page, pageSize int, licenseKey string) (*models.WorkTypesResponse, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) GetWorkTypesPaginated(page, pageSize int, sessionToken, licenseKey string) (*models.WorkTypesResponse, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermLabView); err != nil {
 		return nil, err
 	}
 	return a.workTypeHandler.GetWorkTypesPaginated(page, pageSize)
 }
 
 // UpdateWorkType updates a work type
-func (a *App) UpdateWorkType(id int, workType models.WorkTypeForm, userID int, licenseKey string) error {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) UpdateWorkType(id int, workType models.WorkTypeForm, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermLabManage)
+	if err != nil {
 		return err
 	}
-	return a.workTypeHandler.UpdateWorkType(id, workType, userID)
+	return a.workTypeHandler.UpdateWorkType(id, workType, user.ID)
 }
 
 // DeleteWorkType deletes a work type
-func (a *App) DeleteWorkType(id int, licenseKey string) error {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) DeleteWorkType(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermLabManage)
+	if err != nil {
 		return err
 	}
-	return a.workTypeHandler.DeleteWorkType(id)
+	return a.workTypeHandler.DeleteWorkType(id, user.ID)
 }
 
 // Color Shade Management Methods
 
 // CreateColorShade creates a new color shade
-func (a *App) CreateColorShade(shade models.ColorShadeForm, userID int, licenseKey string) (int64, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) CreateColorShade(shade models.ColorShadeForm, sessionToken, licenseKey string) (int64, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermLabManage)
+	if err != nil {
 		return 0, err
 	}
-	return a.colorShadeHandler.CreateColorShade(shade, userID)
+	return a.colorShadeHandler.CreateColorShade(shade, user.ID)
 }
 
 // GetColorShadesPaginated returns paginated color shades
-func (a *App) GetColorShadesPaginated(page, pageSize int, licenseKey string) (*models.ColorShadesResponse, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) GetColorShadesPaginated(page, pageSize int, sessionToken, licenseKey string) (*models.ColorShadesResponse, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermLabView); err != nil {
 		return nil, err
 	}
 	return a.colorShadeHandler.GetColorShadesPaginated(page, pageSize)
 }
 
 // UpdateColorShade updates a color shade
-func (a *App) UpdateColorShade(id int, shade models.ColorShadeForm, userID int, licenseKey string) error {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) UpdateColorShade(id int, shade models.ColorShadeForm, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermLabManage)
+	if err != nil {
 		return err
 	}
-	return a.colorShadeHandler.UpdateColorShade(id, shade, userID)
+	return a.colorShadeHandler.UpdateColorShade(id, shade, user.ID)
 }
 
 // DeleteColorShade deletes a color shade
-func (a *App) DeleteColorShade(id int, licenseKey string) error {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) DeleteColorShade(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermLabManage)
+	if err != nil {
 		return err
 	}
-	return a.colorShadeHandler.DeleteColorShade(id)
+	return a.colorShadeHandler.DeleteColorShade(id, user.ID)
 }
 
 // Dental Lab Management Methods
 
 // CreateDentalLab creates a new dental lab
-func (a *App) CreateDentalLab(lab models.DentalLabForm, licenseKey string) (int64, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) CreateDentalLab(lab models.DentalLabForm, sessionToken, licenseKey string) (int64, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermLabManage)
+	if err != nil {
 		return 0, err
 	}
-	return a.dentalLabHandler.CreateDentalLab(lab)
+	return a.dentalLabHandler.CreateDentalLab(lab, user.ID)
 }
 
 // GetDentalLabsPaginated returns paginated dental labs
-func (a *App) GetDentalLabsPaginated(page, pageSize int, licenseKey string) (*models.DentalLabsResponse, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) GetDentalLabsPaginated(page, pageSize int, sessionToken, licenseKey string) (*models.DentalLabsResponse, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermLabView); err != nil {
 		return nil, err
 	}
 	return a.dentalLabHandler.GetDentalLabsPaginated(page, pageSize)
 }
 
 // GetDentalLab returns a specific dental lab by id
-func (a *App) GetDentalLab(id int, licenseKey string) (*models.DentalLab, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) GetDentalLab(id int, sessionToken, licenseKey string) (*models.DentalLab, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermLabView); err != nil {
 		return nil, err
 	}
 	return a.dentalLabHandler.GetDentalLab(id)
 }
 
 // UpdateDentalLab updates a dental lab
-func (a *App) UpdateDentalLab(id int, lab models.DentalLabForm, licenseKey string) error {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) UpdateDentalLab(id int, lab models.DentalLabForm, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermLabManage)
+	if err != nil {
 		return err
 	}
-	return a.dentalLabHandler.UpdateDentalLab(id, lab)
+	return a.dentalLabHandler.UpdateDentalLab(id, lab, user.ID)
 }
 
 // DeleteDentalLab deletes a dental lab
-func (a *App) DeleteDentalLab(id int, licenseKey string) error {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) DeleteDentalLab(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermLabManage)
+	if err != nil {
 		return err
 	}
-	return a.dentalLabHandler.DeleteDentalLab(id)
+	return a.dentalLabHandler.DeleteDentalLab(id, user.ID)
 }
 
 // Lab Order Management Methods
 
 // GetLabOrdersPaginated returns paginated lab orders
-func (a *App) GetLabOrdersPaginated(page, pageSize int, searchOrderNumber, searchPatientName, searchLabName, statusFilter, licenseKey string) (*models.LabOrdersResponse, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) GetLabOrdersPaginated(page, pageSize int, searchOrderNumber, searchPatientName, searchLabName, statusFilter, sessionToken, licenseKey string) (*models.LabOrdersResponse, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermLabOrderView); err != nil {
 		return nil, err
 	}
 	return a.labOrderHandler.GetLabOrdersPaginated(page, pageSize, searchOrderNumber, searchPatientName, searchLabName, statusFilter)
 }
 
 // GetLabOrder returns a specific lab order by id
-func (a *App) GetLabOrder(id int, licenseKey string) (*models.LabOrderDetail, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) GetLabOrder(id int, sessionToken, licenseKey string) (*models.LabOrderDetail, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermLabOrderView); err != nil {
 		return nil, err
 	}
 	return a.labOrderHandler.GetLabOrder(id)
 }
 
 // CreateLabOrder creates a new lab order
-func (a *App) CreateLabOrder(order models.LabOrderForm, userID int, licenseKey string) (*models.CreateLabOrderResponse, error) {
-	if err := a.checkLicense(licenseKey); err != nil {
+func (a *App) CreateLabOrder(order models.LabOrderForm, sessionToken, licenseKey string) (*models.CreateLabOrderResponse, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermLabOrderCreate)
+	if err != nil {
+		return nil, err
+	}
+	return a.labOrderHandler.CreateLabOrder(order, user.ID)
+}
+
+// Audit Log Methods
+
+// GetAuditLog returns paginated audit log entries, newest first (admin only)
+func (a *App) GetAuditLog(page, pageSize int, filters *models.AuditLogFilters, sessionToken, licenseKey string) (*models.AuditLogResponse, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermAuditView); err != nil {
+		return nil, err
+	}
+	return a.auditHandler.GetAuditLog(page, pageSize, filters)
+}
+
+// Backup Methods
+
+// ListBackups returns the stored backups, newest first (admin only)
+func (a *App) ListBackups(sessionToken, licenseKey string) ([]backup.Info, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermBackupManage); err != nil {
+		return nil, err
+	}
+	return a.backupManager.List()
+}
+
+// CreateBackup makes a backup now, encrypted if the backup settings say so (admin only)
+func (a *App) CreateBackup(sessionToken, licenseKey string) (*backup.Info, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermBackupManage); err != nil {
+		return nil, err
+	}
+	passphrase, err := a.backupHandler.BackupPassphrase()
+	if err != nil {
+		return nil, err
+	}
+	return a.backupManager.Create(passphrase, "manual")
+}
+
+// VerifyBackup checks a backup's checksums and database integrity without restoring it (admin only)
+func (a *App) VerifyBackup(name, passphrase string, sessionToken, licenseKey string) (*backup.Manifest, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermBackupManage); err != nil {
+		return nil, err
+	}
+	return a.backupManager.Verify(name, passphrase)
+}
+
+// RestoreBackup replaces all clinic data with a verified backup (admin only). The current data is
+// backed up first. Sessions are restored from the backup too, so the user must log in again.
+func (a *App) RestoreBackup(name, passphrase string, sessionToken, licenseKey string) error {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermBackupManage); err != nil {
+		return err
+	}
+	safetyPassphrase, err := a.backupHandler.BackupPassphrase()
+	if err != nil {
+		return err
+	}
+	return a.backupManager.Restore(name, passphrase, safetyPassphrase)
+}
+
+// GetBackupSettings returns the automatic backup schedule (admin only)
+func (a *App) GetBackupSettings(sessionToken, licenseKey string) (*models.BackupSettings, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermBackupManage); err != nil {
+		return nil, err
+	}
+	return a.backupHandler.GetBackupSettings()
+}
+
+// SaveBackupSettings updates the automatic backup schedule (admin only)
+func (a *App) SaveBackupSettings(settings models.BackupSettings, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermBackupManage)
+	if err != nil {
+		return err
+	}
+	return a.backupHandler.SaveBackupSettings(settings, user.ID)
+}
+
+// Clinic Settings Methods
+
+// GetClinicSettings returns the clinic profile and shared preferences (any signed-in user)
+func (a *App) GetClinicSettings(sessionToken, licenseKey string) (*models.ClinicSettings, error) {
+	if _, err := a.currentUser(sessionToken, licenseKey); err != nil {
+		return nil, err
+	}
+	return a.settingsHandler.GetClinicSettings()
+}
+
+// SaveClinicSettings updates the clinic profile, numbering prefixes and defaults (admin only)
+func (a *App) SaveClinicSettings(settings models.ClinicSettings, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSettingsManage)
+	if err != nil {
+		return err
+	}
+	return a.settingsHandler.SaveClinicSettings(settings, user.ID)
+}
+
+// SetClinicLogo replaces the clinic logo with a PNG or JPEG data: URL, or removes it when empty (admin only)
+func (a *App) SetClinicLogo(dataURL string, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSettingsManage)
+	if err != nil {
+		return err
+	}
+	return a.settingsHandler.SetClinicLogo(dataURL, user.ID)
+}
+
+// SetTheme changes the colour theme for every workstation (any signed-in user)
+func (a *App) SetTheme(theme string, sessionToken, licenseKey string) error {
+	user, err := a.currentUser(sessionToken, licenseKey)
+	if err != nil {
+		return err
+	}
+	return a.settingsHandler.SetTheme(theme, user.ID)
+}
+
+// GetReminderSettings returns the appointment reminder settings without the SMS API key or SMTP password (admin only)
+func (a *App) GetReminderSettings(sessionToken, licenseKey string) (*models.ReminderSettings, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermSettingsManage); err != nil {
+		return nil, err
+	}
+	return a.reminderHandler.GetReminderSettings()
+}
+
+// SaveReminderSettings updates the appointment reminder settings (admin only)
+func (a *App) SaveReminderSettings(settings models.ReminderSettings, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSettingsManage)
+	if err != nil {
+		return err
+	}
+	return a.reminderHandler.SaveReminderSettings(settings, user.ID)
+}
+
+// GetOutboundMessages returns a page of queued and sent reminders, optionally with one status
+func (a *App) GetOutboundMessages(status string, page, pageSize int, sessionToken, licenseKey string) (*models.OutboundMessageList, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentView); err != nil {
+		return nil, err
+	}
+	return a.reminderHandler.GetOutboundMessages(status, page, pageSize)
+}
+
+// RetryOutboundMessage queues a failed or cancelled reminder again
+func (a *App) RetryOutboundMessage(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentManage)
+	if err != nil {
+		return err
+	}
+	return a.reminderHandler.RetryOutboundMessage(id, user.ID)
+}
+
+// CancelOutboundMessage stops a pending reminder from being sent
+func (a *App) CancelOutboundMessage(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentManage)
+	if err != nil {
+		return err
+	}
+	return a.reminderHandler.CancelOutboundMessage(id, user.ID)
+}
+
+// GetWaitlist returns the waiting list entries with a status, or those still waiting or offered a slot when status is empty
+func (a *App) GetWaitlist(status string, sessionToken, licenseKey string) ([]models.WaitlistEntry, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentView); err != nil {
+		return nil, err
+	}
+	return a.waitlistHandler.GetWaitlist(status)
+}
+
+// AddWaitlistEntry puts a patient on the waiting list for an earlier appointment
+func (a *App) AddWaitlistEntry(entry models.WaitlistEntry, sessionToken, licenseKey string) (int64, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentManage)
+	if err != nil {
+		return 0, err
+	}
+	return a.waitlistHandler.AddWaitlistEntry(entry, user.ID)
+}
+
+// UpdateWaitlistEntry changes a waiting list entry
+func (a *App) UpdateWaitlistEntry(entry models.WaitlistEntry, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentManage)
+	if err != nil {
+		return err
+	}
+	return a.waitlistHandler.UpdateWaitlistEntry(entry, user.ID)
+}
+
+// RemoveWaitlistEntry takes a patient off the waiting list
+func (a *App) RemoveWaitlistEntry(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentManage)
+	if err != nil {
+		return err
+	}
+	return a.waitlistHandler.RemoveWaitlistEntry(id, user.ID)
+}
+
+// FindWaitlistMatches ranks the waiting patients who fit a freed slot
+func (a *App) FindWaitlistMatches(slot models.WaitlistSlot, sessionToken, licenseKey string) ([]models.WaitlistMatch, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentView); err != nil {
+		return nil, err
+	}
+	return a.waitlistHandler.FindWaitlistMatches(slot)
+}
+
+// OfferWaitlistSlot records that a waiting patient has been offered a slot
+func (a *App) OfferWaitlistSlot(id int, slot models.WaitlistSlot, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentManage)
+	if err != nil {
+		return err
+	}
+	return a.waitlistHandler.OfferWaitlistSlot(id, slot, user.ID)
+}
+
+// DeclineWaitlistOffer puts a patient who turned down a slot back on the waiting list
+func (a *App) DeclineWaitlistOffer(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentManage)
+	if err != nil {
+		return err
+	}
+	return a.waitlistHandler.DeclineWaitlistOffer(id, user.ID)
+}
+
+// BookWaitlistSlot books a waiting patient into a slot, moving their current booking if they have one
+func (a *App) BookWaitlistSlot(id int, slot models.WaitlistSlot, sessionToken, licenseKey string) (int64, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermAppointmentManage)
+	if err != nil {
+		return 0, err
+	}
+	return a.waitlistHandler.BookWaitlistSlot(id, slot, user.ID)
+}
+
+// GetToothChart returns the patient's current tooth chart
+func (a *App) GetToothChart(patientID int, sessionToken, licenseKey string) ([]models.ToothCondition, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermPatientView); err != nil {
+		return nil, err
+	}
+	return a.toothChartHandler.GetToothChart(patientID)
+}
+
+// GetToothHistory returns every condition charted on one tooth, or on all teeth when tooth is 0
+func (a *App) GetToothHistory(patientID, tooth int, sessionToken, licenseKey string) ([]models.ToothCondition, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermPatientView); err != nil {
+		return nil, err
+	}
+	return a.toothChartHandler.GetToothHistory(patientID, tooth)
+}
+
+// AddToothCondition charts a condition on a tooth by hand
+func (a *App) AddToothCondition(form models.ToothConditionForm, sessionToken, licenseKey string) (int64, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSessionUpdate)
+	if err != nil {
+		return 0, err
+	}
+	return a.toothChartHandler.AddToothCondition(form, user.ID)
+}
+
+// ResolveToothCondition takes a condition off the current tooth chart
+func (a *App) ResolveToothCondition(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSessionUpdate)
+	if err != nil {
+		return err
+	}
+	return a.toothChartHandler.ResolveToothCondition(id, user.ID)
+}
+
+// GetTreatmentPlans returns the patient's treatment plans with their totals
+func (a *App) GetTreatmentPlans(patientID int, sessionToken, licenseKey string) ([]models.TreatmentPlan, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermSessionView); err != nil {
+		return nil, err
+	}
+	return a.treatmentPlanHandler.GetTreatmentPlans(patientID)
+}
+
+// CreateTreatmentPlan proposes a treatment plan for a patient
+func (a *App) CreateTreatmentPlan(plan models.TreatmentPlan, sessionToken, licenseKey string) (int64, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSessionUpdate)
+	if err != nil {
+		return 0, err
+	}
+	return a.treatmentPlanHandler.CreateTreatmentPlan(plan, user.ID)
+}
+
+// UpdateTreatmentPlan saves a treatment plan and its items
+func (a *App) UpdateTreatmentPlan(plan models.TreatmentPlan, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSessionUpdate)
+	if err != nil {
+		return err
+	}
+	return a.treatmentPlanHandler.UpdateTreatmentPlan(plan, user.ID)
+}
+
+// DeleteTreatmentPlan deletes a treatment plan
+func (a *App) DeleteTreatmentPlan(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSessionDelete)
+	if err != nil {
+		return err
+	}
+	return a.treatmentPlanHandler.DeleteTreatmentPlan(id, user.ID)
+}
+
+// AcceptTreatmentPlan records which proposed items the patient accepted
+func (a *App) AcceptTreatmentPlan(id int, acceptedItemIDs []int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermTreatmentPlanAccept)
+	if err != nil {
+		return err
+	}
+	return a.treatmentPlanHandler.AcceptTreatmentPlan(id, acceptedItemIDs, user.ID)
+}
+
+// PlanSessionItems returns session lines for the plan items about to be performed
+func (a *App) PlanSessionItems(itemIDs []int, sessionToken, licenseKey string) ([]models.SessionItemForm, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermSessionCreate); err != nil {
+		return nil, err
+	}
+	return a.treatmentPlanHandler.PlanSessionItems(itemIDs)
+}
+
+// GetPerioExams returns the patient's periodontal exams with their summaries
+func (a *App) GetPerioExams(patientID int, sessionToken, licenseKey string) ([]models.PerioExam, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermPatientView); err != nil {
+		return nil, err
+	}
+	return a.perioHandler.GetPerioExams(patientID)
+}
+
+// GetPerioExam returns a periodontal exam with its measurements
+func (a *App) GetPerioExam(id int, sessionToken, licenseKey string) (models.PerioExam, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermPatientView); err != nil {
+		return models.PerioExam{}, err
+	}
+	return a.perioHandler.GetPerioExam(id)
+}
+
+// CreatePerioExam records a periodontal exam
+func (a *App) CreatePerioExam(exam models.PerioExam, sessionToken, licenseKey string) (int64, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSessionUpdate)
+	if err != nil {
+		return 0, err
+	}
+	return a.perioHandler.CreatePerioExam(exam, user.ID)
+}
+
+// UpdatePerioExam saves a periodontal exam
+func (a *App) UpdatePerioExam(exam models.PerioExam, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSessionUpdate)
+	if err != nil {
+		return err
+	}
+	return a.perioHandler.UpdatePerioExam(exam, user.ID)
+}
+
+// DeletePerioExam deletes a periodontal exam
+func (a *App) DeletePerioExam(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSessionDelete)
+	if err != nil {
+		return err
+	}
+	return a.perioHandler.DeletePerioExam(id, user.ID)
+}
+
+// ComparePerioExams shows the change between two periodontal exams of a patient
+func (a *App) ComparePerioExams(firstID, secondID int, sessionToken, licenseKey string) (models.PerioComparison, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermPatientView); err != nil {
+		return models.PerioComparison{}, err
+	}
+	return a.perioHandler.ComparePerioExams(firstID, secondID)
+}
+
+// GetNoteTemplates returns the clinical note templates
+func (a *App) GetNoteTemplates(sessionToken, licenseKey string) ([]models.NoteTemplate, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermSessionView); err != nil {
+		return nil, err
+	}
+	return a.clinicalNoteHandler.GetNoteTemplates()
+}
+
+// CreateNoteTemplate adds a clinical note template
+func (a *App) CreateNoteTemplate(template models.NoteTemplate, sessionToken, licenseKey string) (int64, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSessionUpdate)
+	if err != nil {
+		return 0, err
+	}
+	return a.clinicalNoteHandler.CreateNoteTemplate(template, user.ID)
+}
+
+// UpdateNoteTemplate saves a clinical note template
+func (a *App) UpdateNoteTemplate(template models.NoteTemplate, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSessionUpdate)
+	if err != nil {
+		return err
+	}
+	return a.clinicalNoteHandler.UpdateNoteTemplate(template, user.ID)
+}
+
+// DeleteNoteTemplate deletes a clinical note template
+func (a *App) DeleteNoteTemplate(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSessionUpdate)
+	if err != nil {
+		return err
+	}
+	return a.clinicalNoteHandler.DeleteNoteTemplate(id, user.ID)
+}
+
+// GetClinicalNote returns the SOAP note of a session
+func (a *App) GetClinicalNote(sessionID int, sessionToken, licenseKey string) (models.ClinicalNote, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermClinicalNoteView); err != nil {
+		return models.ClinicalNote{}, err
+	}
+	return a.clinicalNoteHandler.GetClinicalNote(sessionID)
+}
+
+// ApplyNoteTemplate appends a filled-in template to a draft note
+func (a *App) ApplyNoteTemplate(note models.ClinicalNote, templateID int, values models.NoteTemplateValues, sessionToken, licenseKey string) (models.ClinicalNote, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermSessionUpdate); err != nil {
+		return models.ClinicalNote{}, err
+	}
+	return a.clinicalNoteHandler.ApplyNoteTemplate(note, templateID, values)
+}
+
+// SaveClinicalNote saves a new version of a session's SOAP note
+func (a *App) SaveClinicalNote(note models.ClinicalNote, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSessionUpdate)
+	if err != nil {
+		return err
+	}
+	return a.clinicalNoteHandler.SaveClinicalNote(note, user.ID)
+}
+
+// SignClinicalNote signs and locks a session's SOAP note
+func (a *App) SignClinicalNote(sessionID int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSessionUpdate)
+	if err != nil {
+		return err
+	}
+	return a.clinicalNoteHandler.SignClinicalNote(sessionID, user.ID)
+}
+
+// GetClinicalNoteVersions returns the saved versions of a session's SOAP note
+func (a *App) GetClinicalNoteVersions(sessionID int, sessionToken, licenseKey string) ([]models.ClinicalNoteVersion, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermClinicalNoteView); err != nil {
+		return nil, err
+	}
+	return a.clinicalNoteHandler.GetClinicalNoteVersions(sessionID)
+}
+
+// SearchClinicalNotes finds SOAP notes containing text, for one patient or all of them when patientID is 0
+func (a *App) SearchClinicalNotes(text string, patientID int, sessionToken, licenseKey string) ([]models.ClinicalNoteMatch, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermClinicalNoteView); err != nil {
+		return nil, err
+	}
+	return a.clinicalNoteHandler.SearchClinicalNotes(text, patientID)
+}
+
+// GetDrugs returns the drug catalogue
+func (a *App) GetDrugs(sessionToken, licenseKey string) ([]models.Drug, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermSessionView); err != nil {
+		return nil, err
+	}
+	return a.prescriptionHandler.GetDrugs()
+}
+
+// CreateDrug adds a drug to the catalogue
+func (a *App) CreateDrug(drug models.Drug, sessionToken, licenseKey string) (int64, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermProcedureManage)
+	if err != nil {
+		return 0, err
+	}
+	return a.prescriptionHandler.CreateDrug(drug, user.ID)
+}
+
+// UpdateDrug saves a drug of the catalogue
+func (a *App) UpdateDrug(drug models.Drug, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermProcedureManage)
+	if err != nil {
+		return err
+	}
+	return a.prescriptionHandler.UpdateDrug(drug, user.ID)
+}
+
+// DeleteDrug removes a drug from the catalogue
+func (a *App) DeleteDrug(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermProcedureManage)
+	if err != nil {
+		return err
+	}
+	return a.prescriptionHandler.DeleteDrug(id, user.ID)
+}
+
+// GetDrugRules returns the allergy and interaction rules prescriptions are checked against
+func (a *App) GetDrugRules(sessionToken, licenseKey string) ([]models.DrugRule, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermProcedureView); err != nil {
+		return nil, err
+	}
+	return a.prescriptionHandler.GetDrugRules()
+}
+
+// CreateDrugRule adds an allergy or interaction rule
+func (a *App) CreateDrugRule(rule models.DrugRule, sessionToken, licenseKey string) (int64, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermProcedureManage)
+	if err != nil {
+		return 0, err
+	}
+	return a.prescriptionHandler.CreateDrugRule(rule, user.ID)
+}
+
+// UpdateDrugRule saves an allergy or interaction rule
+func (a *App) UpdateDrugRule(rule models.DrugRule, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermProcedureManage)
+	if err != nil {
+		return err
+	}
+	return a.prescriptionHandler.UpdateDrugRule(rule, user.ID)
+}
+
+// DeleteDrugRule deletes an allergy or interaction rule
+func (a *App) DeleteDrugRule(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermProcedureManage)
+	if err != nil {
+		return err
+	}
+	return a.prescriptionHandler.DeleteDrugRule(id, user.ID)
+}
+
+// CheckPrescription returns the allergy and interaction alerts of a draft prescription
+func (a *App) CheckPrescription(sessionID int, items []models.PrescriptionItem, sessionToken, licenseKey string) ([]models.PrescriptionAlert, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermSessionUpdate); err != nil {
+		return nil, err
+	}
+	return a.prescriptionHandler.CheckPrescription(sessionID, items)
+}
+
+// CreatePrescription writes a prescription during a session
+func (a *App) CreatePrescription(prescription models.Prescription, sessionToken, licenseKey string) (int64, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSessionUpdate)
+	if err != nil {
+		return 0, err
+	}
+	return a.prescriptionHandler.CreatePrescription(prescription, user.ID)
+}
+
+// DeletePrescription deletes a prescription
+func (a *App) DeletePrescription(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermSessionDelete)
+	if err != nil {
+		return err
+	}
+	return a.prescriptionHandler.DeletePrescription(id, user.ID)
+}
+
+// GetSessionPrescriptions returns the prescriptions written during a session
+func (a *App) GetSessionPrescriptions(sessionID int, sessionToken, licenseKey string) ([]models.Prescription, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermPrescriptionView); err != nil {
+		return nil, err
+	}
+	return a.prescriptionHandler.GetSessionPrescriptions(sessionID)
+}
+
+// GetPatientPrescriptions returns every prescription of a patient
+func (a *App) GetPatientPrescriptions(patientID int, sessionToken, licenseKey string) ([]models.Prescription, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermPrescriptionView); err != nil {
+		return nil, err
+	}
+	return a.prescriptionHandler.GetPatientPrescriptions(patientID)
+}
+
+// ExportPrescriptionPDF saves a printable PDF of the prescription in the patient's folder and returns its path
+func (a *App) ExportPrescriptionPDF(id int, sessionToken, licenseKey string) (string, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermPrescriptionView); err != nil {
+		return "", err
+	}
+	clinic, err := a.clinicBranding(licenseKey)
+	if err != nil {
+		return "", err
+	}
+	return a.prescriptionHandler.ExportPrescriptionPDF(id, clinic)
+}
+
+// GetPatientMedicalAlerts returns the patient's medical alerts, most severe first
+func (a *App) GetPatientMedicalAlerts(patientID int, sessionToken, licenseKey string) ([]models.MedicalAlert, error) {
+	if _, err := a.authorize(sessionToken, licenseKey, models.PermPatientView); err != nil {
 		return nil, err
 	}
-	return a.labOrderHandler.CreateLabOrder(order, userID)
+	return a.medicalAlertHandler.GetPatientMedicalAlerts(patientID)
+}
+
+// CreateMedicalAlert adds a medical alert to a patient
+func (a *App) CreateMedicalAlert(alert models.MedicalAlert, sessionToken, licenseKey string) (int64, error) {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermMedicalAlertManage)
+	if err != nil {
+		return 0, err
+	}
+	return a.medicalAlertHandler.CreateMedicalAlert(alert, user.ID)
+}
+
+// UpdateMedicalAlert saves a medical alert
+func (a *App) UpdateMedicalAlert(alert models.MedicalAlert, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermMedicalAlertManage)
+	if err != nil {
+		return err
+	}
+	return a.medicalAlertHandler.UpdateMedicalAlert(alert, user.ID)
+}
+
+// ReviewMedicalAlert records that a medical alert was checked today and sets its next review date
+func (a *App) ReviewMedicalAlert(id int, nextReviewDate string, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermMedicalAlertManage)
+	if err != nil {
+		return err
+	}
+	return a.medicalAlertHandler.ReviewMedicalAlert(id, nextReviewDate, user.ID)
+}
+
+// DeleteMedicalAlert removes a medical alert that no longer applies
+func (a *App) DeleteMedicalAlert(id int, sessionToken, licenseKey string) error {
+	user, err := a.authorize(sessionToken, licenseKey, models.PermMedicalAlertManage)
+	if err != nil {
+		return err
+	}
+	return a.medicalAlertHandler.DeleteMedicalAlert(id, user.ID)
 }
@@ -0,0 +1,568 @@
+// Package backup creates and restores snapshots of the clinic data.
+//
+// A backup is a zip archive holding a consistent copy of the SQLite database (taken online with
+// VACUUM INTO), the patient_data folder and a manifest with a SHA-256 checksum of every file.
+// Archives can be encrypted with a passphrase (see crypto.go). Restores verify the checksums and
+// the database integrity before anything is replaced, and load the database through the SQLite
+// backup API so the running app keeps its open connection.
+package backup
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"DentistApp/database"
+
+	"modernc.org/sqlite"
+)
+
+const (
+	filePrefix      = "dentist-backup-"
+	plainExt        = ".zip"
+	encryptedExt    = ".zip.enc"
+	nameTimeLayout  = "20060102-150405.000"
+	manifestName    = "manifest.json"
+	databaseName    = "dentist.db"
+	manifestFormat  = 1
+	dataDirArchived = "patient_data"
+	createdAtLayout = "2006-01-02 15:04:05"
+)
+
+// Info describes a backup file
+type Info struct {
+	Name      string `json:"name"`
+	CreatedAt string `json:"created_at"`
+	Size      int64  `json:"size"`
+	Encrypted bool   `json:"encrypted"`
+}
+
+// Manifest is stored in every archive and lists its files with their SHA-256 checksums
+type Manifest struct {
+	Format        int               `json:"format"`
+	CreatedAt     string            `json:"created_at"`
+	Reason        string            `json:"reason"`
+	SchemaVersion int               `json:"schema_version"`
+	Files         map[string]string `json:"files"`
+}
+
+// Manager creates, lists and restores backups of one database and patient data folder
+type Manager struct {
+	db      *sql.DB
+	dir     string
+	dataDir string
+	now     func() time.Time
+	// mu keeps backups and restores from running at the same time
+	mu sync.Mutex
+}
+
+// NewManager creates a Manager that stores backups in dir and includes the dataDir folder
+func NewManager(db *sql.DB, dir, dataDir string) *Manager {
+	return &Manager{db: db, dir: dir, dataDir: dataDir, now: time.Now}
+}
+
+// Dir returns the folder backups are stored in
+func (m *Manager) Dir() string {
+	return m.dir
+}
+
+// Create writes a new backup and returns its info. If passphrase is not empty the archive is
+// encrypted. reason is recorded in the manifest (e.g. "manual", "scheduled", "pre-restore").
+func (m *Manager) Create(passphrase, reason string) (*Info, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.create(passphrase, reason)
+}
+
+func (m *Manager) create(passphrase, reason string) (*Info, error) {
+	if err := os.MkdirAll(m.dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create backup folder: %v", err)
+	}
+
+	tmpDir, err := os.MkdirTemp(m.dir, ".tmp-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary folder: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// VACUUM INTO writes a consistent, compacted copy without blocking other connections for long
+	snapshot := filepath.Join(tmpDir, databaseName)
+	if _, err := m.db.Exec(`VACUUM INTO ?`, snapshot); err != nil {
+		return nil, fmt.Errorf("failed to snapshot database: %v", err)
+	}
+	schemaVersion, err := database.SchemaVersion(m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt := m.now()
+	zipPath := filepath.Join(tmpDir, "backup.zip")
+	manifest := &Manifest{
+		Format:        manifestFormat,
+		CreatedAt:     createdAt.Format(createdAtLayout),
+		Reason:        reason,
+		SchemaVersion: schemaVersion,
+		Files:         map[string]string{},
+	}
+	if err := m.writeArchive(zipPath, snapshot, manifest); err != nil {
+		return nil, err
+	}
+
+	name := filePrefix + createdAt.Format(nameTimeLayout) + plainExt
+	if passphrase != "" {
+		name = filePrefix + createdAt.Format(nameTimeLayout) + encryptedExt
+	}
+	finalPath := filepath.Join(m.dir, name)
+
+	if passphrase != "" {
+		encryptedPath := filepath.Join(tmpDir, "backup.enc")
+		if err := encryptFile(encryptedPath, zipPath, passphrase); err != nil {
+			return nil, fmt.Errorf("failed to encrypt backup: %v", err)
+		}
+		zipPath = encryptedPath
+	}
+	if err := os.Rename(zipPath, finalPath); err != nil {
+		return nil, fmt.Errorf("failed to store backup: %v", err)
+	}
+
+	return m.info(name)
+}
+
+// writeArchive zips the database snapshot and the data folder, then adds the manifest
+func (m *Manager) writeArchive(zipPath, snapshot string, manifest *Manifest) error {
+	out, err := os.OpenFile(zipPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %v", err)
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+	if err := addFile(zw, databaseName, snapshot, manifest); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(m.dataDir); err == nil {
+		err = filepath.Walk(m.dataDir, func(path string, fi os.FileInfo, err error) error {
+			if err != nil || !fi.Mode().IsRegular() {
+				return err
+			}
+			rel, err := filepath.Rel(m.dataDir, path)
+			if err != nil {
+				return err
+			}
+			return addFile(zw, dataDirArchived+"/"+filepath.ToSlash(rel), path, manifest)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to archive patient data: %v", err)
+		}
+	}
+
+	w, err := zw.Create(manifestName)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finish archive: %v", err)
+	}
+	return out.Close()
+}
+
+func addFile(zw *zip.Writer, name, path string, manifest *Manifest) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w, hash), in); err != nil {
+		return fmt.Errorf("failed to archive %s: %v", name, err)
+	}
+	manifest.Files[name] = hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
+
+func encryptFile(dst, src, passphrase string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if err := encrypt(out, in, passphrase); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// List returns the backups in the backup folder, newest first
+func (m *Manager) List() ([]Info, error) {
+	entries, err := os.ReadDir(m.dir)
+	if os.IsNotExist(err) {
+		return []Info{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup folder: %v", err)
+	}
+
+	backups := make([]Info, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || !isBackupName(entry.Name()) {
+			continue
+		}
+		info, err := m.info(entry.Name())
+		if err != nil {
+			continue
+		}
+		backups = append(backups, *info)
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Name > backups[j].Name })
+	return backups, nil
+}
+
+// Latest returns the newest backup, or nil if there is none
+func (m *Manager) Latest() (*Info, error) {
+	backups, err := m.List()
+	if err != nil || len(backups) == 0 {
+		return nil, err
+	}
+	return &backups[0], nil
+}
+
+// Prune deletes all but the newest keep backups
+func (m *Manager) Prune(keep int) error {
+	if keep < 1 {
+		return fmt.Errorf("at least one backup must be kept")
+	}
+	backups, err := m.List()
+	if err != nil {
+		return err
+	}
+	for _, b := range backups[min(keep, len(backups)):] {
+		if err := os.Remove(filepath.Join(m.dir, b.Name)); err != nil {
+			return fmt.Errorf("failed to delete old backup %s: %v", b.Name, err)
+		}
+	}
+	return nil
+}
+
+func (m *Manager) info(name string) (*Info, error) {
+	fi, err := os.Stat(filepath.Join(m.dir, name))
+	if err != nil {
+		return nil, err
+	}
+	stamp := strings.TrimPrefix(name, filePrefix)
+	stamp = strings.TrimSuffix(strings.TrimSuffix(stamp, encryptedExt), plainExt)
+	createdAt, err := time.ParseInLocation(nameTimeLayout, stamp, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	return &Info{
+		Name:      name,
+		CreatedAt: createdAt.Format(createdAtLayout),
+		Size:      fi.Size(),
+		Encrypted: strings.HasSuffix(name, encryptedExt),
+	}, nil
+}
+
+func isBackupName(name string) bool {
+	return strings.HasPrefix(name, filePrefix) &&
+		(strings.HasSuffix(name, plainExt) || strings.HasSuffix(name, encryptedExt)) &&
+		filepath.Base(name) == name
+}
+
+// openedBackup is a verified archive extracted to a temporary folder
+type openedBackup struct {
+	dir      string
+	manifest Manifest
+}
+
+// Verify checks that the backup can be opened, every file matches its checksum and the
+// database passes an integrity check. It returns the backup's manifest.
+func (m *Manager) Verify(name, passphrase string) (*Manifest, error) {
+	opened, err := m.open(name, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(opened.dir)
+	return &opened.manifest, nil
+}
+
+// open decrypts and extracts a backup into a temporary folder and verifies it
+func (m *Manager) open(name, passphrase string) (*openedBackup, error) {
+	if !isBackupName(name) {
+		return nil, fmt.Errorf("invalid backup name")
+	}
+	path := filepath.Join(m.dir, name)
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("backup not found")
+	}
+
+	tmpDir, err := os.MkdirTemp(m.dir, ".restore-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary folder: %v", err)
+	}
+	opened := &openedBackup{dir: tmpDir}
+	ok := false
+	defer func() {
+		if !ok {
+			os.RemoveAll(tmpDir)
+		}
+	}()
+
+	zipPath := path
+	if strings.HasSuffix(name, encryptedExt) {
+		if passphrase == "" {
+			return nil, ErrPassphraseRequired
+		}
+		zipPath = filepath.Join(tmpDir, "backup.zip")
+		if err := decryptFile(zipPath, path, passphrase); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := extractArchive(zipPath, filepath.Join(tmpDir, "files"), &opened.manifest); err != nil {
+		return nil, err
+	}
+	if opened.manifest.SchemaVersion > database.LatestVersion() {
+		return nil, fmt.Errorf("backup was made by a newer version of the app (schema %d), please update first", opened.manifest.SchemaVersion)
+	}
+	if err := checkDatabase(filepath.Join(tmpDir, "files", databaseName)); err != nil {
+		return nil, err
+	}
+
+	ok = true
+	return opened, nil
+}
+
+func decryptFile(dst, src, passphrase string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if err := decrypt(out, in, passphrase); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// extractArchive extracts every file listed in the manifest into dir and checks its checksum
+func extractArchive(zipPath, dir string, manifest *Manifest) error {
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return fmt.Errorf("backup is not a valid archive: %v", err)
+	}
+	defer zr.Close()
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	manifestFile, ok := files[manifestName]
+	if !ok {
+		return fmt.Errorf("backup has no manifest")
+	}
+	rc, err := manifestFile.Open()
+	if err != nil {
+		return err
+	}
+	err = json.NewDecoder(rc).Decode(manifest)
+	rc.Close()
+	if err != nil {
+		return fmt.Errorf("backup manifest is invalid: %v", err)
+	}
+	if manifest.Format != manifestFormat {
+		return fmt.Errorf("unsupported backup format %d", manifest.Format)
+	}
+	if _, ok := manifest.Files[databaseName]; !ok {
+		return fmt.Errorf("backup does not contain a database")
+	}
+
+	for name, checksum := range manifest.Files {
+		f, ok := files[name]
+		if !ok {
+			return fmt.Errorf("backup is missing %s", name)
+		}
+		target, err := safeJoin(dir, name)
+		if err != nil {
+			return err
+		}
+		if err := extractFile(f, target, checksum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// safeJoin joins an archive path to dir, rejecting paths that would escape it
+func safeJoin(dir, name string) (string, error) {
+	if name != databaseName && !strings.HasPrefix(name, dataDirArchived+"/") {
+		return "", fmt.Errorf("unexpected file in backup: %s", name)
+	}
+	target := filepath.Join(dir, filepath.FromSlash(name))
+	if !strings.HasPrefix(target, filepath.Clean(dir)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("unsafe path in backup: %s", name)
+	}
+	return target, nil
+}
+
+func extractFile(f *zip.File, target, checksum string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(out, hash), rc); err != nil {
+		return fmt.Errorf("failed to extract %s: %v", f.Name, err)
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != checksum {
+		return fmt.Errorf("checksum mismatch for %s, the backup is corrupted", f.Name)
+	}
+	return out.Close()
+}
+
+// checkDatabase runs SQLite's integrity check on a database file
+func checkDatabase(path string) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRow(`PRAGMA integrity_check`).Scan(&result); err != nil {
+		return fmt.Errorf("backup database cannot be read: %v", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("backup database failed the integrity check: %s", result)
+	}
+	return nil
+}
+
+// Restore verifies a backup and replaces the live database and patient data with its contents.
+// A "pre-restore" backup of the current data is written first, encrypted with safetyPassphrase
+// if it is set. Older backups are migrated to the current schema after loading.
+func (m *Manager) Restore(name, passphrase, safetyPassphrase string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	opened, err := m.open(name, passphrase)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(opened.dir)
+
+	if _, err := m.create(safetyPassphrase, "pre-restore"); err != nil {
+		return fmt.Errorf("failed to back up current data before restoring: %v", err)
+	}
+
+	if err := m.restoreDatabase(filepath.Join(opened.dir, "files", databaseName)); err != nil {
+		return err
+	}
+	if err := database.Migrate(m.db); err != nil {
+		return fmt.Errorf("restored database could not be migrated: %v", err)
+	}
+	return m.restoreDataDir(filepath.Join(opened.dir, "files", dataDirArchived))
+}
+
+// restoreDatabase copies the database file over the live database with the SQLite backup API
+func (m *Manager) restoreDatabase(path string) error {
+	conn, err := m.db.Conn(context.Background())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		restorer, ok := driverConn.(interface {
+			NewRestore(string) (*sqlite.Backup, error)
+		})
+		if !ok {
+			return fmt.Errorf("database driver does not support restore")
+		}
+		restore, err := restorer.NewRestore(path)
+		if err != nil {
+			return fmt.Errorf("failed to start restore: %v", err)
+		}
+		for {
+			more, err := restore.Step(-1)
+			if err != nil {
+				restore.Finish()
+				return fmt.Errorf("failed to restore database: %v", err)
+			}
+			if !more {
+				break
+			}
+		}
+		return restore.Finish()
+	})
+}
+
+// restoreDataDir swaps the data folder for the restored one
+func (m *Manager) restoreDataDir(restored string) error {
+	if _, err := os.Stat(restored); os.IsNotExist(err) {
+		if err := os.MkdirAll(restored, 0755); err != nil {
+			return err
+		}
+	}
+
+	old := m.dataDir + ".old-" + m.now().Format(nameTimeLayout)
+	if _, err := os.Stat(m.dataDir); err == nil {
+		if err := os.Rename(m.dataDir, old); err != nil {
+			return fmt.Errorf("failed to move current patient data aside: %v", err)
+		}
+	}
+	if err := os.Rename(restored, m.dataDir); err != nil {
+		os.Rename(old, m.dataDir)
+		return fmt.Errorf("failed to restore patient data: %v", err)
+	}
+	return os.RemoveAll(old)
+}
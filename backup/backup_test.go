@@ -0,0 +1,179 @@
+package backup
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"DentistApp/database"
+)
+
+// newTestManager returns a manager for a migrated database and data folder in a temporary directory
+func newTestManager(t *testing.T) (*Manager, *sql.DB) {
+	t.Helper()
+	root := t.TempDir()
+	db, err := sql.Open("sqlite", filepath.Join(root, "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	dataDir := filepath.Join(root, "patient_data")
+	if err := os.MkdirAll(filepath.Join(dataDir, "1_Ali"), 0755); err != nil {
+		t.Fatalf("failed to create data folder: %v", err)
+	}
+	return NewManager(db, filepath.Join(root, "backups"), dataDir), db
+}
+
+func countPatients(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM patients").Scan(&count); err != nil {
+		t.Fatalf("failed to count patients: %v", err)
+	}
+	return count
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	// Larger than one chunk so the chunk sequence is exercised
+	plain := bytes.Repeat([]byte("dental record "), chunkSize/7)
+
+	var sealed bytes.Buffer
+	if err := encrypt(&sealed, bytes.NewReader(plain), "correct horse"); err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := decrypt(&out, bytes.NewReader(sealed.Bytes()), "correct horse"); err != nil {
+		t.Fatalf("decrypt failed: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), plain) {
+		t.Fatal("decrypted data does not match the original")
+	}
+
+	if err := decrypt(&bytes.Buffer{}, bytes.NewReader(sealed.Bytes()), "wrong horse"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("expected ErrWrongPassphrase, got %v", err)
+	}
+
+	truncated := sealed.Bytes()[:sealed.Len()-chunkSize/2]
+	if err := decrypt(&bytes.Buffer{}, bytes.NewReader(truncated), "correct horse"); err == nil {
+		t.Error("expected truncated backup to fail")
+	}
+}
+
+func TestCreateAndRestore(t *testing.T) {
+	for _, passphrase := range []string{"", "backup-secret"} {
+		manager, db := newTestManager(t)
+		record := filepath.Join(manager.dataDir, "1_Ali", "xray.txt")
+		if err := os.WriteFile(record, []byte("before"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec("INSERT INTO patients (name, phone, age, gender) VALUES ('Ali', '0100', 30, 'male')"); err != nil {
+			t.Fatal(err)
+		}
+
+		info, err := manager.Create(passphrase, "manual")
+		if err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+		if info.Encrypted != (passphrase != "") {
+			t.Errorf("expected encrypted=%v, got %v", passphrase != "", info.Encrypted)
+		}
+
+		manifest, err := manager.Verify(info.Name, passphrase)
+		if err != nil {
+			t.Fatalf("verify failed: %v", err)
+		}
+		if manifest.SchemaVersion != database.LatestVersion() {
+			t.Errorf("expected schema version %d, got %d", database.LatestVersion(), manifest.SchemaVersion)
+		}
+		if passphrase != "" {
+			if _, err := manager.Verify(info.Name, ""); !errors.Is(err, ErrPassphraseRequired) {
+				t.Errorf("expected ErrPassphraseRequired, got %v", err)
+			}
+			if _, err := manager.Verify(info.Name, "not-the-secret"); !errors.Is(err, ErrWrongPassphrase) {
+				t.Errorf("expected ErrWrongPassphrase, got %v", err)
+			}
+		}
+
+		// Change the data after the backup
+		if _, err := db.Exec("DELETE FROM patients"); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(record, []byte("after"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := manager.Restore(info.Name, passphrase, ""); err != nil {
+			t.Fatalf("restore failed: %v", err)
+		}
+		if got := countPatients(t, db); got != 1 {
+			t.Errorf("expected 1 patient after restore, got %d", got)
+		}
+		content, err := os.ReadFile(record)
+		if err != nil || string(content) != "before" {
+			t.Errorf("expected restored patient file, got %q (%v)", content, err)
+		}
+
+		// The data replaced by the restore is kept in a pre-restore backup
+		backups, err := manager.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(backups) != 2 {
+			t.Fatalf("expected original and pre-restore backups, got %d", len(backups))
+		}
+	}
+}
+
+func TestRestoreRejectsInvalidNames(t *testing.T) {
+	manager, _ := newTestManager(t)
+	for _, name := range []string{"../test.db", "dentist-backup-x.txt", "dentist-backup-../../x.zip"} {
+		if err := manager.Restore(name, "", ""); err == nil {
+			t.Errorf("expected %q to be rejected", name)
+		}
+	}
+}
+
+func TestPruneAndScheduler(t *testing.T) {
+	manager, _ := newTestManager(t)
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.Local)
+	manager.now = func() time.Time { return now }
+
+	scheduler := NewScheduler(manager, func() (Schedule, error) {
+		return Schedule{Enabled: true, IntervalHours: 24, Keep: 2}, nil
+	})
+
+	if !scheduler.RunDue(now) {
+		t.Fatal("expected a backup when none exist")
+	}
+	if scheduler.RunDue(now.Add(time.Hour)) {
+		t.Error("expected no backup before the interval has passed")
+	}
+
+	for day := 1; day <= 3; day++ {
+		now = now.Add(24 * time.Hour)
+		if !scheduler.RunDue(now) {
+			t.Fatalf("expected a backup on day %d", day)
+		}
+	}
+
+	backups, err := manager.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 backups after pruning, got %d", len(backups))
+	}
+	if backups[0].CreatedAt != now.Format(createdAtLayout) {
+		t.Errorf("expected newest backup first, got %s", backups[0].CreatedAt)
+	}
+}
@@ -0,0 +1,172 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Encrypted archives start with encryptedMagic, followed by the scrypt salt and a random nonce
+// prefix. The zip is then stored as a sequence of AES-256-GCM sealed chunks, each preceded by its
+// sealed length. The high bit of the length marks the final chunk, so a truncated file fails to
+// decrypt instead of restoring partially.
+var encryptedMagic = []byte("DABK1")
+
+const (
+	saltSize        = 16
+	noncePrefixSize = 8
+	chunkSize       = 64 * 1024
+	finalChunkFlag  = 1 << 31
+
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// ErrWrongPassphrase is returned when an encrypted backup cannot be opened with the passphrase
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted backup")
+
+// ErrPassphraseRequired is returned when opening an encrypted backup without a passphrase
+var ErrPassphraseRequired = errors.New("this backup is encrypted, a passphrase is required")
+
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, 32)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(prefix []byte, counter uint32) []byte {
+	nonce := make([]byte, noncePrefixSize+4)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], counter)
+	return nonce
+}
+
+// encrypt reads src to the end and writes it to dst encrypted with the passphrase
+func encrypt(dst io.Writer, src io.Reader, passphrase string) error {
+	salt := make([]byte, saltSize)
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	if _, err := rand.Read(prefix); err != nil {
+		return err
+	}
+
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+
+	header := append(append(append([]byte{}, encryptedMagic...), salt...), prefix...)
+	if _, err := dst.Write(header); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReaderSize(src, chunkSize)
+	buf := make([]byte, chunkSize)
+	for counter := uint32(0); ; counter++ {
+		n, err := io.ReadFull(reader, buf)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return err
+		}
+		_, peekErr := reader.Peek(1)
+		final := peekErr == io.EOF
+
+		aad := []byte{0}
+		if final {
+			aad[0] = 1
+		}
+		sealed := gcm.Seal(nil, chunkNonce(prefix, counter), buf[:n], aad)
+
+		length := uint32(len(sealed))
+		if final {
+			length |= finalChunkFlag
+		}
+		if err := binary.Write(dst, binary.BigEndian, length); err != nil {
+			return err
+		}
+		if _, err := dst.Write(sealed); err != nil {
+			return err
+		}
+		if final {
+			return nil
+		}
+	}
+}
+
+// decrypt reads an archive written by encrypt and writes the plaintext to dst
+func decrypt(dst io.Writer, src io.Reader, passphrase string) error {
+	header := make([]byte, len(encryptedMagic)+saltSize+noncePrefixSize)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return fmt.Errorf("failed to read backup header: %v", err)
+	}
+	if !bytes.Equal(header[:len(encryptedMagic)], encryptedMagic) {
+		return fmt.Errorf("not an encrypted backup")
+	}
+	salt := header[len(encryptedMagic) : len(encryptedMagic)+saltSize]
+	prefix := header[len(encryptedMagic)+saltSize:]
+
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+
+	maxSealed := uint32(chunkSize + gcm.Overhead())
+	for counter := uint32(0); ; counter++ {
+		var length uint32
+		if err := binary.Read(src, binary.BigEndian, &length); err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("backup is truncated")
+			}
+			return err
+		}
+		final := length&finalChunkFlag != 0
+		length &^= finalChunkFlag
+		if length > maxSealed {
+			return ErrWrongPassphrase
+		}
+
+		sealed := make([]byte, length)
+		if _, err := io.ReadFull(src, sealed); err != nil {
+			return fmt.Errorf("backup is truncated")
+		}
+
+		aad := []byte{0}
+		if final {
+			aad[0] = 1
+		}
+		plain, err := gcm.Open(nil, chunkNonce(prefix, counter), sealed, aad)
+		if err != nil {
+			return ErrWrongPassphrase
+		}
+		if _, err := dst.Write(plain); err != nil {
+			return err
+		}
+		if final {
+			return nil
+		}
+	}
+}
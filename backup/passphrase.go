@@ -0,0 +1,51 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PassphraseFile is the file holding the passphrase backups are encrypted with. It is kept outside
+// the database and the patient_data folder so the passphrase never ends up in the backups it protects.
+type PassphraseFile string
+
+// DefaultPassphraseFile returns the passphrase file in the user's configuration folder
+func DefaultPassphraseFile() (PassphraseFile, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the configuration folder: %v", err)
+	}
+	return PassphraseFile(filepath.Join(dir, "DentistApp", "backup-passphrase")), nil
+}
+
+// Load returns the stored passphrase, or "" if none has been set
+func (f PassphraseFile) Load() (string, error) {
+	data, err := os.ReadFile(string(f))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to read backup passphrase: %v", err)
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+// Save replaces the stored passphrase. The file is only readable by the current user.
+func (f PassphraseFile) Save(passphrase string) error {
+	path := string(f)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %v", filepath.Dir(path), err)
+	}
+	// Write a temporary file first so a failed save never leaves half a passphrase behind
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(passphrase+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to save backup passphrase: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save backup passphrase: %v", err)
+	}
+	return nil
+}
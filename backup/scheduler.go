@@ -0,0 +1,114 @@
+package backup
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Schedule controls automatic backups
+type Schedule struct {
+	Enabled       bool
+	IntervalHours int
+	// Keep is the number of backups to keep; older ones are deleted after each scheduled backup
+	Keep       int
+	Passphrase string
+}
+
+// checkInterval is how often the scheduler checks whether a backup is due. Due-ness is based on
+// the newest backup file, so the schedule survives app restarts.
+const checkInterval = 10 * time.Minute
+
+// Scheduler makes a backup whenever the newest one is older than the schedule's interval
+type Scheduler struct {
+	manager  *Manager
+	schedule func() (Schedule, error)
+	stop     chan struct{}
+	done     chan struct{}
+	mu       sync.Mutex
+}
+
+// NewScheduler creates a scheduler. schedule is called on every check so changes to the
+// settings apply without a restart.
+func NewScheduler(manager *Manager, schedule func() (Schedule, error)) *Scheduler {
+	return &Scheduler{manager: manager, schedule: schedule}
+}
+
+// Start runs the scheduler in the background until Stop is called
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go func(stop, done chan struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		s.RunDue(time.Now())
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				s.RunDue(now)
+			}
+		}
+	}(s.stop, s.done)
+}
+
+// Stop stops the scheduler and waits for a running backup to finish
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	stop, done := s.stop, s.done
+	s.stop, s.done = nil, nil
+	s.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// RunDue makes a backup if one is due at now and prunes old backups. It reports whether a
+// backup was made.
+func (s *Scheduler) RunDue(now time.Time) bool {
+	schedule, err := s.schedule()
+	if err != nil {
+		log.Printf("[Backup] Failed to load backup schedule: %v", err)
+		return false
+	}
+	if !schedule.Enabled || schedule.IntervalHours < 1 {
+		return false
+	}
+
+	latest, err := s.manager.Latest()
+	if err != nil {
+		log.Printf("[Backup] Failed to list backups: %v", err)
+		return false
+	}
+	if latest != nil {
+		last, err := time.ParseInLocation(createdAtLayout, latest.CreatedAt, time.Local)
+		if err == nil && now.Sub(last) < time.Duration(schedule.IntervalHours)*time.Hour {
+			return false
+		}
+	}
+
+	info, err := s.manager.Create(schedule.Passphrase, "scheduled")
+	if err != nil {
+		log.Printf("[Backup] Scheduled backup failed: %v", err)
+		return false
+	}
+	log.Printf("[Backup] Created scheduled backup %s", info.Name)
+
+	if schedule.Keep > 0 {
+		if err := s.manager.Prune(schedule.Keep); err != nil {
+			log.Printf("[Backup] Failed to delete old backups: %v", err)
+		}
+	}
+	return true
+}
@@ -0,0 +1,156 @@
+// Command licgen issues DentistApp license keys.
+//
+// Usage:
+//
+//	licgen keygen -out licgen.key
+//	licgen sign -key licgen.key -clinic "Smile Clinic" -expires 2027-12-31 -seats 5 -features backup,reminders
+//	licgen verify -pub <base64 public key> <license key>
+//
+// keygen writes the private key to -out and prints the public key, which must be set as
+// licensePublicKey in handlers/license_service.go. Keep the private key out of the repository.
+// The private key can also be passed in the LICGEN_PRIVATE_KEY environment variable.
+// Legacy-format keys are no longer issued; the app still accepts existing ones until
+// legacyKeysAcceptedUntil in handlers/license_service.go.
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"DentistApp/license"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "keygen":
+		err = runKeygen(os.Args[2:])
+	case "sign":
+		err = runSign(os.Args[2:])
+	case "verify":
+		err = runVerify(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "licgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: licgen <keygen|sign|verify> [flags]")
+}
+
+func runKeygen(args []string) error {
+	fs := flag.NewFlagSet("keygen", flag.ExitOnError)
+	out := fs.String("out", "licgen.key", "file to write the private key to")
+	fs.Parse(args)
+
+	if _, err := os.Stat(*out); err == nil {
+		return fmt.Errorf("%s already exists, refusing to overwrite", *out)
+	}
+
+	publicKey, privateKey, err := license.GenerateKeyPair()
+	if err != nil {
+		return fmt.Errorf("failed to generate key pair: %v", err)
+	}
+	if err := os.WriteFile(*out, []byte(license.EncodeKey(privateKey)+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write private key: %v", err)
+	}
+
+	fmt.Printf("private key written to %s\n", *out)
+	fmt.Printf("public key: %s\n", license.EncodeKey(publicKey))
+	return nil
+}
+
+func runSign(args []string) error {
+	fs := flag.NewFlagSet("sign", flag.ExitOnError)
+	keyFile := fs.String("key", "", "private key file (defaults to $LICGEN_PRIVATE_KEY)")
+	clinic := fs.String("clinic", "", "clinic name")
+	expires := fs.String("expires", "", "last valid day, YYYY-MM-DD")
+	seats := fs.Int("seats", 0, "number of active users allowed, 0 for unlimited")
+	features := fs.String("features", "", "comma separated feature flags")
+	fs.Parse(args)
+
+	privateKeyText := os.Getenv("LICGEN_PRIVATE_KEY")
+	if *keyFile != "" {
+		data, err := os.ReadFile(*keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read private key: %v", err)
+		}
+		privateKeyText = string(data)
+	}
+	if privateKeyText == "" {
+		return fmt.Errorf("a private key is required (-key or LICGEN_PRIVATE_KEY)")
+	}
+	privateKey, err := license.DecodePrivateKey(privateKeyText)
+	if err != nil {
+		return err
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Errorf("failed to generate license id: %v", err)
+	}
+
+	claims := license.Claims{
+		LicenseID: "LIC-" + strings.ToUpper(hex.EncodeToString(id)),
+		Clinic:    strings.TrimSpace(*clinic),
+		IssuedAt:  time.Now().Format(license.DateLayout),
+		ExpiresAt: *expires,
+		Seats:     *seats,
+		Features:  splitFeatures(*features),
+	}
+
+	key, err := license.Sign(claims, privateKey)
+	if err != nil {
+		return err
+	}
+	fmt.Println(key)
+	return nil
+}
+
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	pub := fs.String("pub", "", "base64 public key")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: licgen verify -pub <public key> <license key>")
+	}
+	publicKey, err := license.DecodePublicKey(*pub)
+	if err != nil {
+		return err
+	}
+
+	claims, err := license.Verify(fs.Arg(0), publicKey)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("license:  %s\nclinic:   %s\nissued:   %s\nexpires:  %s\nseats:    %d\nfeatures: %s\n",
+		claims.LicenseID, claims.Clinic, claims.IssuedAt, claims.ExpiresAt, claims.Seats, strings.Join(claims.Features, ","))
+	return nil
+}
+
+func splitFeatures(s string) []string {
+	features := make([]string, 0)
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			features = append(features, f)
+		}
+	}
+	return features
+}
@@ -0,0 +1,370 @@
+package database
+
+// baselineTables is the schema InitDB created before versioned migrations were introduced.
+// Migration 1 creates any of these tables that are missing, so it works for both new and
+// pre-migration databases.
+var baselineTables = []string{
+	// patients
+	`CREATE TABLE IF NOT EXISTS patients (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		phone TEXT NOT NULL,
+		age INTEGER NOT NULL,
+		gender TEXT NOT NULL,
+		occupation TEXT,
+		total_required INTEGER DEFAULT 0
+	);`,
+
+	// appointments
+	`CREATE TABLE IF NOT EXISTS appointments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		patient_id INTEGER NOT NULL,
+		datetime TEXT NOT NULL,
+		duration INTEGER,
+		notes TEXT,
+		FOREIGN KEY(patient_id) REFERENCES patients(id) ON DELETE CASCADE
+	);`,
+
+	// payments
+	`CREATE TABLE IF NOT EXISTS payments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		invoice_id INTEGER,
+		patient_id INTEGER NOT NULL,
+		payment_code TEXT UNIQUE,
+		amount INTEGER NOT NULL,
+		payment_date TEXT NOT NULL DEFAULT (datetime('now')),
+		note TEXT,
+		payment_method TEXT NOT NULL DEFAULT 'cash',
+		created_at TEXT NOT NULL DEFAULT (datetime('now')),
+		updated_at TEXT NOT NULL DEFAULT (datetime('now')),
+		FOREIGN KEY(invoice_id) REFERENCES invoices(id) ON DELETE CASCADE,
+		FOREIGN KEY(patient_id) REFERENCES patients(id)
+	);`,
+
+	// users
+	`CREATE TABLE IF NOT EXISTS users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT NOT NULL UNIQUE,
+		password_hash TEXT NOT NULL,
+		role TEXT NOT NULL DEFAULT 'Dentist'
+	);`,
+
+	// dental_procedures
+	`CREATE TABLE IF NOT EXISTS dental_procedures (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		price INTEGER NOT NULL,
+		created_at TEXT NOT NULL DEFAULT (datetime('now'))
+	);`,
+
+	// sessions
+	`CREATE TABLE IF NOT EXISTS sessions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		patient_id INTEGER NOT NULL,
+		dentist_id INTEGER NOT NULL,
+		session_date TEXT NOT NULL DEFAULT (datetime('now')),
+		total_amount INTEGER DEFAULT 0,
+		status TEXT DEFAULT 'completed',
+		notes TEXT,
+		FOREIGN KEY(patient_id) REFERENCES patients(id) ON DELETE CASCADE,
+		FOREIGN KEY(dentist_id) REFERENCES users(id)
+	);`,
+
+	// session_items
+	`CREATE TABLE IF NOT EXISTS session_items (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		session_id INTEGER NOT NULL,
+		procedure_id INTEGER,
+		item_name TEXT NOT NULL,
+		amount INTEGER NOT NULL,
+		FOREIGN KEY(session_id) REFERENCES sessions(id) ON DELETE CASCADE,
+		FOREIGN KEY(procedure_id) REFERENCES dental_procedures(id)
+	);`,
+
+	// invoices
+	`CREATE TABLE IF NOT EXISTS invoices (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		session_id INTEGER UNIQUE NOT NULL,
+		patient_id INTEGER NOT NULL,
+		invoice_number TEXT UNIQUE,
+		invoice_date TEXT NOT NULL DEFAULT (datetime('now')),
+		total_amount INTEGER NOT NULL,
+		status TEXT DEFAULT 'issued',
+		notes TEXT,
+		FOREIGN KEY(session_id) REFERENCES sessions(id) ON DELETE CASCADE,
+		FOREIGN KEY(patient_id) REFERENCES patients(id)
+	);`,
+
+	// expense_categories
+	`CREATE TABLE IF NOT EXISTS expense_categories (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE,
+		description TEXT,
+		color TEXT DEFAULT '#3498db',
+		budget_amount INTEGER DEFAULT 0,
+		budget_period TEXT NOT NULL DEFAULT 'monthly' CHECK(budget_period IN ('monthly', 'quarterly', 'yearly')),
+		expense_type TEXT NOT NULL DEFAULT 'operational' CHECK(expense_type IN ('operational', 'capital', 'personnel', 'marketing', 'administrative')),
+		is_tax_deductible BOOLEAN DEFAULT 1,
+		cost_center TEXT DEFAULT 'main',
+		account_code TEXT UNIQUE,
+		parent_category_id INTEGER,
+		is_active BOOLEAN DEFAULT 1,
+		requires_approval BOOLEAN DEFAULT 0,
+		approval_threshold INTEGER DEFAULT 0,
+		reporting_group TEXT,
+		sort_order INTEGER DEFAULT 0,
+		created_by INTEGER,
+		updated_by INTEGER,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(parent_category_id) REFERENCES expense_categories(id)
+	);`,
+
+	// expenses
+	`CREATE TABLE IF NOT EXISTS expenses (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		expense_code TEXT UNIQUE,
+		expense_date TEXT NOT NULL DEFAULT (datetime('now')),
+		description TEXT NOT NULL,
+		amount INTEGER NOT NULL,
+		category_id INTEGER NOT NULL,
+		payment_status TEXT DEFAULT 'unpaid' CHECK(payment_status IN ('unpaid', 'paid', 'partially_paid')),
+		payment_method TEXT DEFAULT 'cash' CHECK(payment_method IN ('cash', 'bank_transfer', 'check', 'card')),
+		vendor_name TEXT,
+		vendor_contact TEXT,
+		receipt_number TEXT,
+		notes TEXT,
+		receipt_file_path TEXT,
+		reporting_period TEXT,
+		is_recurring BOOLEAN DEFAULT 0,
+		recurring_period TEXT,
+		created_by INTEGER,
+		updated_by INTEGER,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(category_id) REFERENCES expense_categories(id),
+		FOREIGN KEY(created_by) REFERENCES users(id),
+		FOREIGN KEY(updated_by) REFERENCES users(id)
+	);`,
+
+	// expense_payments
+	`CREATE TABLE IF NOT EXISTS expense_payments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		expense_id INTEGER NOT NULL,
+		payment_code TEXT UNIQUE,
+		amount INTEGER NOT NULL,
+		payment_date TEXT NOT NULL DEFAULT (datetime('now')),
+		payment_method TEXT NOT NULL DEFAULT 'cash' CHECK(payment_method IN ('cash', 'bank_transfer', 'check', 'card')),
+		note TEXT,
+		created_by INTEGER,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(expense_id) REFERENCES expenses(id) ON DELETE CASCADE,
+		FOREIGN KEY(created_by) REFERENCES users(id)
+	);`,
+
+	// dental_labs
+	`CREATE TABLE IF NOT EXISTS dental_labs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE,
+		code TEXT UNIQUE,
+		contact_person TEXT,
+		phone_primary TEXT NOT NULL,
+		phone_secondary TEXT,
+		email TEXT,
+		specialties TEXT,
+		is_active BOOLEAN DEFAULT 1,
+		notes TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+
+	// color_shades
+	`CREATE TABLE IF NOT EXISTS color_shades (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE,
+		description TEXT,
+		hex_color TEXT,
+		is_active BOOLEAN DEFAULT 1,
+		sort_order INTEGER DEFAULT 0,
+		created_by INTEGER,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (created_by) REFERENCES users(id)
+	);`,
+
+	// work_types
+	`CREATE TABLE IF NOT EXISTS work_types (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE,
+		description TEXT,
+		sort_order INTEGER DEFAULT 0,
+		created_by INTEGER,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (created_by) REFERENCES users(id)
+	);`,
+
+	// lab_orders
+	`CREATE TABLE IF NOT EXISTS lab_orders (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		order_number TEXT UNIQUE NOT NULL,
+		patient_id INTEGER NOT NULL,
+		lab_id INTEGER NOT NULL,
+		created_by INTEGER NOT NULL,
+		work_type_id INTEGER NOT NULL,
+		description TEXT,
+		upper_left TEXT,
+		upper_right TEXT,
+		lower_left TEXT,
+		lower_right TEXT,
+		quantity INTEGER DEFAULT 1,
+		color_shade_id INTEGER,
+		lab_cost INTEGER,
+		order_date DATETIME DEFAULT CURRENT_TIMESTAMP,
+		status TEXT DEFAULT 'draft' CHECK(status IN ('draft', 'sent', 'delivered', 'cancelled')),
+		notes TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (patient_id) REFERENCES patients(id),
+		FOREIGN KEY (lab_id) REFERENCES dental_labs(id),
+		FOREIGN KEY (created_by) REFERENCES users(id),
+		FOREIGN KEY (work_type_id) REFERENCES work_types(id),
+		FOREIGN KEY (color_shade_id) REFERENCES color_shades(id)
+	);`,
+}
+
+// baselineIndexes are the indexes InitDB created before versioned migrations were introduced
+var baselineIndexes = []string{
+	`CREATE INDEX IF NOT EXISTS idx_lab_orders_created_at ON lab_orders(created_at DESC);`,
+	`CREATE INDEX IF NOT EXISTS idx_lab_orders_patient_id ON lab_orders(patient_id);`,
+	`CREATE INDEX IF NOT EXISTS idx_lab_orders_lab_id ON lab_orders(lab_id);`,
+	`CREATE INDEX IF NOT EXISTS idx_lab_orders_work_type_id ON lab_orders(work_type_id);`,
+	`CREATE INDEX IF NOT EXISTS idx_lab_orders_created_by ON lab_orders(created_by);`,
+	`CREATE INDEX IF NOT EXISTS idx_lab_orders_status ON lab_orders(status);`,
+	`CREATE INDEX IF NOT EXISTS idx_lab_orders_status_created_at ON lab_orders(status, created_at DESC);`,
+	`CREATE INDEX IF NOT EXISTS idx_patients_name ON patients(name);`,
+	`CREATE INDEX IF NOT EXISTS idx_dental_labs_name ON dental_labs(name);`,
+}
+
+// baselineColumn is a column that was added to an existing table with ALTER TABLE before
+// versioned migrations were introduced. Definitions are restricted to what SQLite accepts
+// in ADD COLUMN on a non-empty table (no UNIQUE, no non-constant defaults); the lost
+// constraints are restored with uniqueIndex and backfill.
+type baselineColumn struct {
+	table       string
+	name        string
+	definition  string
+	uniqueIndex bool   // create a unique index in place of the UNIQUE constraint
+	backfill    string // value expression for rows where the new column is NULL
+}
+
+// baselineColumns lists every column the old InitDB tried to add with ALTER TABLE
+var baselineColumns = []baselineColumn{
+	// patients
+	{table: "patients", name: "total_required", definition: "INTEGER DEFAULT 0"},
+	{table: "patients", name: "allergies", definition: "TEXT"},
+	{table: "patients", name: "current_medications", definition: "TEXT"},
+	{table: "patients", name: "medical_conditions", definition: "TEXT"},
+	{table: "patients", name: "smoking_status", definition: "INTEGER DEFAULT 0"},
+	{table: "patients", name: "pregnancy_status", definition: "INTEGER DEFAULT 0"},
+	{table: "patients", name: "dental_history", definition: "TEXT"},
+	{table: "patients", name: "special_notes", definition: "TEXT"},
+
+	// payments
+	{table: "payments", name: "invoice_id", definition: "INTEGER"},
+	{table: "payments", name: "payment_code", definition: "TEXT", uniqueIndex: true},
+	{table: "payments", name: "payment_date", definition: "TEXT", backfill: "datetime('now')"},
+	{table: "payments", name: "payment_method", definition: "TEXT DEFAULT 'cash'"},
+	{table: "payments", name: "created_at", definition: "TEXT", backfill: "datetime('now')"},
+	{table: "payments", name: "updated_at", definition: "TEXT", backfill: "datetime('now')"},
+
+	// expense_categories
+	{table: "expense_categories", name: "description", definition: "TEXT"},
+	{table: "expense_categories", name: "color", definition: "TEXT DEFAULT '#3498db'"},
+	{table: "expense_categories", name: "budget_amount", definition: "INTEGER DEFAULT 0"},
+	{table: "expense_categories", name: "budget_period", definition: "TEXT NOT NULL DEFAULT 'monthly'"},
+	{table: "expense_categories", name: "expense_type", definition: "TEXT NOT NULL DEFAULT 'operational'"},
+	{table: "expense_categories", name: "is_tax_deductible", definition: "BOOLEAN DEFAULT 1"},
+	{table: "expense_categories", name: "cost_center", definition: "TEXT DEFAULT 'main'"},
+	{table: "expense_categories", name: "account_code", definition: "TEXT", uniqueIndex: true},
+	{table: "expense_categories", name: "parent_category_id", definition: "INTEGER"},
+	{table: "expense_categories", name: "is_active", definition: "BOOLEAN DEFAULT 1"},
+	{table: "expense_categories", name: "requires_approval", definition: "BOOLEAN DEFAULT 0"},
+	{table: "expense_categories", name: "approval_threshold", definition: "INTEGER DEFAULT 0"},
+	{table: "expense_categories", name: "reporting_group", definition: "TEXT"},
+	{table: "expense_categories", name: "sort_order", definition: "INTEGER DEFAULT 0"},
+	{table: "expense_categories", name: "created_by", definition: "INTEGER"},
+	{table: "expense_categories", name: "updated_by", definition: "INTEGER"},
+	{table: "expense_categories", name: "created_at", definition: "DATETIME", backfill: "CURRENT_TIMESTAMP"},
+	{table: "expense_categories", name: "updated_at", definition: "DATETIME", backfill: "CURRENT_TIMESTAMP"},
+
+	// expenses
+	{table: "expenses", name: "expense_code", definition: "TEXT", uniqueIndex: true},
+	{table: "expenses", name: "expense_date", definition: "TEXT", backfill: "datetime('now')"},
+	{table: "expenses", name: "payment_status", definition: "TEXT DEFAULT 'unpaid'"},
+	{table: "expenses", name: "payment_method", definition: "TEXT DEFAULT 'cash'"},
+	{table: "expenses", name: "vendor_name", definition: "TEXT"},
+	{table: "expenses", name: "vendor_contact", definition: "TEXT"},
+	{table: "expenses", name: "receipt_number", definition: "TEXT"},
+	{table: "expenses", name: "notes", definition: "TEXT"},
+	{table: "expenses", name: "receipt_file_path", definition: "TEXT"},
+	{table: "expenses", name: "reporting_period", definition: "TEXT"},
+	{table: "expenses", name: "is_recurring", definition: "BOOLEAN DEFAULT 0"},
+	{table: "expenses", name: "recurring_period", definition: "TEXT"},
+	{table: "expenses", name: "created_by", definition: "INTEGER"},
+	{table: "expenses", name: "updated_by", definition: "INTEGER"},
+	{table: "expenses", name: "created_at", definition: "DATETIME", backfill: "CURRENT_TIMESTAMP"},
+	{table: "expenses", name: "updated_at", definition: "DATETIME", backfill: "CURRENT_TIMESTAMP"},
+
+	// expense_payments
+	{table: "expense_payments", name: "payment_code", definition: "TEXT", uniqueIndex: true},
+	{table: "expense_payments", name: "payment_date", definition: "TEXT", backfill: "datetime('now')"},
+	{table: "expense_payments", name: "payment_method", definition: "TEXT NOT NULL DEFAULT 'cash'"},
+	{table: "expense_payments", name: "note", definition: "TEXT"},
+	{table: "expense_payments", name: "created_by", definition: "INTEGER"},
+	{table: "expense_payments", name: "created_at", definition: "DATETIME", backfill: "CURRENT_TIMESTAMP"},
+	{table: "expense_payments", name: "updated_at", definition: "DATETIME", backfill: "CURRENT_TIMESTAMP"},
+
+	// dental_labs
+	{table: "dental_labs", name: "code", definition: "TEXT", uniqueIndex: true},
+	{table: "dental_labs", name: "contact_person", definition: "TEXT"},
+	{table: "dental_labs", name: "phone_secondary", definition: "TEXT"},
+	{table: "dental_labs", name: "email", definition: "TEXT"},
+	{table: "dental_labs", name: "specialties", definition: "TEXT"},
+	{table: "dental_labs", name: "is_active", definition: "BOOLEAN DEFAULT 1"},
+	{table: "dental_labs", name: "notes", definition: "TEXT"},
+	{table: "dental_labs", name: "created_at", definition: "DATETIME", backfill: "CURRENT_TIMESTAMP"},
+	{table: "dental_labs", name: "updated_at", definition: "DATETIME", backfill: "CURRENT_TIMESTAMP"},
+
+	// color_shades
+	{table: "color_shades", name: "description", definition: "TEXT"},
+	{table: "color_shades", name: "hex_color", definition: "TEXT"},
+	{table: "color_shades", name: "is_active", definition: "BOOLEAN DEFAULT 1"},
+	{table: "color_shades", name: "sort_order", definition: "INTEGER DEFAULT 0"},
+	{table: "color_shades", name: "created_by", definition: "INTEGER"},
+	{table: "color_shades", name: "created_at", definition: "DATETIME", backfill: "CURRENT_TIMESTAMP"},
+	{table: "color_shades", name: "updated_at", definition: "DATETIME", backfill: "CURRENT_TIMESTAMP"},
+
+	// work_types
+	{table: "work_types", name: "description", definition: "TEXT"},
+	{table: "work_types", name: "sort_order", definition: "INTEGER DEFAULT 0"},
+	{table: "work_types", name: "created_by", definition: "INTEGER"},
+	{table: "work_types", name: "created_at", definition: "DATETIME", backfill: "CURRENT_TIMESTAMP"},
+	{table: "work_types", name: "updated_at", definition: "DATETIME", backfill: "CURRENT_TIMESTAMP"},
+
+	// lab_orders
+	{table: "lab_orders", name: "description", definition: "TEXT"},
+	{table: "lab_orders", name: "upper_left", definition: "TEXT"},
+	{table: "lab_orders", name: "upper_right", definition: "TEXT"},
+	{table: "lab_orders", name: "lower_left", definition: "TEXT"},
+	{table: "lab_orders", name: "lower_right", definition: "TEXT"},
+	{table: "lab_orders", name: "quantity", definition: "INTEGER DEFAULT 1"},
+	{table: "lab_orders", name: "color_shade_id", definition: "INTEGER"},
+	{table: "lab_orders", name: "lab_cost", definition: "INTEGER"},
+	{table: "lab_orders", name: "order_date", definition: "DATETIME", backfill: "CURRENT_TIMESTAMP"},
+	{table: "lab_orders", name: "status", definition: "TEXT DEFAULT 'draft'"},
+	{table: "lab_orders", name: "notes", definition: "TEXT"},
+	{table: "lab_orders", name: "created_at", definition: "DATETIME", backfill: "CURRENT_TIMESTAMP"},
+	{table: "lab_orders", name: "updated_at", definition: "DATETIME", backfill: "CURRENT_TIMESTAMP"},
+}
@@ -0,0 +1,862 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Migration is a single, numbered schema change. Up runs inside a transaction that also
+// records the migration in schema_migrations, so a migration is either fully applied or not at all.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *sql.Tx) error
+}
+
+// AppliedMigration is a row of the schema_migrations table
+type AppliedMigration struct {
+	Version   int    `json:"version"`
+	Name      string `json:"name"`
+	AppliedAt string `json:"applied_at"`
+}
+
+// migrations is the ordered list of schema migrations. Never edit or reorder a migration that
+// has shipped; add a new one with the next version number instead.
+var migrations = []Migration{
+	{Version: 1, Name: "baseline schema", Up: migrateBaselineSchema},
+	{Version: 2, Name: "drop patients.occupation", Up: migrateDropPatientOccupation},
+	{Version: 3, Name: "user sessions", Up: migrateUserSessions},
+	{Version: 4, Name: "replace Assistant role", Up: migrateAssistantRole},
+	{Version: 5, Name: "user status and forced password change", Up: migrateUserStatus},
+	{Version: 6, Name: "audit log", Up: migrateAuditLog},
+	{Version: 7, Name: "backup settings", Up: migrateBackupSettings},
+	{Version: 8, Name: "clinic settings", Up: migrateClinicSettings},
+	{Version: 9, Name: "appointment dentist and chair", Up: migrateAppointmentAssignment},
+	{Version: 10, Name: "appointment status", Up: migrateAppointmentStatus},
+	{Version: 11, Name: "appointment series", Up: migrateAppointmentSeries},
+	{Version: 12, Name: "appointment datetime index", Up: migrateAppointmentDatetimeIndex},
+	{Version: 13, Name: "working hours and time off", Up: migrateWorkingHours},
+	{Version: 14, Name: "appointment calendar uid", Up: migrateAppointmentCalendarUID},
+	{Version: 15, Name: "appointment reminders", Up: migrateAppointmentReminders},
+	{Version: 16, Name: "waiting list", Up: migrateWaitlist},
+	{Version: 17, Name: "tooth chart", Up: migrateToothChart},
+	{Version: 18, Name: "treatment plans", Up: migrateTreatmentPlans},
+	{Version: 19, Name: "periodontal exams", Up: migratePerioExams},
+	{Version: 20, Name: "clinical notes", Up: migrateClinicalNotes},
+	{Version: 21, Name: "prescriptions", Up: migratePrescriptions},
+	{Version: 22, Name: "medical alerts", Up: migrateMedicalAlerts},
+	{Version: 23, Name: "date of birth", Up: migrateDateOfBirth},
+}
+
+// Migrate brings the database schema up to the latest version.
+// It returns an error (and leaves the failing migration unapplied) if any migration fails.
+func Migrate(db *sql.DB) error {
+	return runMigrations(db, migrations)
+}
+
+func runMigrations(db *sql.DB, list []Migration) error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TEXT NOT NULL
+	);`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %v", err)
+	}
+
+	current, err := currentVersion(db)
+	if err != nil {
+		return err
+	}
+
+	latest := 0
+	for i, m := range list {
+		if m.Version != i+1 {
+			return fmt.Errorf("migration %q has version %d, expected %d", m.Name, m.Version, i+1)
+		}
+		latest = m.Version
+	}
+
+	if current > latest {
+		return fmt.Errorf("database schema version %d is newer than this application supports (%d)", current, latest)
+	}
+
+	if current == 0 {
+		legacy, err := tableExists(db, "patients")
+		if err != nil {
+			return err
+		}
+		if legacy {
+			log.Printf("[Database] Existing database without schema_migrations detected, upgrading from pre-migration schema")
+		}
+	}
+
+	for _, m := range list {
+		if m.Version <= current {
+			continue
+		}
+		if err := applyMigration(db, m); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %v", m.Version, m.Name, err)
+		}
+		log.Printf("[Database] Applied migration %d: %s", m.Version, m.Name)
+	}
+
+	return nil
+}
+
+func applyMigration(db *sql.DB, m Migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := m.Up(tx); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+		m.Version, m.Name, time.Now().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return fmt.Errorf("failed to record migration: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+func currentVersion(db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %v", err)
+	}
+	return version, nil
+}
+
+// SchemaVersion returns the version of the latest applied migration
+func SchemaVersion(db *sql.DB) (int, error) {
+	return currentVersion(db)
+}
+
+// LatestVersion returns the schema version this build migrates databases to
+func LatestVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// GetAppliedMigrations returns every applied migration, oldest first
+func GetAppliedMigrations(db *sql.DB) ([]AppliedMigration, error) {
+	rows, err := db.Query(`SELECT version, name, applied_at FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load applied migrations: %v", err)
+	}
+	defer rows.Close()
+
+	applied := make([]AppliedMigration, 0)
+	for rows.Next() {
+		var m AppliedMigration
+		if err := rows.Scan(&m.Version, &m.Name, &m.AppliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan migration: %v", err)
+		}
+		applied = append(applied, m)
+	}
+
+	return applied, rows.Err()
+}
+
+type schemaQuerier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func tableExists(q schemaQuerier, table string) (bool, error) {
+	var count int
+	err := q.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check table %s: %v", table, err)
+	}
+	return count > 0, nil
+}
+
+func columnExists(q schemaQuerier, table, column string) (bool, error) {
+	rows, err := q.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return false, fmt.Errorf("failed to read columns of %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return false, fmt.Errorf("failed to scan column of %s: %v", table, err)
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+
+	return false, rows.Err()
+}
+
+// addColumnIfMissing adds a column unless it already exists
+func addColumnIfMissing(tx *sql.Tx, table, column, definition string) (bool, error) {
+	exists, err := columnExists(tx, table, column)
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		return false, nil
+	}
+
+	_, err = tx.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s;`, table, column, definition))
+	if err != nil {
+		return false, fmt.Errorf("failed to add column %s.%s: %v", table, column, err)
+	}
+	return true, nil
+}
+
+func execStatements(tx *sql.Tx, statements ...string) error {
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("%v (statement: %s)", err, stmt)
+		}
+	}
+	return nil
+}
+
+// migrateBaselineSchema creates the original schema on a new database and brings a database
+// created by the pre-migration InitDB up to the same shape without touching existing data
+func migrateBaselineSchema(tx *sql.Tx) error {
+	if err := execStatements(tx, baselineTables...); err != nil {
+		return err
+	}
+
+	for _, col := range baselineColumns {
+		added, err := addColumnIfMissing(tx, col.table, col.name, col.definition)
+		if err != nil {
+			return err
+		}
+		if !added {
+			continue
+		}
+		if col.uniqueIndex {
+			_, err = tx.Exec(fmt.Sprintf(`CREATE UNIQUE INDEX IF NOT EXISTS idx_%s_%s_unique ON %s(%s);`,
+				col.table, col.name, col.table, col.name))
+			if err != nil {
+				return fmt.Errorf("failed to create unique index on %s.%s: %v", col.table, col.name, err)
+			}
+		}
+		if col.backfill != "" {
+			_, err = tx.Exec(fmt.Sprintf(`UPDATE %s SET %s = %s WHERE %s IS NULL;`,
+				col.table, col.name, col.backfill, col.name))
+			if err != nil {
+				return fmt.Errorf("failed to backfill %s.%s: %v", col.table, col.name, err)
+			}
+		}
+	}
+
+	return execStatements(tx, baselineIndexes...)
+}
+
+// migrateDropPatientOccupation removes the unused occupation column from patients
+func migrateDropPatientOccupation(tx *sql.Tx) error {
+	exists, err := columnExists(tx, "patients", "occupation")
+	if err != nil || !exists {
+		return err
+	}
+	_, err = tx.Exec(`ALTER TABLE patients DROP COLUMN occupation;`)
+	return err
+}
+
+// migrateUserSessions adds server-side login sessions; only a SHA-256 hash of each token is stored
+func migrateUserSessions(tx *sql.Tx) error {
+	return execStatements(tx,
+		`CREATE TABLE IF NOT EXISTS user_sessions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			created_at TEXT NOT NULL,
+			last_seen_at TEXT NOT NULL,
+			expires_at TEXT NOT NULL,
+			revoked_at TEXT,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_user_sessions_user_id ON user_sessions(user_id);`,
+	)
+}
+
+// migrateAssistantRole moves users with the retired Assistant role to Receptionist
+func migrateAssistantRole(tx *sql.Tx) error {
+	_, err := tx.Exec(`UPDATE users SET role = 'Receptionist' WHERE role = 'Assistant';`)
+	return err
+}
+
+// migrateUserStatus lets users be deactivated instead of deleted and flags accounts that must change their password
+func migrateUserStatus(tx *sql.Tx) error {
+	if _, err := addColumnIfMissing(tx, "users", "is_active", "INTEGER NOT NULL DEFAULT 1"); err != nil {
+		return err
+	}
+	_, err := addColumnIfMissing(tx, "users", "must_change_password", "INTEGER NOT NULL DEFAULT 0")
+	return err
+}
+
+// migrateAuditLog adds the audit trail written alongside every data mutation
+func migrateAuditLog(tx *sql.Tx) error {
+	return execStatements(tx,
+		`CREATE TABLE IF NOT EXISTS audit_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER,
+			created_at TEXT NOT NULL,
+			entity_type TEXT NOT NULL,
+			entity_id INTEGER NOT NULL,
+			action TEXT NOT NULL,
+			changes TEXT NOT NULL DEFAULT '{}',
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE SET NULL
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at DESC);`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_user_id ON audit_log(user_id);`,
+	)
+}
+
+// migrateBackupSettings adds the single-row table holding the automatic backup schedule
+// The passphrase column is no longer written: the passphrase moved to a file outside the database
+// (see handlers.BackupHandler.MoveStoredPassphrase).
+func migrateBackupSettings(tx *sql.Tx) error {
+	return execStatements(tx,
+		`CREATE TABLE IF NOT EXISTS backup_settings (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			enabled INTEGER NOT NULL DEFAULT 1,
+			interval_hours INTEGER NOT NULL DEFAULT 24,
+			keep_count INTEGER NOT NULL DEFAULT 14,
+			encrypt INTEGER NOT NULL DEFAULT 0,
+			passphrase TEXT NOT NULL DEFAULT '',
+			updated_at TEXT
+		);`,
+		`INSERT OR IGNORE INTO backup_settings (id) VALUES (1);`,
+	)
+}
+
+// migrateClinicSettings adds the single-row table holding the clinic profile and shared preferences,
+// so every workstation using the database sees the same configuration
+func migrateClinicSettings(tx *sql.Tx) error {
+	return execStatements(tx,
+		`CREATE TABLE IF NOT EXISTS clinic_settings (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			name TEXT NOT NULL DEFAULT '',
+			address TEXT NOT NULL DEFAULT '',
+			phone TEXT NOT NULL DEFAULT '',
+			tax_number TEXT NOT NULL DEFAULT '',
+			logo BLOB,
+			logo_type TEXT NOT NULL DEFAULT '',
+			currency_symbol TEXT NOT NULL DEFAULT 'SYP',
+			invoice_footer TEXT NOT NULL DEFAULT '',
+			invoice_prefix TEXT NOT NULL DEFAULT 'INV-',
+			lab_order_prefix TEXT NOT NULL DEFAULT 'ORDER-',
+			default_appointment_duration INTEGER NOT NULL DEFAULT 30,
+			theme TEXT NOT NULL DEFAULT 'dark',
+			updated_at TEXT
+		);`,
+		`INSERT OR IGNORE INTO clinic_settings (id) VALUES (1);`,
+	)
+}
+
+// migrateAppointmentAssignment adds chairs (operatories) and assigns appointments to a dentist and
+// a chair, so overlapping bookings can be detected
+func migrateAppointmentAssignment(tx *sql.Tx) error {
+	if err := execStatements(tx,
+		`CREATE TABLE IF NOT EXISTS chairs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			is_active INTEGER NOT NULL DEFAULT 1,
+			created_at TEXT NOT NULL DEFAULT (datetime('now')),
+			updated_at TEXT NOT NULL DEFAULT (datetime('now'))
+		);`,
+		`INSERT INTO chairs (name) SELECT 'Chair 1' WHERE NOT EXISTS (SELECT 1 FROM chairs);`,
+	); err != nil {
+		return err
+	}
+	if _, err := addColumnIfMissing(tx, "appointments", "dentist_id", "INTEGER REFERENCES users(id) ON DELETE SET NULL"); err != nil {
+		return err
+	}
+	if _, err := addColumnIfMissing(tx, "appointments", "chair_id", "INTEGER REFERENCES chairs(id) ON DELETE SET NULL"); err != nil {
+		return err
+	}
+	return execStatements(tx,
+		`CREATE INDEX IF NOT EXISTS idx_appointments_dentist ON appointments(dentist_id, datetime);`,
+		`CREATE INDEX IF NOT EXISTS idx_appointments_chair ON appointments(chair_id, datetime);`,
+	)
+}
+
+// migrateAppointmentStatus adds the appointment status lifecycle with a timestamp for each state,
+// so cancellations and no-shows are kept instead of deleted
+func migrateAppointmentStatus(tx *sql.Tx) error {
+	columns := []struct{ name, definition string }{
+		{"status", "TEXT NOT NULL DEFAULT 'scheduled'"},
+		{"cancellation_reason", "TEXT NOT NULL DEFAULT ''"},
+		{"late_cancellation", "INTEGER NOT NULL DEFAULT 0"},
+		{"confirmed_at", "TEXT"},
+		{"checked_in_at", "TEXT"},
+		{"in_chair_at", "TEXT"},
+		{"completed_at", "TEXT"},
+		{"cancelled_at", "TEXT"},
+		{"no_show_at", "TEXT"},
+	}
+	for _, c := range columns {
+		if _, err := addColumnIfMissing(tx, "appointments", c.name, c.definition); err != nil {
+			return err
+		}
+	}
+	return execStatements(tx,
+		`CREATE INDEX IF NOT EXISTS idx_appointments_patient_status ON appointments(patient_id, status);`,
+	)
+}
+
+// migrateAppointmentSeries adds recurring appointment series; each visit stays an ordinary appointment
+// linked to its series
+func migrateAppointmentSeries(tx *sql.Tx) error {
+	if err := execStatements(tx,
+		`CREATE TABLE IF NOT EXISTS appointment_series (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			rrule TEXT NOT NULL,
+			created_at TEXT NOT NULL DEFAULT (datetime('now'))
+		);`,
+	); err != nil {
+		return err
+	}
+	if _, err := addColumnIfMissing(tx, "appointments", "series_id", "INTEGER REFERENCES appointment_series(id) ON DELETE SET NULL"); err != nil {
+		return err
+	}
+	return execStatements(tx,
+		`CREATE INDEX IF NOT EXISTS idx_appointments_series ON appointments(series_id, datetime);`,
+	)
+}
+
+// migrateAppointmentDatetimeIndex indexes appointment start times for calendar range queries
+func migrateAppointmentDatetimeIndex(tx *sql.Tx) error {
+	return execStatements(tx,
+		`CREATE INDEX IF NOT EXISTS idx_appointments_datetime ON appointments(datetime);`,
+	)
+}
+
+// migrateWorkingHours adds each dentist's weekly working hours, clinic holidays and per-dentist time off
+func migrateWorkingHours(tx *sql.Tx) error {
+	return execStatements(tx,
+		`CREATE TABLE IF NOT EXISTS working_hours (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			weekday INTEGER NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+			start_time TEXT NOT NULL,
+			end_time TEXT NOT NULL
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_working_hours_user ON working_hours(user_id, weekday);`,
+		`CREATE TABLE IF NOT EXISTS clinic_holidays (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			date TEXT NOT NULL UNIQUE,
+			name TEXT NOT NULL DEFAULT ''
+		);`,
+		`CREATE TABLE IF NOT EXISTS time_off (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			start_at TEXT NOT NULL,
+			end_at TEXT NOT NULL,
+			reason TEXT NOT NULL DEFAULT ''
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_time_off_user ON time_off(user_id, start_at);`,
+	)
+}
+
+// migrateAppointmentCalendarUID remembers the iCalendar UID of imported appointments so importing
+// the same file twice does not book them twice
+func migrateAppointmentCalendarUID(tx *sql.Tx) error {
+	if _, err := addColumnIfMissing(tx, "appointments", "ical_uid", "TEXT"); err != nil {
+		return err
+	}
+	return execStatements(tx,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_appointments_ical_uid ON appointments(ical_uid) WHERE ical_uid IS NOT NULL;`,
+	)
+}
+
+// migrateAppointmentReminders adds patient email addresses, the single-row reminder settings table and
+// the outbox of reminder messages waiting to be sent or already delivered
+func migrateAppointmentReminders(tx *sql.Tx) error {
+	if _, err := addColumnIfMissing(tx, "patients", "email", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	return execStatements(tx,
+		`CREATE TABLE IF NOT EXISTS reminder_settings (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			enabled INTEGER NOT NULL DEFAULT 0,
+			lead_hours INTEGER NOT NULL DEFAULT 24,
+			sms_enabled INTEGER NOT NULL DEFAULT 1,
+			email_enabled INTEGER NOT NULL DEFAULT 0,
+			sms_template TEXT NOT NULL DEFAULT '',
+			email_subject TEXT NOT NULL DEFAULT '',
+			email_template TEXT NOT NULL DEFAULT '',
+			test_mode INTEGER NOT NULL DEFAULT 1,
+			max_attempts INTEGER NOT NULL DEFAULT 5,
+			sms_url TEXT NOT NULL DEFAULT '',
+			sms_api_key TEXT NOT NULL DEFAULT '',
+			sms_from TEXT NOT NULL DEFAULT '',
+			smtp_host TEXT NOT NULL DEFAULT '',
+			smtp_port INTEGER NOT NULL DEFAULT 587,
+			smtp_username TEXT NOT NULL DEFAULT '',
+			smtp_password TEXT NOT NULL DEFAULT '',
+			smtp_from TEXT NOT NULL DEFAULT '',
+			updated_at TEXT
+		);`,
+		`INSERT OR IGNORE INTO reminder_settings (id) VALUES (1);`,
+		`CREATE TABLE IF NOT EXISTS outbound_messages (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			appointment_id INTEGER REFERENCES appointments(id) ON DELETE SET NULL,
+			patient_id INTEGER REFERENCES patients(id) ON DELETE CASCADE,
+			appointment_datetime TEXT NOT NULL DEFAULT '',
+			channel TEXT NOT NULL,
+			recipient TEXT NOT NULL,
+			subject TEXT NOT NULL DEFAULT '',
+			body TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			next_attempt_at TEXT NOT NULL,
+			last_error TEXT NOT NULL DEFAULT '',
+			dedupe_key TEXT UNIQUE,
+			created_at TEXT NOT NULL DEFAULT (datetime('now')),
+			sent_at TEXT
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_outbound_messages_due ON outbound_messages(status, next_attempt_at);`,
+	)
+}
+
+// migrateWaitlist adds the waiting list of patients who want an earlier appointment, with the times
+// of day each of them can come in
+func migrateWaitlist(tx *sql.Tx) error {
+	return execStatements(tx,
+		`CREATE TABLE IF NOT EXISTS waitlist (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			patient_id INTEGER NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+			dentist_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			procedure_id INTEGER REFERENCES dental_procedures(id) ON DELETE SET NULL,
+			appointment_id INTEGER REFERENCES appointments(id) ON DELETE SET NULL,
+			duration INTEGER NOT NULL,
+			priority INTEGER NOT NULL DEFAULT 3,
+			earliest_date TEXT NOT NULL DEFAULT '',
+			latest_date TEXT NOT NULL DEFAULT '',
+			notes TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL DEFAULT 'waiting',
+			offered_datetime TEXT NOT NULL DEFAULT '',
+			offered_dentist_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			offered_chair_id INTEGER REFERENCES chairs(id) ON DELETE SET NULL,
+			offered_at TEXT NOT NULL DEFAULT '',
+			booked_appointment_id INTEGER REFERENCES appointments(id) ON DELETE SET NULL,
+			created_at TEXT NOT NULL DEFAULT (datetime('now'))
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_waitlist_status ON waitlist(status, priority);`,
+		`CREATE TABLE IF NOT EXISTS waitlist_windows (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			waitlist_id INTEGER NOT NULL REFERENCES waitlist(id) ON DELETE CASCADE,
+			weekday INTEGER NOT NULL CHECK (weekday BETWEEN -1 AND 6),
+			start_time TEXT NOT NULL,
+			end_time TEXT NOT NULL
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_waitlist_windows_entry ON waitlist_windows(waitlist_id);`,
+	)
+}
+
+// migrateToothChart adds the per-tooth chart (FDI numbering) with its history, the teeth each session
+// item was performed on and the condition it leaves behind
+func migrateToothChart(tx *sql.Tx) error {
+	if _, err := addColumnIfMissing(tx, "session_items", "chart_condition", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if _, err := addColumnIfMissing(tx, "sessions", "charted_at", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	return execStatements(tx,
+		`CREATE TABLE IF NOT EXISTS tooth_chart (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			patient_id INTEGER NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+			tooth INTEGER NOT NULL,
+			surfaces TEXT NOT NULL DEFAULT '',
+			condition TEXT NOT NULL,
+			notes TEXT NOT NULL DEFAULT '',
+			session_id INTEGER REFERENCES sessions(id) ON DELETE SET NULL,
+			recorded_at TEXT NOT NULL DEFAULT (datetime('now')),
+			recorded_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			resolved_at TEXT NOT NULL DEFAULT '',
+			resolved_by INTEGER REFERENCES users(id) ON DELETE SET NULL
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_tooth_chart_patient ON tooth_chart(patient_id, tooth);`,
+		`CREATE TABLE IF NOT EXISTS session_item_teeth (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_id INTEGER NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+			session_item_id INTEGER NOT NULL REFERENCES session_items(id) ON DELETE CASCADE,
+			tooth INTEGER NOT NULL,
+			surfaces TEXT NOT NULL DEFAULT ''
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_session_item_teeth_item ON session_item_teeth(session_item_id);`,
+	)
+}
+
+// migrateTreatmentPlans adds treatment plans of phased, priced procedures and links session items to
+// the plan item they perform
+func migrateTreatmentPlans(tx *sql.Tx) error {
+	if err := execStatements(tx,
+		`CREATE TABLE IF NOT EXISTS treatment_plans (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			patient_id INTEGER NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+			dentist_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			title TEXT NOT NULL,
+			notes TEXT NOT NULL DEFAULT '',
+			accepted_at TEXT NOT NULL DEFAULT '',
+			created_at TEXT NOT NULL DEFAULT (datetime('now'))
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_treatment_plans_patient ON treatment_plans(patient_id);`,
+		`CREATE TABLE IF NOT EXISTS treatment_plan_items (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			plan_id INTEGER NOT NULL REFERENCES treatment_plans(id) ON DELETE CASCADE,
+			phase INTEGER NOT NULL DEFAULT 1,
+			procedure_id INTEGER REFERENCES dental_procedures(id) ON DELETE SET NULL,
+			item_name TEXT NOT NULL,
+			tooth INTEGER NOT NULL DEFAULT 0,
+			surfaces TEXT NOT NULL DEFAULT '',
+			price INTEGER NOT NULL DEFAULT 0,
+			status TEXT NOT NULL DEFAULT 'proposed'
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_treatment_plan_items_plan ON treatment_plan_items(plan_id, phase);`,
+	); err != nil {
+		return err
+	}
+	if _, err := addColumnIfMissing(tx, "session_items", "plan_item_id", "INTEGER REFERENCES treatment_plan_items(id) ON DELETE SET NULL"); err != nil {
+		return err
+	}
+	return execStatements(tx,
+		`CREATE INDEX IF NOT EXISTS idx_session_items_plan_item ON session_items(plan_item_id);`,
+	)
+}
+
+// migratePerioExams adds periodontal exams: per-tooth mobility and furcation, and probing depth,
+// recession and bleeding for each of the six sites probed around a tooth
+func migratePerioExams(tx *sql.Tx) error {
+	return execStatements(tx,
+		`CREATE TABLE IF NOT EXISTS perio_exams (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			patient_id INTEGER NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+			exam_date TEXT NOT NULL,
+			examiner_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			notes TEXT NOT NULL DEFAULT '',
+			created_at TEXT NOT NULL DEFAULT (datetime('now')),
+			UNIQUE (patient_id, exam_date)
+		);`,
+		`CREATE TABLE IF NOT EXISTS perio_teeth (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			exam_id INTEGER NOT NULL REFERENCES perio_exams(id) ON DELETE CASCADE,
+			tooth INTEGER NOT NULL,
+			mobility INTEGER NOT NULL DEFAULT 0,
+			furcation INTEGER NOT NULL DEFAULT 0,
+			UNIQUE (exam_id, tooth)
+		);`,
+		`CREATE TABLE IF NOT EXISTS perio_sites (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			exam_id INTEGER NOT NULL REFERENCES perio_exams(id) ON DELETE CASCADE,
+			tooth INTEGER NOT NULL,
+			site TEXT NOT NULL,
+			probing_depth INTEGER NOT NULL,
+			recession INTEGER NOT NULL DEFAULT 0,
+			bleeding INTEGER NOT NULL DEFAULT 0,
+			UNIQUE (exam_id, tooth, site)
+		);`,
+	)
+}
+
+// migrateClinicalNotes adds note templates and one structured SOAP note per session. Every saved
+// revision of a note is copied to clinical_note_versions; a signed note is locked.
+func migrateClinicalNotes(tx *sql.Tx) error {
+	return execStatements(tx,
+		`CREATE TABLE IF NOT EXISTS note_templates (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+			subjective TEXT NOT NULL DEFAULT '',
+			objective TEXT NOT NULL DEFAULT '',
+			assessment TEXT NOT NULL DEFAULT '',
+			plan TEXT NOT NULL DEFAULT '',
+			created_at TEXT NOT NULL DEFAULT (datetime('now'))
+		);`,
+		`CREATE TABLE IF NOT EXISTS clinical_notes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_id INTEGER NOT NULL UNIQUE REFERENCES sessions(id) ON DELETE CASCADE,
+			template_id INTEGER REFERENCES note_templates(id) ON DELETE SET NULL,
+			subjective TEXT NOT NULL DEFAULT '',
+			objective TEXT NOT NULL DEFAULT '',
+			assessment TEXT NOT NULL DEFAULT '',
+			plan TEXT NOT NULL DEFAULT '',
+			version INTEGER NOT NULL DEFAULT 1,
+			updated_at TEXT NOT NULL,
+			updated_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			signed_at TEXT NOT NULL DEFAULT '',
+			signed_by INTEGER REFERENCES users(id) ON DELETE SET NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS clinical_note_versions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			note_id INTEGER NOT NULL REFERENCES clinical_notes(id) ON DELETE CASCADE,
+			version INTEGER NOT NULL,
+			subjective TEXT NOT NULL DEFAULT '',
+			objective TEXT NOT NULL DEFAULT '',
+			assessment TEXT NOT NULL DEFAULT '',
+			plan TEXT NOT NULL DEFAULT '',
+			saved_at TEXT NOT NULL,
+			saved_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			UNIQUE (note_id, version)
+		);`,
+	)
+}
+
+// migratePrescriptions adds the drug catalogue, the allergy and interaction rules prescriptions are
+// checked against, and prescriptions written during a session. The catalogue and rules are seeded
+// with common dental drugs; clinics can edit both.
+func migratePrescriptions(tx *sql.Tx) error {
+	err := execStatements(tx,
+		`CREATE TABLE IF NOT EXISTS drugs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+			strength TEXT NOT NULL DEFAULT '',
+			form TEXT NOT NULL DEFAULT '',
+			drug_class TEXT NOT NULL DEFAULT '',
+			default_dose TEXT NOT NULL DEFAULT '',
+			default_frequency TEXT NOT NULL DEFAULT '',
+			default_duration TEXT NOT NULL DEFAULT '',
+			default_instructions TEXT NOT NULL DEFAULT ''
+		);`,
+		`CREATE TABLE IF NOT EXISTS drug_rules (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			kind TEXT NOT NULL CHECK (kind IN ('allergy', 'interaction')),
+			drug_class TEXT NOT NULL,
+			term TEXT NOT NULL,
+			severity TEXT NOT NULL DEFAULT 'warning' CHECK (severity IN ('warning', 'contraindicated')),
+			message TEXT NOT NULL DEFAULT '',
+			UNIQUE (kind, drug_class, term)
+		);`,
+		`CREATE TABLE IF NOT EXISTS prescriptions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_id INTEGER NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+			patient_id INTEGER NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+			prescriber_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			prescribed_at TEXT NOT NULL,
+			notes TEXT NOT NULL DEFAULT '',
+			override_reason TEXT NOT NULL DEFAULT ''
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_prescriptions_session ON prescriptions(session_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_prescriptions_patient ON prescriptions(patient_id);`,
+		`CREATE TABLE IF NOT EXISTS prescription_items (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			prescription_id INTEGER NOT NULL REFERENCES prescriptions(id) ON DELETE CASCADE,
+			drug_id INTEGER REFERENCES drugs(id) ON DELETE SET NULL,
+			drug_name TEXT NOT NULL,
+			strength TEXT NOT NULL DEFAULT '',
+			dose TEXT NOT NULL,
+			frequency TEXT NOT NULL,
+			duration TEXT NOT NULL DEFAULT '',
+			instructions TEXT NOT NULL DEFAULT ''
+		);`,
+	)
+	if err != nil {
+		return err
+	}
+
+	drugs := [][]string{
+		// name, strength, form, class, dose, frequency, duration, instructions
+		{"Amoxicillin", "500 mg", "capsule", "penicillin", "1 capsule", "3 times a day", "5 days", "Complete the course"},
+		{"Amoxicillin/Clavulanic acid", "625 mg", "tablet", "penicillin", "1 tablet", "3 times a day", "5 days", "Take with food"},
+		{"Clindamycin", "300 mg", "capsule", "lincosamide", "1 capsule", "4 times a day", "5 days", "Take with a full glass of water"},
+		{"Azithromycin", "500 mg", "tablet", "macrolide", "1 tablet", "once a day", "3 days", ""},
+		{"Metronidazole", "400 mg", "tablet", "nitroimidazole", "1 tablet", "3 times a day", "5 days", "Avoid alcohol during and for 48 hours after the course"},
+		{"Ibuprofen", "400 mg", "tablet", "nsaid", "1 tablet", "every 8 hours as needed", "3 days", "Take after food"},
+		{"Paracetamol", "500 mg", "tablet", "paracetamol", "2 tablets", "every 6 hours as needed", "3 days", "No more than 8 tablets in 24 hours"},
+		{"Chlorhexidine mouthwash", "0.12%", "mouthwash", "antiseptic", "15 ml", "twice a day", "7 days", "Rinse for 30 seconds and spit; do not swallow"},
+	}
+	for _, d := range drugs {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO drugs (name, strength, form, drug_class, default_dose, default_frequency, default_duration, default_instructions)
+		                      VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, d[0], d[1], d[2], d[3], d[4], d[5], d[6], d[7]); err != nil {
+			return fmt.Errorf("failed to seed drug %s: %v", d[0], err)
+		}
+	}
+
+	rules := [][]string{
+		// kind, class, term, severity, message
+		{"allergy", "penicillin", "penicillin", "contraindicated", "Penicillin allergy"},
+		{"allergy", "penicillin", "amoxicillin", "contraindicated", "Penicillin allergy"},
+		{"allergy", "penicillin", "ampicillin", "contraindicated", "Penicillin allergy"},
+		{"allergy", "penicillin", "cephalosporin", "warning", "Possible cross-reactivity with cephalosporin allergy"},
+		{"allergy", "lincosamide", "clindamycin", "contraindicated", "Clindamycin allergy"},
+		{"allergy", "macrolide", "erythromycin", "contraindicated", "Macrolide allergy"},
+		{"allergy", "macrolide", "azithromycin", "contraindicated", "Macrolide allergy"},
+		{"allergy", "macrolide", "clarithromycin", "contraindicated", "Macrolide allergy"},
+		{"allergy", "nitroimidazole", "metronidazole", "contraindicated", "Metronidazole allergy"},
+		{"allergy", "nsaid", "nsaid", "contraindicated", "NSAID allergy"},
+		{"allergy", "nsaid", "aspirin", "contraindicated", "Aspirin sensitivity; NSAIDs may cause a reaction"},
+		{"allergy", "nsaid", "ibuprofen", "contraindicated", "Ibuprofen allergy"},
+		{"allergy", "antiseptic", "chlorhexidine", "contraindicated", "Chlorhexidine allergy"},
+		{"interaction", "nsaid", "warfarin", "contraindicated", "NSAIDs increase the bleeding risk with warfarin"},
+		{"interaction", "nsaid", "clopidogrel", "warning", "NSAIDs increase the bleeding risk with antiplatelets"},
+		{"interaction", "nsaid", "methotrexate", "warning", "NSAIDs reduce methotrexate clearance"},
+		{"interaction", "nsaid", "lithium", "warning", "NSAIDs raise lithium levels"},
+		{"interaction", "nitroimidazole", "warfarin", "warning", "Metronidazole increases the effect of warfarin"},
+		{"interaction", "nitroimidazole", "lithium", "warning", "Metronidazole raises lithium levels"},
+		{"interaction", "macrolide", "warfarin", "warning", "Macrolides may increase the effect of warfarin"},
+		{"interaction", "macrolide", "simvastatin", "warning", "Macrolides raise statin levels"},
+		{"interaction", "penicillin", "methotrexate", "warning", "Penicillins reduce methotrexate clearance"},
+		{"interaction", "penicillin", "warfarin", "warning", "Antibiotics may increase the effect of warfarin"},
+	}
+	for _, r := range rules {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO drug_rules (kind, drug_class, term, severity, message) VALUES (?, ?, ?, ?, ?)`,
+			r[0], r[1], r[2], r[3], r[4]); err != nil {
+			return fmt.Errorf("failed to seed drug rule %s/%s: %v", r[1], r[2], err)
+		}
+	}
+	return nil
+}
+
+// migrateMedicalAlerts adds structured medical alerts with a severity and review date. Patients
+// already marked as pregnant get a pregnancy alert. The old flag says nothing about when it was set,
+// so those alerts are due for review today and show as overdue until someone checks them.
+func migrateMedicalAlerts(tx *sql.Tx) error {
+	return execStatements(tx,
+		`CREATE TABLE IF NOT EXISTS medical_alerts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			patient_id INTEGER NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+			alert_type TEXT NOT NULL,
+			severity TEXT NOT NULL CHECK (severity IN ('info', 'warning', 'critical')),
+			description TEXT NOT NULL DEFAULT '',
+			review_date TEXT NOT NULL DEFAULT '',
+			reviewed_at TEXT NOT NULL DEFAULT '',
+			reviewed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			created_at TEXT NOT NULL DEFAULT (datetime('now')),
+			updated_at TEXT NOT NULL DEFAULT (datetime('now'))
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_medical_alerts_patient ON medical_alerts(patient_id);`,
+		`INSERT INTO medical_alerts (patient_id, alert_type, severity, review_date)
+		 SELECT id, 'pregnancy', 'warning', date('now') FROM patients
+		 WHERE pregnancy_status = 1 AND id NOT IN (SELECT patient_id FROM medical_alerts WHERE alert_type = 'pregnancy');`,
+	)
+}
+
+// migrateDateOfBirth adds patients' date of birth. Existing patients only have the age entered at
+// registration, so their date of birth is estimated as the middle of the year they were that age,
+// counting back from today, and flagged as estimated. The age column is kept as the age at
+// registration.
+func migrateDateOfBirth(tx *sql.Tx) error {
+	if _, err := addColumnIfMissing(tx, "patients", "date_of_birth", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if _, err := addColumnIfMissing(tx, "patients", "dob_estimated", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	return execStatements(tx,
+		`UPDATE patients SET date_of_birth = date('now', '-' || age || ' years', '-6 months'), dob_estimated = 1
+		 WHERE date_of_birth = '' AND age > 0;`,
+		`CREATE INDEX IF NOT EXISTS idx_patients_date_of_birth ON patients(date_of_birth);`,
+	)
+}
@@ -0,0 +1,195 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestMigrateFreshDatabase(t *testing.T) {
+	db := openTestDB(t)
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	version, err := SchemaVersion(db)
+	if err != nil {
+		t.Fatalf("SchemaVersion failed: %v", err)
+	}
+	if version != len(migrations) {
+		t.Errorf("schema version = %d; expected %d", version, len(migrations))
+	}
+
+	hasOccupation, err := columnExists(db, "patients", "occupation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hasOccupation {
+		t.Errorf("patients.occupation should have been dropped")
+	}
+
+	// Running again must be a no-op
+	if err := Migrate(db); err != nil {
+		t.Fatalf("second Migrate failed: %v", err)
+	}
+	applied, err := GetAppliedMigrations(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != len(migrations) {
+		t.Errorf("applied migrations = %d; expected %d", len(applied), len(migrations))
+	}
+}
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	db := openTestDB(t)
+
+	// Database as created by an early InitDB: no medical history columns and an old payments table
+	_, err := db.Exec(`
+	CREATE TABLE patients (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		phone TEXT NOT NULL,
+		age INTEGER NOT NULL,
+		gender TEXT NOT NULL,
+		occupation TEXT,
+		total_required INTEGER DEFAULT 0
+	);
+	CREATE TABLE payments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		patient_id INTEGER NOT NULL,
+		amount INTEGER NOT NULL,
+		note TEXT
+	);
+	INSERT INTO patients (name, phone, age, gender, occupation, total_required) VALUES ('John Doe', '0999999999', 40, 'male', 'teacher', 500);
+	INSERT INTO payments (patient_id, amount, note) VALUES (1, 200, 'first visit');`)
+	if err != nil {
+		t.Fatalf("failed to create legacy schema: %v", err)
+	}
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	var name string
+	var totalRequired int
+	var allergies sql.NullString
+	err = db.QueryRow(`SELECT name, total_required, allergies FROM patients WHERE id = 1`).Scan(&name, &totalRequired, &allergies)
+	if err != nil {
+		t.Fatalf("failed to read migrated patient: %v", err)
+	}
+	if name != "John Doe" || totalRequired != 500 {
+		t.Errorf("patient data changed: name=%q total_required=%d", name, totalRequired)
+	}
+
+	// The date of birth is estimated from the age
+	var dateOfBirth string
+	var estimated bool
+	if err := db.QueryRow(`SELECT date_of_birth, dob_estimated FROM patients WHERE id = 1`).Scan(&dateOfBirth, &estimated); err != nil {
+		t.Fatalf("failed to read date of birth: %v", err)
+	}
+	dob, err := time.Parse("2006-01-02", dateOfBirth)
+	if err != nil || !estimated {
+		t.Errorf("date_of_birth=%q dob_estimated=%v; expected an estimate", dateOfBirth, estimated)
+	} else if age := time.Since(dob).Hours() / 24 / 365.25; age < 40 || age >= 41 {
+		t.Errorf("estimated date of birth %s gives age %.1f; expected 40", dateOfBirth, age)
+	}
+
+	var amount int
+	var paymentDate, paymentMethod string
+	err = db.QueryRow(`SELECT amount, payment_date, payment_method FROM payments WHERE id = 1`).Scan(&amount, &paymentDate, &paymentMethod)
+	if err != nil {
+		t.Fatalf("failed to read migrated payment: %v", err)
+	}
+	if amount != 200 || paymentDate == "" || paymentMethod != "cash" {
+		t.Errorf("payment not migrated correctly: amount=%d payment_date=%q payment_method=%q", amount, paymentDate, paymentMethod)
+	}
+
+	for _, table := range []string{"sessions", "invoices", "expenses", "lab_orders"} {
+		exists, err := tableExists(db, table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !exists {
+			t.Errorf("table %s was not created", table)
+		}
+	}
+}
+
+func TestMigratePregnancyAlerts(t *testing.T) {
+	db := openTestDB(t)
+
+	// Stop before the medical alerts migration and mark a patient as pregnant the old way
+	var before []Migration
+	for _, m := range migrations {
+		if m.Name == "medical alerts" {
+			break
+		}
+		before = append(before, m)
+	}
+	if err := runMigrations(db, before); err != nil {
+		t.Fatalf("runMigrations failed: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO patients (name, phone, age, gender, pregnancy_status) VALUES ('Jane Doe', '0100000000', 30, 'female', 1)`); err != nil {
+		t.Fatal(err)
+	}
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	var alertType, reviewDate string
+	if err := db.QueryRow(`SELECT alert_type, review_date FROM medical_alerts WHERE patient_id = 1`).Scan(&alertType, &reviewDate); err != nil {
+		t.Fatalf("failed to read pregnancy alert: %v", err)
+	}
+	if today := time.Now().UTC().Format("2006-01-02"); alertType != "pregnancy" || reviewDate != today {
+		t.Errorf("alert = %s reviewed by %q; expected a pregnancy alert due for review %s", alertType, reviewDate, today)
+	}
+}
+
+func TestMigrateFailureRollsBack(t *testing.T) {
+	db := openTestDB(t)
+
+	list := []Migration{
+		migrations[0],
+		{Version: 2, Name: "broken", Up: func(tx *sql.Tx) error {
+			if _, err := tx.Exec(`CREATE TABLE half_done (id INTEGER)`); err != nil {
+				return err
+			}
+			return fmt.Errorf("boom")
+		}},
+	}
+
+	if err := runMigrations(db, list); err == nil {
+		t.Fatal("expected migration error")
+	}
+
+	version, err := SchemaVersion(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 1 {
+		t.Errorf("schema version = %d; expected 1", version)
+	}
+
+	exists, err := tableExists(db, "half_done")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Errorf("failed migration was not rolled back")
+	}
+}
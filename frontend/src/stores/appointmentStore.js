@@ -3,13 +3,30 @@ import {
     GetAppointments,
     AddAppointment,
     UpdateAppointment,
-    DeleteAppointment
+    DeleteAppointment,
+    GetDentists,
+    GetChairs,
+    CreateChair,
+    UpdateChair,
+    DeleteChair,
+    SetAppointmentStatus,
+    SessionFormForAppointment,
+    CreateAppointmentSeries,
+    UpdateAppointmentInSeries,
+    CancelAppointmentInSeries,
+    GetAppointmentsInRange,
+    GetAppointmentDaySummary,
+    ExportAppointmentsICS,
+    ImportAppointmentsICS
 } from '../../wailsjs/go/main/App.js';
+import { getSessionToken } from './authStore.js';
 import { currentLicenseKey } from './settingsStore.js';
 
 export const appointments = writable([]);
 export const loadingAppointments = writable(false);
 export const appointmentError = writable(null);
+export const dentists = writable([]);
+export const chairs = writable([]);
 
 // Helper function to get current license key
 function getLicenseKey() {
@@ -23,7 +40,7 @@ export async function loadAppointments() {
     appointmentError.set(null);
     try {
         const licenseKey = getLicenseKey();
-        const data = await GetAppointments(licenseKey);
+        const data = await GetAppointments(getSessionToken(), licenseKey);
         appointments.set(data);
     } catch (err) {
         const errorMessage = err.message || 'Failed to load appointments';
@@ -38,32 +55,135 @@ export async function loadAppointments() {
     }
 }
 
+// Errors are rethrown so the modal can show booking conflicts and offer to book anyway
+// loadAppointmentsInRange returns the appointments between two YYYY-MM-DD dates (inclusive),
+// optionally for one dentist or chair (0 for all), with patient names filled in
+export async function loadAppointmentsInRange(from, to, dentistId = 0, chairId = 0) {
+    return await GetAppointmentsInRange(from, to, dentistId, chairId, getSessionToken(), getLicenseKey()) || [];
+}
+
+// loadDaySummary returns { date, count, booked_minutes } for each booked day in the range
+export async function loadDaySummary(from, to, dentistId = 0, chairId = 0) {
+    return await GetAppointmentDaySummary(from, to, dentistId, chairId, getSessionToken(), getLicenseKey()) || [];
+}
+
+// exportAppointmentsICS saves the range as an .ics file and returns its path
+export async function exportAppointmentsICS(from, to, dentistId = 0) {
+    return await ExportAppointmentsICS(from, to, dentistId, getSessionToken(), getLicenseKey());
+}
+
+// importAppointmentsICS books the events of an .ics file's text for existing patients;
+// returns { imported, already_imported, unmatched, skipped }
+export async function importAppointmentsICS(content) {
+    const result = await ImportAppointmentsICS(content, getSessionToken(), getLicenseKey());
+    await loadAppointments();
+    return result;
+}
+
+// Errors are rethrown so the modal can show booking conflicts and offer to book anyway
 export async function addAppointment(appt) {
-    try {
-        const licenseKey = getLicenseKey();
-        await AddAppointment(appt, licenseKey);
-        await loadAppointments();
-    } catch (err) {
-        appointmentError.set(err.message || 'Failed to add appointment');
-    }
+    const licenseKey = getLicenseKey();
+    await AddAppointment(appt, getSessionToken(), licenseKey);
+    await loadAppointments();
 }
 
 export async function updateAppointment(appt) {
+    const licenseKey = getLicenseKey();
+    await UpdateAppointment(appt, getSessionToken(), licenseKey);
+    await loadAppointments();
+}
+
+// Statuses each status may move to; mirrors the check in the appointment handler
+export const appointmentTransitions = {
+    scheduled: ['confirmed', 'checked_in', 'cancelled', 'no_show'],
+    confirmed: ['checked_in', 'cancelled', 'no_show'],
+    checked_in: ['in_chair', 'completed', 'cancelled'],
+    in_chair: ['completed']
+};
+
+export const appointmentStatusLabels = {
+    scheduled: 'Scheduled',
+    confirmed: 'Confirmed',
+    checked_in: 'Checked in',
+    in_chair: 'In chair',
+    completed: 'Completed',
+    cancelled: 'Cancelled',
+    no_show: 'No-show'
+};
+
+// setAppointmentStatus changes an appointment's status; reason is required when cancelling
+export async function setAppointmentStatus(id, status, reason = '') {
+    await SetAppointmentStatus(id, status, reason, getSessionToken(), getLicenseKey());
+    await loadAppointments();
+}
+
+// sessionFormForAppointment returns a new session form filled from a completed appointment
+export async function sessionFormForAppointment(id) {
+    return await SessionFormForAppointment(id, getSessionToken(), getLicenseKey());
+}
+
+// createAppointmentSeries books a recurring series; returns { series_id, appointment_ids, skipped }
+export async function createAppointmentSeries(form) {
+    const result = await CreateAppointmentSeries(form, getSessionToken(), getLicenseKey());
+    await loadAppointments();
+    return result;
+}
+
+// scope is 'this', 'following' or 'series'
+export async function updateAppointmentInSeries(appt, scope) {
+    await UpdateAppointmentInSeries(appt, scope, getSessionToken(), getLicenseKey());
+    await loadAppointments();
+}
+
+export async function cancelAppointmentInSeries(id, scope, reason) {
+    const cancelled = await CancelAppointmentInSeries(id, scope, reason, getSessionToken(), getLicenseKey());
+    await loadAppointments();
+    return cancelled;
+}
+
+// isConflictError reports whether an add/update failed because of an overlapping booking
+export function isConflictError(err) {
+    const message = err?.message || err || '';
+    return typeof message === 'string' &&
+        (message.startsWith('appointment conflicts with') || message.startsWith('series conflicts with'));
+}
+
+export async function deleteAppointment(id) {
     try {
         const licenseKey = getLicenseKey();
-        await UpdateAppointment(appt, licenseKey);
+        await DeleteAppointment(id, getSessionToken(), licenseKey);
         await loadAppointments();
     } catch (err) {
-        appointmentError.set(err.message || 'Failed to update appointment');
+        appointmentError.set(err.message || 'Failed to delete appointment');
     }
 }
 
-export async function deleteAppointment(id) {
+// loadAssignees loads the dentists and chairs that appointments can be assigned to
+export async function loadAssignees() {
     try {
         const licenseKey = getLicenseKey();
-        await DeleteAppointment(id, licenseKey);
-        await loadAppointments();
+        const [dentistList, chairList] = await Promise.all([
+            GetDentists(getSessionToken(), licenseKey),
+            GetChairs(getSessionToken(), licenseKey)
+        ]);
+        dentists.set(dentistList || []);
+        chairs.set(chairList || []);
     } catch (err) {
-        appointmentError.set(err.message || 'Failed to delete appointment');
+        appointmentError.set(err.message || 'Failed to load dentists and chairs');
     }
-} 
\ No newline at end of file
+}
+
+export async function createChair(chair) {
+    await CreateChair(chair, getSessionToken(), getLicenseKey());
+    await loadAssignees();
+}
+
+export async function updateChair(id, chair) {
+    await UpdateChair(id, chair, getSessionToken(), getLicenseKey());
+    await loadAssignees();
+}
+
+export async function deleteChair(id) {
+    await DeleteChair(id, getSessionToken(), getLicenseKey());
+    await loadAssignees();
+}
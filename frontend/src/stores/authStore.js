@@ -1,5 +1,5 @@
 import { writable, get } from 'svelte/store';
-import { Login, GetCurrentUser } from '../../wailsjs/go/main/App.js';
+import { Login, Logout, ValidateSession, GetPermissions, ChangePassword } from '../../wailsjs/go/main/App.js';
 import { currentLicenseKey } from './settingsStore.js';
 
 // Session state
@@ -8,6 +8,7 @@ export const currentUser = writable(null);
 export const sessionToken = writable(null);
 export const authError = writable(null);
 export const authLoading = writable(false);
+export const permissions = writable([]);
 
 // Helper function to get current license key
 function getLicenseKey() {
@@ -44,6 +45,8 @@ export async function login(username, password) {
             localStorage.setItem('dentist_user_id', response.user.id.toString());
             localStorage.setItem('dentist_username', response.user.username);
             localStorage.setItem('dentist_user_role', response.user.role);
+
+            await loadPermissions(response.token);
             
             return { success: true, user: response.user };
         } else {
@@ -59,11 +62,28 @@ export async function login(username, password) {
     }
 }
 
+// Returns the current session token, used to identify the acting user on the backend
+export function getSessionToken() {
+    let token = null;
+    try {
+        token = get(sessionToken);
+    } catch (e) {
+        token = null;
+    }
+    return token || localStorage.getItem('dentist_session_token') || '';
+}
+
 // Logout function
 export function logout() {
+    const token = getSessionToken();
+    if (token) {
+        Logout(token).catch((err) => console.error('[authStore] Logout error:', err));
+    }
+
     isAuthenticated.set(false);
     currentUser.set(null);
     sessionToken.set(null);
+    permissions.set([]);
     authError.set(null);
     
     // Clear localStorage
@@ -89,15 +109,52 @@ export function checkAuth() {
             role: role || 'Dentist'
         });
         isAuthenticated.set(true);
+
+        // The stored token may have expired or been revoked on the server
+        verifySession(token);
         return true;
     }
     
     return false;
 }
 
+// Verify a restored session with the backend and log out if it is no longer valid
+async function verifySession(token) {
+    try {
+        const user = await ValidateSession(token, getLicenseKey());
+        currentUser.set(user);
+        await loadPermissions(token);
+    } catch (err) {
+        console.warn('[authStore] Stored session rejected:', err);
+        logout();
+    }
+}
+
+// Change the current user's own password
+export async function changePassword(oldPassword, newPassword) {
+    try {
+        await ChangePassword({ old_password: oldPassword, new_password: newPassword }, getSessionToken(), getLicenseKey());
+        currentUser.update((user) => user ? { ...user, must_change_password: false } : user);
+        return { success: true };
+    } catch (err) {
+        return { success: false, message: err.message || err || 'Failed to change password' };
+    }
+}
+
 // Check if current user is admin
 export function isAdmin() {
     const user = get(currentUser);
     return user && user.role === 'Admin';
 }
 
+
+// Load the permissions granted to the session's role
+async function loadPermissions(token) {
+    try {
+        permissions.set(await GetPermissions(token, getLicenseKey()) || []);
+    } catch (err) {
+        console.error('[authStore] Failed to load permissions:', err);
+        permissions.set([]);
+    }
+}
+
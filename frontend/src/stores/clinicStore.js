@@ -0,0 +1,73 @@
+import { writable, derived, get } from 'svelte/store';
+import { GetClinicSettings, SaveClinicSettings, SetClinicLogo, SetTheme } from '../../wailsjs/go/main/App.js';
+import { currentLicenseKey, theme } from './settingsStore.js';
+import { getSessionToken, isAuthenticated } from './authStore.js';
+
+// Clinic profile and shared preferences, loaded from the database after login
+export const clinicSettings = writable(null);
+
+// Currency shown next to amounts; falls back to the default until settings load
+export const currencySymbol = derived(clinicSettings, ($settings) => $settings?.currency_symbol || 'SYP');
+
+// Default length of new appointments, in minutes
+export const defaultAppointmentDuration = derived(clinicSettings, ($settings) => $settings?.default_appointment_duration || 30);
+
+function getLicenseKey() {
+  try {
+    const key = get(currentLicenseKey);
+    if (key) {
+      return key;
+    }
+  } catch (err) {
+    // Fallback to localStorage in case store is unavailable
+  }
+  return localStorage.getItem('dentist_license_key') || '';
+}
+
+export async function loadClinicSettings() {
+  try {
+    const settings = await GetClinicSettings(getSessionToken(), getLicenseKey());
+    clinicSettings.set(settings);
+    if (settings?.theme) {
+      theme.set(settings.theme);
+    }
+    return { success: true, settings };
+  } catch (error) {
+    console.error('[ClinicStore] loadClinicSettings error:', error);
+    return { success: false, error: error?.message || error || 'Failed to load clinic settings' };
+  }
+}
+
+export async function saveClinicSettings(settings) {
+  try {
+    await SaveClinicSettings(settings, getSessionToken(), getLicenseKey());
+    return await loadClinicSettings();
+  } catch (error) {
+    console.error('[ClinicStore] saveClinicSettings error:', error);
+    return { success: false, error: error?.message || error || 'Failed to save clinic settings' };
+  }
+}
+
+// updateClinicLogo takes a PNG or JPEG data: URL, or '' to remove the logo
+export async function updateClinicLogo(dataURL) {
+  try {
+    await SetClinicLogo(dataURL, getSessionToken(), getLicenseKey());
+    return await loadClinicSettings();
+  } catch (error) {
+    console.error('[ClinicStore] updateClinicLogo error:', error);
+    return { success: false, error: error?.message || error || 'Failed to update logo' };
+  }
+}
+
+// toggleTheme switches between light and dark and, once signed in, shares the choice with other workstations
+export function toggleTheme() {
+  const next = get(theme) === 'dark' ? 'light' : 'dark';
+  theme.set(next);
+  clinicSettings.update((settings) => (settings ? { ...settings, theme: next } : settings));
+
+  if (get(isAuthenticated)) {
+    SetTheme(next, getSessionToken(), getLicenseKey()).catch((error) => {
+      console.error('[ClinicStore] SetTheme error:', error);
+    });
+  }
+}
@@ -0,0 +1,73 @@
+import {
+    GetNoteTemplates,
+    CreateNoteTemplate,
+    UpdateNoteTemplate,
+    DeleteNoteTemplate,
+    GetClinicalNote,
+    ApplyNoteTemplate,
+    SaveClinicalNote,
+    SignClinicalNote,
+    GetClinicalNoteVersions,
+    SearchClinicalNotes
+} from '../../wailsjs/go/main/App.js';
+import { getSessionToken } from './authStore.js';
+import { currentLicenseKey } from './settingsStore.js';
+
+// Helper function to get current license key
+function getLicenseKey() {
+    let licenseKey = '';
+    currentLicenseKey.subscribe(key => licenseKey = key)();
+    return licenseKey;
+}
+
+export const soapSections = [
+    { key: 'subjective', label: 'Subjective' },
+    { key: 'objective', label: 'Objective' },
+    { key: 'assessment', label: 'Assessment' },
+    { key: 'plan', label: 'Plan' }
+];
+
+// Placeholders a template may use; anaesthetic and lot are asked for when the template is applied
+export const notePlaceholders = ['{patient}', '{date}', '{dentist}', '{teeth}', '{procedure}', '{anaesthetic}', '{lot}'];
+
+// Errors are rethrown so the note forms can show validation messages
+
+export async function getNoteTemplates() {
+    return await GetNoteTemplates(getSessionToken(), getLicenseKey()) || [];
+}
+
+export async function createNoteTemplate(template) {
+    return await CreateNoteTemplate(template, getSessionToken(), getLicenseKey());
+}
+
+export async function updateNoteTemplate(template) {
+    await UpdateNoteTemplate(template, getSessionToken(), getLicenseKey());
+}
+
+export async function deleteNoteTemplate(id) {
+    await DeleteNoteTemplate(id, getSessionToken(), getLicenseKey());
+}
+
+export async function getClinicalNote(sessionId) {
+    return await GetClinicalNote(sessionId, getSessionToken(), getLicenseKey());
+}
+
+export async function applyNoteTemplate(note, templateId, values) {
+    return await ApplyNoteTemplate(note, templateId, values, getSessionToken(), getLicenseKey());
+}
+
+export async function saveClinicalNote(note) {
+    await SaveClinicalNote(note, getSessionToken(), getLicenseKey());
+}
+
+export async function signClinicalNote(sessionId) {
+    await SignClinicalNote(sessionId, getSessionToken(), getLicenseKey());
+}
+
+export async function getClinicalNoteVersions(sessionId) {
+    return await GetClinicalNoteVersions(sessionId, getSessionToken(), getLicenseKey()) || [];
+}
+
+export async function searchClinicalNotes(text, patientId = 0) {
+    return await SearchClinicalNotes(text, patientId, getSessionToken(), getLicenseKey()) || [];
+}
@@ -6,7 +6,7 @@ import {
     DeleteColorShade 
 } from '../../wailsjs/go/main/App.js';
 import { currentLicenseKey } from './settingsStore.js';
-import { currentUser } from './authStore.js';
+import { getSessionToken } from './authStore.js';
 
 // Store state
 export const colorShades = writable([]);
@@ -62,7 +62,7 @@ export async function loadColorShadesPaginated(page = 1) {
         }
 
         const pageSize = get(colorShadesPageSize) || 10;
-        const response = await GetColorShadesPaginated(page, pageSize, licenseKey);
+        const response = await GetColorShadesPaginated(page, pageSize, getSessionToken(), licenseKey);
         
         if (response) {
             colorShades.set(response.color_shades || []);
@@ -100,14 +100,13 @@ export async function createColorShade(form) {
             throw new Error('License key required. Please validate your license.');
         }
         
-        const user = get(currentUser);
-        const userID = user ? user.id : parseInt(localStorage.getItem('dentist_user_id') || '0');
+        const token = getSessionToken();
         
-        if (!userID || userID === 0) {
+        if (!token) {
             throw new Error('User not authenticated. Please log in.');
         }
         
-        await CreateColorShade(form, userID, licenseKey);
+        await CreateColorShade(form, token, licenseKey);
         colorShadesSuccess.set('Color shade created successfully');
         
         // Reload current page
@@ -139,14 +138,13 @@ export async function updateColorShade(id, form) {
             throw new Error('License key required. Please validate your license.');
         }
         
-        const user = get(currentUser);
-        const userID = user ? user.id : parseInt(localStorage.getItem('dentist_user_id') || '0');
+        const token = getSessionToken();
         
-        if (!userID || userID === 0) {
+        if (!token) {
             throw new Error('User not authenticated. Please log in.');
         }
         
-        await UpdateColorShade(id, form, userID, licenseKey);
+        await UpdateColorShade(id, form, token, licenseKey);
         colorShadesSuccess.set('Color shade updated successfully');
         
         // Reload current page
@@ -177,7 +175,7 @@ export async function deleteColorShade(id) {
         if (!licenseKey) {
             throw new Error('License key required. Please validate your license.');
         }
-        await DeleteColorShade(id, licenseKey);
+        await DeleteColorShade(id, getSessionToken(), licenseKey);
         colorShadesSuccess.set('Color shade deleted successfully');
         
         // Reload current page
@@ -6,6 +6,7 @@ import {
     UpdateDentalLab, 
     DeleteDentalLab 
 } from '../../wailsjs/go/main/App.js';
+import { getSessionToken } from './authStore.js';
 import { currentLicenseKey } from './settingsStore.js';
 
 // Store state
@@ -123,7 +124,7 @@ export async function loadDentalLabsPaginated(page = 1) {
         }
 
         const pageSize = get(dentalLabsPageSize) || 10;
-        const response = await GetDentalLabsPaginated(page, pageSize, licenseKey);
+        const response = await GetDentalLabsPaginated(page, pageSize, getSessionToken(), licenseKey);
         
         if (response) {
             dentalLabs.set(response.labs || []);
@@ -156,7 +157,7 @@ export async function getDentalLab(id) {
         if (!licenseKey) {
             throw new Error('License key required. Please validate your license.');
         }
-        return await GetDentalLab(id, licenseKey);
+        return await GetDentalLab(id, getSessionToken(), licenseKey);
     } catch (err) {
         console.error('[dentalLabStore] getDentalLab error:', err);
         throw err;
@@ -175,7 +176,7 @@ export async function createDentalLab(form) {
             throw new Error('License key required. Please validate your license.');
         }
         
-        await CreateDentalLab(form, licenseKey);
+        await CreateDentalLab(form, getSessionToken(), licenseKey);
         dentalLabsSuccess.set('Dental lab created successfully');
         
         // Reload current page
@@ -209,7 +210,7 @@ export async function updateDentalLab(id, form) {
             throw new Error('License key required. Please validate your license.');
         }
         
-        await UpdateDentalLab(id, form, licenseKey);
+        await UpdateDentalLab(id, form, getSessionToken(), licenseKey);
         dentalLabsSuccess.set('Dental lab updated successfully');
         
         // Reload current page
@@ -241,7 +242,7 @@ export async function deleteDentalLab(id) {
         if (!licenseKey) {
             throw new Error('License key required. Please validate your license.');
         }
-        await DeleteDentalLab(id, licenseKey);
+        await DeleteDentalLab(id, getSessionToken(), licenseKey);
         dentalLabsSuccess.set('Dental lab deleted successfully');
         
         // Reload current page
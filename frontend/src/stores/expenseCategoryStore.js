@@ -8,7 +8,7 @@ import {
     PermanentlyDeleteExpenseCategory 
 } from '../../wailsjs/go/main/App.js';
 import { currentLicenseKey } from './settingsStore.js';
-import { currentUser } from './authStore.js';
+import { getSessionToken } from './authStore.js';
 
 // Store state
 export const expenseCategories = writable([]);
@@ -57,7 +57,7 @@ export async function loadExpenseCategories() {
     
     try {
         const licenseKey = getLicenseKey();
-        const categories = await GetExpenseCategories(licenseKey);
+        const categories = await GetExpenseCategories(getSessionToken(), licenseKey);
         expenseCategories.set(categories || []);
         expenseCategoriesLoading.set(false);
     } catch (error) {
@@ -75,7 +75,7 @@ export async function loadExpenseCategoriesPaginated(page = 1) {
     try {
         const licenseKey = getLicenseKey();
         const pageSize = get(expenseCategoriesPageSize);
-        const response = await GetExpenseCategoriesPaginated(page, pageSize, licenseKey);
+        const response = await GetExpenseCategoriesPaginated(page, pageSize, getSessionToken(), licenseKey);
         
         if (response) {
             // Handle both possible response structures
@@ -101,14 +101,13 @@ export async function createExpenseCategory(category) {
     
     try {
         const licenseKey = getLicenseKey();
-        const user = get(currentUser);
-        const userID = user ? user.id : parseInt(localStorage.getItem('dentist_user_id') || '0');
+        const token = getSessionToken();
         
-        if (!userID || userID === 0) {
+        if (!token) {
             throw new Error('User not authenticated');
         }
         
-        const id = await CreateExpenseCategory(category, userID, licenseKey);
+        const id = await CreateExpenseCategory(category, token, licenseKey);
         
         // Reload categories
         await loadExpenseCategoriesPaginated(get(expenseCategoriesCurrentPage));
@@ -132,14 +131,13 @@ export async function updateExpenseCategory(id, category) {
     
     try {
         const licenseKey = getLicenseKey();
-        const user = get(currentUser);
-        const userID = user ? user.id : parseInt(localStorage.getItem('dentist_user_id') || '0');
+        const token = getSessionToken();
         
-        if (!userID || userID === 0) {
+        if (!token) {
             throw new Error('User not authenticated');
         }
         
-        await UpdateExpenseCategory(id, category, userID, licenseKey);
+        await UpdateExpenseCategory(id, category, token, licenseKey);
         
         // Reload categories
         await loadExpenseCategoriesPaginated(get(expenseCategoriesCurrentPage));
@@ -162,7 +160,7 @@ export async function deleteExpenseCategory(id) {
     
     try {
         const licenseKey = getLicenseKey();
-        await DeleteExpenseCategory(id, licenseKey);
+        await DeleteExpenseCategory(id, getSessionToken(), licenseKey);
         
         // Reload categories
         await loadExpenseCategoriesPaginated(get(expenseCategoriesCurrentPage));
@@ -185,7 +183,7 @@ export async function permanentlyDeleteExpenseCategory(id) {
     
     try {
         const licenseKey = getLicenseKey();
-        await PermanentlyDeleteExpenseCategory(id, licenseKey);
+        await PermanentlyDeleteExpenseCategory(id, getSessionToken(), licenseKey);
         
         // Reload categories
         await loadExpenseCategoriesPaginated(get(expenseCategoriesCurrentPage));
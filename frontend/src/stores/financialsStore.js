@@ -1,5 +1,6 @@
 import { writable, get } from 'svelte/store';
 import { GetInvoiceOverview } from '../../wailsjs/go/main/App.js';
+import { getSessionToken } from './authStore.js';
 import { currentLicenseKey } from './settingsStore.js';
 
 const defaultOverview = {
@@ -37,7 +38,7 @@ export async function loadInvoiceOverview() {
             throw new Error('License key required. Please validate your license.');
         }
 
-        const data = await GetInvoiceOverview(licenseKey);
+        const data = await GetInvoiceOverview(getSessionToken(), licenseKey);
         invoiceOverview.set(data || defaultOverview);
     } catch (error) {
         console.error('[financialsStore] Failed to load invoice overview', error);
@@ -1,5 +1,6 @@
 import { writable, get } from 'svelte/store';
 import { GetInvoices } from '../../wailsjs/go/main/App.js';
+import { getSessionToken } from './authStore.js';
 import { currentLicenseKey } from './settingsStore.js';
 
 const PAGE_SIZE = 5;
@@ -32,7 +33,7 @@ export async function loadInvoices(page = 1) {
       throw new Error('License key required. Please validate your license.');
     }
 
-    const result = await GetInvoices(page, PAGE_SIZE, licenseKey);
+    const result = await GetInvoices(page, PAGE_SIZE, getSessionToken(), licenseKey);
     invoices.set(result?.invoices || []);
     invoicesCurrentPage.set(result?.current_page || 1);
     invoicesTotalPages.set(result?.total_pages || 1);
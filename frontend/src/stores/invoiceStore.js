@@ -3,6 +3,7 @@ import {
     GetInvoiceBySession,
     PreviewInvoice
 } from '../../wailsjs/go/main/App.js';
+import { getSessionToken } from './authStore.js';
 import { currentLicenseKey } from './settingsStore.js';
 import { get } from 'svelte/store';
 
@@ -27,7 +28,7 @@ export async function createInvoice(sessionID) {
             throw new Error('License key required. Please validate your license.');
         }
         
-        const invoice = await CreateInvoice(sessionID, licenseKey);
+        const invoice = await CreateInvoice(sessionID, getSessionToken(), licenseKey);
         return { success: true, invoice };
     } catch (error) {
         console.error('Error creating invoice:', error);
@@ -43,7 +44,7 @@ export async function getInvoiceBySession(sessionID) {
             throw new Error('License key required. Please validate your license.');
         }
         
-        const invoice = await GetInvoiceBySession(sessionID, licenseKey);
+        const invoice = await GetInvoiceBySession(sessionID, getSessionToken(), licenseKey);
         return { success: true, invoice };
     } catch (error) {
         console.error('Error getting invoice:', error);
@@ -59,7 +60,7 @@ export async function previewInvoice(sessionID) {
             throw new Error('License key required. Please validate your license.');
         }
         
-        const preview = await PreviewInvoice(sessionID, licenseKey);
+        const preview = await PreviewInvoice(sessionID, getSessionToken(), licenseKey);
         return { success: true, preview };
     } catch (error) {
         console.error('Error previewing invoice:', error);
@@ -5,6 +5,7 @@ import {
     CreateLabOrder
 } from '../../wailsjs/go/main/App.js';
 import { currentLicenseKey } from './settingsStore.js';
+import { getSessionToken } from './authStore.js';
 
 // Store state
 export const labOrders = writable([]);
@@ -59,7 +60,7 @@ export async function loadLabOrdersPaginated(page = 1, searchOrderNumber = '', s
         }
 
         const pageSize = get(labOrdersPageSize) || 20;
-        const response = await GetLabOrdersPaginated(page, pageSize, searchOrderNumber, searchPatientName, searchLabName, statusFilter, licenseKey);
+        const response = await GetLabOrdersPaginated(page, pageSize, searchOrderNumber, searchPatientName, searchLabName, statusFilter, getSessionToken(), licenseKey);
         
         if (response) {
             labOrders.set(response.orders || []);
@@ -92,7 +93,7 @@ export async function getLabOrder(id) {
         if (!licenseKey) {
             throw new Error('License key required. Please validate your license.');
         }
-        return await GetLabOrder(id, licenseKey);
+        return await GetLabOrder(id, getSessionToken(), licenseKey);
     } catch (err) {
         console.error('[labOrderStore] getLabOrder error:', err);
         throw err;
@@ -136,7 +137,7 @@ export function formatOrderDateTime(dateString) {
 }
 
 // Create a new lab order
-export async function createLabOrder(form, userID) {
+export async function createLabOrder(form) {
     labOrdersLoading.set(true);
     labOrdersError.set(null);
 
@@ -146,7 +147,7 @@ export async function createLabOrder(form, userID) {
             throw new Error('License key required. Please validate your license.');
         }
         
-        const result = await CreateLabOrder(form, userID, licenseKey);
+        const result = await CreateLabOrder(form, getSessionToken(), licenseKey);
         
         if (result && result.id && result.order_number) {
             // Reload orders list to show the new order
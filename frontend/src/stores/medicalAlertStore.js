@@ -0,0 +1,64 @@
+import {
+    GetPatientMedicalAlerts,
+    CreateMedicalAlert,
+    UpdateMedicalAlert,
+    ReviewMedicalAlert,
+    DeleteMedicalAlert
+} from '../../wailsjs/go/main/App.js';
+import { getSessionToken } from './authStore.js';
+import { currentLicenseKey } from './settingsStore.js';
+
+// Helper function to get current license key
+function getLicenseKey() {
+    let licenseKey = '';
+    currentLicenseKey.subscribe(key => licenseKey = key)();
+    return licenseKey;
+}
+
+export const medicalAlertTypes = [
+    { value: 'anticoagulant', label: 'Anticoagulants' },
+    { value: 'penicillin_allergy', label: 'Penicillin allergy' },
+    { value: 'latex_allergy', label: 'Latex allergy' },
+    { value: 'local_anaesthetic_allergy', label: 'Local anaesthetic allergy' },
+    { value: 'pregnancy', label: 'Pregnancy' },
+    { value: 'bisphosphonate', label: 'Bisphosphonates' },
+    { value: 'diabetes', label: 'Diabetes' },
+    { value: 'cardiac', label: 'Cardiac condition' },
+    { value: 'other', label: 'Other' }
+];
+
+export const medicalAlertSeverities = [
+    { value: 'critical', label: 'Critical' },
+    { value: 'warning', label: 'Warning' },
+    { value: 'info', label: 'Info' }
+];
+
+// alertLabel names an alert by its type, or by its description for alerts of type other
+export function alertLabel(alert) {
+    if (alert.alert_type === 'other') {
+        return alert.description;
+    }
+    return medicalAlertTypes.find(t => t.value === alert.alert_type)?.label || alert.alert_type;
+}
+
+// Errors are rethrown so the alert form can show validation messages
+
+export async function getPatientMedicalAlerts(patientId) {
+    return await GetPatientMedicalAlerts(patientId, getSessionToken(), getLicenseKey()) || [];
+}
+
+export async function createMedicalAlert(alert) {
+    return await CreateMedicalAlert(alert, getSessionToken(), getLicenseKey());
+}
+
+export async function updateMedicalAlert(alert) {
+    await UpdateMedicalAlert(alert, getSessionToken(), getLicenseKey());
+}
+
+export async function reviewMedicalAlert(id, nextReviewDate) {
+    await ReviewMedicalAlert(id, nextReviewDate, getSessionToken(), getLicenseKey());
+}
+
+export async function deleteMedicalAlert(id) {
+    await DeleteMedicalAlert(id, getSessionToken(), getLicenseKey());
+}
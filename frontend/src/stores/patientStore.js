@@ -1,13 +1,16 @@
 import { writable, get } from 'svelte/store';
 import { 
     GetPatients, 
+    GetPatient,
     SearchPatients, 
     AddPatient, 
     UpdatePatient, 
     DeletePatient, 
     DeleteAllPatients,
-    OpenPatientFolder
+    OpenPatientFolder,
+    GetUpcomingBirthdays
 } from '../../wailsjs/go/main/App.js';
+import { getSessionToken } from './authStore.js';
 import { currentLicenseKey, account } from './settingsStore.js';
 
 // Create stores
@@ -62,7 +65,7 @@ export async function loadPatients() {
     
     try {
         const licenseKey = getLicenseKey();
-        const patientList = await GetPatients(licenseKey);
+        const patientList = await GetPatients(getSessionToken(), licenseKey);
         patients.set(patientList);
     } catch (err) {
         const errorMessage = err.message || 'Failed to load patients';
@@ -78,9 +81,18 @@ export async function loadPatients() {
     }
 }
 
-// Search patients
-export async function searchPatients(term) {
-    if (!term.trim()) {
+// Age bands offered when filtering the patient list
+export const ageBands = [
+    { value: '', label: 'All ages' },
+    { value: 'child', label: 'Under 18', max_age: 17 },
+    { value: 'young', label: '18–39', min_age: 18, max_age: 39 },
+    { value: 'middle', label: '40–64', min_age: 40, max_age: 64 },
+    { value: 'senior', label: '65 and over', min_age: 65 }
+];
+
+// Search patients, optionally within an age band ({ min_age, max_age })
+export async function searchPatients(term, filters = {}) {
+    if (!term.trim() && filters.min_age == null && filters.max_age == null) {
         await loadPatients();
         return;
     }
@@ -90,7 +102,7 @@ export async function searchPatients(term) {
     
     try {
         const licenseKey = getLicenseKey();
-        const results = await SearchPatients(term, licenseKey);
+        const results = await SearchPatients(term, filters, getSessionToken(), licenseKey);
         patients.set(results);
     } catch (err) {
         const errorMessage = err.message || 'Failed to search patients';
@@ -101,6 +113,11 @@ export async function searchPatients(term) {
     }
 }
 
+// Patients with a birthday between from and to (YYYY-MM-DD); errors are rethrown for the caller
+export async function getUpcomingBirthdays(from, to) {
+    return await GetUpcomingBirthdays(from, to, getSessionToken(), getLicenseKey()) || [];
+}
+
 // Add new patient
 export async function addPatient(patientData) {
     loading.set(true);
@@ -108,7 +125,7 @@ export async function addPatient(patientData) {
     
     try {
         const licenseKey = getLicenseKey();
-        const newId = await AddPatient(patientData, licenseKey);
+        const newId = await AddPatient(patientData, getSessionToken(), licenseKey);
         await loadPatients(); // Reload the list
         return newId;
     } catch (err) {
@@ -128,7 +145,7 @@ export async function updatePatient(patientData) {
     
     try {
         const licenseKey = getLicenseKey();
-        await UpdatePatient(patientData, licenseKey);
+        await UpdatePatient(patientData, getSessionToken(), licenseKey);
         await loadPatients(); // Reload the list
     } catch (err) {
         const errorMessage = err.message || 'Failed to update patient';
@@ -140,6 +157,11 @@ export async function updatePatient(patientData) {
     }
 }
 
+// Get one patient, including the no-show and late cancellation counts
+export async function getPatient(id) {
+    return await GetPatient(id, getSessionToken(), getLicenseKey());
+}
+
 // Delete patient
 export async function deletePatient(id) {
     loading.set(true);
@@ -147,7 +169,7 @@ export async function deletePatient(id) {
     
     try {
         const licenseKey = getLicenseKey();
-        await DeletePatient(id, licenseKey);
+        await DeletePatient(id, getSessionToken(), licenseKey);
         await loadPatients(); // Reload the list
     } catch (err) {
         const errorMessage = err.message || 'Failed to delete patient';
@@ -165,7 +187,7 @@ export async function deleteAllPatients() {
     error.set(null);
     try {
         const licenseKey = getLicenseKey();
-        await DeleteAllPatients(licenseKey);
+        await DeleteAllPatients(getSessionToken(), licenseKey);
         await loadPatients(); // Reload the list
     } catch (err) {
         const errorMessage = err.message || 'Failed to delete all patients';
@@ -220,7 +242,7 @@ export async function openPatientFolder(patientId) {
             throw new Error('No license key found. Please check your license settings.');
         }
         
-        await OpenPatientFolder(patientId, licenseKey);
+        await OpenPatientFolder(patientId, getSessionToken(), licenseKey);
         console.log('OpenPatientFolder - Success: Folder opened for patient', patientId);
     } catch (err) {
         const errorMessage = err.message || 'Failed to open patient folder';
@@ -1,5 +1,6 @@
 import { writable, get } from 'svelte/store';
 import { GetInvoicePayments } from '../../wailsjs/go/main/App.js';
+import { getSessionToken } from './authStore.js';
 import { currentLicenseKey } from './settingsStore.js';
 
 const PAGE_SIZE = 10;
@@ -32,7 +33,7 @@ export async function loadPayments(page = 1) {
       throw new Error('License key required. Please validate your license.');
     }
 
-    const response = await GetInvoicePayments(page, PAGE_SIZE, licenseKey);
+    const response = await GetInvoicePayments(page, PAGE_SIZE, getSessionToken(), licenseKey);
     payments.set(response?.payments || []);
     paymentsCurrentPage.set(response?.current_page || 1);
     paymentsTotalPages.set(response?.total_pages || 1);
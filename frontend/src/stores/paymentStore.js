@@ -1,6 +1,7 @@
 import { writable, get } from 'svelte/store';
 import { currentLicenseKey } from './settingsStore.js';
-import { GetInvoicePaymentDetails, CreateInvoicePayment } from '../../wailsjs/go/main/App.js';
+import { GetInvoicePaymentDetails, CreateInvoicePayment, ExportInvoicePDF } from '../../wailsjs/go/main/App.js';
+import { getSessionToken } from './authStore.js';
 
 const paymentDetailsStore = writable(null);
 const paymentLoading = writable(false);
@@ -34,7 +35,7 @@ export async function loadInvoicePayments(invoiceId) {
       throw new Error('License key required. Please validate your license.');
     }
 
-    const details = await GetInvoicePaymentDetails(invoiceId, licenseKey);
+    const details = await GetInvoicePaymentDetails(invoiceId, getSessionToken(), licenseKey);
     paymentDetailsStore.set(details);
     return { success: true, details };
   } catch (error) {
@@ -58,7 +59,7 @@ export async function addInvoicePayment({ invoiceId, amount, paymentDate, note }
       throw new Error('License key required. Please validate your license.');
     }
 
-    const details = await CreateInvoicePayment(invoiceId, amount, paymentDate, note || '', licenseKey);
+    const details = await CreateInvoicePayment(invoiceId, amount, paymentDate, note || '', getSessionToken(), licenseKey);
     paymentDetailsStore.set(details);
     return { success: true, details };
   } catch (error) {
@@ -67,6 +68,25 @@ export async function addInvoicePayment({ invoiceId, amount, paymentDate, note }
   }
 }
 
+export async function exportInvoicePDF(invoiceId) {
+  if (!invoiceId) {
+    return { success: false, error: 'Missing invoice id' };
+  }
+
+  try {
+    const licenseKey = getLicenseKey();
+    if (!licenseKey) {
+      throw new Error('License key required. Please validate your license.');
+    }
+
+    const path = await ExportInvoicePDF(invoiceId, getSessionToken(), licenseKey);
+    return { success: true, path };
+  } catch (error) {
+    console.error('[PaymentStore] exportInvoicePDF error:', error);
+    return { success: false, error: error?.message || 'Failed to export invoice' };
+  }
+}
+
 export const paymentDetails = paymentDetailsStore;
 export const paymentDetailsLoading = paymentLoading;
 export const paymentDetailsError = paymentError;
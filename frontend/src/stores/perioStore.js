@@ -0,0 +1,59 @@
+import {
+    GetPerioExams,
+    GetPerioExam,
+    CreatePerioExam,
+    UpdatePerioExam,
+    DeletePerioExam,
+    ComparePerioExams
+} from '../../wailsjs/go/main/App.js';
+import { getSessionToken } from './authStore.js';
+import { currentLicenseKey } from './settingsStore.js';
+
+// Helper function to get current license key
+function getLicenseKey() {
+    let licenseKey = '';
+    currentLicenseKey.subscribe(key => licenseKey = key)();
+    return licenseKey;
+}
+
+// Probing sites in chart order: three buccal, then three lingual/palatal
+export const perioSites = ['DB', 'B', 'MB', 'DL', 'L', 'ML'];
+
+// Pockets this deep (mm) are counted as deep sites
+export const perioDeepPocket = 5;
+
+// emptyPerioTooth returns a tooth row with all six sites at zero
+export function emptyPerioTooth(tooth) {
+    return {
+        tooth,
+        mobility: 0,
+        furcation: 0,
+        sites: perioSites.map(site => ({ site, probing_depth: 0, recession: 0, bleeding: false }))
+    };
+}
+
+// Errors are rethrown so the exam form can show validation messages
+
+export async function getPerioExams(patientId) {
+    return await GetPerioExams(patientId, getSessionToken(), getLicenseKey()) || [];
+}
+
+export async function getPerioExam(id) {
+    return await GetPerioExam(id, getSessionToken(), getLicenseKey());
+}
+
+export async function createPerioExam(exam) {
+    return await CreatePerioExam(exam, getSessionToken(), getLicenseKey());
+}
+
+export async function updatePerioExam(exam) {
+    await UpdatePerioExam(exam, getSessionToken(), getLicenseKey());
+}
+
+export async function deletePerioExam(id) {
+    await DeletePerioExam(id, getSessionToken(), getLicenseKey());
+}
+
+export async function comparePerioExams(firstId, secondId) {
+    return await ComparePerioExams(firstId, secondId, getSessionToken(), getLicenseKey());
+}
@@ -0,0 +1,106 @@
+import {
+    GetDrugs,
+    CreateDrug,
+    UpdateDrug,
+    DeleteDrug,
+    GetDrugRules,
+    CreateDrugRule,
+    UpdateDrugRule,
+    DeleteDrugRule,
+    CheckPrescription,
+    CreatePrescription,
+    DeletePrescription,
+    GetSessionPrescriptions,
+    GetPatientPrescriptions,
+    ExportPrescriptionPDF
+} from '../../wailsjs/go/main/App.js';
+import { getSessionToken } from './authStore.js';
+import { currentLicenseKey } from './settingsStore.js';
+
+// Helper function to get current license key
+function getLicenseKey() {
+    let licenseKey = '';
+    currentLicenseKey.subscribe(key => licenseKey = key)();
+    return licenseKey;
+}
+
+export const drugRuleKinds = [
+    { value: 'allergy', label: 'Allergy' },
+    { value: 'interaction', label: 'Interaction' }
+];
+
+export const drugRuleSeverities = [
+    { value: 'warning', label: 'Warning' },
+    { value: 'contraindicated', label: 'Contraindicated' }
+];
+
+// A new prescription line filled from the drug's defaults
+export function prescriptionLine(drug) {
+    return {
+        drug_id: drug.id,
+        drug_name: drug.name,
+        strength: drug.strength,
+        dose: drug.default_dose,
+        frequency: drug.default_frequency,
+        duration: drug.default_duration,
+        instructions: drug.default_instructions
+    };
+}
+
+// Errors are rethrown so the prescription forms can show validation messages
+
+export async function getDrugs() {
+    return await GetDrugs(getSessionToken(), getLicenseKey()) || [];
+}
+
+export async function createDrug(drug) {
+    return await CreateDrug(drug, getSessionToken(), getLicenseKey());
+}
+
+export async function updateDrug(drug) {
+    await UpdateDrug(drug, getSessionToken(), getLicenseKey());
+}
+
+export async function deleteDrug(id) {
+    await DeleteDrug(id, getSessionToken(), getLicenseKey());
+}
+
+export async function getDrugRules() {
+    return await GetDrugRules(getSessionToken(), getLicenseKey()) || [];
+}
+
+export async function createDrugRule(rule) {
+    return await CreateDrugRule(rule, getSessionToken(), getLicenseKey());
+}
+
+export async function updateDrugRule(rule) {
+    await UpdateDrugRule(rule, getSessionToken(), getLicenseKey());
+}
+
+export async function deleteDrugRule(id) {
+    await DeleteDrugRule(id, getSessionToken(), getLicenseKey());
+}
+
+export async function checkPrescription(sessionId, items) {
+    return await CheckPrescription(sessionId, items, getSessionToken(), getLicenseKey()) || [];
+}
+
+export async function createPrescription(prescription) {
+    return await CreatePrescription(prescription, getSessionToken(), getLicenseKey());
+}
+
+export async function deletePrescription(id) {
+    await DeletePrescription(id, getSessionToken(), getLicenseKey());
+}
+
+export async function getSessionPrescriptions(sessionId) {
+    return await GetSessionPrescriptions(sessionId, getSessionToken(), getLicenseKey()) || [];
+}
+
+export async function getPatientPrescriptions(patientId) {
+    return await GetPatientPrescriptions(patientId, getSessionToken(), getLicenseKey()) || [];
+}
+
+export async function exportPrescriptionPDF(id) {
+    return await ExportPrescriptionPDF(id, getSessionToken(), getLicenseKey());
+}
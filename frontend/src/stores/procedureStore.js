@@ -6,6 +6,7 @@ import {
     UpdateProcedure, 
     DeleteProcedure 
 } from '../../wailsjs/go/main/App.js';
+import { getSessionToken } from './authStore.js';
 import { currentLicenseKey } from './settingsStore.js';
 
 // Store state
@@ -70,7 +71,7 @@ export async function loadProcedures() {
         
         // console.log('[procedureStore] Calling GetProcedures backend function...');
         // const startTime = Date.now();
-        const list = await GetProcedures(licenseKey);
+        const list = await GetProcedures(getSessionToken(), licenseKey);
         // const duration = Date.now() - startTime;
         // console.log('[procedureStore] GetProcedures returned after', duration, 'ms');
         // console.log('[procedureStore] Raw response:', list);
@@ -114,7 +115,7 @@ export async function createProcedure(form) {
             throw new Error('License key required. Please validate your license.');
         }
         console.log('[procedureStore] Calling CreateProcedure backend function...');
-        const result = await CreateProcedure(form, licenseKey);
+        const result = await CreateProcedure(form, getSessionToken(), licenseKey);
         console.log('[procedureStore] CreateProcedure returned:', result);
         proceduresSuccess.set('Procedure created successfully');
         console.log('[procedureStore] Reloading procedures...');
@@ -149,7 +150,7 @@ export async function updateProcedure(procedure) {
         if (!licenseKey) {
             throw new Error('License key required. Please validate your license.');
         }
-        await UpdateProcedure(procedure, licenseKey);
+        await UpdateProcedure(procedure, getSessionToken(), licenseKey);
         proceduresSuccess.set('Procedure updated successfully');
         // Reload current page if using pagination, otherwise load all
         const currentPage = get(proceduresCurrentPage);
@@ -179,7 +180,7 @@ export async function deleteProcedure(id) {
         if (!licenseKey) {
             throw new Error('License key required. Please validate your license.');
         }
-        await DeleteProcedure(id, licenseKey);
+        await DeleteProcedure(id, getSessionToken(), licenseKey);
         proceduresSuccess.set('Procedure deleted successfully');
         // Reload current page if using pagination, otherwise load all
         const currentPage = get(proceduresCurrentPage);
@@ -211,7 +212,7 @@ export async function loadProceduresPaginated(page = 1) {
         }
 
         const pageSize = get(proceduresPageSize) || 10;
-        const response = await GetProceduresPaginated(page, pageSize, licenseKey);
+        const response = await GetProceduresPaginated(page, pageSize, getSessionToken(), licenseKey);
         
         if (response) {
             procedures.set(response.procedures || []);
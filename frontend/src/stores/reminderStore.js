@@ -0,0 +1,40 @@
+import {
+    GetReminderSettings,
+    SaveReminderSettings,
+    GetOutboundMessages,
+    RetryOutboundMessage,
+    CancelOutboundMessage
+} from '../../wailsjs/go/main/App.js';
+import { getSessionToken } from './authStore.js';
+import { currentLicenseKey } from './settingsStore.js';
+
+// Helper function to get current license key
+function getLicenseKey() {
+    let licenseKey = '';
+    currentLicenseKey.subscribe(key => licenseKey = key)();
+    return licenseKey;
+}
+
+// Errors are rethrown so the reminder settings can show validation messages
+
+export async function getReminderSettings() {
+    return await GetReminderSettings(getSessionToken(), getLicenseKey());
+}
+
+// saveReminderSettings stores the settings; an empty sms_api_key or smtp_password keeps the current one
+export async function saveReminderSettings(settings) {
+    await SaveReminderSettings(settings, getSessionToken(), getLicenseKey());
+}
+
+// getOutboundMessages returns a page of the outbox; status may be '' for all messages
+export async function getOutboundMessages(status, page = 1, pageSize = 20) {
+    return await GetOutboundMessages(status, page, pageSize, getSessionToken(), getLicenseKey());
+}
+
+export async function retryOutboundMessage(id) {
+    await RetryOutboundMessage(id, getSessionToken(), getLicenseKey());
+}
+
+export async function cancelOutboundMessage(id) {
+    await CancelOutboundMessage(id, getSessionToken(), getLicenseKey());
+}
@@ -0,0 +1,68 @@
+import {
+    GetWorkingHours,
+    SetWorkingHours,
+    GetClinicHolidays,
+    AddClinicHoliday,
+    DeleteClinicHoliday,
+    GetTimeOff,
+    AddTimeOff,
+    DeleteTimeOff,
+    FindFreeSlots
+} from '../../wailsjs/go/main/App.js';
+import { getSessionToken } from './authStore.js';
+import { currentLicenseKey } from './settingsStore.js';
+
+// Helper function to get current license key
+function getLicenseKey() {
+    let licenseKey = '';
+    currentLicenseKey.subscribe(key => licenseKey = key)();
+    return licenseKey;
+}
+
+// Errors are rethrown so the schedule editor can show validation messages
+
+export async function getWorkingHours(userId) {
+    return await GetWorkingHours(userId, getSessionToken(), getLicenseKey()) || [];
+}
+
+// setWorkingHours replaces a dentist's weekly hours; each entry is { weekday, start_time, end_time }
+export async function setWorkingHours(userId, hours) {
+    await SetWorkingHours(userId, hours, getSessionToken(), getLicenseKey());
+}
+
+export async function getClinicHolidays() {
+    return await GetClinicHolidays(getSessionToken(), getLicenseKey()) || [];
+}
+
+export async function addClinicHoliday(holiday) {
+    return await AddClinicHoliday(holiday, getSessionToken(), getLicenseKey());
+}
+
+export async function deleteClinicHoliday(id) {
+    await DeleteClinicHoliday(id, getSessionToken(), getLicenseKey());
+}
+
+// getTimeOff returns time off for one dentist, or for everyone when userId is 0
+export async function getTimeOff(userId = 0) {
+    return await GetTimeOff(userId, getSessionToken(), getLicenseKey()) || [];
+}
+
+export async function addTimeOff(entry) {
+    return await AddTimeOff(entry, getSessionToken(), getLicenseKey());
+}
+
+export async function deleteTimeOff(id) {
+    await DeleteTimeOff(id, getSessionToken(), getLicenseKey());
+}
+
+// findFreeSlots returns { start, end } openings of duration minutes in the dentist's working hours
+// between two YYYY-MM-DD dates (inclusive)
+export async function findFreeSlots(dentistId, duration, from, to) {
+    return await FindFreeSlots(dentistId, duration, from, to, getSessionToken(), getLicenseKey()) || [];
+}
+
+// isOutsideHoursError reports whether an add/update failed because the dentist is not working then
+export function isOutsideHoursError(err) {
+    const message = err?.message || err || '';
+    return typeof message === 'string' && message.startsWith('appointment is outside working hours');
+}
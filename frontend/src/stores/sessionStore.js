@@ -6,6 +6,7 @@ import {
   UpdateSession,
   DeleteSession
 } from '../../wailsjs/go/main/App.js';
+import { getSessionToken } from './authStore.js';
 import { currentLicenseKey } from './settingsStore.js';
 
 const DEFAULT_PAGE_SIZE = 10;
@@ -73,7 +74,7 @@ export async function loadSessions(page = 1, filters = null) {
         };
         
         console.log('[sessionStore] Calling GetSessions backend function with filters:', backendFilters);
-        const result = await GetSessions(page, backendFilters, licenseKey);
+        const result = await GetSessions(page, backendFilters, getSessionToken(), licenseKey);
         console.log('[sessionStore] GetSessions returned:', result);
         console.log('[sessionStore] Result type:', typeof result);
         
@@ -138,7 +139,7 @@ export async function loadSession(id) {
             throw new Error('License key required. Please validate your license.');
         }
         
-        const session = await GetSession(id, licenseKey);
+        const session = await GetSession(id, getSessionToken(), licenseKey);
         currentSession.set(session);
         return session;
     } catch (err) {
@@ -165,7 +166,7 @@ export async function createSession(sessionForm) {
         }
         
         console.log('[sessionStore] Calling CreateSession backend function...');
-        const sessionId = await CreateSession(sessionForm, licenseKey);
+        const sessionId = await CreateSession(sessionForm, getSessionToken(), licenseKey);
         console.log('[sessionStore] CreateSession returned session ID:', sessionId);
         sessionsSuccess.set('Session created successfully');
         
@@ -195,7 +196,7 @@ export async function updateSession(session, items) {
         if (!licenseKey) {
             throw new Error('License key required. Please validate your license.');
         }
-        await UpdateSession(session, items, licenseKey);
+        await UpdateSession(session, items, getSessionToken(), licenseKey);
         sessionsSuccess.set('Session updated successfully');
         await loadSessions(get(currentPage), get(sessionFilters));
         if (currentSession) {
@@ -222,7 +223,7 @@ export async function deleteSession(id) {
         if (!licenseKey) {
             throw new Error('License key required. Please validate your license.');
         }
-        await DeleteSession(id, licenseKey);
+        await DeleteSession(id, getSessionToken(), licenseKey);
         sessionsSuccess.set('Session deleted successfully');
         await loadSessions(get(currentPage), get(sessionFilters));
         return true;
@@ -59,7 +59,10 @@ export async function validateCurrentLicense() {
             isValid: licenseInfo.is_valid,
             isChecking: false,
             lastChecked: new Date(),
-            message: licenseInfo.message
+            message: licenseInfo.message,
+            clinic: licenseInfo.clinic || '',
+            seats: licenseInfo.seats || 0,
+            legacy: licenseInfo.legacy
         });
 
         // Update expiry date if validation was successful
@@ -0,0 +1,83 @@
+import {
+    GetToothChart,
+    GetToothHistory,
+    AddToothCondition,
+    ResolveToothCondition
+} from '../../wailsjs/go/main/App.js';
+import { getSessionToken } from './authStore.js';
+import { currentLicenseKey } from './settingsStore.js';
+
+// Helper function to get current license key
+function getLicenseKey() {
+    let licenseKey = '';
+    currentLicenseKey.subscribe(key => licenseKey = key)();
+    return licenseKey;
+}
+
+export const toothConditionLabels = {
+    caries: 'Caries',
+    filling: 'Filling',
+    crown: 'Crown',
+    missing: 'Missing',
+    implant: 'Implant',
+    root_canal: 'Root canal'
+};
+
+// Caries and fillings are charted on surfaces; the other conditions cover the whole tooth
+export const surfaceConditions = ['caries', 'filling'];
+
+export const toothSurfaces = ['M', 'O', 'D', 'B', 'L'];
+
+// FDI rows as seen facing the patient: upper right, upper left / lower right, lower left
+function quadrant(q, count, reverse) {
+    const teeth = Array.from({ length: count }, (_, i) => q * 10 + i + 1);
+    return reverse ? teeth.reverse() : teeth;
+}
+
+export const permanentRows = [
+    [...quadrant(1, 8, true), ...quadrant(2, 8, false)],
+    [...quadrant(4, 8, true), ...quadrant(3, 8, false)]
+];
+
+export const primaryRows = [
+    [...quadrant(5, 5, true), ...quadrant(6, 5, false)],
+    [...quadrant(8, 5, true), ...quadrant(7, 5, false)]
+];
+
+// formatTeeth renders session item teeth as text, e.g. "16 MO, 36"
+export function formatTeeth(teeth) {
+    return (teeth || []).map(t => t.surfaces ? `${t.tooth} ${t.surfaces}` : `${t.tooth}`).join(', ');
+}
+
+// parseTeeth reads the text written by formatTeeth; the backend validates the numbers and surfaces
+export function parseTeeth(text) {
+    return (text || '')
+        .split(',')
+        .map(part => part.trim())
+        .filter(Boolean)
+        .map(part => {
+            const match = part.match(/^(\d+)\s*([A-Za-z]*)$/);
+            if (!match) {
+                throw new Error(`"${part}" is not a tooth; write the FDI number and surfaces, e.g. 16 MO`);
+            }
+            return { tooth: parseInt(match[1]), surfaces: match[2].toUpperCase() };
+        });
+}
+
+// Errors are rethrown so the chart can show validation messages
+
+export async function getToothChart(patientId) {
+    return await GetToothChart(patientId, getSessionToken(), getLicenseKey()) || [];
+}
+
+export async function getToothHistory(patientId, tooth) {
+    return await GetToothHistory(patientId, tooth, getSessionToken(), getLicenseKey()) || [];
+}
+
+export async function addToothCondition(form) {
+    return await AddToothCondition(form, getSessionToken(), getLicenseKey());
+}
+
+export async function resolveToothCondition(id) {
+    await ResolveToothCondition(id, getSessionToken(), getLicenseKey());
+}
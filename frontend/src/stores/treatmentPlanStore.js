@@ -0,0 +1,61 @@
+import {
+    GetTreatmentPlans,
+    CreateTreatmentPlan,
+    UpdateTreatmentPlan,
+    DeleteTreatmentPlan,
+    AcceptTreatmentPlan,
+    PlanSessionItems
+} from '../../wailsjs/go/main/App.js';
+import { getSessionToken } from './authStore.js';
+import { currentLicenseKey } from './settingsStore.js';
+
+// Helper function to get current license key
+function getLicenseKey() {
+    let licenseKey = '';
+    currentLicenseKey.subscribe(key => licenseKey = key)();
+    return licenseKey;
+}
+
+export const planItemStatusLabels = {
+    proposed: 'Proposed',
+    accepted: 'Accepted',
+    scheduled: 'Scheduled',
+    done: 'Done',
+    declined: 'Declined'
+};
+
+// openPlanItems lists the accepted items of a patient's plans that have not been performed yet
+export function openPlanItems(plans) {
+    return (plans || []).flatMap(plan =>
+        plan.items
+            .filter(item => item.status === 'accepted' || (item.status === 'scheduled' && !item.session_id))
+            .map(item => ({ ...item, plan_title: plan.title }))
+    );
+}
+
+// Errors are rethrown so the plan forms can show validation messages
+
+export async function getTreatmentPlans(patientId) {
+    return await GetTreatmentPlans(patientId, getSessionToken(), getLicenseKey()) || [];
+}
+
+export async function createTreatmentPlan(plan) {
+    return await CreateTreatmentPlan(plan, getSessionToken(), getLicenseKey());
+}
+
+export async function updateTreatmentPlan(plan) {
+    await UpdateTreatmentPlan(plan, getSessionToken(), getLicenseKey());
+}
+
+export async function deleteTreatmentPlan(id) {
+    await DeleteTreatmentPlan(id, getSessionToken(), getLicenseKey());
+}
+
+export async function acceptTreatmentPlan(id, acceptedItemIds) {
+    await AcceptTreatmentPlan(id, acceptedItemIds, getSessionToken(), getLicenseKey());
+}
+
+// planSessionItems returns session lines for the plan items being performed
+export async function planSessionItems(itemIds) {
+    return await PlanSessionItems(itemIds, getSessionToken(), getLicenseKey()) || [];
+}
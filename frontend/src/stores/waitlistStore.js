@@ -0,0 +1,84 @@
+import { writable } from 'svelte/store';
+import {
+    GetWaitlist,
+    AddWaitlistEntry,
+    UpdateWaitlistEntry,
+    RemoveWaitlistEntry,
+    FindWaitlistMatches,
+    OfferWaitlistSlot,
+    DeclineWaitlistOffer,
+    BookWaitlistSlot
+} from '../../wailsjs/go/main/App.js';
+import { getSessionToken } from './authStore.js';
+import { currentLicenseKey } from './settingsStore.js';
+import { loadAppointments } from './appointmentStore.js';
+
+export const waitlist = writable([]);
+
+// Helper function to get current license key
+function getLicenseKey() {
+    let licenseKey = '';
+    currentLicenseKey.subscribe(key => licenseKey = key)();
+    return licenseKey;
+}
+
+export const waitlistPriorityLabels = {
+    1: 'Urgent',
+    2: 'High',
+    3: 'Normal'
+};
+
+// Errors are rethrown so the waiting list can show validation messages
+
+// loadWaitlist loads the patients still waiting or offered a slot
+export async function loadWaitlist() {
+    waitlist.set(await GetWaitlist('', getSessionToken(), getLicenseKey()) || []);
+}
+
+export async function addWaitlistEntry(entry) {
+    const id = await AddWaitlistEntry(entry, getSessionToken(), getLicenseKey());
+    await loadWaitlist();
+    return id;
+}
+
+export async function updateWaitlistEntry(entry) {
+    await UpdateWaitlistEntry(entry, getSessionToken(), getLicenseKey());
+    await loadWaitlist();
+}
+
+export async function removeWaitlistEntry(id) {
+    await RemoveWaitlistEntry(id, getSessionToken(), getLicenseKey());
+    await loadWaitlist();
+}
+
+// findWaitlistMatches ranks the waiting patients who fit a slot { datetime, duration, dentist_id, chair_id }
+export async function findWaitlistMatches(slot) {
+    return await FindWaitlistMatches(slot, getSessionToken(), getLicenseKey()) || [];
+}
+
+// slotFromAppointment describes the time an appointment held, to offer it to the waiting list
+export function slotFromAppointment(appt) {
+    return {
+        datetime: appt.datetime,
+        duration: appt.duration,
+        dentist_id: appt.dentist_id,
+        chair_id: appt.chair_id
+    };
+}
+
+export async function offerWaitlistSlot(id, slot) {
+    await OfferWaitlistSlot(id, slot, getSessionToken(), getLicenseKey());
+    await loadWaitlist();
+}
+
+export async function declineWaitlistOffer(id) {
+    await DeclineWaitlistOffer(id, getSessionToken(), getLicenseKey());
+    await loadWaitlist();
+}
+
+// bookWaitlistSlot books the patient into the slot and returns the appointment ID
+export async function bookWaitlistSlot(id, slot) {
+    const appointmentId = await BookWaitlistSlot(id, slot, getSessionToken(), getLicenseKey());
+    await Promise.all([loadWaitlist(), loadAppointments()]);
+    return appointmentId;
+}
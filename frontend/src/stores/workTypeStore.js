@@ -6,7 +6,7 @@ import {
     DeleteWorkType 
 } from '../../wailsjs/go/main/App.js';
 import { currentLicenseKey } from './settingsStore.js';
-import { currentUser } from './authStore.js';
+import { getSessionToken } from './authStore.js';
 
 // Store state
 export const workTypes = writable([]);
@@ -61,7 +61,7 @@ export async function loadWorkTypesPaginated(page = 1) {
         }
 
         const pageSize = get(workTypesPageSize) || 10;
-        const response = await GetWorkTypesPaginated(page, pageSize, licenseKey);
+        const response = await GetWorkTypesPaginated(page, pageSize, getSessionToken(), licenseKey);
         
         if (response) {
             workTypes.set(response.work_types || []);
@@ -99,14 +99,13 @@ export async function createWorkType(form) {
             throw new Error('License key required. Please validate your license.');
         }
         
-        const user = get(currentUser);
-        const userID = user ? user.id : parseInt(localStorage.getItem('dentist_user_id') || '0');
+        const token = getSessionToken();
         
-        if (!userID || userID === 0) {
+        if (!token) {
             throw new Error('User not authenticated. Please log in.');
         }
         
-        await CreateWorkType(form, userID, licenseKey);
+        await CreateWorkType(form, token, licenseKey);
         workTypesSuccess.set('Work type created successfully');
         
         // Reload current page
@@ -138,14 +137,13 @@ export async function updateWorkType(id, form) {
             throw new Error('License key required. Please validate your license.');
         }
         
-        const user = get(currentUser);
-        const userID = user ? user.id : parseInt(localStorage.getItem('dentist_user_id') || '0');
+        const token = getSessionToken();
         
-        if (!userID || userID === 0) {
+        if (!token) {
             throw new Error('User not authenticated. Please log in.');
         }
         
-        await UpdateWorkType(id, form, userID, licenseKey);
+        await UpdateWorkType(id, form, token, licenseKey);
         workTypesSuccess.set('Work type updated successfully');
         
         // Reload current page
@@ -176,7 +174,7 @@ export async function deleteWorkType(id) {
         if (!licenseKey) {
             throw new Error('License key required. Please validate your license.');
         }
-        await DeleteWorkType(id, licenseKey);
+        await DeleteWorkType(id, getSessionToken(), licenseKey);
         workTypesSuccess.set('Work type deleted successfully');
         
         // Reload current page
@@ -3,6 +3,10 @@ package handlers
 import (
 	"DentistApp/models"
 	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
 )
 
 // AppointmentHandler handles appointment-related database operations
@@ -15,20 +19,123 @@ func NewAppointmentHandler(db *sql.DB) *AppointmentHandler {
 	return &AppointmentHandler{db: db}
 }
 
-// AddAppointment adds a new appointment to the database
-func (h *AppointmentHandler) AddAppointment(appt models.Appointment) (int64, error) {
-	query := `INSERT INTO appointments (patient_id, datetime, duration, notes) VALUES (?, ?, ?, ?)`
-	result, err := h.db.Exec(query, appt.PatientID, appt.DateTime, appt.Duration, appt.Notes)
+// appointmentTimeLayouts are the datetime formats stored by the frontend over time
+var appointmentTimeLayouts = []string{
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 03:04 PM",
+	time.RFC3339,
+}
+
+// parseAppointmentTime parses an appointment datetime as wall-clock time
+func parseAppointmentTime(value string) (time.Time, error) {
+	for _, layout := range appointmentTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid appointment date/time %q", value)
+}
+
+// AppointmentConflictError is returned when an appointment overlaps others for the same dentist or chair
+type AppointmentConflictError struct {
+	Conflicts []models.Appointment
+}
+
+func (e *AppointmentConflictError) Error() string {
+	clashes := make([]string, 0, len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		var with []string
+		if c.DentistName != "" {
+			with = append(with, "dentist "+c.DentistName)
+		}
+		if c.ChairName != "" {
+			with = append(with, c.ChairName)
+		}
+		clash := fmt.Sprintf("#%d at %s for %d min", c.ID, strings.Replace(c.DateTime, "T", " ", 1), c.Duration)
+		if len(with) > 0 {
+			clash += " (" + strings.Join(with, ", ") + ")"
+		}
+		clashes = append(clashes, clash)
+	}
+	return fmt.Sprintf("appointment conflicts with %d existing appointment(s): %s", len(e.Conflicts), strings.Join(clashes, "; "))
+}
+
+const appointmentSelect = `SELECT a.id, a.patient_id, a.datetime, COALESCE(a.duration, 0), COALESCE(a.notes, ''),
+	                                a.dentist_id, a.chair_id, COALESCE(u.username, ''), COALESCE(c.name, ''),
+	                                a.status, a.cancellation_reason, a.late_cancellation,
+	                                COALESCE(a.confirmed_at, ''), COALESCE(a.checked_in_at, ''), COALESCE(a.in_chair_at, ''),
+	                                COALESCE(a.completed_at, ''), COALESCE(a.cancelled_at, ''), COALESCE(a.no_show_at, ''),
+	                                a.series_id, COALESCE(p.name, '')
+	                         FROM appointments a
+	                         LEFT JOIN patients p ON a.patient_id = p.id
+	                         LEFT JOIN users u ON a.dentist_id = u.id
+	                         LEFT JOIN chairs c ON a.chair_id = c.id`
+
+func scanAppointment(scanner interface{ Scan(...any) error }) (models.Appointment, error) {
+	var appt models.Appointment
+	var dentistID, chairID, seriesID sql.NullInt64
+	err := scanner.Scan(&appt.ID, &appt.PatientID, &appt.DateTime, &appt.Duration, &appt.Notes,
+		&dentistID, &chairID, &appt.DentistName, &appt.ChairName,
+		&appt.Status, &appt.CancellationReason, &appt.LateCancellation,
+		&appt.ConfirmedAt, &appt.CheckedInAt, &appt.InChairAt, &appt.CompletedAt, &appt.CancelledAt, &appt.NoShowAt,
+		&seriesID, &appt.PatientName)
 	if err != nil {
+		return appt, err
+	}
+	if dentistID.Valid {
+		id := int(dentistID.Int64)
+		appt.DentistID = &id
+	}
+	if chairID.Valid {
+		id := int(chairID.Int64)
+		appt.ChairID = &id
+	}
+	if seriesID.Valid {
+		id := int(seriesID.Int64)
+		appt.SeriesID = &id
+	}
+	return appt, nil
+}
+
+// AddAppointment adds a new appointment to the database. A duration of 0 uses the clinic's default.
+// Overlaps with the same dentist or chair are rejected with an AppointmentConflictError unless
+// AllowConflict is set, and bookings outside the dentist's working hours with an
+// OutsideWorkingHoursError unless AllowOutsideHours is set.
+func (h *AppointmentHandler) AddAppointment(appt models.Appointment, actorID int) (int64, error) {
+	if appt.Duration <= 0 {
+		settings, err := loadClinicSettings(h.db)
+		if err != nil {
+			return 0, err
+		}
+		appt.Duration = settings.DefaultAppointmentDuration
+	}
+
+	// The checks run on the transaction that books the slot, so a workstation that booked it in the
+	// meantime makes this commit fail instead of double-booking
+	tx, err := h.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := validateAppointment(tx, appt); err != nil {
 		return 0, err
 	}
-	return result.LastInsertId()
+	query := `INSERT INTO appointments (patient_id, datetime, duration, notes, dentist_id, chair_id) VALUES (?, ?, ?, ?, ?, ?)`
+	id, err := auditedInsertTx(tx, actorID, auditAppointment, query, appt.PatientID, appt.DateTime, appt.Duration, appt.Notes,
+		appt.DentistID, appt.ChairID)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
 }
 
 // GetAppointments returns all appointments
 func (h *AppointmentHandler) GetAppointments() ([]models.Appointment, error) {
-	query := `SELECT id, patient_id, datetime, duration, notes FROM appointments ORDER BY datetime DESC`
-	rows, err := h.db.Query(query)
+	rows, err := h.db.Query(appointmentSelect + ` ORDER BY a.datetime DESC`)
 	if err != nil {
 		return nil, err
 	}
@@ -36,34 +143,416 @@ func (h *AppointmentHandler) GetAppointments() ([]models.Appointment, error) {
 
 	appointments := []models.Appointment{}
 	for rows.Next() {
-		var appt models.Appointment
-		err := rows.Scan(&appt.ID, &appt.PatientID, &appt.DateTime, &appt.Duration, &appt.Notes)
+		appt, err := scanAppointment(rows)
 		if err != nil {
 			return nil, err
 		}
 		appointments = append(appointments, appt)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return appointments, attachAppointmentAlerts(h.db, appointments)
+}
+
+// appointmentScanBounds returns string bounds on appointments.datetime covering from's day through
+// to's day, padded by a day either side, which covers bookings up to maxAppointmentDuration long
+// that started the day before. Every stored format starts with the date, so comparing
+// the raw strings can use the datetime index; callers then compare parsed times exactly.
+func appointmentScanBounds(from, to time.Time) (string, string) {
+	return from.AddDate(0, 0, -1).Format("2006-01-02"), to.AddDate(0, 0, 2).Format("2006-01-02")
+}
+
+// GetAppointmentsInRange returns the appointments starting on the days from through to (YYYY-MM-DD,
+// inclusive), oldest first, with their patients' medical alerts. A dentistID or chairID of 0 matches
+// every dentist or chair.
+func (h *AppointmentHandler) GetAppointmentsInRange(from, to string, dentistID, chairID int) ([]models.Appointment, error) {
+	appointments, err := h.appointmentsInRange(from, to, dentistID, chairID)
+	if err != nil {
+		return nil, err
+	}
+	return appointments, attachAppointmentAlerts(h.db, appointments)
+}
+
+// appointmentsInRange is GetAppointmentsInRange without the medical alerts
+func (h *AppointmentHandler) appointmentsInRange(from, to string, dentistID, chairID int) ([]models.Appointment, error) {
+	start, err := time.ParseInLocation("2006-01-02", from, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date %q", from)
+	}
+	end, err := time.ParseInLocation("2006-01-02", to, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end date %q", to)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end date is before start date")
+	}
+	if end.Sub(start) > 366*24*time.Hour {
+		return nil, fmt.Errorf("date range is limited to one year")
+	}
+	end = end.AddDate(0, 0, 1)
+
+	query := appointmentSelect + ` WHERE a.datetime >= ? AND a.datetime < ?`
+	lower, upper := appointmentScanBounds(start, end)
+	args := []any{lower, upper}
+	if dentistID > 0 {
+		query += ` AND a.dentist_id = ?`
+		args = append(args, dentistID)
+	}
+	if chairID > 0 {
+		query += ` AND a.chair_id = ?`
+		args = append(args, chairID)
+	}
+	rows, err := h.db.Query(query+` ORDER BY a.datetime`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query appointments: %v", err)
+	}
+	defer rows.Close()
+
+	appointments := []models.Appointment{}
+	for rows.Next() {
+		appt, err := scanAppointment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan appointment: %v", err)
+		}
+		t, err := parseAppointmentTime(appt.DateTime)
+		if err != nil || t.Before(start) || !t.Before(end) {
+			continue
+		}
+		appointments = append(appointments, appt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("appointment rows error: %v", err)
+	}
+	// Stored formats differ, so order by the parsed time rather than the raw string
+	sort.SliceStable(appointments, func(i, j int) bool {
+		a, _ := parseAppointmentTime(appointments[i].DateTime)
+		b, _ := parseAppointmentTime(appointments[j].DateTime)
+		return a.Before(b)
+	})
 	return appointments, nil
 }
 
-// GetAppointment returns a specific appointment by ID
+// GetAppointmentDaySummary returns the number of appointments and booked minutes for each day from
+// through to that has bookings, filtered as in GetAppointmentsInRange
+func (h *AppointmentHandler) GetAppointmentDaySummary(from, to string, dentistID, chairID int) ([]models.AppointmentDaySummary, error) {
+	appointments, err := h.appointmentsInRange(from, to, dentistID, chairID)
+	if err != nil {
+		return nil, err
+	}
+	summary := []models.AppointmentDaySummary{}
+	for _, appt := range appointments {
+		if appt.Status == models.AppointmentCancelled || appt.Status == models.AppointmentNoShow {
+			continue
+		}
+		t, _ := parseAppointmentTime(appt.DateTime)
+		day := t.Local().Format("2006-01-02")
+		if len(summary) == 0 || summary[len(summary)-1].Date != day {
+			summary = append(summary, models.AppointmentDaySummary{Date: day})
+		}
+		summary[len(summary)-1].Count++
+		summary[len(summary)-1].BookedMinutes += appt.Duration
+	}
+	return summary, nil
+}
+
+// GetAppointment returns a specific appointment by ID with its patient's medical alerts
 func (h *AppointmentHandler) GetAppointment(id int) (models.Appointment, error) {
-	var appt models.Appointment
-	query := `SELECT id, patient_id, datetime, duration, notes FROM appointments WHERE id = ?`
-	err := h.db.QueryRow(query, id).Scan(&appt.ID, &appt.PatientID, &appt.DateTime, &appt.Duration, &appt.Notes)
-	return appt, err
+	appt, err := scanAppointment(h.db.QueryRow(appointmentSelect+` WHERE a.id = ?`, id))
+	if err != nil {
+		return appt, err
+	}
+	alerts, err := patientMedicalAlerts(h.db, appt.PatientID)
+	if err != nil {
+		return appt, err
+	}
+	appt.MedicalAlerts = alerts[appt.PatientID]
+	return appt, nil
 }
 
-// UpdateAppointment updates an existing appointment
-func (h *AppointmentHandler) UpdateAppointment(appt models.Appointment) error {
-	query := `UPDATE appointments SET patient_id = ?, datetime = ?, duration = ?, notes = ? WHERE id = ?`
-	_, err := h.db.Exec(query, appt.PatientID, appt.DateTime, appt.Duration, appt.Notes, appt.ID)
-	return err
+// UpdateAppointment updates an existing appointment. Overlaps are handled as in AddAppointment.
+func (h *AppointmentHandler) UpdateAppointment(appt models.Appointment, actorID int) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkAppointmentEditable(tx, appt.ID); err != nil {
+		return err
+	}
+	if err := validateAppointment(tx, appt); err != nil {
+		return err
+	}
+	query := `UPDATE appointments SET patient_id = ?, datetime = ?, duration = ?, notes = ?, dentist_id = ?, chair_id = ? WHERE id = ?`
+	result, err := auditedExecTx(tx, actorID, auditAppointment, int64(appt.ID), AuditActionUpdate, query,
+		appt.PatientID, appt.DateTime, appt.Duration, appt.Notes, appt.DentistID, appt.ChairID, appt.ID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("appointment not found")
+	}
+	return tx.Commit()
+}
+
+// checkAppointmentEditable returns an error unless the appointment exists and is still scheduled or
+// confirmed. Once checked in, an appointment only moves on through SetAppointmentStatus.
+func checkAppointmentEditable(q queryRunner, id int) error {
+	var status string
+	err := q.QueryRow(`SELECT status FROM appointments WHERE id = ?`, id).Scan(&status)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("appointment not found")
+	} else if err != nil {
+		return fmt.Errorf("failed to get appointment: %v", err)
+	}
+	if status != models.AppointmentScheduled && status != models.AppointmentConfirmed {
+		return fmt.Errorf("only scheduled or confirmed appointments can be edited")
+	}
+	return nil
 }
 
 // DeleteAppointment deletes an appointment
-func (h *AppointmentHandler) DeleteAppointment(id int) error {
+func (h *AppointmentHandler) DeleteAppointment(id int, actorID int) error {
 	query := `DELETE FROM appointments WHERE id = ?`
-	_, err := h.db.Exec(query, id)
+	_, err := auditedExec(h.db, actorID, auditAppointment, int64(id), AuditActionDelete, query, id)
 	return err
 }
+
+// maxAppointmentDuration bounds a booking's length in minutes. It keeps every booking within a day
+// of its start, which appointmentScanBounds relies on.
+const maxAppointmentDuration = 480
+
+// validateAppointment checks the time, the dentist and chair, the dentist's working hours and
+// overlapping bookings. Run it on the transaction that writes the appointment.
+func validateAppointment(q queryRunner, appt models.Appointment) error {
+	if err := validateAppointmentFields(q, appt); err != nil {
+		return err
+	}
+	if err := checkDentistHours(q, appt); err != nil {
+		return err
+	}
+	if appt.AllowConflict {
+		return nil
+	}
+	conflicts, err := findConflicts(q, appt)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return &AppointmentConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
+
+// checkDentistHours returns an OutsideWorkingHoursError if the appointment falls outside its
+// dentist's schedule, unless AllowOutsideHours is set
+func checkDentistHours(q queryRunner, appt models.Appointment) error {
+	if appt.DentistID == nil || appt.AllowOutsideHours {
+		return nil
+	}
+	start, err := parseAppointmentTime(appt.DateTime)
+	if err != nil {
+		return err
+	}
+	return checkWorkingHours(q, *appt.DentistID, start, start.Add(time.Duration(appt.Duration)*time.Minute))
+}
+
+// validateAppointmentFields checks the time, the duration and that the dentist and chair exist
+func validateAppointmentFields(q queryRunner, appt models.Appointment) error {
+	if _, err := parseAppointmentTime(appt.DateTime); err != nil {
+		return err
+	}
+	if appt.Duration <= 0 || appt.Duration > maxAppointmentDuration {
+		return fmt.Errorf("appointment duration must be between 1 and %d minutes", maxAppointmentDuration)
+	}
+	if appt.DentistID != nil {
+		var isActive bool
+		err := q.QueryRow(`SELECT is_active FROM users WHERE id = ?`, *appt.DentistID).Scan(&isActive)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("dentist not found")
+		} else if err != nil {
+			return fmt.Errorf("failed to check dentist: %v", err)
+		}
+		if !isActive {
+			return fmt.Errorf("dentist account is deactivated")
+		}
+	}
+	if appt.ChairID != nil {
+		var exists bool
+		if err := q.QueryRow(`SELECT EXISTS(SELECT 1 FROM chairs WHERE id = ?)`, *appt.ChairID).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check chair: %v", err)
+		}
+		if !exists {
+			return fmt.Errorf("chair not found")
+		}
+	}
+	return nil
+}
+
+// FindConflicts returns the other appointments that overlap appt and share its dentist or chair.
+// Cancelled and no-show appointments no longer hold their slot.
+func (h *AppointmentHandler) FindConflicts(appt models.Appointment) ([]models.Appointment, error) {
+	return findConflicts(h.db, appt)
+}
+
+// findConflicts is FindConflicts on the given connection or transaction
+func findConflicts(q queryRunner, appt models.Appointment) ([]models.Appointment, error) {
+	if appt.DentistID == nil && appt.ChairID == nil {
+		return []models.Appointment{}, nil
+	}
+	start, err := parseAppointmentTime(appt.DateTime)
+	if err != nil {
+		return nil, err
+	}
+	end := start.Add(time.Duration(appt.Duration) * time.Minute)
+
+	lower, upper := appointmentScanBounds(start, start)
+	rows, err := q.Query(appointmentSelect+`
+	                         WHERE a.id != ? AND (a.dentist_id = ? OR a.chair_id = ?)
+	                           AND a.status NOT IN ('cancelled', 'no_show')
+	                           AND a.datetime >= ? AND a.datetime < ?
+	                         ORDER BY a.datetime`,
+		appt.ID, appt.DentistID, appt.ChairID, lower, upper)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for conflicts: %v", err)
+	}
+	defer rows.Close()
+
+	conflicts := []models.Appointment{}
+	for rows.Next() {
+		other, err := scanAppointment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan appointment: %v", err)
+		}
+		otherStart, err := parseAppointmentTime(other.DateTime)
+		if err != nil {
+			// Unparseable legacy rows cannot be compared
+			continue
+		}
+		otherEnd := otherStart.Add(time.Duration(other.Duration) * time.Minute)
+		if start.Before(otherEnd) && otherStart.Before(end) {
+			conflicts = append(conflicts, other)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("appointment rows error: %v", err)
+	}
+	return conflicts, nil
+}
+
+// lateCancellationWindow is how close to the start a cancellation counts as late
+const lateCancellationWindow = 24 * time.Hour
+
+// appointmentTransitions lists the statuses each status may move to. Completed, cancelled and
+// no-show appointments are final.
+var appointmentTransitions = map[string][]string{
+	models.AppointmentScheduled: {models.AppointmentConfirmed, models.AppointmentCheckedIn, models.AppointmentCancelled, models.AppointmentNoShow},
+	models.AppointmentConfirmed: {models.AppointmentCheckedIn, models.AppointmentCancelled, models.AppointmentNoShow},
+	models.AppointmentCheckedIn: {models.AppointmentInChair, models.AppointmentCompleted, models.AppointmentCancelled},
+	models.AppointmentInChair:   {models.AppointmentCompleted},
+}
+
+// appointmentStatusColumns maps each status to the column recording when it was entered
+var appointmentStatusColumns = map[string]string{
+	models.AppointmentConfirmed: "confirmed_at",
+	models.AppointmentCheckedIn: "checked_in_at",
+	models.AppointmentInChair:   "in_chair_at",
+	models.AppointmentCompleted: "completed_at",
+	models.AppointmentCancelled: "cancelled_at",
+	models.AppointmentNoShow:    "no_show_at",
+}
+
+// SetAppointmentStatus moves an appointment to a new status if the transition is allowed.
+// Cancelling requires a reason; cancelling within 24 hours of the start is flagged as late.
+func (h *AppointmentHandler) SetAppointmentStatus(id int, status, reason string, actorID int) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := setAppointmentStatus(tx, id, status, reason, actorID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// setAppointmentStatus is SetAppointmentStatus inside the caller's transaction
+func setAppointmentStatus(tx auditRunner, id int, status, reason string, actorID int) error {
+	column, ok := appointmentStatusColumns[status]
+	if !ok {
+		return fmt.Errorf("invalid appointment status %q", status)
+	}
+	appt, err := scanAppointment(tx.QueryRow(appointmentSelect+` WHERE a.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("appointment not found")
+	} else if err != nil {
+		return fmt.Errorf("failed to get appointment: %v", err)
+	}
+	if !canTransition(appt.Status, status) {
+		return fmt.Errorf("cannot change appointment from %s to %s", appt.Status, status)
+	}
+
+	now := time.Now()
+	reason = strings.TrimSpace(reason)
+	late := false
+	if status == models.AppointmentCancelled {
+		if reason == "" {
+			return fmt.Errorf("a cancellation reason is required")
+		}
+		if start, err := parseAppointmentTime(appt.DateTime); err == nil {
+			late = start.Sub(now) < lateCancellationWindow
+		}
+	} else {
+		reason = ""
+	}
+
+	// The status guard rejects the change if another workstation moved the appointment first
+	query := fmt.Sprintf(`UPDATE appointments SET status = ?, %s = ?, cancellation_reason = ?, late_cancellation = ?
+	                      WHERE id = ? AND status = ?`, column)
+	result, err := auditedExecTx(tx, actorID, auditAppointment, int64(id), AuditActionUpdate, query,
+		status, now.Format("2006-01-02 15:04:05"), reason, late, id, appt.Status)
+	if err != nil {
+		return fmt.Errorf("failed to update appointment status: %v", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("appointment status was changed by someone else, please reload")
+	}
+	return nil
+}
+
+// SessionFormForAppointment returns a session form filled with the appointment's patient, dentist,
+// time and notes, ready to record the treatment. dentistID is used when the appointment has no dentist.
+func (h *AppointmentHandler) SessionFormForAppointment(id int, dentistID int) (models.SessionForm, error) {
+	appt, err := h.GetAppointment(id)
+	if err == sql.ErrNoRows {
+		return models.SessionForm{}, fmt.Errorf("appointment not found")
+	} else if err != nil {
+		return models.SessionForm{}, fmt.Errorf("failed to get appointment: %v", err)
+	}
+	form := models.SessionForm{
+		PatientID: appt.PatientID,
+		DentistID: dentistID,
+		Status:    "completed",
+		Notes:     appt.Notes,
+		Items:     []models.SessionItemForm{},
+	}
+	if appt.DentistID != nil {
+		form.DentistID = *appt.DentistID
+	}
+	if start, err := parseAppointmentTime(appt.DateTime); err == nil {
+		form.SessionDate = start.Format("2006-01-02T15:04")
+	}
+	return form, nil
+}
+
+// canTransition reports whether an appointment may move from one status to another
+func canTransition(from, to string) bool {
+	for _, next := range appointmentTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
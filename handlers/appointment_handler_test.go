@@ -0,0 +1,461 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"DentistApp/models"
+)
+
+func TestAppointmentConflicts(t *testing.T) {
+	db, admin := newTestAdmin(t)
+	auth := NewAuthHandler(db)
+	dentistID, err := auth.CreateUser(models.UserForm{Username: "dr.smith", Password: "secret1", Role: models.RoleDentist}, admin.ID)
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	dentist := int(dentistID)
+
+	newTestPatient(t, db, models.PatientForm{Name: "Jane Doe", Phone: "0100000000"})
+	chairs := NewChairHandler(db)
+	secondChairID, err := chairs.CreateChair(models.ChairForm{Name: "Chair 2", IsActive: true}, admin.ID)
+	if err != nil {
+		t.Fatalf("CreateChair failed: %v", err)
+	}
+	chair1, chair2 := 1, int(secondChairID)
+
+	appointments := NewAppointmentHandler(db)
+	firstID, err := appointments.AddAppointment(models.Appointment{
+		PatientID: 1, DateTime: "2025-03-01T10:00", Duration: 30, DentistID: &dentist, ChairID: &chair1,
+	}, admin.ID)
+	if err != nil {
+		t.Fatalf("AddAppointment failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		appt     models.Appointment
+		conflict bool
+	}{
+		{"same dentist overlapping", models.Appointment{DateTime: "2025-03-01 10:15", Duration: 30, DentistID: &dentist, ChairID: &chair2}, true},
+		{"same chair overlapping", models.Appointment{DateTime: "2025-03-01T09:45", Duration: 30, ChairID: &chair1}, true},
+		{"back to back", models.Appointment{DateTime: "2025-03-01T10:30", Duration: 30, DentistID: &dentist, ChairID: &chair1}, false},
+		{"other chair, no dentist", models.Appointment{DateTime: "2025-03-01T10:00", Duration: 30, ChairID: &chair2}, false},
+	}
+	for _, tt := range tests {
+		tt.appt.PatientID = 1
+		conflicts, err := appointments.FindConflicts(tt.appt)
+		if err != nil {
+			t.Fatalf("%s: FindConflicts failed: %v", tt.name, err)
+		}
+		if (len(conflicts) > 0) != tt.conflict {
+			t.Errorf("%s: conflicts = %v; expected conflict %v", tt.name, conflicts, tt.conflict)
+		}
+	}
+
+	// The longest allowed booking, started the evening before, still holds the slot after midnight
+	if _, err := appointments.AddAppointment(models.Appointment{PatientID: 1, DateTime: "2025-03-02T20:00", Duration: maxAppointmentDuration, ChairID: &chair2}, admin.ID); err != nil {
+		t.Fatalf("AddAppointment failed: %v", err)
+	}
+	if conflicts, err := appointments.FindConflicts(models.Appointment{PatientID: 1, DateTime: "2025-03-03T03:30", Duration: 30, ChairID: &chair2}); err != nil || len(conflicts) != 1 {
+		t.Errorf("conflicts after midnight = %+v, %v; expected the overnight booking", conflicts, err)
+	}
+	if _, err := appointments.AddAppointment(models.Appointment{PatientID: 1, DateTime: "2025-03-05T08:00", Duration: maxAppointmentDuration + 1, ChairID: &chair2}, admin.ID); err == nil {
+		t.Errorf("AddAppointment accepted a booking longer than %d minutes", maxAppointmentDuration)
+	}
+
+	clash := models.Appointment{PatientID: 1, DateTime: "2025-03-01T10:15", Duration: 30, DentistID: &dentist}
+	_, err = appointments.AddAppointment(clash, admin.ID)
+	var conflictErr *AppointmentConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("expected AppointmentConflictError, got %v", err)
+	}
+	if len(conflictErr.Conflicts) != 1 || int64(conflictErr.Conflicts[0].ID) != firstID {
+		t.Errorf("conflicts = %+v; expected appointment %d", conflictErr.Conflicts, firstID)
+	}
+	if !strings.Contains(err.Error(), "dr.smith") || !strings.Contains(err.Error(), "2025-03-01 10:00") {
+		t.Errorf("conflict message should name the clashing appointment: %v", err)
+	}
+
+	clash.AllowConflict = true
+	clashID, err := appointments.AddAppointment(clash, admin.ID)
+	if err != nil {
+		t.Fatalf("AddAppointment with override failed: %v", err)
+	}
+
+	// An appointment never conflicts with itself
+	first, err := appointments.GetAppointment(int(firstID))
+	if err != nil {
+		t.Fatalf("GetAppointment failed: %v", err)
+	}
+	if first.DentistName != "dr.smith" || first.ChairName != "Chair 1" {
+		t.Errorf("appointment = %+v; expected dentist and chair names", first)
+	}
+	if err := appointments.DeleteAppointment(int(clashID), admin.ID); err != nil {
+		t.Fatalf("DeleteAppointment failed: %v", err)
+	}
+	first.Duration = 45
+	if err := appointments.UpdateAppointment(first, admin.ID); err != nil {
+		t.Errorf("updating an appointment should not conflict with itself: %v", err)
+	}
+
+	if err := chairs.DeleteChair(chair1, admin.ID); err == nil {
+		t.Errorf("expected deleting a chair with appointments to fail")
+	}
+}
+
+func TestAppointmentStatusLifecycle(t *testing.T) {
+	db, admin := newTestAdmin(t)
+	newTestPatient(t, db, models.PatientForm{Name: "Jane Doe", Phone: "0100000000"})
+
+	appointments := NewAppointmentHandler(db)
+	chair := 1
+	add := func(start time.Time) int {
+		id, err := appointments.AddAppointment(models.Appointment{
+			PatientID: 1, DateTime: start.Format("2006-01-02T15:04"), Duration: 30, ChairID: &chair, Notes: "check-up",
+		}, admin.ID)
+		if err != nil {
+			t.Fatalf("AddAppointment failed: %v", err)
+		}
+		return int(id)
+	}
+
+	// Walk one appointment through to completion
+	now := time.Now().Truncate(time.Minute)
+	visit := add(now.Add(-time.Hour))
+	if err := appointments.SetAppointmentStatus(visit, models.AppointmentCompleted, "", admin.ID); err == nil {
+		t.Errorf("expected scheduled -> completed to be rejected")
+	}
+	for _, status := range []string{models.AppointmentConfirmed, models.AppointmentCheckedIn, models.AppointmentInChair, models.AppointmentCompleted} {
+		if err := appointments.SetAppointmentStatus(visit, status, "", admin.ID); err != nil {
+			t.Fatalf("SetAppointmentStatus(%s) failed: %v", status, err)
+		}
+	}
+	appt, err := appointments.GetAppointment(visit)
+	if err != nil {
+		t.Fatalf("GetAppointment failed: %v", err)
+	}
+	if appt.Status != models.AppointmentCompleted || appt.ConfirmedAt == "" || appt.InChairAt == "" || appt.CompletedAt == "" {
+		t.Errorf("unexpected status or timestamps: %+v", appt)
+	}
+	if err := appointments.SetAppointmentStatus(visit, models.AppointmentCancelled, "too late", admin.ID); err == nil {
+		t.Errorf("expected a completed appointment to be final")
+	}
+	appt.Notes = "edited"
+	if err := appointments.UpdateAppointment(appt, admin.ID); err == nil || !strings.Contains(err.Error(), "scheduled or confirmed") {
+		t.Errorf("editing a completed appointment = %v; expected it rejected", err)
+	}
+	appt.ID = 9999
+	if err := appointments.UpdateAppointment(appt, admin.ID); err == nil || err.Error() != "appointment not found" {
+		t.Errorf("editing a missing appointment = %v; expected appointment not found", err)
+	}
+
+	form, err := appointments.SessionFormForAppointment(visit, admin.ID)
+	if err != nil {
+		t.Fatalf("SessionFormForAppointment failed: %v", err)
+	}
+	if form.PatientID != 1 || form.DentistID != admin.ID || form.SessionDate != now.Add(-time.Hour).Format("2006-01-02T15:04") || form.Notes != "check-up" {
+		t.Errorf("unexpected session form: %+v", form)
+	}
+
+	// A cancelled slot can be booked again
+	late := add(now.Add(2 * time.Hour))
+	if err := appointments.SetAppointmentStatus(late, models.AppointmentCancelled, "", admin.ID); err == nil {
+		t.Errorf("expected a cancellation without a reason to be rejected")
+	}
+	if err := appointments.SetAppointmentStatus(late, models.AppointmentCancelled, "feeling unwell", admin.ID); err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	add(now.Add(2 * time.Hour))
+
+	early := add(now.Add(72 * time.Hour))
+	if err := appointments.SetAppointmentStatus(early, models.AppointmentCancelled, "travelling", admin.ID); err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	missed := add(now.Add(-3 * time.Hour))
+	if err := appointments.SetAppointmentStatus(missed, models.AppointmentNoShow, "", admin.ID); err != nil {
+		t.Fatalf("no-show failed: %v", err)
+	}
+
+	patient, err := NewPatientHandler(db).GetPatient(1)
+	if err != nil {
+		t.Fatalf("GetPatient failed: %v", err)
+	}
+	if patient.NoShowCount != 1 || patient.LateCancellationCount != 1 {
+		t.Errorf("expected 1 no-show and 1 late cancellation, got %d and %d", patient.NoShowCount, patient.LateCancellationCount)
+	}
+}
+
+func TestAppointmentSeries(t *testing.T) {
+	db, admin := newTestAdmin(t)
+	newTestPatient(t, db, models.PatientForm{Name: "Jane Doe", Phone: "0100000000"})
+
+	appointments := NewAppointmentHandler(db)
+	chair := 1
+	// Occupies the chair on the third visit
+	blockerID, err := appointments.AddAppointment(models.Appointment{PatientID: 1, DateTime: "2030-03-18T10:15", Duration: 30, ChairID: &chair}, admin.ID)
+	if err != nil {
+		t.Fatalf("AddAppointment failed: %v", err)
+	}
+
+	form := models.AppointmentSeriesForm{
+		Appointment: models.Appointment{PatientID: 1, DateTime: "2030-03-04T10:00", Duration: 30, ChairID: &chair, Notes: "aligners"},
+		RRule:       "FREQ=WEEKLY;INTERVAL=2;COUNT=4",
+	}
+	_, err = appointments.CreateAppointmentSeries(form, admin.ID)
+	var seriesConflict *SeriesConflictError
+	if !errors.As(err, &seriesConflict) || len(seriesConflict.Skipped) != 1 || seriesConflict.Skipped[0].Conflicts[0].ID != int(blockerID) {
+		t.Fatalf("expected a series conflict on one visit, got %v", err)
+	}
+	var count int
+	db.QueryRow(`SELECT COUNT(*) FROM appointments`).Scan(&count)
+	if count != 1 {
+		t.Errorf("expected nothing booked after a conflict, found %d appointments", count)
+	}
+
+	form.SkipConflicts = true
+	result, err := appointments.CreateAppointmentSeries(form, admin.ID)
+	if err != nil {
+		t.Fatalf("CreateAppointmentSeries failed: %v", err)
+	}
+	if len(result.AppointmentIDs) != 3 || len(result.Skipped) != 1 || result.Skipped[0].DateTime != "2030-03-18T10:00" {
+		t.Fatalf("unexpected series result: %+v", result)
+	}
+	visit := func(i int) models.Appointment {
+		appt, err := appointments.GetAppointment(int(result.AppointmentIDs[i]))
+		if err != nil {
+			t.Fatalf("GetAppointment failed: %v", err)
+		}
+		return appt
+	}
+
+	// Move the second visit and those after it a day later and to 11:30
+	second := visit(1)
+	if second.SeriesID == nil || *second.SeriesID != int(result.SeriesID) {
+		t.Fatalf("visit not linked to series: %+v", second)
+	}
+	second.DateTime = "2030-04-02T11:30"
+	second.Notes = "aligners, new tray"
+	if err := appointments.UpdateAppointmentInSeries(second, models.SeriesScopeFollowing, admin.ID); err != nil {
+		t.Fatalf("UpdateAppointmentInSeries failed: %v", err)
+	}
+	if got := []string{visit(0).DateTime, visit(1).DateTime, visit(2).DateTime}; got[0] != "2030-03-04T10:00" || got[1] != "2030-04-02T11:30" || got[2] != "2030-04-16T11:30" {
+		t.Errorf("unexpected visit times after update: %v", got)
+	}
+	if visit(2).Notes != "aligners, new tray" || visit(0).Notes != "aligners" {
+		t.Errorf("notes not applied to the following visits only")
+	}
+
+	// A failure part way through cancels none of the visits
+	if _, err := db.Exec(fmt.Sprintf(`CREATE TRIGGER fail_cancel BEFORE UPDATE OF status ON appointments WHEN NEW.id = %d
+	                                   BEGIN SELECT RAISE(ABORT, 'cancel failed'); END`, result.AppointmentIDs[2])); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := appointments.CancelAppointmentInSeries(int(result.AppointmentIDs[0]), models.SeriesScopeAll, "treatment paused", admin.ID); err == nil {
+		t.Fatalf("expected the cancellation to fail")
+	}
+	if visit(0).Status != models.AppointmentScheduled || visit(1).Status != models.AppointmentScheduled {
+		t.Errorf("visits cancelled despite the failure: %s, %s", visit(0).Status, visit(1).Status)
+	}
+	db.Exec(`DROP TRIGGER fail_cancel`)
+
+	cancelled, err := appointments.CancelAppointmentInSeries(int(result.AppointmentIDs[0]), models.SeriesScopeAll, "treatment paused", admin.ID)
+	if err != nil {
+		t.Fatalf("CancelAppointmentInSeries failed: %v", err)
+	}
+	if cancelled != 3 || visit(2).Status != models.AppointmentCancelled || visit(2).CancellationReason != "treatment paused" {
+		t.Errorf("expected all 3 visits cancelled, got %d", cancelled)
+	}
+	closed := visit(0)
+	closed.Notes = "resumed"
+	if err := appointments.UpdateAppointmentInSeries(closed, models.SeriesScopeAll, admin.ID); err == nil || !strings.Contains(err.Error(), "scheduled or confirmed") {
+		t.Errorf("editing a cancelled series = %v; expected it rejected", err)
+	}
+
+	// Changing a series that is under way leaves the visits already past alone
+	start := time.Now().AddDate(0, 0, -15)
+	form = models.AppointmentSeriesForm{
+		Appointment: models.Appointment{PatientID: 1, DateTime: time.Date(start.Year(), start.Month(), start.Day(), 10, 0, 0, 0, time.Local).Format(seriesTimeLayout), Duration: 30},
+		RRule:       "FREQ=WEEKLY;COUNT=4",
+	}
+	result, err = appointments.CreateAppointmentSeries(form, admin.ID)
+	if err != nil || len(result.AppointmentIDs) != 4 {
+		t.Fatalf("CreateAppointmentSeries = %+v, %v", result, err)
+	}
+	upcoming := visit(3)
+	past := visit(0).DateTime
+	upcoming.Notes = "rescheduled"
+	if err := appointments.UpdateAppointmentInSeries(upcoming, models.SeriesScopeAll, admin.ID); err != nil {
+		t.Fatalf("UpdateAppointmentInSeries failed: %v", err)
+	}
+	if visit(0).Notes == "rescheduled" || visit(0).DateTime != past {
+		t.Errorf("past visit was changed with the series: %+v", visit(0))
+	}
+	cancelled, err = appointments.CancelAppointmentInSeries(int(result.AppointmentIDs[3]), models.SeriesScopeAll, "moved away", admin.ID)
+	if err != nil || cancelled != 1 {
+		t.Fatalf("CancelAppointmentInSeries = %d, %v; expected only the upcoming visit", cancelled, err)
+	}
+	for i := 0; i < 3; i++ {
+		if v := visit(i); v.Status != models.AppointmentScheduled || v.LateCancellation {
+			t.Errorf("past visit %d = %s (late %v); expected it left scheduled", i, v.Status, v.LateCancellation)
+		}
+	}
+	var late int
+	db.QueryRow(`SELECT COUNT(*) FROM appointments WHERE late_cancellation = 1`).Scan(&late)
+	if late != 0 {
+		t.Errorf("late cancellations = %d; expected none", late)
+	}
+}
+
+func TestAppointmentsInRange(t *testing.T) {
+	db, admin := newTestAdmin(t)
+	newTestPatient(t, db, models.PatientForm{Name: "Jane Doe", Phone: "0100000000"})
+
+	appointments := NewAppointmentHandler(db)
+	admin1 := admin.ID
+	// Older rows were saved in other formats; they must sort and filter by time, not by string
+	for _, appt := range []models.Appointment{
+		{DateTime: "2025-03-02 03:30 PM", Duration: 45, DentistID: &admin1},
+		{DateTime: "2025-03-02T09:00", Duration: 30},
+		{DateTime: "2025-03-01T23:30", Duration: 30, DentistID: &admin1},
+		{DateTime: "2025-03-03T08:00", Duration: 60, DentistID: &admin1},
+		{DateTime: "2025-02-28T12:00", Duration: 30, DentistID: &admin1},
+	} {
+		appt.PatientID = 1
+		if _, err := appointments.AddAppointment(appt, admin.ID); err != nil {
+			t.Fatalf("AddAppointment(%s) failed: %v", appt.DateTime, err)
+		}
+	}
+	cancelled, err := appointments.AddAppointment(models.Appointment{PatientID: 1, DateTime: "2025-03-02T11:00", Duration: 30}, admin.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`UPDATE appointments SET status = 'cancelled' WHERE id = ?`, cancelled); err != nil {
+		t.Fatal(err)
+	}
+
+	inRange, err := appointments.GetAppointmentsInRange("2025-03-01", "2025-03-02", 0, 0)
+	if err != nil {
+		t.Fatalf("GetAppointmentsInRange failed: %v", err)
+	}
+	var times []string
+	for _, appt := range inRange {
+		times = append(times, appt.DateTime)
+		if appt.PatientName != "Jane Doe" {
+			t.Errorf("expected patient name on appointment %d, got %q", appt.ID, appt.PatientName)
+		}
+	}
+	if got := strings.Join(times, ", "); got != "2025-03-01T23:30, 2025-03-02T09:00, 2025-03-02T11:00, 2025-03-02 03:30 PM" {
+		t.Errorf("unexpected appointments in range: %s", got)
+	}
+
+	mine, err := appointments.GetAppointmentsInRange("2025-03-01", "2025-03-03", admin.ID, 0)
+	if err != nil {
+		t.Fatalf("GetAppointmentsInRange failed: %v", err)
+	}
+	if len(mine) != 3 {
+		t.Errorf("expected 3 appointments for the dentist, got %d", len(mine))
+	}
+
+	summary, err := appointments.GetAppointmentDaySummary("2025-03-01", "2025-03-31", 0, 0)
+	if err != nil {
+		t.Fatalf("GetAppointmentDaySummary failed: %v", err)
+	}
+	want := []models.AppointmentDaySummary{
+		{Date: "2025-03-01", Count: 1, BookedMinutes: 30},
+		{Date: "2025-03-02", Count: 2, BookedMinutes: 75},
+		{Date: "2025-03-03", Count: 1, BookedMinutes: 60},
+	}
+	if len(summary) != len(want) {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+	for i := range want {
+		if summary[i] != want[i] {
+			t.Errorf("summary[%d] = %+v, want %+v", i, summary[i], want[i])
+		}
+	}
+
+	if _, err := appointments.GetAppointmentsInRange("2025-03-02", "2025-03-01", 0, 0); err == nil {
+		t.Errorf("expected an error for a reversed range")
+	}
+}
+
+func TestAppointmentCalendarExportImport(t *testing.T) {
+	db, admin := newTestAdmin(t)
+	for _, p := range [][2]string{{"Jane Doe", "0100 123 4567"}, {"John Smith", ""}, {"Sam Lee", "0111111111"}, {"Sam  lee", "0122222222"}} {
+		newTestPatient(t, db, models.PatientForm{Name: p[0], Phone: p[1]})
+	}
+
+	appointments := NewAppointmentHandler(db)
+	id, err := appointments.AddAppointment(models.Appointment{PatientID: 1, DateTime: "2025-03-01T10:00", Duration: 45, Notes: "Crown fitting"}, admin.ID)
+	if err != nil {
+		t.Fatalf("AddAppointment failed: %v", err)
+	}
+
+	var exported strings.Builder
+	count, err := appointments.WriteAppointmentsICS(&exported, "2025-03-01", "2025-03-31", 0, "Smile Clinic")
+	if err != nil || count != 1 {
+		t.Fatalf("WriteAppointmentsICS = %d, %v", count, err)
+	}
+	for _, want := range []string{fmt.Sprintf("UID:appointment-%d@dentistapp", id), "SUMMARY:Jane Doe", "ATTENDEE;CN=Jane Doe:tel:01001234567", "STATUS:TENTATIVE"} {
+		if !strings.Contains(exported.String(), want) {
+			t.Errorf("export is missing %q:\n%s", want, exported.String())
+		}
+	}
+
+	// Our own export is recognised by its UIDs and not booked a second time
+	result, err := appointments.ImportAppointmentsICS(exported.String(), admin.ID)
+	if err != nil {
+		t.Fatalf("ImportAppointmentsICS failed: %v", err)
+	}
+	if result.Imported != 0 || result.AlreadyImported != 1 {
+		t.Errorf("unexpected result importing own export: %+v", result)
+	}
+
+	external := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT", "UID:ext-1", "DTSTART:20250310T090000", "DTEND:20250310T093000",
+		"SUMMARY:Cleaning", "ATTENDEE;CN=J. Doe:tel:+20 100 123 4567", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:ext-2", "DTSTART:20250311T090000", "SUMMARY:john smith", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:ext-3", "DTSTART:20250312T090000", "SUMMARY:Sam Lee", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:ext-4", "DTSTART:20250313T090000", "ATTENDEE;CN=Unknown Person:mailto:x@example.com", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:ext-5", "DTSTART;VALUE=DATE:20250314", "SUMMARY:Jane Doe", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:ext-6", "DTSTART:20250315T090000", "SUMMARY:Jane Doe", "STATUS:CANCELLED", "END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	result, err = appointments.ImportAppointmentsICS(external, admin.ID)
+	if err != nil {
+		t.Fatalf("ImportAppointmentsICS failed: %v", err)
+	}
+	if result.Imported != 2 || len(result.Unmatched) != 2 || len(result.Skipped) != 2 {
+		t.Fatalf("unexpected import result: %+v", result)
+	}
+	if !strings.Contains(result.Unmatched[0].Reason, "several patients") || result.Unmatched[1].Attendee != "Unknown Person" {
+		t.Errorf("unexpected unmatched events: %+v", result.Unmatched)
+	}
+
+	imported, err := appointments.GetAppointmentsInRange("2025-03-10", "2025-03-11", 0, 0)
+	if err != nil {
+		t.Fatalf("GetAppointmentsInRange failed: %v", err)
+	}
+	if len(imported) != 2 || imported[0].PatientID != 1 || imported[0].Duration != 30 || imported[0].Notes != "Cleaning" || imported[1].PatientID != 2 {
+		t.Errorf("unexpected imported appointments: %+v", imported)
+	}
+
+	// Importing the same file again books nothing new and creates no patients
+	result, err = appointments.ImportAppointmentsICS(external, admin.ID)
+	if err != nil {
+		t.Fatalf("ImportAppointmentsICS failed: %v", err)
+	}
+	if result.Imported != 0 || result.AlreadyImported != 2 {
+		t.Errorf("unexpected result on re-import: %+v", result)
+	}
+	var patients int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM patients`).Scan(&patients); err != nil || patients != 4 {
+		t.Errorf("expected 4 patients after import, got %d (%v)", patients, err)
+	}
+}
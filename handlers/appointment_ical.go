@@ -0,0 +1,399 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"DentistApp/ical"
+	"DentistApp/models"
+)
+
+// appointmentUIDFormat builds the UID of an exported appointment. It depends only on the appointment
+// ID, so calendar apps update the same event when the schedule is exported again.
+const appointmentUIDFormat = "appointment-%d@dentistapp"
+
+// WriteAppointmentsICS writes the appointments starting on the days from through to (YYYY-MM-DD,
+// inclusive) as an iCalendar file, optionally for one dentist (0 for all). Cancelled and no-show
+// visits are included as cancelled events so calendar apps remove them. It returns the number of events.
+func (h *AppointmentHandler) WriteAppointmentsICS(w io.Writer, from, to string, dentistID int, calendarName string) (int, error) {
+	appointments, err := h.appointmentsInRange(from, to, dentistID, 0)
+	if err != nil {
+		return 0, err
+	}
+	phones, err := h.patientPhones(appointments)
+	if err != nil {
+		return 0, err
+	}
+
+	cal := ical.Calendar{Name: calendarName, Events: make([]ical.Event, 0, len(appointments))}
+	for _, appt := range appointments {
+		start, _ := parseAppointmentTime(appt.DateTime)
+		event := ical.Event{
+			UID:      fmt.Sprintf(appointmentUIDFormat, appt.ID),
+			Start:    start,
+			End:      start.Add(time.Duration(appt.Duration) * time.Minute),
+			Summary:  appt.PatientName,
+			Location: appt.ChairName,
+			Status:   calendarStatus(appt.Status),
+		}
+		var details []string
+		if appt.DentistName != "" {
+			details = append(details, "Dentist: "+appt.DentistName)
+		}
+		if appt.Notes != "" {
+			details = append(details, appt.Notes)
+		}
+		event.Description = strings.Join(details, "\n")
+		if phone := strings.Join(strings.Fields(phones[appt.PatientID]), ""); phone != "" {
+			event.Attendees = []ical.Attendee{{Name: appt.PatientName, Address: "tel:" + phone}}
+		}
+		cal.Events = append(cal.Events, event)
+	}
+	if err := ical.Write(w, cal, time.Now()); err != nil {
+		return 0, fmt.Errorf("failed to write calendar: %v", err)
+	}
+	return len(cal.Events), nil
+}
+
+// ExportAppointmentsICS saves the appointments from through to as an .ics file in the exports folder
+// and returns its path
+func (h *AppointmentHandler) ExportAppointmentsICS(from, to string, dentistID int, calendarName string) (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current working directory: %v", err)
+	}
+	folderPath := filepath.Join(cwd, "exports")
+	if err := os.MkdirAll(folderPath, 0755); err != nil {
+		return "", fmt.Errorf("failed to create exports folder: %v", err)
+	}
+
+	name := fmt.Sprintf("appointments-%s-to-%s", from, to)
+	if dentistID > 0 {
+		name += fmt.Sprintf("-dentist-%d", dentistID)
+	}
+	icsPath := filepath.Join(folderPath, cleanPatientName(name)+".ics")
+	tmp, err := os.CreateTemp(folderPath, ".appointments-*.ics")
+	if err != nil {
+		return "", fmt.Errorf("failed to create calendar file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := h.WriteAppointmentsICS(tmp, from, to, dentistID, calendarName); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write calendar file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), icsPath); err != nil {
+		return "", fmt.Errorf("failed to save calendar file: %v", err)
+	}
+	return icsPath, nil
+}
+
+// calendarStatus maps an appointment status to an iCalendar event status
+func calendarStatus(status string) string {
+	switch status {
+	case models.AppointmentScheduled:
+		return ical.StatusTentative
+	case models.AppointmentCancelled, models.AppointmentNoShow:
+		return ical.StatusCancelled
+	default:
+		return ical.StatusConfirmed
+	}
+}
+
+func (h *AppointmentHandler) patientPhones(appointments []models.Appointment) (map[int]string, error) {
+	phones := map[int]string{}
+	if len(appointments) == 0 {
+		return phones, nil
+	}
+	ids := map[int]bool{}
+	var args []any
+	for _, appt := range appointments {
+		if !ids[appt.PatientID] {
+			ids[appt.PatientID] = true
+			args = append(args, appt.PatientID)
+		}
+	}
+	rows, err := h.db.Query(`SELECT id, COALESCE(phone, '') FROM patients WHERE id IN (?`+strings.Repeat(", ?", len(args)-1)+`)`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient phones: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var phone string
+		if err := rows.Scan(&id, &phone); err != nil {
+			return nil, fmt.Errorf("failed to scan patient phone: %v", err)
+		}
+		phones[id] = phone
+	}
+	return phones, rows.Err()
+}
+
+// ImportAppointmentsICS books the events of an iCalendar file as appointments. Each event must name
+// exactly one existing patient through an attendee's name or tel: number, or through its summary
+// when it has no attendees; other events are reported as unmatched and no patients are created.
+// Events imported before (by UID) or exported from this clinic are skipped. Imported visits are not
+// checked for conflicts or working hours, since they usually come from an existing schedule.
+func (h *AppointmentHandler) ImportAppointmentsICS(content string, actorID int) (models.CalendarImportResult, error) {
+	result := models.CalendarImportResult{Unmatched: []models.CalendarImportIssue{}, Skipped: []models.CalendarImportIssue{}}
+	cal, err := ical.Parse(strings.NewReader(content))
+	if err != nil {
+		return result, err
+	}
+	settings, err := loadClinicSettings(h.db)
+	if err != nil {
+		return result, err
+	}
+	matcher, err := h.loadPatientMatcher()
+	if err != nil {
+		return result, err
+	}
+
+	var pending []models.Appointment
+	var pendingUIDs []any
+	seen := map[string]bool{}
+	for _, e := range cal.Events {
+		issue := models.CalendarImportIssue{UID: e.UID, Summary: e.Summary}
+		if !e.Start.IsZero() {
+			issue.DateTime = e.Start.Format(seriesTimeLayout)
+		}
+		skip := func(reason string) {
+			issue.Reason = reason
+			result.Skipped = append(result.Skipped, issue)
+		}
+		switch {
+		case e.Invalid != "":
+			skip(fmt.Sprintf("line %d: %s", e.Line, e.Invalid))
+			continue
+		case e.AllDay:
+			skip("all-day event")
+			continue
+		case e.Recurring:
+			skip("recurring events are not supported; export the individual visits instead")
+			continue
+		case e.Status == ical.StatusCancelled:
+			skip("event is cancelled")
+			continue
+		case e.End.Sub(e.Start) > maxAppointmentDuration*time.Minute:
+			skip(fmt.Sprintf("event is longer than %d minutes", maxAppointmentDuration))
+			continue
+		}
+
+		if e.UID != "" {
+			known, err := h.calendarUIDKnown(e.UID)
+			if err != nil {
+				return result, err
+			}
+			if known || seen[e.UID] {
+				result.AlreadyImported++
+				continue
+			}
+		}
+
+		patientID, attendee, reason := matcher.match(e)
+		if patientID == 0 {
+			issue.Attendee = attendee
+			issue.Reason = reason
+			result.Unmatched = append(result.Unmatched, issue)
+			continue
+		}
+
+		duration := int(e.End.Sub(e.Start).Minutes())
+		if duration <= 0 {
+			duration = settings.DefaultAppointmentDuration
+		}
+		var notes []string
+		if e.Summary != "" && normalizeName(e.Summary) != normalizeName(matcher.names[patientID]) {
+			notes = append(notes, e.Summary)
+		}
+		if e.Description != "" {
+			notes = append(notes, e.Description)
+		}
+		pending = append(pending, models.Appointment{
+			PatientID: patientID,
+			DateTime:  e.Start.Local().Format(seriesTimeLayout),
+			Duration:  duration,
+			Notes:     strings.Join(notes, "\n"),
+		})
+		var uid any
+		if e.UID != "" {
+			uid = e.UID
+			seen[e.UID] = true
+		}
+		pendingUIDs = append(pendingUIDs, uid)
+	}
+	if len(pending) == 0 {
+		return result, nil
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return result, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+	for i, appt := range pending {
+		res, err := tx.Exec(`INSERT INTO appointments (patient_id, datetime, duration, notes, ical_uid) VALUES (?, ?, ?, ?, ?)`,
+			appt.PatientID, appt.DateTime, appt.Duration, appt.Notes, pendingUIDs[i])
+		if err != nil {
+			return result, fmt.Errorf("failed to add appointment: %v", err)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return result, err
+		}
+		if err := recordAudit(tx, actorID, auditAppointment, id, AuditActionCreate, nil); err != nil {
+			return result, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return result, fmt.Errorf("failed to commit import: %v", err)
+	}
+	result.Imported = len(pending)
+	return result, nil
+}
+
+// calendarUIDKnown reports whether an event UID was imported before or belongs to an appointment
+// exported from this clinic
+func (h *AppointmentHandler) calendarUIDKnown(uid string) (bool, error) {
+	var id int
+	if _, err := fmt.Sscanf(uid, appointmentUIDFormat, &id); err == nil && fmt.Sprintf(appointmentUIDFormat, id) == uid {
+		return true, nil
+	}
+	var known bool
+	if err := h.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM appointments WHERE ical_uid = ?)`, uid).Scan(&known); err != nil {
+		return false, fmt.Errorf("failed to check imported appointments: %v", err)
+	}
+	return known, nil
+}
+
+// patientMatcher finds patients by name or phone number for calendar imports
+type patientMatcher struct {
+	names   map[int]string
+	byName  map[string][]int
+	byPhone map[string][]int
+}
+
+func (h *AppointmentHandler) loadPatientMatcher() (*patientMatcher, error) {
+	rows, err := h.db.Query(`SELECT id, name, COALESCE(phone, '') FROM patients`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patients: %v", err)
+	}
+	defer rows.Close()
+
+	m := &patientMatcher{names: map[int]string{}, byName: map[string][]int{}, byPhone: map[string][]int{}}
+	for rows.Next() {
+		var id int
+		var name, phone string
+		if err := rows.Scan(&id, &name, &phone); err != nil {
+			return nil, fmt.Errorf("failed to scan patient: %v", err)
+		}
+		m.names[id] = name
+		if key := normalizeName(name); key != "" {
+			m.byName[key] = append(m.byName[key], id)
+		}
+		if key := phoneKey(phone); key != "" {
+			m.byPhone[key] = append(m.byPhone[key], id)
+		}
+	}
+	return m, rows.Err()
+}
+
+// match returns the one patient an event refers to, or 0 with the attendee it tried and the reason
+func (m *patientMatcher) match(e ical.Event) (int, string, string) {
+	type candidate struct{ name, phone string }
+	var candidates []candidate
+	for _, a := range e.Attendees {
+		if a.Name != "" || a.Phone() != "" {
+			candidates = append(candidates, candidate{a.Name, a.Phone()})
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = append(candidates, candidate{name: e.Summary})
+	}
+
+	found := map[int]bool{}
+	var ambiguous, tried []string
+	for _, c := range candidates {
+		label := strings.TrimSpace(c.name + " " + c.phone)
+		tried = append(tried, label)
+		var ids []int
+		if key := phoneKey(c.phone); key != "" {
+			ids = m.byPhone[key]
+		}
+		if len(ids) != 1 && c.name != "" {
+			byName := m.byName[normalizeName(c.name)]
+			if len(ids) > 1 {
+				ids = intersectIDs(ids, byName)
+			} else {
+				ids = byName
+			}
+		}
+		switch len(ids) {
+		case 0:
+		case 1:
+			found[ids[0]] = true
+		default:
+			ambiguous = append(ambiguous, label)
+		}
+	}
+
+	attendee := strings.Join(tried, ", ")
+	switch {
+	case len(found) == 1:
+		for id := range found {
+			return id, attendee, ""
+		}
+	case len(found) > 1:
+		return 0, attendee, "attendees match different patients"
+	case len(ambiguous) > 0:
+		return 0, attendee, "several patients match " + strings.Join(ambiguous, ", ")
+	}
+	if attendee == "" {
+		return 0, attendee, "event names no patient"
+	}
+	return 0, attendee, "no patient with this name or phone number"
+}
+
+// normalizeName lower-cases a name and collapses its whitespace
+func normalizeName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// phoneKey reduces a phone number to its last nine digits, so numbers written with or without a
+// country code or trunk prefix compare equal. Numbers with fewer than six digits give "".
+func phoneKey(phone string) string {
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, phone)
+	if len(digits) < 6 {
+		return ""
+	}
+	if len(digits) > 9 {
+		digits = digits[len(digits)-9:]
+	}
+	return digits
+}
+
+func intersectIDs(a, b []int) []int {
+	var out []int
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				out = append(out, x)
+				break
+			}
+		}
+	}
+	return out
+}
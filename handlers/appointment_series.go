@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"DentistApp/models"
+	"DentistApp/recurrence"
+)
+
+// seriesTimeLayout is the format series visits are stored in, matching the appointment form
+const seriesTimeLayout = "2006-01-02T15:04"
+
+// SeriesConflictError is returned when visits of a new series overlap existing appointments and
+// SkipConflicts was not set. No visits are booked.
+type SeriesConflictError struct {
+	Skipped []models.SkippedAppointment
+	Total   int
+}
+
+func (e *SeriesConflictError) Error() string {
+	visits := make([]string, 0, len(e.Skipped))
+	for _, s := range e.Skipped {
+		reason := s.Reason
+		if len(s.Conflicts) > 0 {
+			ids := make([]string, 0, len(s.Conflicts))
+			for _, c := range s.Conflicts {
+				ids = append(ids, fmt.Sprintf("#%d", c.ID))
+			}
+			reason = "clashes with " + strings.Join(ids, ", ")
+		}
+		visits = append(visits, fmt.Sprintf("%s (%s)", strings.Replace(s.DateTime, "T", " ", 1), reason))
+	}
+	return fmt.Sprintf("series conflicts with existing appointments or working hours on %d of %d visits: %s", len(e.Skipped), e.Total, strings.Join(visits, "; "))
+}
+
+// CreateAppointmentSeries books a recurring series of visits. Visits that overlap other appointments
+// for the same dentist or chair, or fall outside the dentist's working hours, make the whole series
+// fail with a SeriesConflictError, unless SkipConflicts is set (they are left out and reported) or
+// the appointment's AllowConflict or AllowOutsideHours is set.
+func (h *AppointmentHandler) CreateAppointmentSeries(form models.AppointmentSeriesForm, actorID int) (models.AppointmentSeriesResult, error) {
+	result := models.AppointmentSeriesResult{AppointmentIDs: []int64{}, Skipped: []models.SkippedAppointment{}}
+
+	rule, err := recurrence.Parse(form.RRule)
+	if err != nil {
+		return result, err
+	}
+	appt := form.Appointment
+	if appt.Duration <= 0 {
+		settings, err := loadClinicSettings(h.db)
+		if err != nil {
+			return result, err
+		}
+		appt.Duration = settings.DefaultAppointmentDuration
+	}
+
+	// The checks run on the transaction that books the visits, so the series is booked whole or not at all
+	tx, err := h.db.Begin()
+	if err != nil {
+		return result, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := validateAppointmentFields(tx, appt); err != nil {
+		return result, err
+	}
+	start, _ := parseAppointmentTime(appt.DateTime)
+	starts, err := rule.Occurrences(start)
+	if err != nil {
+		return result, err
+	}
+
+	var visits []models.Appointment
+	for _, t := range starts {
+		visit := appt
+		visit.DateTime = t.Format(seriesTimeLayout)
+		if err := checkDentistHours(tx, visit); err != nil {
+			var outside *OutsideWorkingHoursError
+			if !errors.As(err, &outside) {
+				return result, err
+			}
+			result.Skipped = append(result.Skipped, models.SkippedAppointment{DateTime: visit.DateTime, Reason: outside.Reason})
+			continue
+		}
+		if !appt.AllowConflict {
+			conflicts, err := findConflicts(tx, visit)
+			if err != nil {
+				return result, err
+			}
+			if len(conflicts) > 0 {
+				result.Skipped = append(result.Skipped, models.SkippedAppointment{DateTime: visit.DateTime, Conflicts: conflicts})
+				continue
+			}
+		}
+		visits = append(visits, visit)
+	}
+	if len(result.Skipped) > 0 && !form.SkipConflicts {
+		return result, &SeriesConflictError{Skipped: result.Skipped, Total: len(starts)}
+	}
+	if len(visits) == 0 {
+		return result, fmt.Errorf("every visit in the series conflicts with existing appointments")
+	}
+
+	res, err := tx.Exec(`INSERT INTO appointment_series (rrule) VALUES (?)`, rule.String())
+	if err != nil {
+		return result, fmt.Errorf("failed to create series: %v", err)
+	}
+	seriesID, err := res.LastInsertId()
+	if err != nil {
+		return result, err
+	}
+	if err := recordAudit(tx, actorID, auditAppointmentSeries, seriesID, AuditActionCreate, nil); err != nil {
+		return result, err
+	}
+
+	for _, visit := range visits {
+		res, err := tx.Exec(`INSERT INTO appointments (patient_id, datetime, duration, notes, dentist_id, chair_id, series_id) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			visit.PatientID, visit.DateTime, visit.Duration, visit.Notes, visit.DentistID, visit.ChairID, seriesID)
+		if err != nil {
+			return result, fmt.Errorf("failed to add appointment: %v", err)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return result, err
+		}
+		if err := recordAudit(tx, actorID, auditAppointment, id, AuditActionCreate, nil); err != nil {
+			return result, err
+		}
+		result.AppointmentIDs = append(result.AppointmentIDs, id)
+	}
+	if err := tx.Commit(); err != nil {
+		return result, fmt.Errorf("failed to commit series: %v", err)
+	}
+	result.SeriesID = seriesID
+	return result, nil
+}
+
+// UpdateAppointmentInSeries updates one visit, or with scope "following" or "series" also the later or
+// all upcoming visits of its series. Those visits move by the same number of days to the new time
+// of day and take the new patient, duration, dentist, chair and notes. Completed, cancelled and
+// no-show visits are left alone.
+func (h *AppointmentHandler) UpdateAppointmentInSeries(appt models.Appointment, scope string, actorID int) error {
+	current, err := h.GetAppointment(appt.ID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("appointment not found")
+	} else if err != nil {
+		return fmt.Errorf("failed to get appointment: %v", err)
+	}
+	if scope == models.SeriesScopeThis || current.SeriesID == nil {
+		return h.UpdateAppointment(appt, actorID)
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkAppointmentEditable(tx, current.ID); err != nil {
+		return err
+	}
+	targets, err := seriesTargets(tx, current, scope)
+	if err != nil {
+		return err
+	}
+	oldStart, err := parseAppointmentTime(current.DateTime)
+	if err != nil {
+		return err
+	}
+	newStart, err := parseAppointmentTime(appt.DateTime)
+	if err != nil {
+		return err
+	}
+	dayShift := int(dateOnly(newStart).Sub(dateOnly(oldStart)).Hours()) / 24
+
+	inSeries := map[int]bool{}
+	for _, t := range targets {
+		inSeries[t.ID] = true
+	}
+	updated := make([]models.Appointment, 0, len(targets))
+	var conflicts []models.Appointment
+	for _, t := range targets {
+		visit := appt
+		visit.ID = t.ID
+		if t.ID != appt.ID {
+			start, err := parseAppointmentTime(t.DateTime)
+			if err != nil {
+				return err
+			}
+			moved := time.Date(start.Year(), start.Month(), start.Day()+dayShift, newStart.Hour(), newStart.Minute(), 0, 0, time.Local)
+			visit.DateTime = moved.Format(seriesTimeLayout)
+		}
+		if err := validateAppointmentFields(tx, visit); err != nil {
+			return err
+		}
+		if err := checkDentistHours(tx, visit); err != nil {
+			return err
+		}
+		if !appt.AllowConflict {
+			clashes, err := findConflicts(tx, visit)
+			if err != nil {
+				return err
+			}
+			// Visits being moved together do not block each other
+			for _, c := range clashes {
+				if !inSeries[c.ID] {
+					conflicts = append(conflicts, c)
+				}
+			}
+		}
+		updated = append(updated, visit)
+	}
+	if len(conflicts) > 0 {
+		return &AppointmentConflictError{Conflicts: conflicts}
+	}
+
+	for _, visit := range updated {
+		before, err := auditSnapshot(tx, auditAppointment, int64(visit.ID))
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE appointments SET patient_id = ?, datetime = ?, duration = ?, notes = ?, dentist_id = ?, chair_id = ? WHERE id = ?`,
+			visit.PatientID, visit.DateTime, visit.Duration, visit.Notes, visit.DentistID, visit.ChairID, visit.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update appointment: %v", err)
+		}
+		if err := recordAudit(tx, actorID, auditAppointment, int64(visit.ID), AuditActionUpdate, before); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// CancelAppointmentInSeries cancels one visit, or with scope "following" or "series" also the later or
+// all upcoming visits of its series, all or none of them. It returns the number of visits cancelled.
+func (h *AppointmentHandler) CancelAppointmentInSeries(id int, scope, reason string, actorID int) (int, error) {
+	current, err := h.GetAppointment(id)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("appointment not found")
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to get appointment: %v", err)
+	}
+	if scope == models.SeriesScopeThis || current.SeriesID == nil {
+		if err := h.SetAppointmentStatus(id, models.AppointmentCancelled, reason, actorID); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	targets, err := seriesTargets(tx, current, scope)
+	if err != nil {
+		return 0, err
+	}
+	cancelled := 0
+	for _, t := range targets {
+		if !canTransition(t.Status, models.AppointmentCancelled) {
+			continue
+		}
+		if err := setAppointmentStatus(tx, t.ID, models.AppointmentCancelled, reason, actorID); err != nil {
+			return 0, err
+		}
+		cancelled++
+	}
+	if cancelled == 0 {
+		return 0, fmt.Errorf("no upcoming visits in the series can be cancelled")
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit cancellations: %v", err)
+	}
+	return cancelled, nil
+}
+
+// seriesTargets returns the visits of current's series that a "following" or "series" change applies
+// to: the upcoming ones still scheduled or confirmed, plus current itself. Past visits that were never
+// closed are left for the front desk to resolve, so a series change neither moves them nor flags them
+// as late cancellations.
+func seriesTargets(q queryRunner, current models.Appointment, scope string) ([]models.Appointment, error) {
+	if scope != models.SeriesScopeFollowing && scope != models.SeriesScopeAll {
+		return nil, fmt.Errorf("invalid series scope %q", scope)
+	}
+	from, err := parseAppointmentTime(current.DateTime)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	rows, err := q.Query(appointmentSelect+` WHERE a.series_id = ? ORDER BY a.datetime`, *current.SeriesID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get series appointments: %v", err)
+	}
+	defer rows.Close()
+
+	var targets []models.Appointment
+	for rows.Next() {
+		visit, err := scanAppointment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan appointment: %v", err)
+		}
+		if visit.ID != current.ID {
+			if visit.Status != models.AppointmentScheduled && visit.Status != models.AppointmentConfirmed {
+				continue
+			}
+			start, err := parseAppointmentTime(visit.DateTime)
+			if err != nil || start.Before(now) || (scope == models.SeriesScopeFollowing && start.Before(from)) {
+				continue
+			}
+		}
+		targets = append(targets, visit)
+	}
+	return targets, rows.Err()
+}
+
+// dateOnly returns t's calendar date at midnight UTC, so differences are whole days
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
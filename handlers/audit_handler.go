@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"DentistApp/models"
+)
+
+// Audit actions
+const (
+	AuditActionCreate    = "create"
+	AuditActionUpdate    = "update"
+	AuditActionDelete    = "delete"
+	AuditActionDeleteAll = "delete_all"
+)
+
+// auditRedactedColumns are recorded as changed without their values. logo is an image, not a secret,
+// but its bytes do not belong in the log.
+var auditRedactedColumns = map[string]bool{
+	"password_hash": true,
+	"passphrase":    true,
+	"sms_api_key":   true,
+	"smtp_password": true,
+	"logo":          true,
+}
+
+// auditChild is a child table whose rows are included in the parent's snapshot
+type auditChild struct {
+	key   string
+	table string
+	fk    string
+}
+
+// auditEntity maps an audited entity type to its table
+type auditEntity struct {
+	name     string
+	table    string
+	children []auditChild
+}
+
+var (
+	auditPatient         = auditEntity{name: "patient", table: "patients"}
+	auditAppointment     = auditEntity{name: "appointment", table: "appointments"}
+	auditPayment         = auditEntity{name: "payment", table: "payments"}
+	auditProcedure       = auditEntity{name: "procedure", table: "dental_procedures"}
+	auditSession         = auditEntity{name: "session", table: "sessions", children: []auditChild{{key: "items", table: "session_items", fk: "session_id"}, {key: "teeth", table: "session_item_teeth", fk: "session_id"}}}
+	auditInvoice         = auditEntity{name: "invoice", table: "invoices"}
+	auditExpenseCategory = auditEntity{name: "expense_category", table: "expense_categories"}
+	auditExpense         = auditEntity{name: "expense", table: "expenses"}
+	// auditExpenseWithPayments is used when deleting an expense, which also removes its payments
+	auditExpenseWithPayments = auditEntity{name: "expense", table: "expenses", children: []auditChild{{key: "payments", table: "expense_payments", fk: "expense_id"}}}
+	auditExpensePayment      = auditEntity{name: "expense_payment", table: "expense_payments"}
+	auditWorkType            = auditEntity{name: "work_type", table: "work_types"}
+	auditColorShade          = auditEntity{name: "color_shade", table: "color_shades"}
+	auditDentalLab           = auditEntity{name: "dental_lab", table: "dental_labs"}
+	auditLabOrder            = auditEntity{name: "lab_order", table: "lab_orders"}
+	auditUser                = auditEntity{name: "user", table: "users"}
+	auditBackupSettings      = auditEntity{name: "backup_settings", table: "backup_settings"}
+	auditClinicSettings      = auditEntity{name: "clinic_settings", table: "clinic_settings"}
+	auditChair               = auditEntity{name: "chair", table: "chairs"}
+	auditAppointmentSeries   = auditEntity{name: "appointment_series", table: "appointment_series"}
+	auditWorkingHours        = auditEntity{name: "working_hours", table: "working_hours"}
+	auditClinicHoliday       = auditEntity{name: "clinic_holiday", table: "clinic_holidays"}
+	auditTimeOff             = auditEntity{name: "time_off", table: "time_off"}
+	auditReminderSettings    = auditEntity{name: "reminder_settings", table: "reminder_settings"}
+	auditOutboundMessage     = auditEntity{name: "outbound_message", table: "outbound_messages"}
+	auditWaitlist            = auditEntity{name: "waitlist", table: "waitlist", children: []auditChild{{key: "windows", table: "waitlist_windows", fk: "waitlist_id"}}}
+	auditToothCondition      = auditEntity{name: "tooth_chart", table: "tooth_chart"}
+	auditTreatmentPlan       = auditEntity{name: "treatment_plan", table: "treatment_plans", children: []auditChild{{key: "items", table: "treatment_plan_items", fk: "plan_id"}}}
+	auditPerioExam           = auditEntity{name: "perio_exam", table: "perio_exams", children: []auditChild{{key: "teeth", table: "perio_teeth", fk: "exam_id"}, {key: "sites", table: "perio_sites", fk: "exam_id"}}}
+	auditNoteTemplate        = auditEntity{name: "note_template", table: "note_templates"}
+	auditClinicalNote        = auditEntity{name: "clinical_note", table: "clinical_notes"}
+	auditDrug                = auditEntity{name: "drug", table: "drugs"}
+	auditDrugRule            = auditEntity{name: "drug_rule", table: "drug_rules"}
+	auditPrescription        = auditEntity{name: "prescription", table: "prescriptions", children: []auditChild{{key: "items", table: "prescription_items", fk: "prescription_id"}}}
+	auditMedicalAlert        = auditEntity{name: "medical_alert", table: "medical_alerts"}
+)
+
+// auditRunner is satisfied by both *sql.DB and *sql.Tx
+type auditRunner interface {
+	queryRunner
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// auditSnapshot returns the entity's row (and child rows) as column/value pairs, or nil if it does not exist
+func auditSnapshot(q queryRunner, entity auditEntity, id int64) (map[string]any, error) {
+	rows, err := scanAuditRows(q, fmt.Sprintf(`SELECT * FROM %s WHERE id = ?`, entity.table), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot %s: %v", entity.name, err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	snapshot := rows[0]
+	for _, child := range entity.children {
+		childRows, err := scanAuditRows(q, fmt.Sprintf(`SELECT * FROM %s WHERE %s = ? ORDER BY id`, child.table, child.fk), id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to snapshot %s %s: %v", entity.name, child.key, err)
+		}
+		snapshot[child.key] = childRows
+	}
+	return snapshot, nil
+}
+
+func scanAuditRows(q queryRunner, query string, args ...any) ([]map[string]any, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]any, 0)
+	for rows.Next() {
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]any, len(columns))
+		for i, column := range columns {
+			switch v := values[i].(type) {
+			case []byte:
+				row[column] = string(v)
+			case time.Time:
+				row[column] = v.Format("2006-01-02 15:04:05")
+			default:
+				row[column] = v
+			}
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// auditDiff returns the fields that differ between two snapshots as {"field": {"old": ..., "new": ...}}
+func auditDiff(before, after map[string]any) map[string]any {
+	changes := make(map[string]any)
+	for key, oldValue := range before {
+		newValue, ok := after[key]
+		if ok && reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		change := map[string]any{"old": oldValue}
+		if ok {
+			change["new"] = newValue
+		}
+		changes[key] = change
+	}
+	for key, newValue := range after {
+		if _, ok := before[key]; !ok {
+			changes[key] = map[string]any{"new": newValue}
+		}
+	}
+
+	for key := range changes {
+		if auditRedactedColumns[key] {
+			changes[key] = "[redacted]"
+		}
+	}
+	return changes
+}
+
+// recordAudit snapshots the entity after a mutation and writes an audit entry with the diff
+// against before (nil for creates). Call it inside the mutation's transaction.
+func recordAudit(tx auditRunner, actorID int, entity auditEntity, id int64, action string, before map[string]any) error {
+	after, err := auditSnapshot(tx, entity, id)
+	if err != nil {
+		return err
+	}
+	changes := auditDiff(before, after)
+	if action == AuditActionUpdate && len(changes) == 0 {
+		return nil
+	}
+	return writeAuditEntry(tx, actorID, entity.name, id, action, changes)
+}
+
+// writeAuditEntry inserts an audit_log row with the given changes
+func writeAuditEntry(tx auditRunner, actorID int, entityType string, id int64, action string, changes map[string]any) error {
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit changes: %v", err)
+	}
+
+	var userID sql.NullInt64
+	if actorID > 0 {
+		userID = sql.NullInt64{Int64: int64(actorID), Valid: true}
+	}
+
+	_, err = tx.Exec(`INSERT INTO audit_log (user_id, created_at, entity_type, entity_id, action, changes) VALUES (?, ?, ?, ?, ?, ?)`,
+		userID, time.Now().Format("2006-01-02 15:04:05"), entityType, id, action, string(encoded))
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %v", err)
+	}
+	return nil
+}
+
+// auditedInsert runs an INSERT and records the created row in the audit log in one transaction
+func auditedInsert(db *sql.DB, actorID int, entity auditEntity, query string, args ...any) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	id, err := auditedInsertTx(tx, actorID, entity, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// auditedInsertTx is auditedInsert inside the caller's transaction, for inserts that must be atomic
+// with checks made on the same transaction
+func auditedInsertTx(tx auditRunner, actorID int, entity auditEntity, query string, args ...any) (int64, error) {
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if err := recordAudit(tx, actorID, entity, id, AuditActionCreate, nil); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// auditedExec runs an UPDATE or DELETE on one entity and records its before/after values in the
+// audit log in one transaction. Nothing is recorded if no row was affected.
+func auditedExec(db *sql.DB, actorID int, entity auditEntity, id int64, action string, query string, args ...any) (sql.Result, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	result, err := auditedExecTx(tx, actorID, entity, id, action, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return result, tx.Commit()
+}
+
+// auditedExecTx is auditedExec inside the caller's transaction
+func auditedExecTx(tx auditRunner, actorID int, entity auditEntity, id int64, action string, query string, args ...any) (sql.Result, error) {
+	before, err := auditSnapshot(tx, entity, id)
+	if err != nil {
+		return nil, err
+	}
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if before != nil && affected > 0 {
+		if err := recordAudit(tx, actorID, entity, id, action, before); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// AuditHandler handles reading the audit log
+type AuditHandler struct {
+	db *sql.DB
+}
+
+// NewAuditHandler creates a new AuditHandler
+func NewAuditHandler(db *sql.DB) *AuditHandler {
+	return &AuditHandler{db: db}
+}
+
+// GetAuditLog returns paginated audit entries, newest first
+// If filters is nil, returns all entries
+func (h *AuditHandler) GetAuditLog(page, pageSize int, filters *models.AuditLogFilters) (*models.AuditLogResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+
+	whereConditions := []string{}
+	args := []interface{}{}
+
+	if filters != nil {
+		if filters.UserID != nil {
+			whereConditions = append(whereConditions, "a.user_id = ?")
+			args = append(args, *filters.UserID)
+		}
+		if filters.EntityType != nil && *filters.EntityType != "" {
+			whereConditions = append(whereConditions, "a.entity_type = ?")
+			args = append(args, *filters.EntityType)
+		}
+		if filters.EntityID != nil {
+			whereConditions = append(whereConditions, "a.entity_id = ?")
+			args = append(args, *filters.EntityID)
+		}
+		if filters.Action != nil && *filters.Action != "" {
+			whereConditions = append(whereConditions, "a.action = ?")
+			args = append(args, *filters.Action)
+		}
+		if filters.DateFrom != nil && *filters.DateFrom != "" {
+			whereConditions = append(whereConditions, "DATE(a.created_at) >= ?")
+			args = append(args, *filters.DateFrom)
+		}
+		if filters.DateTo != nil && *filters.DateTo != "" {
+			whereConditions = append(whereConditions, "DATE(a.created_at) <= ?")
+			args = append(args, *filters.DateTo)
+		}
+	}
+
+	whereClause := ""
+	if len(whereConditions) > 0 {
+		whereClause = "WHERE " + strings.Join(whereConditions, " AND ")
+	}
+
+	var totalCount int
+	countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM audit_log a %s`, whereClause)
+	if err := h.db.QueryRow(countQuery, args...).Scan(&totalCount); err != nil {
+		return nil, fmt.Errorf("failed to count audit log: %v", err)
+	}
+
+	totalPages := int(math.Ceil(float64(totalCount) / float64(pageSize)))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+	if page > totalPages {
+		page = totalPages
+	}
+
+	offset := (page - 1) * pageSize
+
+	query := fmt.Sprintf(`SELECT a.id, a.user_id, COALESCE(u.username, ''), a.created_at, a.entity_type, a.entity_id, a.action, a.changes
+	          FROM audit_log a
+	          LEFT JOIN users u ON u.id = a.user_id
+	          %s
+	          ORDER BY a.id DESC
+	          LIMIT ? OFFSET ?`, whereClause)
+
+	rows, err := h.db.Query(query, append(args, pageSize, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %v", err)
+	}
+	defer rows.Close()
+
+	entries := make([]models.AuditEntry, 0)
+	for rows.Next() {
+		var entry models.AuditEntry
+		var userID sql.NullInt64
+		if err := rows.Scan(&entry.ID, &userID, &entry.Username, &entry.CreatedAt, &entry.EntityType,
+			&entry.EntityID, &entry.Action, &entry.Changes); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %v", err)
+		}
+		if userID.Valid {
+			id := int(userID.Int64)
+			entry.UserID = &id
+		}
+		entries = append(entries, entry)
+	}
+
+	return &models.AuditLogResponse{
+		Entries:     entries,
+		CurrentPage: page,
+		TotalPages:  totalPages,
+		TotalCount:  totalCount,
+		PageSize:    pageSize,
+	}, nil
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"DentistApp/models"
+)
+
+func TestPaymentMutationsAreAudited(t *testing.T) {
+	db, admin := newTestAdmin(t)
+
+	patientID := newTestPatient(t, db, models.PatientForm{Name: "Jane Doe", Phone: "0100000000"})
+
+	payments := NewPaymentHandler(db)
+	paymentID, err := payments.AddPayment(models.Payment{PatientID: patientID, Amount: 500, PaymentDate: "2025-01-10"}, admin.ID)
+	if err != nil {
+		t.Fatalf("AddPayment failed: %v", err)
+	}
+	err = payments.UpdatePayment(models.Payment{ID: int(paymentID), Amount: 300, PaymentDate: "2025-01-10"}, admin.ID)
+	if err != nil {
+		t.Fatalf("UpdatePayment failed: %v", err)
+	}
+	if err := payments.DeletePayment(int(paymentID), admin.ID); err != nil {
+		t.Fatalf("DeletePayment failed: %v", err)
+	}
+
+	entityType := "payment"
+	log, err := NewAuditHandler(db).GetAuditLog(1, 10, &models.AuditLogFilters{EntityType: &entityType})
+	if err != nil {
+		t.Fatalf("GetAuditLog failed: %v", err)
+	}
+	if log.TotalCount != 3 {
+		t.Fatalf("audit entries = %d; expected 3", log.TotalCount)
+	}
+
+	// Newest first: delete, update, create
+	expected := []string{AuditActionDelete, AuditActionUpdate, AuditActionCreate}
+	for i, entry := range log.Entries {
+		if entry.Action != expected[i] {
+			t.Errorf("entry %d action = %q; expected %q", i, entry.Action, expected[i])
+		}
+		if entry.EntityID != int(paymentID) || entry.Username != "admin" {
+			t.Errorf("entry %d = %+v; expected payment %d by admin", i, entry, paymentID)
+		}
+	}
+
+	var changes map[string]map[string]any
+	if err := json.Unmarshal([]byte(log.Entries[1].Changes), &changes); err != nil {
+		t.Fatalf("invalid changes JSON: %v", err)
+	}
+	if changes["amount"]["old"] != float64(500) || changes["amount"]["new"] != float64(300) {
+		t.Errorf("amount change = %v; expected 500 -> 300", changes["amount"])
+	}
+	if _, ok := changes["patient_id"]; ok {
+		t.Errorf("unchanged patient_id recorded in update diff")
+	}
+}
+
+func TestPasswordChangesAreRedacted(t *testing.T) {
+	db, admin := newTestAdmin(t)
+	auth := NewAuthHandler(db)
+
+	if err := auth.ResetPassword(admin.ID, "newsecret", admin.ID); err != nil {
+		t.Fatalf("ResetPassword failed: %v", err)
+	}
+
+	action := AuditActionUpdate
+	log, err := NewAuditHandler(db).GetAuditLog(1, 10, &models.AuditLogFilters{Action: &action})
+	if err != nil {
+		t.Fatalf("GetAuditLog failed: %v", err)
+	}
+	if log.TotalCount != 1 {
+		t.Fatalf("audit entries = %d; expected 1", log.TotalCount)
+	}
+	if strings.Contains(log.Entries[0].Changes, "$2a$") || !strings.Contains(log.Entries[0].Changes, `"password_hash":"[redacted]"`) {
+		t.Errorf("password hash not redacted: %s", log.Entries[0].Changes)
+	}
+}
@@ -2,9 +2,12 @@ package handlers
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"DentistApp/models"
@@ -12,6 +15,25 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	sessionIdleTimeout     = 2 * time.Hour
+	sessionAbsoluteTimeout = 12 * time.Hour
+	sessionTimeLayout      = "2006-01-02 15:04:05"
+
+	defaultAdminUsername = "admin"
+	defaultAdminPassword = "admin123"
+	minPasswordLength    = 6
+)
+
+var (
+	// ErrSessionInvalid is returned for unknown, revoked or malformed session tokens
+	ErrSessionInvalid = errors.New("invalid session, please log in again")
+	// ErrSessionExpired is returned for sessions past their idle or absolute timeout
+	ErrSessionExpired = errors.New("session expired, please log in again")
+	// ErrPasswordChangeRequired is returned while a user must change their password before doing anything else
+	ErrPasswordChangeRequired = errors.New("password change required")
+)
+
 // AuthHandler handles authentication-related operations
 type AuthHandler struct {
 	db *sql.DB
@@ -26,7 +48,7 @@ func NewAuthHandler(db *sql.DB) *AuthHandler {
 func (h *AuthHandler) InitializeAdmin() error {
 	// Check if admin user exists
 	var count int
-	err := h.db.QueryRow("SELECT COUNT(*) FROM users WHERE username = ?", "admin").Scan(&count)
+	err := h.db.QueryRow("SELECT COUNT(*) FROM users WHERE username = ?", defaultAdminUsername).Scan(&count)
 	if err != nil {
 		return fmt.Errorf("failed to check admin user: %v", err)
 	}
@@ -37,14 +59,14 @@ func (h *AuthHandler) InitializeAdmin() error {
 	}
 
 	// Create default admin user
-	// Default credentials: admin / admin123
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte("admin123"), bcrypt.DefaultCost)
+	// Default credentials: admin / admin123, which must be changed on first login
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(defaultAdminPassword), bcrypt.DefaultCost)
 	if err != nil {
 		return fmt.Errorf("failed to hash admin password: %v", err)
 	}
 
-	query := `INSERT INTO users (username, password_hash, role) VALUES (?, ?, ?)`
-	_, err = h.db.Exec(query, "admin", string(passwordHash), "Admin")
+	query := `INSERT INTO users (username, password_hash, role, must_change_password) VALUES (?, ?, ?, 1)`
+	_, err = h.db.Exec(query, defaultAdminUsername, string(passwordHash), models.RoleAdmin)
 	if err != nil {
 		return fmt.Errorf("failed to create admin user: %v", err)
 	}
@@ -55,8 +77,9 @@ func (h *AuthHandler) InitializeAdmin() error {
 // Login validates user credentials and returns a session token
 func (h *AuthHandler) Login(username, password string) (*models.LoginResponse, error) {
 	var user models.User
-	query := `SELECT id, username, password_hash, role FROM users WHERE username = ?`
-	err := h.db.QueryRow(query, username).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role)
+	query := `SELECT id, username, password_hash, role, is_active, must_change_password FROM users WHERE username = ?`
+	err := h.db.QueryRow(query, username).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role,
+		&user.IsActive, &user.MustChangePassword)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return &models.LoginResponse{
@@ -76,36 +99,140 @@ func (h *AuthHandler) Login(username, password string) (*models.LoginResponse, e
 		}, nil
 	}
 
-	// Generate session token
-	token, err := generateSessionToken()
+	if !user.IsActive {
+		return &models.LoginResponse{
+			Success: false,
+			Message: "This account has been deactivated",
+		}, nil
+	}
+
+	// The default admin password must be changed before the app can be used
+	if user.Username == defaultAdminUsername && password == defaultAdminPassword && !user.MustChangePassword {
+		if _, err := h.db.Exec(`UPDATE users SET must_change_password = 1 WHERE id = ?`, user.ID); err != nil {
+			return nil, fmt.Errorf("failed to flag default password: %v", err)
+		}
+		user.MustChangePassword = true
+	}
+
+	// Generate and store session token
+	token, expiresAt, err := h.createSession(user.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate session token: %v", err)
+		return nil, err
 	}
 
 	// Clear password hash from response
 	user.PasswordHash = ""
 
 	return &models.LoginResponse{
-		Success: true,
-		Token:   token,
-		User:    user,
-		Message: "Login successful",
+		Success:   true,
+		Token:     token,
+		ExpiresAt: expiresAt.Format(sessionTimeLayout),
+		User:      user,
+		Message:   "Login successful",
 	}, nil
 }
 
-// ValidateSession validates a session token (simple implementation)
-// In production, you might want to store tokens in database or use JWT
+// ValidateSession resolves a session token to its user.
+// Tokens expire after sessionIdleTimeout without activity and sessionAbsoluteTimeout after login.
 func (h *AuthHandler) ValidateSession(token string) (*models.User, error) {
-	// For now, we'll use a simple approach where token contains user info
-	// In production, implement proper token validation (JWT, database lookup, etc.)
-	// This is a basic implementation - you may want to enhance it
-	
-	// For simplicity, we'll extract username from token format: username_timestamp_random
-	// In production, use JWT or store tokens in database
-	
-	// For now, return nil to indicate we need to re-authenticate
-	// This is a placeholder - implement proper token validation
-	return nil, fmt.Errorf("session validation not fully implemented - please re-login")
+	if token == "" {
+		return nil, ErrSessionInvalid
+	}
+
+	var sessionID int
+	var user models.User
+	var lastSeenAt, expiresAt string
+	query := `SELECT s.id, s.last_seen_at, s.expires_at, u.id, u.username, u.role, u.is_active, u.must_change_password
+	          FROM user_sessions s
+	          JOIN users u ON u.id = s.user_id
+	          WHERE s.token_hash = ? AND s.revoked_at IS NULL AND u.is_active = 1`
+	err := h.db.QueryRow(query, hashSessionToken(token)).Scan(
+		&sessionID, &lastSeenAt, &expiresAt, &user.ID, &user.Username, &user.Role, &user.IsActive, &user.MustChangePassword)
+	if err == sql.ErrNoRows {
+		return nil, ErrSessionInvalid
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to validate session: %v", err)
+	}
+
+	now := time.Now()
+	lastSeen, err := time.ParseInLocation(sessionTimeLayout, lastSeenAt, time.Local)
+	if err != nil {
+		return nil, ErrSessionInvalid
+	}
+	expires, err := time.ParseInLocation(sessionTimeLayout, expiresAt, time.Local)
+	if err != nil {
+		return nil, ErrSessionInvalid
+	}
+	if now.After(expires) || now.Sub(lastSeen) > sessionIdleTimeout {
+		_, _ = h.db.Exec(`UPDATE user_sessions SET revoked_at = ? WHERE id = ?`, now.Format(sessionTimeLayout), sessionID)
+		return nil, ErrSessionExpired
+	}
+
+	_, err = h.db.Exec(`UPDATE user_sessions SET last_seen_at = ? WHERE id = ?`, now.Format(sessionTimeLayout), sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh session: %v", err)
+	}
+
+	return &user, nil
+}
+
+// Logout revokes the given session token
+func (h *AuthHandler) Logout(token string) error {
+	if token == "" {
+		return nil
+	}
+	_, err := h.db.Exec(`UPDATE user_sessions SET revoked_at = ? WHERE token_hash = ? AND revoked_at IS NULL`,
+		time.Now().Format(sessionTimeLayout), hashSessionToken(token))
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %v", err)
+	}
+	return nil
+}
+
+// RevokeUserSessions revokes every active session of a user
+func (h *AuthHandler) RevokeUserSessions(userID int) error {
+	_, err := h.db.Exec(`UPDATE user_sessions SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`,
+		time.Now().Format(sessionTimeLayout), userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke user sessions: %v", err)
+	}
+	return nil
+}
+
+// revokeOtherSessions revokes every active session of a user except the one for keepToken
+func (h *AuthHandler) revokeOtherSessions(userID int, keepToken string) error {
+	_, err := h.db.Exec(`UPDATE user_sessions SET revoked_at = ? WHERE user_id = ? AND token_hash != ? AND revoked_at IS NULL`,
+		time.Now().Format(sessionTimeLayout), userID, hashSessionToken(keepToken))
+	if err != nil {
+		return fmt.Errorf("failed to revoke user sessions: %v", err)
+	}
+	return nil
+}
+
+// createSession stores a new session for the user and returns the raw token and its absolute expiry
+func (h *AuthHandler) createSession(userID int) (string, time.Time, error) {
+	token, err := generateSessionToken()
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate session token: %v", err)
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(sessionAbsoluteTimeout)
+
+	// Drop sessions that can no longer be used so the table does not grow forever
+	_, err = h.db.Exec(`DELETE FROM user_sessions WHERE revoked_at IS NOT NULL OR expires_at < ?`, now.Format(sessionTimeLayout))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to clean up sessions: %v", err)
+	}
+
+	query := `INSERT INTO user_sessions (user_id, token_hash, created_at, last_seen_at, expires_at) VALUES (?, ?, ?, ?, ?)`
+	_, err = h.db.Exec(query, userID, hashSessionToken(token),
+		now.Format(sessionTimeLayout), now.Format(sessionTimeLayout), expiresAt.Format(sessionTimeLayout))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to store session: %v", err)
+	}
+
+	return token, expiresAt, nil
 }
 
 // CreateUser creates a new user (admin only)
@@ -117,10 +244,14 @@ func (h *AuthHandler) CreateUser(userForm models.UserForm, createdByID int) (int
 		return 0, fmt.Errorf("failed to verify creator: %v", err)
 	}
 
-	if creatorRole != "Admin" {
+	if creatorRole != models.RoleAdmin {
 		return 0, fmt.Errorf("only admins can create users")
 	}
 
+	if err := validatePassword(userForm.Password); err != nil {
+		return 0, err
+	}
+
 	// Check if username already exists
 	var existingCount int
 	err = h.db.QueryRow("SELECT COUNT(*) FROM users WHERE username = ?", userForm.Username).Scan(&existingCount)
@@ -140,12 +271,21 @@ func (h *AuthHandler) CreateUser(userForm models.UserForm, createdByID int) (int
 	// Set default role if not provided
 	role := userForm.Role
 	if role == "" {
-		role = "Dentist"
+		role = models.RoleDentist
+	}
+	if !IsValidRole(role) {
+		return 0, fmt.Errorf("invalid role: %s", role)
 	}
 
+	tx, err := h.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
 	// Insert user
 	query := `INSERT INTO users (username, password_hash, role) VALUES (?, ?, ?)`
-	result, err := h.db.Exec(query, userForm.Username, string(passwordHash), role)
+	result, err := tx.Exec(query, userForm.Username, string(passwordHash), role)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create user: %v", err)
 	}
@@ -155,14 +295,22 @@ func (h *AuthHandler) CreateUser(userForm models.UserForm, createdByID int) (int
 		return 0, fmt.Errorf("failed to get user ID: %v", err)
 	}
 
+	if err := recordAudit(tx, createdByID, auditUser, id, AuditActionCreate, nil); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
 	return id, nil
 }
 
 // GetUserByID retrieves a user by ID
 func (h *AuthHandler) GetUserByID(id int) (*models.User, error) {
 	var user models.User
-	query := `SELECT id, username, password_hash, role FROM users WHERE id = ?`
-	err := h.db.QueryRow(query, id).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role)
+	query := `SELECT id, username, password_hash, role, is_active, must_change_password FROM users WHERE id = ?`
+	err := h.db.QueryRow(query, id).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role,
+		&user.IsActive, &user.MustChangePassword)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("user not found")
@@ -178,8 +326,9 @@ func (h *AuthHandler) GetUserByID(id int) (*models.User, error) {
 // GetUserByUsername retrieves a user by username
 func (h *AuthHandler) GetUserByUsername(username string) (*models.User, error) {
 	var user models.User
-	query := `SELECT id, username, password_hash, role FROM users WHERE username = ?`
-	err := h.db.QueryRow(query, username).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role)
+	query := `SELECT id, username, password_hash, role, is_active, must_change_password FROM users WHERE username = ?`
+	err := h.db.QueryRow(query, username).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role,
+		&user.IsActive, &user.MustChangePassword)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("user not found")
@@ -194,7 +343,7 @@ func (h *AuthHandler) GetUserByUsername(username string) (*models.User, error) {
 
 // GetAllUsers returns all users (admin only)
 func (h *AuthHandler) GetAllUsers() ([]models.User, error) {
-	query := `SELECT id, username, role FROM users ORDER BY username`
+	query := `SELECT id, username, role, is_active, must_change_password FROM users ORDER BY is_active DESC, username`
 	rows, err := h.db.Query(query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query users: %v", err)
@@ -204,7 +353,7 @@ func (h *AuthHandler) GetAllUsers() ([]models.User, error) {
 	users := []models.User{}
 	for rows.Next() {
 		var user models.User
-		err := rows.Scan(&user.ID, &user.Username, &user.Role)
+		err := rows.Scan(&user.ID, &user.Username, &user.Role, &user.IsActive, &user.MustChangePassword)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %v", err)
 		}
@@ -214,19 +363,213 @@ func (h *AuthHandler) GetAllUsers() ([]models.User, error) {
 	return users, nil
 }
 
-// generateSessionToken generates a simple session token
-// In production, consider using JWT or storing tokens in database
+// GetDentists returns the active users who can be booked for appointments
+func (h *AuthHandler) GetDentists() ([]models.User, error) {
+	query := `SELECT id, username, role, is_active, must_change_password FROM users
+	          WHERE is_active = 1 AND role IN (?, ?) ORDER BY username`
+	rows, err := h.db.Query(query, models.RoleDentist, models.RoleAdmin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dentists: %v", err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var user models.User
+		err := rows.Scan(&user.ID, &user.Username, &user.Role, &user.IsActive, &user.MustChangePassword)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %v", err)
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// UpdateUser changes a user's username and role (admin only)
+func (h *AuthHandler) UpdateUser(id int, form models.UserUpdateForm, actorID int) error {
+	user, err := h.GetUserByID(id)
+	if err != nil {
+		return err
+	}
+
+	username := strings.TrimSpace(form.Username)
+	if username == "" {
+		return fmt.Errorf("username is required")
+	}
+	if !IsValidRole(form.Role) {
+		return fmt.Errorf("invalid role: %s", form.Role)
+	}
+
+	var existingCount int
+	err = h.db.QueryRow("SELECT COUNT(*) FROM users WHERE username = ? AND id != ?", username, id).Scan(&existingCount)
+	if err != nil {
+		return fmt.Errorf("failed to check username uniqueness: %v", err)
+	}
+	if existingCount > 0 {
+		return fmt.Errorf("username already exists")
+	}
+
+	if user.Role == models.RoleAdmin && form.Role != models.RoleAdmin && user.IsActive {
+		if err := h.ensureOtherActiveAdmin(id); err != nil {
+			return err
+		}
+	}
+
+	err = h.updateUserAudited(actorID, id, `UPDATE users SET username = ?, role = ? WHERE id = ?`, username, form.Role, id)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %v", err)
+	}
+
+	// A role change takes effect on the user's next login
+	if user.Role != form.Role {
+		return h.RevokeUserSessions(id)
+	}
+	return nil
+}
+
+// DeactivateUser disables a user's login without deleting them, so sessions and lab orders
+// they recorded keep their history. Their active sessions are revoked.
+func (h *AuthHandler) DeactivateUser(id int, actingUserID int) error {
+	if id == actingUserID {
+		return fmt.Errorf("you cannot deactivate your own account")
+	}
+
+	user, err := h.GetUserByID(id)
+	if err != nil {
+		return err
+	}
+	if !user.IsActive {
+		return nil
+	}
+	if user.Role == models.RoleAdmin {
+		if err := h.ensureOtherActiveAdmin(id); err != nil {
+			return err
+		}
+	}
+
+	err = h.updateUserAudited(actingUserID, id, `UPDATE users SET is_active = 0 WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to deactivate user: %v", err)
+	}
+	return h.RevokeUserSessions(id)
+}
+
+// ReactivateUser re-enables a deactivated user
+func (h *AuthHandler) ReactivateUser(id int, actorID int) error {
+	if _, err := h.GetUserByID(id); err != nil {
+		return err
+	}
+	if err := h.updateUserAudited(actorID, id, `UPDATE users SET is_active = 1 WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to reactivate user: %v", err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password for a user (admin only). The user must change it
+// on their next login and their active sessions are revoked.
+func (h *AuthHandler) ResetPassword(id int, newPassword string, actorID int) error {
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+	if _, err := h.GetUserByID(id); err != nil {
+		return err
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %v", err)
+	}
+
+	err = h.updateUserAudited(actorID, id, `UPDATE users SET password_hash = ?, must_change_password = 1 WHERE id = ?`, string(passwordHash), id)
+	if err != nil {
+		return fmt.Errorf("failed to reset password: %v", err)
+	}
+	return h.RevokeUserSessions(id)
+}
+
+// ChangePassword changes the user's own password after checking the old one. Every other session of
+// the user is signed out; the one identified by sessionToken stays logged in.
+func (h *AuthHandler) ChangePassword(userID int, sessionToken, oldPassword, newPassword string) error {
+	var passwordHash string
+	err := h.db.QueryRow(`SELECT password_hash FROM users WHERE id = ?`, userID).Scan(&passwordHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("user not found")
+		}
+		return fmt.Errorf("failed to query user: %v", err)
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(oldPassword)) != nil {
+		return fmt.Errorf("current password is incorrect")
+	}
+	if oldPassword == newPassword {
+		return fmt.Errorf("new password must be different from the current password")
+	}
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+
+	newHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %v", err)
+	}
+
+	err = h.updateUserAudited(userID, userID, `UPDATE users SET password_hash = ?, must_change_password = 0 WHERE id = ?`, string(newHash), userID)
+	if err != nil {
+		return fmt.Errorf("failed to change password: %v", err)
+	}
+	return h.revokeOtherSessions(userID, sessionToken)
+}
+
+// updateUserAudited runs an UPDATE on a user row and records it in the audit log
+func (h *AuthHandler) updateUserAudited(actorID, id int, query string, args ...any) error {
+	_, err := auditedExec(h.db, actorID, auditUser, int64(id), AuditActionUpdate, query, args...)
+	return err
+}
+
+// CountActiveUsers returns the number of users that can log in
+func (h *AuthHandler) CountActiveUsers() (int, error) {
+	var count int
+	if err := h.db.QueryRow(`SELECT COUNT(*) FROM users WHERE is_active = 1`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count users: %v", err)
+	}
+	return count, nil
+}
+
+// ensureOtherActiveAdmin returns an error if no active admin other than excludeID exists
+func (h *AuthHandler) ensureOtherActiveAdmin(excludeID int) error {
+	var count int
+	err := h.db.QueryRow(`SELECT COUNT(*) FROM users WHERE role = ? AND is_active = 1 AND id != ?`,
+		models.RoleAdmin, excludeID).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to count admins: %v", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("at least one active admin is required")
+	}
+	return nil
+}
+
+// validatePassword enforces the minimum password policy
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	return nil
+}
+
+// generateSessionToken generates a random session token
 func generateSessionToken() (string, error) {
-	// Generate a random token
 	bytes := make([]byte, 32)
 	if _, err := rand.Read(bytes); err != nil {
 		return "", err
 	}
-	
-	// Combine with timestamp for uniqueness
-	timestamp := time.Now().Unix()
-	token := fmt.Sprintf("%d_%s", timestamp, hex.EncodeToString(bytes))
-	
-	return token, nil
+	return hex.EncodeToString(bytes), nil
 }
 
+// hashSessionToken returns the value stored in user_sessions.token_hash for a token
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
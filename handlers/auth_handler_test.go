@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"DentistApp/database"
+	"DentistApp/models"
+)
+
+// newTestDB returns a migrated database in a temporary directory
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	return db
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	db := newTestDB(t)
+	handler := NewAuthHandler(db)
+	if err := handler.InitializeAdmin(); err != nil {
+		t.Fatalf("InitializeAdmin failed: %v", err)
+	}
+
+	response, err := handler.Login("admin", "admin123")
+	if err != nil || !response.Success {
+		t.Fatalf("Login failed: %v %+v", err, response)
+	}
+
+	user, err := handler.ValidateSession(response.Token)
+	if err != nil {
+		t.Fatalf("ValidateSession failed: %v", err)
+	}
+	if user.Username != "admin" {
+		t.Errorf("session resolved to %q; expected admin", user.Username)
+	}
+
+	// Only the hash of the token may be stored
+	var count int
+	db.QueryRow(`SELECT COUNT(*) FROM user_sessions WHERE token_hash = ?`, response.Token).Scan(&count)
+	if count != 0 {
+		t.Errorf("raw session token stored in database")
+	}
+
+	if err := handler.Logout(response.Token); err != nil {
+		t.Fatalf("Logout failed: %v", err)
+	}
+	if _, err := handler.ValidateSession(response.Token); err != ErrSessionInvalid {
+		t.Errorf("ValidateSession after logout = %v; expected ErrSessionInvalid", err)
+	}
+
+	if _, err := handler.ValidateSession("forged-token"); err != ErrSessionInvalid {
+		t.Errorf("ValidateSession with unknown token = %v; expected ErrSessionInvalid", err)
+	}
+}
+
+func TestSessionIdleExpiry(t *testing.T) {
+	db := newTestDB(t)
+	handler := NewAuthHandler(db)
+	if err := handler.InitializeAdmin(); err != nil {
+		t.Fatalf("InitializeAdmin failed: %v", err)
+	}
+
+	response, err := handler.Login("admin", "admin123")
+	if err != nil || !response.Success {
+		t.Fatalf("Login failed: %v %+v", err, response)
+	}
+
+	stale := time.Now().Add(-sessionIdleTimeout - time.Minute).Format(sessionTimeLayout)
+	if _, err := db.Exec(`UPDATE user_sessions SET last_seen_at = ?`, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := handler.ValidateSession(response.Token); err != ErrSessionExpired {
+		t.Errorf("ValidateSession on idle session = %v; expected ErrSessionExpired", err)
+	}
+	if _, err := handler.ValidateSession(response.Token); err != ErrSessionInvalid {
+		t.Errorf("expired session should be revoked, got %v", err)
+	}
+}
+
+func TestDefaultAdminMustChangePassword(t *testing.T) {
+	db := newTestDB(t)
+	handler := NewAuthHandler(db)
+	if err := handler.InitializeAdmin(); err != nil {
+		t.Fatalf("InitializeAdmin failed: %v", err)
+	}
+
+	response, err := handler.Login("admin", "admin123")
+	if err != nil || !response.Success {
+		t.Fatalf("Login failed: %v %+v", err, response)
+	}
+	if !response.User.MustChangePassword {
+		t.Errorf("default admin password should require a change")
+	}
+
+	if err := handler.ChangePassword(response.User.ID, response.Token, "wrong", "n3w-secret"); err == nil {
+		t.Errorf("ChangePassword accepted a wrong old password")
+	}
+	if err := handler.ChangePassword(response.User.ID, response.Token, "admin123", "short"); err == nil {
+		t.Errorf("ChangePassword accepted a too short password")
+	}
+	other, err := handler.Login("admin", "admin123")
+	if err != nil || !other.Success {
+		t.Fatalf("second Login failed: %v %+v", err, other)
+	}
+	if err := handler.ChangePassword(response.User.ID, response.Token, "admin123", "n3w-secret"); err != nil {
+		t.Fatalf("ChangePassword failed: %v", err)
+	}
+	if _, err := handler.ValidateSession(other.Token); err != ErrSessionInvalid {
+		t.Errorf("other session after a password change = %v; expected ErrSessionInvalid", err)
+	}
+
+	user, err := handler.ValidateSession(response.Token)
+	if err != nil {
+		t.Fatalf("ValidateSession failed: %v", err)
+	}
+	if user.MustChangePassword {
+		t.Errorf("password change should clear must_change_password")
+	}
+	if r, _ := handler.Login("admin", "admin123"); r.Success {
+		t.Errorf("old password still accepted")
+	}
+}
+
+func TestDeactivateAndResetUser(t *testing.T) {
+	db := newTestDB(t)
+	handler := NewAuthHandler(db)
+	if err := handler.InitializeAdmin(); err != nil {
+		t.Fatalf("InitializeAdmin failed: %v", err)
+	}
+	admin, err := handler.GetUserByUsername("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := handler.CreateUser(models.UserForm{Username: "dr.smith", Password: "secret1", Role: models.RoleDentist}, admin.ID)
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	dentistID := int(id)
+
+	if err := handler.DeactivateUser(admin.ID, admin.ID); err == nil {
+		t.Errorf("admin was able to deactivate themselves")
+	}
+	if err := handler.UpdateUser(admin.ID, models.UserUpdateForm{Username: "admin", Role: models.RoleDentist}, admin.ID); err == nil {
+		t.Errorf("last admin was demoted")
+	}
+
+	response, err := handler.Login("dr.smith", "secret1")
+	if err != nil || !response.Success {
+		t.Fatalf("Login failed: %v %+v", err, response)
+	}
+
+	if err := handler.DeactivateUser(dentistID, admin.ID); err != nil {
+		t.Fatalf("DeactivateUser failed: %v", err)
+	}
+	if _, err := handler.ValidateSession(response.Token); err != ErrSessionInvalid {
+		t.Errorf("session of deactivated user = %v; expected ErrSessionInvalid", err)
+	}
+	if r, _ := handler.Login("dr.smith", "secret1"); r.Success {
+		t.Errorf("deactivated user was able to log in")
+	}
+
+	if err := handler.ReactivateUser(dentistID, admin.ID); err != nil {
+		t.Fatalf("ReactivateUser failed: %v", err)
+	}
+	if err := handler.ResetPassword(dentistID, "temp123", admin.ID); err != nil {
+		t.Fatalf("ResetPassword failed: %v", err)
+	}
+	response, err = handler.Login("dr.smith", "temp123")
+	if err != nil || !response.Success {
+		t.Fatalf("Login after reset failed: %v %+v", err, response)
+	}
+	if !response.User.MustChangePassword {
+		t.Errorf("reset password should require a change")
+	}
+}
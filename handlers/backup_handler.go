@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"DentistApp/backup"
+	"DentistApp/models"
+)
+
+// minBackupPassphraseLength is the shortest passphrase accepted for encrypted backups
+const minBackupPassphraseLength = 8
+
+// BackupHandler handles the automatic backup settings. The schedule lives in backup_settings; the
+// passphrase is kept in a file outside the database, which is itself part of every backup.
+type BackupHandler struct {
+	db         *sql.DB
+	passphrase backup.PassphraseFile
+}
+
+// NewBackupHandler creates a new BackupHandler that keeps the backup passphrase in passphraseFile
+func NewBackupHandler(db *sql.DB, passphraseFile backup.PassphraseFile) *BackupHandler {
+	return &BackupHandler{db: db, passphrase: passphraseFile}
+}
+
+// MoveStoredPassphrase moves a passphrase saved in backup_settings by earlier versions to the
+// passphrase file and clears it from the database. A passphrase already in the file is kept.
+func (h *BackupHandler) MoveStoredPassphrase() error {
+	var stored string
+	if err := h.db.QueryRow(`SELECT passphrase FROM backup_settings WHERE id = 1`).Scan(&stored); err != nil {
+		return fmt.Errorf("failed to load backup settings: %v", err)
+	}
+	if stored == "" {
+		return nil
+	}
+	current, err := h.passphrase.Load()
+	if err != nil {
+		return err
+	}
+	if current == "" {
+		if err := h.passphrase.Save(stored); err != nil {
+			return err
+		}
+	}
+	if _, err := h.db.Exec(`UPDATE backup_settings SET passphrase = '' WHERE id = 1`); err != nil {
+		return fmt.Errorf("failed to clear the stored backup passphrase: %v", err)
+	}
+	return nil
+}
+
+// GetBackupSettings returns the backup schedule without the passphrase
+func (h *BackupHandler) GetBackupSettings() (*models.BackupSettings, error) {
+	settings, err := h.loadSettings()
+	if err != nil {
+		return nil, err
+	}
+	settings.HasPassphrase = settings.Passphrase != ""
+	settings.Passphrase = ""
+	return settings, nil
+}
+
+// SaveBackupSettings updates the backup schedule. An empty passphrase keeps the current one.
+func (h *BackupHandler) SaveBackupSettings(settings models.BackupSettings, actorID int) error {
+	if settings.IntervalHours < 1 || settings.IntervalHours > 24*30 {
+		return fmt.Errorf("backup interval must be between 1 hour and 30 days")
+	}
+	if settings.KeepCount < 1 || settings.KeepCount > 365 {
+		return fmt.Errorf("number of backups to keep must be between 1 and 365")
+	}
+
+	current, err := h.loadSettings()
+	if err != nil {
+		return err
+	}
+	passphrase := current.Passphrase
+	if settings.Passphrase != "" {
+		if len(settings.Passphrase) < minBackupPassphraseLength {
+			return fmt.Errorf("backup passphrase must be at least %d characters", minBackupPassphraseLength)
+		}
+		passphrase = settings.Passphrase
+	}
+	if settings.Encrypt && passphrase == "" {
+		return fmt.Errorf("a passphrase is required to encrypt backups")
+	}
+
+	if passphrase != current.Passphrase {
+		if err := h.passphrase.Save(passphrase); err != nil {
+			return err
+		}
+	}
+
+	query := `UPDATE backup_settings SET enabled = ?, interval_hours = ?, keep_count = ?, encrypt = ?, updated_at = ?
+	          WHERE id = 1`
+	_, err = auditedExec(h.db, actorID, auditBackupSettings, 1, AuditActionUpdate, query,
+		settings.Enabled, settings.IntervalHours, settings.KeepCount, settings.Encrypt,
+		time.Now().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return fmt.Errorf("failed to save backup settings: %v", err)
+	}
+	return nil
+}
+
+// BackupPassphrase returns the passphrase new backups are encrypted with, or "" if encryption is off
+func (h *BackupHandler) BackupPassphrase() (string, error) {
+	settings, err := h.loadSettings()
+	if err != nil {
+		return "", err
+	}
+	if !settings.Encrypt {
+		return "", nil
+	}
+	return settings.Passphrase, nil
+}
+
+// Schedule returns the settings in the form used by backup.Scheduler
+func (h *BackupHandler) Schedule() (backup.Schedule, error) {
+	settings, err := h.loadSettings()
+	if err != nil {
+		return backup.Schedule{}, err
+	}
+	schedule := backup.Schedule{
+		Enabled:       settings.Enabled,
+		IntervalHours: settings.IntervalHours,
+		Keep:          settings.KeepCount,
+	}
+	if settings.Encrypt {
+		schedule.Passphrase = settings.Passphrase
+	}
+	return schedule, nil
+}
+
+func (h *BackupHandler) loadSettings() (*models.BackupSettings, error) {
+	var settings models.BackupSettings
+	var updatedAt sql.NullString
+	err := h.db.QueryRow(`SELECT enabled, interval_hours, keep_count, encrypt, updated_at FROM backup_settings WHERE id = 1`).
+		Scan(&settings.Enabled, &settings.IntervalHours, &settings.KeepCount, &settings.Encrypt, &updatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load backup settings: %v", err)
+	}
+	settings.UpdatedAt = updatedAt.String
+	if settings.Passphrase, err = h.passphrase.Load(); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
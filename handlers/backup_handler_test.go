@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"path/filepath"
+	"testing"
+
+	"DentistApp/backup"
+	"DentistApp/models"
+)
+
+func TestBackupPassphraseStaysOutOfDatabase(t *testing.T) {
+	db, admin := newTestAdmin(t)
+	file := backup.PassphraseFile(filepath.Join(t.TempDir(), "backup-passphrase"))
+	backups := NewBackupHandler(db, file)
+
+	form := models.BackupSettings{Enabled: true, IntervalHours: 24, KeepCount: 7, Encrypt: true}
+	if err := backups.SaveBackupSettings(form, admin.ID); err == nil {
+		t.Errorf("SaveBackupSettings enabled encryption without a passphrase")
+	}
+	form.Passphrase = "correct horse"
+	if err := backups.SaveBackupSettings(form, admin.ID); err != nil {
+		t.Fatalf("SaveBackupSettings failed: %v", err)
+	}
+
+	var stored string
+	db.QueryRow(`SELECT passphrase FROM backup_settings WHERE id = 1`).Scan(&stored)
+	if stored != "" {
+		t.Errorf("passphrase %q was stored in the database", stored)
+	}
+	if got, err := file.Load(); err != nil || got != "correct horse" {
+		t.Errorf("passphrase file = %q, %v; expected the new passphrase", got, err)
+	}
+	if got, err := backups.BackupPassphrase(); err != nil || got != "correct horse" {
+		t.Errorf("BackupPassphrase = %q, %v", got, err)
+	}
+	settings, err := backups.GetBackupSettings()
+	if err != nil || !settings.HasPassphrase || settings.Passphrase != "" {
+		t.Errorf("GetBackupSettings = %+v, %v; expected the passphrase set but not returned", settings, err)
+	}
+
+	// An empty passphrase keeps the current one
+	form.Passphrase = ""
+	if err := backups.SaveBackupSettings(form, admin.ID); err != nil {
+		t.Fatalf("SaveBackupSettings failed: %v", err)
+	}
+	if got, _ := file.Load(); got != "correct horse" {
+		t.Errorf("passphrase changed to %q when left empty", got)
+	}
+}
+
+func TestMoveStoredPassphrase(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		want     string
+	}{
+		{"moves the stored passphrase", "", "legacy secret"},
+		{"keeps a passphrase already in the file", "newer secret", "newer secret"},
+	}
+	for _, tt := range tests {
+		db, _ := newTestAdmin(t)
+		file := backup.PassphraseFile(filepath.Join(t.TempDir(), "backup-passphrase"))
+		if tt.existing != "" {
+			if err := file.Save(tt.existing); err != nil {
+				t.Fatalf("%s: Save failed: %v", tt.name, err)
+			}
+		}
+		if _, err := db.Exec(`UPDATE backup_settings SET encrypt = 1, passphrase = 'legacy secret' WHERE id = 1`); err != nil {
+			t.Fatal(err)
+		}
+
+		backups := NewBackupHandler(db, file)
+		if err := backups.MoveStoredPassphrase(); err != nil {
+			t.Fatalf("%s: MoveStoredPassphrase failed: %v", tt.name, err)
+		}
+		var stored string
+		db.QueryRow(`SELECT passphrase FROM backup_settings WHERE id = 1`).Scan(&stored)
+		if stored != "" {
+			t.Errorf("%s: passphrase %q left in the database", tt.name, stored)
+		}
+		if got, err := backups.BackupPassphrase(); err != nil || got != tt.want {
+			t.Errorf("%s: BackupPassphrase = %q, %v; expected %q", tt.name, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"DentistApp/models"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ChairHandler handles treatment chair (operatory) operations
+type ChairHandler struct {
+	db *sql.DB
+}
+
+// NewChairHandler creates new handler
+func NewChairHandler(db *sql.DB) *ChairHandler {
+	return &ChairHandler{db: db}
+}
+
+// GetChairs returns all chairs ordered by name
+func (h *ChairHandler) GetChairs() ([]models.Chair, error) {
+	rows, err := h.db.Query(`SELECT id, name, is_active, created_at, updated_at FROM chairs ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chairs: %v", err)
+	}
+	defer rows.Close()
+
+	chairs := make([]models.Chair, 0)
+	for rows.Next() {
+		var chair models.Chair
+		if err := rows.Scan(&chair.ID, &chair.Name, &chair.IsActive, &chair.CreatedAt, &chair.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan chair: %v", err)
+		}
+		chairs = append(chairs, chair)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("chair rows error: %v", err)
+	}
+	return chairs, nil
+}
+
+// CreateChair inserts new chair
+func (h *ChairHandler) CreateChair(chair models.ChairForm, userID int) (int64, error) {
+	chair.Name = strings.TrimSpace(chair.Name)
+	if chair.Name == "" {
+		return 0, fmt.Errorf("chair name is required")
+	}
+
+	query := `INSERT INTO chairs (name, is_active) VALUES (?, ?)`
+	id, err := auditedInsert(h.db, userID, auditChair, query, chair.Name, chair.IsActive)
+	if err != nil && strings.Contains(err.Error(), "UNIQUE") {
+		return 0, fmt.Errorf("a chair named %q already exists", chair.Name)
+	}
+	return id, err
+}
+
+// UpdateChair updates chair by id
+func (h *ChairHandler) UpdateChair(id int, chair models.ChairForm, userID int) error {
+	chair.Name = strings.TrimSpace(chair.Name)
+	if chair.Name == "" {
+		return fmt.Errorf("chair name is required")
+	}
+
+	query := `UPDATE chairs SET name = ?, is_active = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	_, err := auditedExec(h.db, userID, auditChair, int64(id), AuditActionUpdate, query, chair.Name, chair.IsActive, id)
+	if err != nil && strings.Contains(err.Error(), "UNIQUE") {
+		return fmt.Errorf("a chair named %q already exists", chair.Name)
+	}
+	return err
+}
+
+// DeleteChair deletes chair by id. Chairs with appointments must be deactivated instead.
+func (h *ChairHandler) DeleteChair(id int, userID int) error {
+	var inUse bool
+	if err := h.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM appointments WHERE chair_id = ?)`, id).Scan(&inUse); err != nil {
+		return fmt.Errorf("failed to check chair usage: %v", err)
+	}
+	if inUse {
+		return fmt.Errorf("chair has appointments; deactivate it instead")
+	}
+
+	query := `DELETE FROM chairs WHERE id = ?`
+	_, err := auditedExec(h.db, userID, auditChair, int64(id), AuditActionDelete, query, id)
+	return err
+}
@@ -0,0 +1,445 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"DentistApp/models"
+)
+
+// ClinicalNoteHandler handles SOAP notes and note templates
+type ClinicalNoteHandler struct {
+	db *sql.DB
+}
+
+// NewClinicalNoteHandler creates new handler
+func NewClinicalNoteHandler(db *sql.DB) *ClinicalNoteHandler {
+	return &ClinicalNoteHandler{db: db}
+}
+
+var notePlaceholderPattern = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// notePlaceholders lists what each template placeholder is replaced with
+var notePlaceholders = map[string]func(session models.Session, values models.NoteTemplateValues) string{
+	"{patient}": func(s models.Session, _ models.NoteTemplateValues) string { return s.PatientName },
+	"{date}":    func(s models.Session, _ models.NoteTemplateValues) string { return s.SessionDate },
+	"{dentist}": func(s models.Session, _ models.NoteTemplateValues) string { return s.DentistName },
+	"{teeth}": func(s models.Session, _ models.NoteTemplateValues) string {
+		var teeth []string
+		for _, item := range s.Items {
+			for _, ref := range item.Teeth {
+				teeth = append(teeth, strings.TrimSpace(fmt.Sprintf("%d %s", ref.Tooth, ref.Surfaces)))
+			}
+		}
+		return strings.Join(teeth, ", ")
+	},
+	"{procedure}": func(s models.Session, _ models.NoteTemplateValues) string {
+		names := make([]string, 0, len(s.Items))
+		for _, item := range s.Items {
+			names = append(names, item.ItemName)
+		}
+		return strings.Join(names, ", ")
+	},
+	"{anaesthetic}": func(_ models.Session, v models.NoteTemplateValues) string { return strings.TrimSpace(v.Anaesthetic) },
+	"{lot}":         func(_ models.Session, v models.NoteTemplateValues) string { return strings.TrimSpace(v.LotNumber) },
+}
+
+func renderNoteSection(text string, session models.Session, values models.NoteTemplateValues) string {
+	return notePlaceholderPattern.ReplaceAllStringFunc(text, func(p string) string {
+		if fill, ok := notePlaceholders[p]; ok {
+			return fill(session, values)
+		}
+		return p
+	})
+}
+
+func validateNoteTemplate(t *models.NoteTemplate) error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		return fmt.Errorf("template name is required")
+	}
+	sections := []string{t.Subjective, t.Objective, t.Assessment, t.Plan}
+	if strings.TrimSpace(strings.Join(sections, "")) == "" {
+		return fmt.Errorf("template is empty")
+	}
+	for _, section := range sections {
+		for _, p := range notePlaceholderPattern.FindAllString(section, -1) {
+			if _, ok := notePlaceholders[p]; !ok {
+				return fmt.Errorf("unknown placeholder %s; use {patient}, {date}, {dentist}, {teeth}, {procedure}, {anaesthetic} or {lot}", p)
+			}
+		}
+	}
+	return nil
+}
+
+// GetNoteTemplates returns all note templates ordered by name
+func (h *ClinicalNoteHandler) GetNoteTemplates() ([]models.NoteTemplate, error) {
+	rows, err := h.db.Query(`SELECT id, name, subjective, objective, assessment, plan, created_at FROM note_templates ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get note templates: %v", err)
+	}
+	defer rows.Close()
+
+	templates := make([]models.NoteTemplate, 0)
+	for rows.Next() {
+		var t models.NoteTemplate
+		if err := rows.Scan(&t.ID, &t.Name, &t.Subjective, &t.Objective, &t.Assessment, &t.Plan, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan note template: %v", err)
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+// CreateNoteTemplate adds a note template
+func (h *ClinicalNoteHandler) CreateNoteTemplate(t models.NoteTemplate, actorID int) (int64, error) {
+	if err := validateNoteTemplate(&t); err != nil {
+		return 0, err
+	}
+	id, err := auditedInsert(h.db, actorID, auditNoteTemplate,
+		`INSERT INTO note_templates (name, subjective, objective, assessment, plan) VALUES (?, ?, ?, ?, ?)`,
+		t.Name, t.Subjective, t.Objective, t.Assessment, t.Plan)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return 0, fmt.Errorf("a template named %q already exists", t.Name)
+		}
+		return 0, fmt.Errorf("failed to create note template: %v", err)
+	}
+	return id, nil
+}
+
+// UpdateNoteTemplate saves a note template. Notes already written from it are not changed.
+func (h *ClinicalNoteHandler) UpdateNoteTemplate(t models.NoteTemplate, actorID int) error {
+	if err := validateNoteTemplate(&t); err != nil {
+		return err
+	}
+	result, err := auditedExec(h.db, actorID, auditNoteTemplate, int64(t.ID), AuditActionUpdate,
+		`UPDATE note_templates SET name = ?, subjective = ?, objective = ?, assessment = ?, plan = ? WHERE id = ?`,
+		t.Name, t.Subjective, t.Objective, t.Assessment, t.Plan, t.ID)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return fmt.Errorf("a template named %q already exists", t.Name)
+		}
+		return fmt.Errorf("failed to update note template: %v", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("note template not found")
+	}
+	return nil
+}
+
+// DeleteNoteTemplate deletes a note template
+func (h *ClinicalNoteHandler) DeleteNoteTemplate(id int, actorID int) error {
+	result, err := auditedExec(h.db, actorID, auditNoteTemplate, int64(id), AuditActionDelete, `DELETE FROM note_templates WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete note template: %v", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("note template not found")
+	}
+	return nil
+}
+
+const clinicalNoteSelect = `SELECT n.id, n.session_id, s.patient_id, n.template_id, n.subjective, n.objective, n.assessment, n.plan,
+       n.version, n.updated_at, n.updated_by, COALESCE(uu.username, ''), n.signed_at, n.signed_by, COALESCE(su.username, '')
+FROM clinical_notes n
+JOIN sessions s ON n.session_id = s.id
+LEFT JOIN users uu ON n.updated_by = uu.id
+LEFT JOIN users su ON n.signed_by = su.id`
+
+// GetClinicalNote returns the note of a session. A session without a note gets an empty, unsaved one.
+func (h *ClinicalNoteHandler) GetClinicalNote(sessionID int) (models.ClinicalNote, error) {
+	return loadClinicalNote(h.db, sessionID)
+}
+
+func loadClinicalNote(q queryRunner, sessionID int) (models.ClinicalNote, error) {
+	var note models.ClinicalNote
+	var templateID, updatedBy, signedBy sql.NullInt64
+	err := q.QueryRow(clinicalNoteSelect+` WHERE n.session_id = ?`, sessionID).Scan(&note.ID, &note.SessionID, &note.PatientID,
+		&templateID, &note.Subjective, &note.Objective, &note.Assessment, &note.Plan, &note.Version,
+		&note.UpdatedAt, &updatedBy, &note.UpdatedByName, &note.SignedAt, &signedBy, &note.SignedByName)
+	if err == sql.ErrNoRows {
+		note = models.ClinicalNote{SessionID: sessionID}
+		if err := q.QueryRow(`SELECT patient_id FROM sessions WHERE id = ?`, sessionID).Scan(&note.PatientID); err != nil {
+			if err == sql.ErrNoRows {
+				return note, fmt.Errorf("session not found")
+			}
+			return note, fmt.Errorf("failed to get session: %v", err)
+		}
+		return note, nil
+	}
+	if err != nil {
+		return note, fmt.Errorf("failed to get clinical note: %v", err)
+	}
+	note.TemplateID = nullableInt(templateID)
+	note.UpdatedBy = nullableInt(updatedBy)
+	note.SignedBy = nullableInt(signedBy)
+	return note, nil
+}
+
+// ApplyNoteTemplate fills a template's placeholders from the note's session and appends the text to
+// each section of the draft note. The note is not saved.
+func (h *ClinicalNoteHandler) ApplyNoteTemplate(note models.ClinicalNote, templateID int, values models.NoteTemplateValues) (models.ClinicalNote, error) {
+	current, err := h.GetClinicalNote(note.SessionID)
+	if err != nil {
+		return note, err
+	}
+	if current.SignedAt != "" {
+		return note, fmt.Errorf("the note was signed on %s and can no longer be changed", current.SignedAt)
+	}
+	var t models.NoteTemplate
+	err = h.db.QueryRow(`SELECT id, name, subjective, objective, assessment, plan FROM note_templates WHERE id = ?`, templateID).
+		Scan(&t.ID, &t.Name, &t.Subjective, &t.Objective, &t.Assessment, &t.Plan)
+	if err == sql.ErrNoRows {
+		return note, fmt.Errorf("note template not found")
+	}
+	if err != nil {
+		return note, fmt.Errorf("failed to get note template: %v", err)
+	}
+	session, err := NewSessionHandler(h.db).GetSession(note.SessionID)
+	if err != nil {
+		return note, err
+	}
+
+	appendSection := func(current, text string) string {
+		text = strings.TrimSpace(renderNoteSection(text, session, values))
+		if text == "" {
+			return current
+		}
+		if strings.TrimSpace(current) == "" {
+			return text
+		}
+		return strings.TrimRight(current, "\n") + "\n" + text
+	}
+	note.Subjective = appendSection(note.Subjective, t.Subjective)
+	note.Objective = appendSection(note.Objective, t.Objective)
+	note.Assessment = appendSection(note.Assessment, t.Assessment)
+	note.Plan = appendSection(note.Plan, t.Plan)
+	note.TemplateID = &t.ID
+	return note, nil
+}
+
+// SaveClinicalNote writes a new version of the session's note. note.Version must be the version the
+// user edited, so a note changed by someone else in the meantime is not overwritten. Signed notes
+// cannot be saved.
+func (h *ClinicalNoteHandler) SaveClinicalNote(note models.ClinicalNote, actorID int) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	current, err := loadClinicalNote(tx, note.SessionID)
+	if err != nil {
+		return err
+	}
+	if current.SignedAt != "" {
+		return fmt.Errorf("the note was signed on %s and can no longer be changed", current.SignedAt)
+	}
+	if current.ID != 0 && note.Version != current.Version {
+		return fmt.Errorf("the note was changed by %s in the meantime; reload it and try again", current.UpdatedByName)
+	}
+	if current.ID != 0 && sameNoteText(current, note) && sameTemplate(current.TemplateID, note.TemplateID) {
+		return nil
+	}
+
+	now := time.Now().Format("2006-01-02 15:04:05")
+	noteID := int64(current.ID)
+	version := current.Version + 1
+	if current.ID == 0 {
+		result, err := tx.Exec(`INSERT INTO clinical_notes (session_id, template_id, subjective, objective, assessment, plan, version, updated_at, updated_by)
+		                        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			note.SessionID, note.TemplateID, note.Subjective, note.Objective, note.Assessment, note.Plan, version, now, nullableActor(actorID))
+		if err != nil {
+			return fmt.Errorf("failed to save clinical note: %v", err)
+		}
+		if noteID, err = result.LastInsertId(); err != nil {
+			return fmt.Errorf("failed to get clinical note ID: %v", err)
+		}
+		if err := recordAudit(tx, actorID, auditClinicalNote, noteID, AuditActionCreate, nil); err != nil {
+			return err
+		}
+	} else {
+		before, err := auditSnapshot(tx, auditClinicalNote, noteID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE clinical_notes SET template_id = ?, subjective = ?, objective = ?, assessment = ?, plan = ?,
+		                      version = ?, updated_at = ?, updated_by = ? WHERE id = ?`,
+			note.TemplateID, note.Subjective, note.Objective, note.Assessment, note.Plan, version, now, nullableActor(actorID), noteID); err != nil {
+			return fmt.Errorf("failed to save clinical note: %v", err)
+		}
+		if err := recordAudit(tx, actorID, auditClinicalNote, noteID, AuditActionUpdate, before); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`INSERT INTO clinical_note_versions (note_id, version, subjective, objective, assessment, plan, saved_at, saved_by)
+	                      VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		noteID, version, note.Subjective, note.Objective, note.Assessment, note.Plan, now, nullableActor(actorID)); err != nil {
+		return fmt.Errorf("failed to save clinical note version: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+func sameNoteText(a, b models.ClinicalNote) bool {
+	return a.Subjective == b.Subjective && a.Objective == b.Objective && a.Assessment == b.Assessment && a.Plan == b.Plan
+}
+
+func sameTemplate(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// SignClinicalNote locks the session's note. A signed note cannot be edited or removed with its
+// session.
+func (h *ClinicalNoteHandler) SignClinicalNote(sessionID int, actorID int) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	note, err := loadClinicalNote(tx, sessionID)
+	if err != nil {
+		return err
+	}
+	if note.ID == 0 {
+		return fmt.Errorf("save the note before signing it")
+	}
+	if note.SignedAt != "" {
+		return fmt.Errorf("the note was already signed on %s", note.SignedAt)
+	}
+	if strings.TrimSpace(note.Subjective+note.Objective+note.Assessment+note.Plan) == "" {
+		return fmt.Errorf("cannot sign an empty note")
+	}
+
+	before, err := auditSnapshot(tx, auditClinicalNote, int64(note.ID))
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE clinical_notes SET signed_at = ?, signed_by = ? WHERE id = ?`,
+		time.Now().Format("2006-01-02 15:04:05"), nullableActor(actorID), note.ID); err != nil {
+		return fmt.Errorf("failed to sign clinical note: %v", err)
+	}
+	if err := recordAudit(tx, actorID, auditClinicalNote, int64(note.ID), AuditActionUpdate, before); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// GetClinicalNoteVersions returns every saved version of the session's note, newest first
+func (h *ClinicalNoteHandler) GetClinicalNoteVersions(sessionID int) ([]models.ClinicalNoteVersion, error) {
+	rows, err := h.db.Query(`SELECT v.version, v.subjective, v.objective, v.assessment, v.plan, v.saved_at, v.saved_by, COALESCE(u.username, '')
+	                         FROM clinical_note_versions v
+	                         JOIN clinical_notes n ON v.note_id = n.id
+	                         LEFT JOIN users u ON v.saved_by = u.id
+	                         WHERE n.session_id = ?
+	                         ORDER BY v.version DESC`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clinical note versions: %v", err)
+	}
+	defer rows.Close()
+
+	versions := make([]models.ClinicalNoteVersion, 0)
+	for rows.Next() {
+		var v models.ClinicalNoteVersion
+		var savedBy sql.NullInt64
+		if err := rows.Scan(&v.Version, &v.Subjective, &v.Objective, &v.Assessment, &v.Plan, &v.SavedAt, &savedBy, &v.SavedByName); err != nil {
+			return nil, fmt.Errorf("failed to scan clinical note version: %v", err)
+		}
+		v.SavedBy = nullableInt(savedBy)
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// noteSearchLimit caps the number of notes a search returns
+const noteSearchLimit = 100
+
+// SearchClinicalNotes finds notes containing text in any section, newest session first. patientID 0
+// searches every patient.
+func (h *ClinicalNoteHandler) SearchClinicalNotes(text string, patientID int) ([]models.ClinicalNoteMatch, error) {
+	text = strings.TrimSpace(text)
+	matches := make([]models.ClinicalNoteMatch, 0)
+	if text == "" {
+		return matches, nil
+	}
+
+	pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(text) + "%"
+	query := `SELECT n.id, n.session_id, s.patient_id, COALESCE(p.name, ''), s.session_date, n.subjective, n.objective, n.assessment, n.plan, n.signed_at
+	          FROM clinical_notes n
+	          JOIN sessions s ON n.session_id = s.id
+	          LEFT JOIN patients p ON s.patient_id = p.id
+	          WHERE (n.subjective LIKE ? ESCAPE '\' OR n.objective LIKE ? ESCAPE '\' OR n.assessment LIKE ? ESCAPE '\' OR n.plan LIKE ? ESCAPE '\')`
+	args := []any{pattern, pattern, pattern, pattern}
+	if patientID > 0 {
+		query += ` AND s.patient_id = ?`
+		args = append(args, patientID)
+	}
+	query += fmt.Sprintf(` ORDER BY s.session_date DESC, n.id DESC LIMIT %d`, noteSearchLimit)
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search clinical notes: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m models.ClinicalNoteMatch
+		var sections [4]string
+		var signedAt string
+		if err := rows.Scan(&m.NoteID, &m.SessionID, &m.PatientID, &m.PatientName, &m.SessionDate,
+			&sections[0], &sections[1], &sections[2], &sections[3], &signedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan clinical note: %v", err)
+		}
+		m.Signed = signedAt != ""
+		for i, name := range []string{"subjective", "objective", "assessment", "plan"} {
+			if excerpt, ok := noteExcerpt(sections[i], text); ok {
+				m.Section = name
+				m.Excerpt = excerpt
+				break
+			}
+		}
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
+
+// noteExcerpt returns up to 40 characters either side of the first case-insensitive match of text
+func noteExcerpt(section, text string) (string, bool) {
+	at := strings.Index(strings.ToLower(section), strings.ToLower(text))
+	if at < 0 {
+		return "", false
+	}
+	const context = 40
+	start, end := at, min(at+len(text), len(section))
+	for i := 0; i < context && start > 0; i++ {
+		_, size := utf8.DecodeLastRuneInString(section[:start])
+		start -= size
+	}
+	for i := 0; i < context && end < len(section); i++ {
+		_, size := utf8.DecodeRuneInString(section[end:])
+		end += size
+	}
+	excerpt := strings.Join(strings.Fields(section[start:end]), " ")
+	if start > 0 {
+		excerpt = "…" + excerpt
+	}
+	if end < len(section) {
+		excerpt += "…"
+	}
+	return excerpt, true
+}
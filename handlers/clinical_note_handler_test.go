@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"DentistApp/models"
+)
+
+func TestClinicalNoteTemplatesVersionsAndSigning(t *testing.T) {
+	db, admin := newTestAdmin(t)
+	patientID := newTestPatient(t, db, models.PatientForm{Name: "Jane Doe", Phone: "0100000000"})
+
+	sessions := NewSessionHandler(db)
+	sessionID, err := sessions.CreateSession(models.SessionForm{
+		PatientID:   patientID,
+		DentistID:   admin.ID,
+		SessionDate: "2025-03-01",
+		Status:      "in-progress",
+		Items:       []models.SessionItemForm{{ItemName: "Composite filling", Amount: 200000, Teeth: []models.ToothRef{{Tooth: 16, Surfaces: "mo"}}}},
+	}, admin.ID)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	notes := NewClinicalNoteHandler(db)
+	if _, err := notes.CreateNoteTemplate(models.NoteTemplate{Name: "Bad", Plan: "Review {nextweek}"}, admin.ID); err == nil {
+		t.Errorf("CreateNoteTemplate accepted an unknown placeholder")
+	}
+	templateID, err := notes.CreateNoteTemplate(models.NoteTemplate{
+		Name:       "Restoration",
+		Subjective: "{patient} reports sensitivity.",
+		Objective:  "{procedure} on {teeth} under {anaesthetic}, lot {lot}.",
+		Plan:       "Review in 6 months.",
+	}, admin.ID)
+	if err != nil {
+		t.Fatalf("CreateNoteTemplate failed: %v", err)
+	}
+	if _, err := notes.CreateNoteTemplate(models.NoteTemplate{Name: "restoration", Plan: "x"}, admin.ID); err == nil {
+		t.Errorf("CreateNoteTemplate accepted a duplicate name")
+	}
+
+	note, err := notes.GetClinicalNote(int(sessionID))
+	if err != nil {
+		t.Fatalf("GetClinicalNote failed: %v", err)
+	}
+	if note.ID != 0 || note.PatientID != patientID {
+		t.Fatalf("note of a new session = %+v; expected an unsaved note", note)
+	}
+	note.Subjective = "Came in early."
+	note, err = notes.ApplyNoteTemplate(note, int(templateID), models.NoteTemplateValues{Anaesthetic: "Articaine 4%", LotNumber: "A123"})
+	if err != nil {
+		t.Fatalf("ApplyNoteTemplate failed: %v", err)
+	}
+	if note.Subjective != "Came in early.\nJane Doe reports sensitivity." || note.Objective != "Composite filling on 16 MO under Articaine 4%, lot A123." {
+		t.Fatalf("filled note = %+v", note)
+	}
+
+	if err := notes.SaveClinicalNote(note, admin.ID); err != nil {
+		t.Fatalf("SaveClinicalNote failed: %v", err)
+	}
+	saved, _ := notes.GetClinicalNote(int(sessionID))
+	if saved.Version != 1 || saved.TemplateID == nil || *saved.TemplateID != int(templateID) {
+		t.Errorf("saved note = %+v; expected version 1 from the template", saved)
+	}
+
+	// A stale copy is refused, an unchanged save adds no version, an edit adds one
+	if err := notes.SaveClinicalNote(note, admin.ID); err == nil {
+		t.Errorf("SaveClinicalNote overwrote a newer version")
+	}
+	if err := notes.SaveClinicalNote(saved, admin.ID); err != nil {
+		t.Fatalf("SaveClinicalNote failed: %v", err)
+	}
+	saved.Assessment = "Secondary caries"
+	if err := notes.SaveClinicalNote(saved, admin.ID); err != nil {
+		t.Fatalf("SaveClinicalNote failed: %v", err)
+	}
+	versions, err := notes.GetClinicalNoteVersions(int(sessionID))
+	if err != nil {
+		t.Fatalf("GetClinicalNoteVersions failed: %v", err)
+	}
+	if len(versions) != 2 || versions[0].Version != 2 || versions[1].Assessment != "" {
+		t.Errorf("versions = %+v; expected two, newest first", versions)
+	}
+
+	matches, err := notes.SearchClinicalNotes("articaine", 0)
+	if err != nil {
+		t.Fatalf("SearchClinicalNotes failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Section != "objective" || !strings.Contains(matches[0].Excerpt, "Articaine") || matches[0].PatientName != "Jane Doe" {
+		t.Errorf("matches = %+v; expected the objective section", matches)
+	}
+	if matches, _ := notes.SearchClinicalNotes("100%", 0); len(matches) != 0 {
+		t.Errorf("search for 100%% = %+v; expected the %% to be matched literally", matches)
+	}
+
+	if err := notes.SignClinicalNote(int(sessionID), admin.ID); err != nil {
+		t.Fatalf("SignClinicalNote failed: %v", err)
+	}
+	signed, _ := notes.GetClinicalNote(int(sessionID))
+	if signed.SignedAt == "" || signed.SignedBy == nil {
+		t.Fatalf("signed note = %+v", signed)
+	}
+	signed.Plan = "Changed after signing"
+	if err := notes.SaveClinicalNote(signed, admin.ID); err == nil {
+		t.Errorf("SaveClinicalNote changed a signed note")
+	}
+	if _, err := notes.ApplyNoteTemplate(signed, int(templateID), models.NoteTemplateValues{}); err == nil {
+		t.Errorf("ApplyNoteTemplate changed a signed note")
+	}
+	if err := sessions.DeleteSession(int(sessionID), admin.ID); err == nil {
+		t.Errorf("DeleteSession deleted a session with a signed note")
+	}
+	// Nor can the note go with its patient
+	patients := NewPatientHandler(db)
+	if err := patients.DeletePatient(patientID, admin.ID); err == nil {
+		t.Errorf("DeletePatient deleted a patient with a signed note")
+	}
+	if err := patients.DeleteAllPatients(admin.ID); err == nil {
+		t.Errorf("DeleteAllPatients deleted a patient with a signed note")
+	}
+	if kept, err := notes.GetClinicalNote(int(sessionID)); err != nil || kept.SignedAt == "" {
+		t.Errorf("signed note after refused deletes = %+v, %v", kept, err)
+	}
+}
@@ -38,17 +38,7 @@ func (h *ColorShadeHandler) CreateColorShade(shade models.ColorShadeForm, userID
 
 	query := `INSERT INTO color_shades (name, description, hex_color, is_active, created_by) 
 	          VALUES (?, ?, ?, ?, ?)`
-	result, err := h.db.Exec(query, shade.Name, shade.Description, shade.HexColor, shade.IsActive, userID)
-	if err != nil {
-		return 0, err
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
-	}
-
-	return id, nil
+	return auditedInsert(h.db, userID, auditColorShade, query, shade.Name, shade.Description, shade.HexColor, shade.IsActive, userID)
 }
 
 // GetColorShadesPaginated returns paginated color shades ordered by name
@@ -133,14 +123,15 @@ func (h *ColorShadeHandler) UpdateColorShade(id int, shade models.ColorShadeForm
 
 	query := `UPDATE color_shades SET name = ?, description = ?, hex_color = ?, is_active = ?, 
 	          updated_at = CURRENT_TIMESTAMP WHERE id = ?`
-	_, err := h.db.Exec(query, shade.Name, shade.Description, shade.HexColor, shade.IsActive, id)
+	_, err := auditedExec(h.db, userID, auditColorShade, int64(id), AuditActionUpdate, query,
+		shade.Name, shade.Description, shade.HexColor, shade.IsActive, id)
 	return err
 }
 
 // DeleteColorShade deletes color shade by id
-func (h *ColorShadeHandler) DeleteColorShade(id int) error {
+func (h *ColorShadeHandler) DeleteColorShade(id int, userID int) error {
 	query := `DELETE FROM color_shades WHERE id = ?`
-	_, err := h.db.Exec(query, id)
+	_, err := auditedExec(h.db, userID, auditColorShade, int64(id), AuditActionDelete, query, id)
 	return err
 }
 
@@ -54,7 +54,7 @@ func (h *DentalLabHandler) generateLabCode() (string, error) {
 }
 
 // CreateDentalLab inserts new dental lab
-func (h *DentalLabHandler) CreateDentalLab(lab models.DentalLabForm) (int64, error) {
+func (h *DentalLabHandler) CreateDentalLab(lab models.DentalLabForm, actorID int) (int64, error) {
 	// Validate required fields
 	if lab.Name == "" {
 		return 0, fmt.Errorf("lab name is required")
@@ -140,18 +140,8 @@ func (h *DentalLabHandler) CreateDentalLab(lab models.DentalLabForm) (int64, err
 
 	query := `INSERT INTO dental_labs (code, name, contact_person, phone_primary, phone_secondary, email, specialties, is_active, notes) 
 	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	result, err := h.db.Exec(query, code, lab.Name, lab.ContactPerson, lab.PhonePrimary, 
+	return auditedInsert(h.db, actorID, auditDentalLab, query, code, lab.Name, lab.ContactPerson, lab.PhonePrimary,
 		lab.PhoneSecondary, lab.Email, lab.Specialties, isActive, lab.Notes)
-	if err != nil {
-		return 0, err
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
-	}
-
-	return id, nil
 }
 
 // GetDentalLabsPaginated returns paginated dental labs ordered by name
@@ -232,7 +222,7 @@ func (h *DentalLabHandler) GetDentalLab(id int) (*models.DentalLab, error) {
 }
 
 // UpdateDentalLab updates dental lab by id
-func (h *DentalLabHandler) UpdateDentalLab(id int, lab models.DentalLabForm) error {
+func (h *DentalLabHandler) UpdateDentalLab(id int, lab models.DentalLabForm, actorID int) error {
 	// Validate required fields
 	if lab.Name == "" {
 		return fmt.Errorf("lab name is required")
@@ -306,15 +296,15 @@ func (h *DentalLabHandler) UpdateDentalLab(id int, lab models.DentalLabForm) err
 
 	query := `UPDATE dental_labs SET name = ?, contact_person = ?, phone_primary = ?, phone_secondary = ?, 
 	          email = ?, specialties = ?, is_active = ?, notes = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
-	_, err = h.db.Exec(query, lab.Name, lab.ContactPerson, lab.PhonePrimary, lab.PhoneSecondary, 
-		lab.Email, lab.Specialties, lab.IsActive, lab.Notes, id)
+	_, err = auditedExec(h.db, actorID, auditDentalLab, int64(id), AuditActionUpdate, query,
+		lab.Name, lab.ContactPerson, lab.PhonePrimary, lab.PhoneSecondary, lab.Email, lab.Specialties, lab.IsActive, lab.Notes, id)
 	return err
 }
 
 // DeleteDentalLab deletes dental lab by id
-func (h *DentalLabHandler) DeleteDentalLab(id int) error {
+func (h *DentalLabHandler) DeleteDentalLab(id int, actorID int) error {
 	query := `DELETE FROM dental_labs WHERE id = ?`
-	_, err := h.db.Exec(query, id)
+	_, err := auditedExec(h.db, actorID, auditDentalLab, int64(id), AuditActionDelete, query, id)
 	return err
 }
 
@@ -71,7 +71,7 @@ func (h *ExpenseCategoryHandler) CreateExpenseCategory(category models.ExpenseCa
 	          is_active, requires_approval, approval_threshold, reporting_group, sort_order, created_by
 	          ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 	
-	result, err := h.db.Exec(query,
+	id, err := auditedInsert(h.db, userID, auditExpenseCategory, query,
 		category.Name, category.Description, color, category.ExpenseType,
 		budgetAmount, budgetPeriod,
 		isTaxDeductible, costCenter, accountCode, parentCategoryID,
@@ -82,11 +82,6 @@ func (h *ExpenseCategoryHandler) CreateExpenseCategory(category models.ExpenseCa
 		return 0, err
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
-	}
-
 	log.Printf("[ExpenseCategoryHandler] Expense category created successfully with ID: %d", id)
 	return id, nil
 }
@@ -295,7 +290,7 @@ func (h *ExpenseCategoryHandler) UpdateExpenseCategory(id int, category models.E
 	              is_active = ?, requires_approval = ?, approval_threshold = ?, reporting_group = ?, sort_order = ?,
 	              updated_by = ?, updated_at = CURRENT_TIMESTAMP 
 	          WHERE id = ?`
-	_, err = h.db.Exec(query,
+	_, err = auditedExec(h.db, userID, auditExpenseCategory, int64(id), AuditActionUpdate, query,
 		category.Name, category.Description, color, category.ExpenseType,
 		budgetAmount, budgetPeriod,
 		isTaxDeductible, costCenter, accountCode, parentCategoryID,
@@ -305,15 +300,15 @@ func (h *ExpenseCategoryHandler) UpdateExpenseCategory(id int, category models.E
 }
 
 // DeleteExpenseCategory deletes expense category by id (soft delete - sets is_active = 0)
-func (h *ExpenseCategoryHandler) DeleteExpenseCategory(id int) error {
+func (h *ExpenseCategoryHandler) DeleteExpenseCategory(id int, userID int) error {
 	query := `UPDATE expense_categories SET is_active = 0, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
-	_, err := h.db.Exec(query, id)
+	_, err := auditedExec(h.db, userID, auditExpenseCategory, int64(id), AuditActionDelete, query, id)
 	return err
 }
 
 // PermanentlyDeleteExpenseCategory permanently deletes expense category by id (hard delete)
 // Returns error if category has child categories or expenses referencing it
-func (h *ExpenseCategoryHandler) PermanentlyDeleteExpenseCategory(id int) error {
+func (h *ExpenseCategoryHandler) PermanentlyDeleteExpenseCategory(id int, userID int) error {
 	// Check for child categories (parent references)
 	var childCount int
 	err := h.db.QueryRow(
@@ -347,7 +342,7 @@ func (h *ExpenseCategoryHandler) PermanentlyDeleteExpenseCategory(id int) error
 
 	// Perform hard delete
 	query := `DELETE FROM expense_categories WHERE id = ?`
-	result, err := h.db.Exec(query, id)
+	result, err := auditedExec(h.db, userID, auditExpenseCategory, int64(id), AuditActionDelete, query, id)
 	if err != nil {
 		return fmt.Errorf("failed to permanently delete category: %v", err)
 	}
@@ -0,0 +1,724 @@
+package handlers
+
+import (
+	"DentistApp/models"
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// ExpenseHandler handles expense and expense payment operations
+type ExpenseHandler struct {
+	db *sql.DB
+}
+
+// NewExpenseHandler creates new handler
+func NewExpenseHandler(db *sql.DB) *ExpenseHandler {
+	return &ExpenseHandler{db: db}
+}
+
+const expensePaymentCodePrefix = "ExpPayment-"
+
+var validExpensePaymentMethods = map[string]bool{
+	"cash":          true,
+	"bank_transfer": true,
+	"check":         true,
+	"card":          true,
+}
+
+var validRecurringPeriods = map[string]bool{
+	"weekly":    true,
+	"monthly":   true,
+	"quarterly": true,
+	"yearly":    true,
+}
+
+var validExpensePaymentStatuses = map[string]bool{
+	"unpaid":         true,
+	"partially_paid": true,
+	"paid":           true,
+}
+
+// expenseSelectColumns lists the columns read for an expense, in scan order
+const expenseSelectColumns = `e.id, COALESCE(e.expense_code, ''), e.expense_date, e.description, e.amount,
+	       e.category_id, COALESCE(c.name, 'Unknown') AS category_name,
+	       COALESCE(e.payment_status, 'unpaid'), COALESCE(e.payment_method, 'cash'),
+	       COALESCE(e.vendor_name, ''), COALESCE(e.vendor_contact, ''), COALESCE(e.receipt_number, ''),
+	       COALESCE(e.notes, ''), COALESCE(e.receipt_file_path, ''), COALESCE(e.reporting_period, ''),
+	       COALESCE(e.is_recurring, 0), COALESCE(e.recurring_period, ''),
+	       (SELECT COALESCE(SUM(ep.amount), 0) FROM expense_payments ep WHERE ep.expense_id = e.id) AS total_paid,
+	       e.created_by, e.updated_by, COALESCE(e.created_at, ''), COALESCE(e.updated_at, '')`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanExpense(row rowScanner) (models.Expense, error) {
+	var expense models.Expense
+	var createdBy sql.NullInt64
+	var updatedBy sql.NullInt64
+
+	err := row.Scan(
+		&expense.ID, &expense.ExpenseCode, &expense.ExpenseDate, &expense.Description, &expense.Amount,
+		&expense.CategoryID, &expense.CategoryName,
+		&expense.PaymentStatus, &expense.PaymentMethod,
+		&expense.VendorName, &expense.VendorContact, &expense.ReceiptNumber,
+		&expense.Notes, &expense.ReceiptFilePath, &expense.ReportingPeriod,
+		&expense.IsRecurring, &expense.RecurringPeriod,
+		&expense.TotalPaid,
+		&createdBy, &updatedBy, &expense.CreatedAt, &expense.UpdatedAt,
+	)
+	if err != nil {
+		return expense, err
+	}
+
+	if createdBy.Valid {
+		val := int(createdBy.Int64)
+		expense.CreatedBy = &val
+	}
+	if updatedBy.Valid {
+		val := int(updatedBy.Int64)
+		expense.UpdatedBy = &val
+	}
+
+	return expense, nil
+}
+
+// expensePaymentStatus derives the payment status of an expense from its amount and total paid
+func expensePaymentStatus(amount, totalPaid int) string {
+	if totalPaid <= 0 {
+		return "unpaid"
+	}
+	if totalPaid >= amount {
+		return "paid"
+	}
+	return "partially_paid"
+}
+
+// parseExpenseDate parses the date formats accepted from the frontend
+func parseExpenseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, fmt.Errorf("expense date is required")
+	}
+
+	layouts := []string{
+		"2006-01-02",
+		"2006-01-02T15:04",
+		"2006-01-02 15:04:05",
+		time.RFC3339,
+	}
+
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid expense date format")
+}
+
+// validateExpenseForm validates the form and returns the parsed expense date
+func (h *ExpenseHandler) validateExpenseForm(expense models.ExpenseForm) (time.Time, error) {
+	if strings.TrimSpace(expense.Description) == "" {
+		return time.Time{}, fmt.Errorf("expense description is required")
+	}
+	if expense.Amount <= 0 {
+		return time.Time{}, fmt.Errorf("expense amount must be greater than zero")
+	}
+	if expense.CategoryID == 0 {
+		return time.Time{}, fmt.Errorf("expense category is required")
+	}
+
+	paymentMethod := expense.PaymentMethod
+	if paymentMethod == "" {
+		paymentMethod = "cash"
+	}
+	if !validExpensePaymentMethods[paymentMethod] {
+		return time.Time{}, fmt.Errorf("invalid payment method: %s", expense.PaymentMethod)
+	}
+
+	if expense.IsRecurring && !validRecurringPeriods[expense.RecurringPeriod] {
+		return time.Time{}, fmt.Errorf("invalid recurring period: %s", expense.RecurringPeriod)
+	}
+
+	expenseDate, err := parseExpenseDate(expense.ExpenseDate)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// Category must exist and still be active
+	var isActive bool
+	err = h.db.QueryRow("SELECT is_active FROM expense_categories WHERE id = ?", expense.CategoryID).Scan(&isActive)
+	if err == sql.ErrNoRows {
+		return time.Time{}, fmt.Errorf("expense category not found")
+	} else if err != nil {
+		return time.Time{}, fmt.Errorf("failed to check expense category: %v", err)
+	}
+	if !isActive {
+		return time.Time{}, fmt.Errorf("expense category is inactive")
+	}
+
+	return expenseDate, nil
+}
+
+// generateExpenseCode generates the next expense code (EXP-001, EXP-002, etc.)
+func generateExpenseCode(runner queryRunner) (string, error) {
+	query := `SELECT expense_code FROM expenses
+	          WHERE expense_code LIKE 'EXP-%'
+	          ORDER BY CAST(SUBSTR(expense_code, 5) AS INTEGER) DESC
+	          LIMIT 1`
+
+	var lastCode string
+	err := runner.QueryRow(query).Scan(&lastCode)
+	if err == sql.ErrNoRows {
+		return "EXP-001", nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to get last expense code: %v", err)
+	}
+
+	var num int
+	_, err = fmt.Sscanf(lastCode, "EXP-%d", &num)
+	if err != nil {
+		return "EXP-001", nil
+	}
+
+	num++
+	return fmt.Sprintf("EXP-%03d", num), nil
+}
+
+// generateExpensePaymentCode generates the next expense payment code (ExpPayment-001, ...)
+func generateExpensePaymentCode(runner queryRunner) (string, error) {
+	query := `SELECT payment_code FROM expense_payments
+	          WHERE payment_code LIKE 'ExpPayment-%'
+	          ORDER BY CAST(SUBSTR(payment_code, LENGTH('ExpPayment-') + 1) AS INTEGER) DESC
+	          LIMIT 1`
+
+	var lastCode string
+	err := runner.QueryRow(query).Scan(&lastCode)
+	if err == sql.ErrNoRows {
+		return fmt.Sprintf("%s%03d", expensePaymentCodePrefix, 1), nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to fetch last expense payment code: %v", err)
+	}
+
+	var num int
+	_, err = fmt.Sscanf(lastCode, expensePaymentCodePrefix+"%d", &num)
+	if err != nil {
+		return fmt.Sprintf("%s%03d", expensePaymentCodePrefix, 1), nil
+	}
+
+	num++
+	return fmt.Sprintf("%s%03d", expensePaymentCodePrefix, num), nil
+}
+
+// CreateExpense inserts a new expense
+func (h *ExpenseHandler) CreateExpense(expense models.ExpenseForm, userID int) (int64, error) {
+	expenseDate, err := h.validateExpenseForm(expense)
+	if err != nil {
+		return 0, err
+	}
+
+	paymentMethod := expense.PaymentMethod
+	if paymentMethod == "" {
+		paymentMethod = "cash"
+	}
+	recurringPeriod := ""
+	if expense.IsRecurring {
+		recurringPeriod = expense.RecurringPeriod
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	expenseCode, err := generateExpenseCode(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `INSERT INTO expenses (
+	          expense_code, expense_date, description, amount, category_id, payment_status, payment_method,
+	          vendor_name, vendor_contact, receipt_number, notes, receipt_file_path, reporting_period,
+	          is_recurring, recurring_period, created_by, updated_by
+	          ) VALUES (?, ?, ?, ?, ?, 'unpaid', ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := tx.Exec(query,
+		expenseCode, expenseDate.Format("2006-01-02 15:04:05"), strings.TrimSpace(expense.Description),
+		expense.Amount, expense.CategoryID, paymentMethod,
+		strings.TrimSpace(expense.VendorName), strings.TrimSpace(expense.VendorContact),
+		strings.TrimSpace(expense.ReceiptNumber), strings.TrimSpace(expense.Notes),
+		expense.ReceiptFilePath, expenseDate.Format("2006-01"),
+		expense.IsRecurring, recurringPeriod, userID, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create expense: %v", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get expense ID: %v", err)
+	}
+
+	if err := recordAudit(tx, userID, auditExpense, id, AuditActionCreate, nil); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit expense: %v", err)
+	}
+
+	return id, nil
+}
+
+// GetExpense returns a specific expense by id
+func (h *ExpenseHandler) GetExpense(id int) (*models.Expense, error) {
+	return h.fetchExpense(h.db, id)
+}
+
+func (h *ExpenseHandler) fetchExpense(runner queryRunner, id int) (*models.Expense, error) {
+	query := fmt.Sprintf(`SELECT %s
+	          FROM expenses e
+	          LEFT JOIN expense_categories c ON c.id = e.category_id
+	          WHERE e.id = ?`, expenseSelectColumns)
+
+	expense, err := scanExpense(runner.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("expense not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get expense: %v", err)
+	}
+
+	return &expense, nil
+}
+
+// GetExpensesPaginated returns paginated expenses, newest first
+// If filters is nil, returns all expenses
+func (h *ExpenseHandler) GetExpensesPaginated(page, pageSize int, filters *models.ExpenseFilters) (*models.ExpensesResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	// Build WHERE clause and arguments
+	whereConditions := []string{}
+	args := []interface{}{}
+
+	if filters != nil {
+		if filters.CategoryID != nil {
+			whereConditions = append(whereConditions, "e.category_id = ?")
+			args = append(args, *filters.CategoryID)
+		}
+
+		if filters.DateFrom != nil && *filters.DateFrom != "" {
+			whereConditions = append(whereConditions, "DATE(e.expense_date) >= ?")
+			args = append(args, *filters.DateFrom)
+		}
+
+		if filters.DateTo != nil && *filters.DateTo != "" {
+			whereConditions = append(whereConditions, "DATE(e.expense_date) <= ?")
+			args = append(args, *filters.DateTo)
+		}
+
+		if filters.VendorName != nil && strings.TrimSpace(*filters.VendorName) != "" {
+			whereConditions = append(whereConditions, "e.vendor_name LIKE ?")
+			args = append(args, "%"+strings.TrimSpace(*filters.VendorName)+"%")
+		}
+
+		if filters.PaymentStatus != nil && *filters.PaymentStatus != "" && *filters.PaymentStatus != "all" {
+			if !validExpensePaymentStatuses[*filters.PaymentStatus] {
+				return nil, fmt.Errorf("invalid payment status: %s", *filters.PaymentStatus)
+			}
+			whereConditions = append(whereConditions, "e.payment_status = ?")
+			args = append(args, *filters.PaymentStatus)
+		}
+	}
+
+	whereClause := ""
+	if len(whereConditions) > 0 {
+		whereClause = "WHERE " + strings.Join(whereConditions, " AND ")
+	}
+
+	var totalCount int
+	var totalAmount int
+	countQuery := fmt.Sprintf(`SELECT COUNT(*), COALESCE(SUM(e.amount), 0) FROM expenses e %s`, whereClause)
+	if err := h.db.QueryRow(countQuery, args...).Scan(&totalCount, &totalAmount); err != nil {
+		return nil, fmt.Errorf("failed to count expenses: %v", err)
+	}
+
+	totalPages := int(math.Ceil(float64(totalCount) / float64(pageSize)))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+	if page > totalPages {
+		page = totalPages
+	}
+
+	offset := (page - 1) * pageSize
+
+	query := fmt.Sprintf(`SELECT %s
+	          FROM expenses e
+	          LEFT JOIN expense_categories c ON c.id = e.category_id
+	          %s
+	          ORDER BY datetime(e.expense_date) DESC, e.id DESC
+	          LIMIT ? OFFSET ?`, expenseSelectColumns, whereClause)
+
+	queryArgs := append(args, pageSize, offset)
+
+	rows, err := h.db.Query(query, queryArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load expenses: %v", err)
+	}
+	defer rows.Close()
+
+	expenses := make([]models.Expense, 0)
+	for rows.Next() {
+		expense, err := scanExpense(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan expense: %v", err)
+		}
+		expenses = append(expenses, expense)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("expense rows error: %v", err)
+	}
+
+	return &models.ExpensesResponse{
+		Expenses:    expenses,
+		CurrentPage: page,
+		TotalPages:  totalPages,
+		TotalCount:  totalCount,
+		TotalAmount: totalAmount,
+		PageSize:    pageSize,
+	}, nil
+}
+
+// UpdateExpense updates an expense by id and keeps its payment status in sync with the new amount
+func (h *ExpenseHandler) UpdateExpense(id int, expense models.ExpenseForm, userID int) error {
+	expenseDate, err := h.validateExpenseForm(expense)
+	if err != nil {
+		return err
+	}
+
+	paymentMethod := expense.PaymentMethod
+	if paymentMethod == "" {
+		paymentMethod = "cash"
+	}
+	recurringPeriod := ""
+	if expense.IsRecurring {
+		recurringPeriod = expense.RecurringPeriod
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	before, err := auditSnapshot(tx, auditExpense, int64(id))
+	if err != nil {
+		return err
+	}
+
+	var totalPaid int
+	err = tx.QueryRow(`SELECT COALESCE(SUM(amount), 0) FROM expense_payments WHERE expense_id = ?`, id).Scan(&totalPaid)
+	if err != nil {
+		return fmt.Errorf("failed to calculate previous payments: %v", err)
+	}
+	if expense.Amount < totalPaid {
+		return fmt.Errorf("expense amount cannot be less than the %d already paid", totalPaid)
+	}
+
+	query := `UPDATE expenses
+	          SET expense_date = ?, description = ?, amount = ?, category_id = ?, payment_status = ?, payment_method = ?,
+	              vendor_name = ?, vendor_contact = ?, receipt_number = ?, notes = ?, receipt_file_path = ?,
+	              reporting_period = ?, is_recurring = ?, recurring_period = ?,
+	              updated_by = ?, updated_at = CURRENT_TIMESTAMP
+	          WHERE id = ?`
+	result, err := tx.Exec(query,
+		expenseDate.Format("2006-01-02 15:04:05"), strings.TrimSpace(expense.Description), expense.Amount,
+		expense.CategoryID, expensePaymentStatus(expense.Amount, totalPaid), paymentMethod,
+		strings.TrimSpace(expense.VendorName), strings.TrimSpace(expense.VendorContact),
+		strings.TrimSpace(expense.ReceiptNumber), strings.TrimSpace(expense.Notes), expense.ReceiptFilePath,
+		expenseDate.Format("2006-01"), expense.IsRecurring, recurringPeriod,
+		userID, id)
+	if err != nil {
+		return fmt.Errorf("failed to update expense: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("expense not found")
+	}
+
+	if err := recordAudit(tx, userID, auditExpense, int64(id), AuditActionUpdate, before); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit expense: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteExpense deletes an expense and its payments (cascade)
+func (h *ExpenseHandler) DeleteExpense(id int, userID int) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	before, err := auditSnapshot(tx, auditExpenseWithPayments, int64(id))
+	if err != nil {
+		return err
+	}
+
+	// Delete payments explicitly in case foreign keys are not enforced on this connection
+	_, err = tx.Exec("DELETE FROM expense_payments WHERE expense_id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete expense payments: %v", err)
+	}
+
+	result, err := tx.Exec("DELETE FROM expenses WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete expense: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("expense not found")
+	}
+
+	if err := recordAudit(tx, userID, auditExpenseWithPayments, int64(id), AuditActionDelete, before); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetExpensePaymentDetails returns expense info along with payment history and totals
+func (h *ExpenseHandler) GetExpensePaymentDetails(expenseID int) (*models.ExpensePaymentDetails, error) {
+	return h.fetchExpensePaymentDetails(h.db, expenseID)
+}
+
+// CreateExpensePayment records a (partial) payment for an expense and updates its payment status
+func (h *ExpenseHandler) CreateExpensePayment(expenseID int, amount int, paymentDateStr string, paymentMethod string, note string, userID int) (*models.ExpensePaymentDetails, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("payment amount must be greater than zero")
+	}
+
+	if paymentMethod == "" {
+		paymentMethod = "cash"
+	}
+	if !validExpensePaymentMethods[paymentMethod] {
+		return nil, fmt.Errorf("invalid payment method: %s", paymentMethod)
+	}
+
+	paymentDate, err := parsePaymentDate(paymentDateStr)
+	if err != nil {
+		return nil, err
+	}
+	if paymentDate.After(time.Now()) {
+		return nil, fmt.Errorf("payment date cannot be in the future")
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var expenseAmount int
+	var status string
+	err = tx.QueryRow(`SELECT amount, COALESCE(payment_status, 'unpaid') FROM expenses WHERE id = ?`, expenseID).Scan(&expenseAmount, &status)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("expense not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load expense: %v", err)
+	}
+
+	var totalPaid int
+	err = tx.QueryRow(`SELECT COALESCE(SUM(amount), 0) FROM expense_payments WHERE expense_id = ?`, expenseID).Scan(&totalPaid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate previous payments: %v", err)
+	}
+
+	remaining := expenseAmount - totalPaid
+	if remaining <= 0 {
+		return nil, fmt.Errorf("expense is already fully paid")
+	}
+	if amount > remaining {
+		return nil, fmt.Errorf("payment exceeds remaining balance")
+	}
+
+	paymentCode, err := generateExpensePaymentCode(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	noteValue := sql.NullString{String: note, Valid: note != ""}
+
+	insertQuery := `INSERT INTO expense_payments (expense_id, payment_code, amount, payment_date, payment_method, note, created_by, created_at, updated_at)
+	                VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+	result, err := tx.Exec(insertQuery, expenseID, paymentCode, amount, paymentDate.Format("2006-01-02 15:04:05"), paymentMethod, noteValue, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save expense payment: %v", err)
+	}
+	paymentID, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expense payment ID: %v", err)
+	}
+	if err := recordAudit(tx, userID, auditExpensePayment, paymentID, AuditActionCreate, nil); err != nil {
+		return nil, err
+	}
+
+	newStatus := expensePaymentStatus(expenseAmount, totalPaid+amount)
+	if newStatus != status {
+		before, err := auditSnapshot(tx, auditExpense, int64(expenseID))
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(`UPDATE expenses SET payment_status = ?, updated_by = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, newStatus, userID, expenseID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update expense status: %v", err)
+		}
+		if err := recordAudit(tx, userID, auditExpense, int64(expenseID), AuditActionUpdate, before); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit expense payment: %v", err)
+	}
+
+	return h.GetExpensePaymentDetails(expenseID)
+}
+
+// DeleteExpensePayment deletes an expense payment and recalculates the expense payment status
+func (h *ExpenseHandler) DeleteExpensePayment(paymentID int, userID int) (*models.ExpensePaymentDetails, error) {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var expenseID int
+	err = tx.QueryRow(`SELECT expense_id FROM expense_payments WHERE id = ?`, paymentID).Scan(&expenseID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("expense payment not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load expense payment: %v", err)
+	}
+
+	paymentBefore, err := auditSnapshot(tx, auditExpensePayment, int64(paymentID))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM expense_payments WHERE id = ?`, paymentID); err != nil {
+		return nil, fmt.Errorf("failed to delete expense payment: %v", err)
+	}
+	if err := recordAudit(tx, userID, auditExpensePayment, int64(paymentID), AuditActionDelete, paymentBefore); err != nil {
+		return nil, err
+	}
+
+	var expenseAmount, totalPaid int
+	err = tx.QueryRow(`SELECT e.amount, (SELECT COALESCE(SUM(amount), 0) FROM expense_payments WHERE expense_id = e.id)
+	                   FROM expenses e WHERE e.id = ?`, expenseID).Scan(&expenseAmount, &totalPaid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recalculate expense payments: %v", err)
+	}
+
+	expenseBefore, err := auditSnapshot(tx, auditExpense, int64(expenseID))
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(`UPDATE expenses SET payment_status = ?, updated_by = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		expensePaymentStatus(expenseAmount, totalPaid), userID, expenseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update expense status: %v", err)
+	}
+	if err := recordAudit(tx, userID, auditExpense, int64(expenseID), AuditActionUpdate, expenseBefore); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit expense payment deletion: %v", err)
+	}
+
+	return h.GetExpensePaymentDetails(expenseID)
+}
+
+func (h *ExpenseHandler) fetchExpensePaymentDetails(runner queryRunner, expenseID int) (*models.ExpensePaymentDetails, error) {
+	expense, err := h.fetchExpense(runner, expenseID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := runner.Query(`SELECT id, expense_id, COALESCE(payment_code, ''), amount, COALESCE(payment_date, ''),
+	                                  COALESCE(payment_method, 'cash'), COALESCE(note, ''), created_by,
+	                                  COALESCE(created_at, ''), COALESCE(updated_at, '')
+	                           FROM expense_payments
+	                           WHERE expense_id = ?
+	                           ORDER BY datetime(payment_date) DESC, id DESC`, expenseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load expense payments: %v", err)
+	}
+	defer rows.Close()
+
+	payments := make([]models.ExpensePayment, 0)
+	totalPaid := 0
+	for rows.Next() {
+		var payment models.ExpensePayment
+		var createdBy sql.NullInt64
+		if err := rows.Scan(
+			&payment.ID,
+			&payment.ExpenseID,
+			&payment.PaymentCode,
+			&payment.Amount,
+			&payment.PaymentDate,
+			&payment.PaymentMethod,
+			&payment.Note,
+			&createdBy,
+			&payment.CreatedAt,
+			&payment.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan expense payment: %v", err)
+		}
+		if createdBy.Valid {
+			val := int(createdBy.Int64)
+			payment.CreatedBy = &val
+		}
+		totalPaid += payment.Amount
+		payments = append(payments, payment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("expense payment rows error: %v", err)
+	}
+
+	remaining := expense.Amount - totalPaid
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return &models.ExpensePaymentDetails{
+		Expense:       *expense,
+		Payments:      payments,
+		TotalPaid:     totalPaid,
+		Remaining:     remaining,
+		AllowPayments: remaining > 0,
+	}, nil
+}
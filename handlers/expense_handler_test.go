@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"DentistApp/models"
+)
+
+// newTestExpenseCategory creates an active expense category and returns its ID
+func newTestExpenseCategory(t *testing.T, categories *ExpenseCategoryHandler, name string, userID int) int {
+	t.Helper()
+	id, err := categories.CreateExpenseCategory(models.ExpenseCategoryForm{Name: name, ExpenseType: "operational", IsActive: true}, userID)
+	if err != nil {
+		t.Fatalf("CreateExpenseCategory failed: %v", err)
+	}
+	return int(id)
+}
+
+func TestExpensePaymentStatusSync(t *testing.T) {
+	db, admin := newTestAdmin(t)
+	expenses := NewExpenseHandler(db)
+	categoryID := newTestExpenseCategory(t, NewExpenseCategoryHandler(db), "Supplies", admin.ID)
+
+	expenseID, err := expenses.CreateExpense(models.ExpenseForm{ExpenseDate: "2025-03-01", Description: "Gloves", Amount: 1000, CategoryID: categoryID}, admin.ID)
+	if err != nil {
+		t.Fatalf("CreateExpense failed: %v", err)
+	}
+	expense, err := expenses.GetExpense(int(expenseID))
+	if err != nil {
+		t.Fatalf("GetExpense failed: %v", err)
+	}
+	if expense.PaymentStatus != "unpaid" {
+		t.Errorf("new expense is %q; expected unpaid", expense.PaymentStatus)
+	}
+
+	tests := []struct {
+		name      string
+		amount    int
+		wantErr   string
+		status    string
+		remaining int
+	}{
+		{"partial payment", 400, "", "partially_paid", 600},
+		{"overpayment", 700, "exceeds remaining balance", "partially_paid", 600},
+		{"zero payment", 0, "greater than zero", "partially_paid", 600},
+		{"settles the balance", 600, "", "paid", 0},
+		{"payment on a paid expense", 1, "already fully paid", "paid", 0},
+	}
+	var paymentIDs []int
+	for _, tt := range tests {
+		created, err := expenses.CreateExpensePayment(int(expenseID), tt.amount, "2025-03-02", "cash", "", admin.ID)
+		if tt.wantErr == "" && err != nil {
+			t.Fatalf("%s: CreateExpensePayment failed: %v", tt.name, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: error = %v; expected %q", tt.name, err, tt.wantErr)
+		}
+		if created != nil {
+			// Payments on the same day are listed newest first
+			paymentIDs = append(paymentIDs, created.Payments[0].ID)
+		}
+		details, err := expenses.GetExpensePaymentDetails(int(expenseID))
+		if err != nil {
+			t.Fatalf("%s: GetExpensePaymentDetails failed: %v", tt.name, err)
+		}
+		if details.Expense.PaymentStatus != tt.status || details.Remaining != tt.remaining {
+			t.Errorf("%s: status %q, remaining %d; expected %q, %d", tt.name, details.Expense.PaymentStatus, details.Remaining, tt.status, tt.remaining)
+		}
+	}
+	if len(paymentIDs) != 2 {
+		t.Fatalf("recorded payments %v; expected 2", paymentIDs)
+	}
+
+	// The amount cannot drop below what was paid, and raising it reopens the balance
+	form := models.ExpenseForm{ExpenseDate: "2025-03-01", Description: "Gloves", Amount: 900, CategoryID: categoryID}
+	if err := expenses.UpdateExpense(int(expenseID), form, admin.ID); err == nil {
+		t.Errorf("UpdateExpense accepted an amount below the total paid")
+	}
+	form.Amount = 1500
+	if err := expenses.UpdateExpense(int(expenseID), form, admin.ID); err != nil {
+		t.Fatalf("UpdateExpense failed: %v", err)
+	}
+	if expense, _ := expenses.GetExpense(int(expenseID)); expense.PaymentStatus != "partially_paid" {
+		t.Errorf("raised expense is %q; expected partially_paid", expense.PaymentStatus)
+	}
+
+	// Deleting payments recalculates the status and records who changed the expense
+	if _, err := db.Exec(`UPDATE expenses SET updated_by = NULL, updated_at = NULL WHERE id = ?`, expenseID); err != nil {
+		t.Fatal(err)
+	}
+	deletions := []struct {
+		paymentID int
+		status    string
+		totalPaid int
+	}{
+		{paymentIDs[1], "partially_paid", 400},
+		{paymentIDs[0], "unpaid", 0},
+	}
+	for _, d := range deletions {
+		details, err := expenses.DeleteExpensePayment(d.paymentID, admin.ID)
+		if err != nil {
+			t.Fatalf("DeleteExpensePayment failed: %v", err)
+		}
+		if details.Expense.PaymentStatus != d.status || details.TotalPaid != d.totalPaid {
+			t.Errorf("after deleting payment %d: status %q, paid %d; expected %q, %d",
+				d.paymentID, details.Expense.PaymentStatus, details.TotalPaid, d.status, d.totalPaid)
+		}
+		if details.Expense.UpdatedBy == nil || *details.Expense.UpdatedBy != admin.ID || details.Expense.UpdatedAt == "" {
+			t.Errorf("after deleting payment %d: updated by %v at %q; expected the admin and a time",
+				d.paymentID, details.Expense.UpdatedBy, details.Expense.UpdatedAt)
+		}
+	}
+	if _, err := expenses.DeleteExpensePayment(paymentIDs[0], admin.ID); err == nil {
+		t.Errorf("DeleteExpensePayment accepted a deleted payment")
+	}
+}
+
+func TestExpenseFilters(t *testing.T) {
+	db, admin := newTestAdmin(t)
+	expenses := NewExpenseHandler(db)
+	categories := NewExpenseCategoryHandler(db)
+	supplies := newTestExpenseCategory(t, categories, "Supplies", admin.ID)
+	rent := newTestExpenseCategory(t, categories, "Rent", admin.ID)
+
+	seed := []models.ExpenseForm{
+		{ExpenseDate: "2025-01-15", Description: "Gloves", Amount: 100, CategoryID: supplies, VendorName: "MedSupply"},
+		{ExpenseDate: "2025-02-01", Description: "February rent", Amount: 5000, CategoryID: rent},
+		{ExpenseDate: "2025-02-20T14:30", Description: "Composite", Amount: 300, CategoryID: supplies, VendorName: "MedSupply"},
+		{ExpenseDate: "2025-03-01", Description: "March rent", Amount: 5000, CategoryID: rent},
+	}
+	for _, form := range seed {
+		if _, err := expenses.CreateExpense(form, admin.ID); err != nil {
+			t.Fatalf("CreateExpense %s failed: %v", form.Description, err)
+		}
+	}
+	// Settle the January gloves so the status filter has something to match
+	if _, err := expenses.CreateExpensePayment(1, 100, "2025-01-15", "cash", "", admin.ID); err != nil {
+		t.Fatalf("CreateExpensePayment failed: %v", err)
+	}
+
+	text := func(s string) *string { return &s }
+	tests := []struct {
+		name    string
+		filters *models.ExpenseFilters
+		want    []string
+		total   int
+	}{
+		{"no filters", nil, []string{"March rent", "Composite", "February rent", "Gloves"}, 10400},
+		{"category", &models.ExpenseFilters{CategoryID: &supplies}, []string{"Composite", "Gloves"}, 400},
+		{"date range is inclusive", &models.ExpenseFilters{DateFrom: text("2025-02-01"), DateTo: text("2025-02-20")}, []string{"Composite", "February rent"}, 5300},
+		{"open-ended start", &models.ExpenseFilters{DateFrom: text("2025-02-21")}, []string{"March rent"}, 5000},
+		{"category and dates", &models.ExpenseFilters{CategoryID: &rent, DateTo: text("2025-02-28")}, []string{"February rent"}, 5000},
+		{"vendor", &models.ExpenseFilters{VendorName: text("medsup")}, []string{"Composite", "Gloves"}, 400},
+		{"paid", &models.ExpenseFilters{PaymentStatus: text("paid")}, []string{"Gloves"}, 100},
+		{"all statuses", &models.ExpenseFilters{PaymentStatus: text("all")}, []string{"March rent", "Composite", "February rent", "Gloves"}, 10400},
+	}
+	for _, tt := range tests {
+		response, err := expenses.GetExpensesPaginated(1, 10, tt.filters)
+		if err != nil {
+			t.Fatalf("%s: GetExpensesPaginated failed: %v", tt.name, err)
+		}
+		var got []string
+		for _, expense := range response.Expenses {
+			got = append(got, expense.Description)
+		}
+		if strings.Join(got, ", ") != strings.Join(tt.want, ", ") || response.TotalCount != len(tt.want) || response.TotalAmount != tt.total {
+			t.Errorf("%s: got %v (count %d, total %d); expected %v (total %d)", tt.name, got, response.TotalCount, response.TotalAmount, tt.want, tt.total)
+		}
+	}
+
+	if _, err := expenses.GetExpensesPaginated(1, 10, &models.ExpenseFilters{PaymentStatus: text("overdue")}); err == nil {
+		t.Errorf("GetExpensesPaginated accepted an unknown payment status")
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"DentistApp/models"
+)
+
+// newTestAdmin returns a migrated test database and its default admin account
+func newTestAdmin(t *testing.T) (*sql.DB, *models.User) {
+	t.Helper()
+	db := newTestDB(t)
+	auth := NewAuthHandler(db)
+	if err := auth.InitializeAdmin(); err != nil {
+		t.Fatalf("InitializeAdmin failed: %v", err)
+	}
+	admin, err := auth.GetUserByUsername("admin")
+	if err != nil {
+		t.Fatalf("GetUserByUsername failed: %v", err)
+	}
+	return db, admin
+}
+
+// newTestPatient inserts the patient directly, without AddPatient's validation and folders, and
+// returns their ID. Gender defaults to female and, when neither is given, the date of birth to 30
+// years ago.
+func newTestPatient(t *testing.T, db *sql.DB, patient models.PatientForm) int {
+	t.Helper()
+	if patient.Gender == "" {
+		patient.Gender = "female"
+	}
+	dateOfBirth, estimated, err := resolveDateOfBirth(patient.DateOfBirth, patient.Age)
+	if err != nil {
+		dateOfBirth, estimated = time.Now().AddDate(-30, 0, 0).Format(dateOfBirthLayout), false
+	}
+	result, err := db.Exec(`INSERT INTO patients (name, phone, email, age, date_of_birth, dob_estimated, gender, allergies,
+	                                              current_medications, medical_conditions, smoking_status, pregnancy_status,
+	                                              dental_history, special_notes)
+	                        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		patient.Name, patient.Phone, patient.Email, patientAge(dateOfBirth, 0), dateOfBirth, estimated, patient.Gender,
+		patient.Allergies, patient.CurrentMedications, patient.MedicalConditions, patient.SmokingStatus, patient.PregnancyStatus,
+		patient.DentalHistory, patient.SpecialNotes)
+	if err != nil {
+		t.Fatalf("failed to insert patient: %v", err)
+	}
+	id, _ := result.LastInsertId()
+	return int(id)
+}
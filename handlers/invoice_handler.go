@@ -2,9 +2,12 @@ package handlers
 
 import (
 	"DentistApp/models"
+	"DentistApp/pdfdoc"
 	"database/sql"
 	"fmt"
 	"math"
+	"os"
+	"path/filepath"
 	"time"
 )
 
@@ -18,35 +21,13 @@ func NewInvoiceHandler(db *sql.DB) *InvoiceHandler {
 	return &InvoiceHandler{db: db}
 }
 
-// GenerateInvoiceNumber generates the next sequential invoice number (INV-001, INV-002, etc.)
+// GenerateInvoiceNumber generates the next sequential invoice number using the clinic's prefix (INV-001, INV-002, etc.)
 func (h *InvoiceHandler) GenerateInvoiceNumber() (string, error) {
-	// Get the highest invoice number
-	query := `SELECT invoice_number FROM invoices 
-	          WHERE invoice_number LIKE 'INV-%' 
-	          ORDER BY CAST(SUBSTR(invoice_number, 5) AS INTEGER) DESC 
-	          LIMIT 1`
-
-	var lastNumber string
-	err := h.db.QueryRow(query).Scan(&lastNumber)
-
-	if err == sql.ErrNoRows {
-		// No invoices exist yet, start with INV-001
-		return "INV-001", nil
-	} else if err != nil {
-		return "", fmt.Errorf("failed to get last invoice number: %v", err)
-	}
-
-	// Extract the number part and increment
-	var num int
-	_, err = fmt.Sscanf(lastNumber, "INV-%d", &num)
+	settings, err := loadClinicSettings(h.db)
 	if err != nil {
-		// If parsing fails, start fresh
-		return "INV-001", nil
+		return "", err
 	}
-
-	// Increment and format
-	num++
-	return fmt.Sprintf("INV-%03d", num), nil
+	return nextSequenceNumber(h.db, "invoices", "invoice_number", settings.InvoicePrefix)
 }
 
 // GetInvoiceBySession checks if an invoice exists for a session
@@ -72,7 +53,7 @@ func (h *InvoiceHandler) GetInvoiceBySession(sessionID int) (*models.Invoice, er
 }
 
 // CreateInvoice creates an invoice from a session
-func (h *InvoiceHandler) CreateInvoice(sessionID int) (*models.Invoice, error) {
+func (h *InvoiceHandler) CreateInvoice(sessionID int, actorID int) (*models.Invoice, error) {
 	// First, check if invoice already exists
 	existing, err := h.GetInvoiceBySession(sessionID)
 	if err != nil {
@@ -109,18 +90,12 @@ func (h *InvoiceHandler) CreateInvoice(sessionID int) (*models.Invoice, error) {
 	          total_amount, status, notes)
 	          VALUES (?, ?, ?, ?, ?, ?, ?)`
 
-	result, err := h.db.Exec(query, sessionID, session.PatientID, invoiceNumber,
+	invoiceID, err := auditedInsert(h.db, actorID, auditInvoice, query, sessionID, session.PatientID, invoiceNumber,
 		invoiceDate, session.TotalAmount, "issued", session.Notes)
-
 	if err != nil {
 		return nil, fmt.Errorf("failed to create invoice: %v", err)
 	}
 
-	invoiceID, err := result.LastInsertId()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get invoice ID: %v", err)
-	}
-
 	// Return the created invoice
 	invoice := &models.Invoice{
 		ID:            int(invoiceID),
@@ -167,27 +142,9 @@ func (h *InvoiceHandler) PreviewInvoice(sessionID int) (*models.InvoicePreview,
 	}
 
 	// Get session items
-	itemsQuery := `SELECT id, session_id, procedure_id, item_name, amount
-	               FROM session_items WHERE session_id = ? ORDER BY id`
-	itemRows, err := h.db.Query(itemsQuery, sessionID)
+	items, err := h.fetchSessionItems(h.db, sessionID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get session items: %v", err)
-	}
-	defer itemRows.Close()
-
-	items := make([]models.SessionItem, 0)
-	for itemRows.Next() {
-		var item models.SessionItem
-		var procedureID sql.NullInt64
-		err := itemRows.Scan(&item.ID, &item.SessionID, &procedureID, &item.ItemName, &item.Amount)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan session item: %v", err)
-		}
-		if procedureID.Valid {
-			procID := int(procedureID.Int64)
-			item.ProcedureID = &procID
-		}
-		items = append(items, item)
+		return nil, err
 	}
 
 	// Generate invoice number (without creating invoice)
@@ -348,7 +305,7 @@ func (h *InvoiceHandler) GetInvoicePaymentDetails(invoiceID int) (*models.Invoic
 }
 
 // CreatePayment records a payment for an invoice and updates invoice status/totals
-func (h *InvoiceHandler) CreatePayment(invoiceID int, amount int, paymentDateStr string, note string) (*models.InvoicePaymentDetails, error) {
+func (h *InvoiceHandler) CreatePayment(invoiceID int, amount int, paymentDateStr string, note string, actorID int) (*models.InvoicePaymentDetails, error) {
 	if amount <= 0 {
 		return nil, fmt.Errorf("payment amount must be greater than zero")
 	}
@@ -422,10 +379,17 @@ func (h *InvoiceHandler) CreatePayment(invoiceID int, amount int, paymentDateStr
 
 	insertQuery := `INSERT INTO payments (invoice_id, patient_id, payment_code, amount, payment_date, note, payment_method, created_at, updated_at)
 					VALUES (?, ?, ?, ?, ?, ?, 'cash', datetime('now'), datetime('now'))`
-	_, err = tx.Exec(insertQuery, invoiceID, invoice.PatientID, paymentCode, amount, paymentDateFormatted, noteValue)
+	result, err := tx.Exec(insertQuery, invoiceID, invoice.PatientID, paymentCode, amount, paymentDateFormatted, noteValue)
 	if err != nil {
 		return nil, fmt.Errorf("failed to save payment: %v", err)
 	}
+	paymentID, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment ID: %v", err)
+	}
+	if err := recordAudit(tx, actorID, auditPayment, paymentID, AuditActionCreate, nil); err != nil {
+		return nil, err
+	}
 
 	newTotalPaid := totalPaid + amount
 	newStatus := "partially_paid"
@@ -434,10 +398,17 @@ func (h *InvoiceHandler) CreatePayment(invoiceID int, amount int, paymentDateStr
 	}
 
 	if newStatus != invoice.Status {
+		before, err := auditSnapshot(tx, auditInvoice, int64(invoiceID))
+		if err != nil {
+			return nil, err
+		}
 		_, err = tx.Exec(`UPDATE invoices SET status = ? WHERE id = ?`, newStatus, invoiceID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to update invoice status: %v", err)
 		}
+		if err := recordAudit(tx, actorID, auditInvoice, int64(invoiceID), AuditActionUpdate, before); err != nil {
+			return nil, err
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -496,6 +467,91 @@ func (h *InvoiceHandler) fetchInvoicePaymentDetails(runner queryRunner, invoiceI
 	}, nil
 }
 
+// ExportInvoicePDF renders an invoice as a PDF in the patient's patient_data/<id> folder and
+// returns the file's path. An existing export of the same invoice is replaced.
+func (h *InvoiceHandler) ExportInvoicePDF(invoiceID int, clinic pdfdoc.Clinic) (string, error) {
+	var doc pdfdoc.Invoice
+	var sessionID int
+	err := h.db.QueryRow(`SELECT i.session_id, i.patient_id, i.invoice_number, COALESCE(i.invoice_date, ''),
+	                             i.total_amount, i.status, COALESCE(p.name, ''), COALESCE(s.session_date, '')
+	                      FROM invoices i
+	                      LEFT JOIN patients p ON i.patient_id = p.id
+	                      LEFT JOIN sessions s ON i.session_id = s.id
+	                      WHERE i.id = ?`, invoiceID).Scan(
+		&sessionID, &doc.PatientID, &doc.Number, &doc.Date,
+		&doc.Total, &doc.Status, &doc.PatientName, &doc.SessionDate)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("invoice not found")
+	} else if err != nil {
+		return "", fmt.Errorf("failed to get invoice: %v", err)
+	}
+
+	doc.Items, err = h.fetchSessionItems(h.db, sessionID)
+	if err != nil {
+		return "", err
+	}
+	doc.Payments, doc.Paid, err = h.fetchPaymentsForInvoice(h.db, invoiceID)
+	if err != nil {
+		return "", err
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current working directory: %v", err)
+	}
+	folderPath := filepath.Join(cwd, "patient_data", fmt.Sprintf("%d", doc.PatientID))
+	if err := os.MkdirAll(folderPath, 0755); err != nil {
+		return "", fmt.Errorf("failed to create patient folder: %v", err)
+	}
+
+	// Render to a temporary file first so a failed export never leaves a half-written PDF
+	pdfPath := filepath.Join(folderPath, fmt.Sprintf("Invoice-%s.pdf", cleanPatientName(doc.Number)))
+	tmp, err := os.CreateTemp(folderPath, ".invoice-*.pdf")
+	if err != nil {
+		return "", fmt.Errorf("failed to create invoice file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := pdfdoc.RenderInvoice(tmp, clinic, doc); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write invoice file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), pdfPath); err != nil {
+		return "", fmt.Errorf("failed to save invoice file: %v", err)
+	}
+	return pdfPath, nil
+}
+
+func (h *InvoiceHandler) fetchSessionItems(runner queryRunner, sessionID int) ([]models.SessionItem, error) {
+	rows, err := runner.Query(`SELECT id, session_id, procedure_id, item_name, amount
+	                           FROM session_items WHERE session_id = ? ORDER BY id`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session items: %v", err)
+	}
+	defer rows.Close()
+
+	items := make([]models.SessionItem, 0)
+	for rows.Next() {
+		var item models.SessionItem
+		var procedureID sql.NullInt64
+		if err := rows.Scan(&item.ID, &item.SessionID, &procedureID, &item.ItemName, &item.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan session item: %v", err)
+		}
+		if procedureID.Valid {
+			procID := int(procedureID.Int64)
+			item.ProcedureID = &procID
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("session item rows error: %v", err)
+	}
+	return items, nil
+}
+
 func (h *InvoiceHandler) fetchPaymentsForInvoice(runner queryRunner, invoiceID int) ([]models.Payment, int, error) {
 	rows, err := runner.Query(`SELECT id, invoice_id, patient_id, COALESCE(payment_code, ''), amount, 
 	                                  COALESCE(payment_date, ''), COALESCE(note, ''), COALESCE(payment_method, 'cash'),
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"DentistApp/models"
+	"DentistApp/pdfdoc"
+)
+
+func TestExportInvoicePDF(t *testing.T) {
+	db, admin := newTestAdmin(t)
+
+	// The PDF is written under patient_data in the working directory
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(cwd) })
+
+	patientID := newTestPatient(t, db, models.PatientForm{Name: "Jane Doe", Phone: "0100000000"})
+
+	sessionID, err := NewSessionHandler(db).CreateSession(models.SessionForm{
+		PatientID:   patientID,
+		DentistID:   admin.ID,
+		SessionDate: "2025-03-01",
+		Status:      "completed",
+		Items: []models.SessionItemForm{
+			{ItemName: "Cleaning", Amount: 150000},
+			{ItemName: "Filling", Amount: 250000},
+		},
+	}, admin.ID)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	invoices := NewInvoiceHandler(db)
+	invoice, err := invoices.CreateInvoice(int(sessionID), admin.ID)
+	if err != nil {
+		t.Fatalf("CreateInvoice failed: %v", err)
+	}
+	if _, err := invoices.CreatePayment(invoice.ID, 100000, "2025-03-01", "first payment", admin.ID); err != nil {
+		t.Fatalf("CreatePayment failed: %v", err)
+	}
+
+	path, err := invoices.ExportInvoicePDF(invoice.ID, pdfdoc.Clinic{Name: "Smile Clinic", Currency: "SYP", Logo: []byte("not an image"), LogoType: "png"})
+	if err != nil {
+		t.Fatalf("ExportInvoicePDF failed: %v", err)
+	}
+
+	expectedDir, _ := filepath.Abs(filepath.Join("patient_data", strconv.Itoa(patientID)))
+	if filepath.Dir(path) != expectedDir {
+		t.Errorf("PDF saved to %s; expected it in %s", path, expectedDir)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read PDF: %v", err)
+	}
+	if !bytes.HasPrefix(content, []byte("%PDF-")) {
+		t.Errorf("exported file is not a PDF")
+	}
+
+	if _, err := invoices.ExportInvoicePDF(invoice.ID+1, pdfdoc.Clinic{}); err == nil {
+		t.Errorf("expected an error for a missing invoice")
+	}
+}
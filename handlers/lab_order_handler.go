@@ -5,8 +5,6 @@ import (
 	"database/sql"
 	"fmt"
 	"math"
-	"regexp"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -224,40 +222,22 @@ func (h *LabOrderHandler) GetLabOrder(id int) (*models.LabOrderDetail, error) {
 		order.ColorShadeID = &val
 	}
 
+	alerts, err := patientMedicalAlerts(h.db, order.PatientID)
+	if err != nil {
+		return nil, err
+	}
+	order.MedicalAlerts = alerts[order.PatientID]
+
 	return &order, nil
 }
 
-// generateOrderNumber generates the next order number (ORDER-001, ORDER-002, etc.)
+// generateOrderNumber generates the next order number using the clinic's prefix (ORDER-001, ORDER-002, etc.)
 func (h *LabOrderHandler) generateOrderNumber() (string, error) {
-	// Get the maximum order number from database
-	var maxOrderNumber sql.NullString
-	err := h.db.QueryRow(`SELECT MAX(order_number) FROM lab_orders WHERE order_number LIKE 'ORDER-%'`).Scan(&maxOrderNumber)
-	if err != nil && err != sql.ErrNoRows {
-		return "", fmt.Errorf("failed to get max order number: %v", err)
-	}
-
-	// If no existing order numbers, start with ORDER-001
-	if !maxOrderNumber.Valid || maxOrderNumber.String == "" {
-		return "ORDER-001", nil
-	}
-
-	// Extract number from order number (e.g., "ORDER-001" -> 1)
-	re := regexp.MustCompile(`ORDER-(\d+)`)
-	matches := re.FindStringSubmatch(maxOrderNumber.String)
-	if len(matches) < 2 {
-		// If format doesn't match, start fresh
-		return "ORDER-001", nil
-	}
-
-	// Parse and increment
-	num, err := strconv.Atoi(matches[1])
+	settings, err := loadClinicSettings(h.db)
 	if err != nil {
-		return "ORDER-001", nil
+		return "", err
 	}
-
-	// Increment and format
-	nextNum := num + 1
-	return fmt.Sprintf("ORDER-%03d", nextNum), nil
+	return nextSequenceNumber(h.db, "lab_orders", "order_number", settings.LabOrderPrefix)
 }
 
 // CreateLabOrder creates a new lab order
@@ -353,7 +333,7 @@ func (h *LabOrderHandler) CreateLabOrder(order models.LabOrderForm, createdBy in
 		colorShadeID = nil
 	}
 
-	result, err := h.db.Exec(query,
+	id, err := auditedInsert(h.db, createdBy, auditLabOrder, query,
 		orderNumber,
 		order.PatientID,
 		order.LabID,
@@ -375,11 +355,6 @@ func (h *LabOrderHandler) CreateLabOrder(order models.LabOrderForm, createdBy in
 		return nil, fmt.Errorf("failed to create lab order: %v", err)
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get order ID: %v", err)
-	}
-
 	return &models.CreateLabOrderResponse{
 		ID:          id,
 		OrderNumber: orderNumber,
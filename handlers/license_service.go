@@ -1,45 +1,54 @@
 package handlers
 
 import (
-	"encoding/base64"
+	"crypto/ed25519"
 	"fmt"
-	"strconv"
 	"time"
-	"unicode/utf8"
+
+	"DentistApp/license"
 )
 
+// licensePublicKey verifies signed license keys. Keys are issued with cmd/licgen using the
+// matching private key, which is never part of the app. It can be replaced at build time with
+// -ldflags "-X DentistApp/handlers.licensePublicKey=<base64 key>".
+var licensePublicKey = "dT+Nr0dUTv4TmruDPyNhVF3J5pVlNo8nzvIWQe1M3Zk="
+
+// legacyKeysAcceptedUntil is the last day old-format (unsigned) license keys are accepted
+const legacyKeysAcceptedUntil = "2027-06-30"
+
 // LicenseService handles license validation
-type LicenseService struct{}
+type LicenseService struct {
+	publicKey   ed25519.PublicKey
+	legacyUntil time.Time
+	now         func() time.Time
+}
 
 // NewLicenseService creates a new license service
 func NewLicenseService() *LicenseService {
-	return &LicenseService{}
+	publicKey, err := license.DecodePublicKey(licensePublicKey)
+	if err != nil {
+		// Every signed key is then rejected, which is the safe failure
+		publicKey = nil
+	}
+	legacyUntil, _ := time.Parse(license.DateLayout, legacyKeysAcceptedUntil)
+
+	return &LicenseService{
+		publicKey:   publicKey,
+		legacyUntil: legacyUntil.Add(24*time.Hour - time.Nanosecond),
+		now:         time.Now,
+	}
 }
 
 // LicenseInfo represents license validation result
 type LicenseInfo struct {
-	IsValid    bool   `json:"is_valid"`
-	ExpiryDate string `json:"expiry_date"`
-	Message    string `json:"message"`
-}
-
-const layout = "20060102"
-
-// arrayInit returns the fibonacci sequence used in key generation
-func arrayInit() []int {
-	return []int{3, 5, 8, 13, 21, 34, 55, 89, 144, 233, 377, 610, 987}
-}
-
-// reverse reverses a string (from gen_key package)
-func reverse(s string) string {
-	size := len(s)
-	buf := make([]byte, size)
-	for start := 0; start < size; {
-		r, n := utf8.DecodeRuneInString(s[start:])
-		start += n
-		utf8.EncodeRune(buf[size-start:], r)
-	}
-	return string(buf)
+	IsValid    bool     `json:"is_valid"`
+	ExpiryDate string   `json:"expiry_date"`
+	Message    string   `json:"message"`
+	Clinic     string   `json:"clinic,omitempty"`
+	Seats      int      `json:"seats"`
+	Features   []string `json:"features"`
+	// Legacy is true for old-format keys, which are accepted only until legacyKeysAcceptedUntil
+	Legacy bool `json:"legacy"`
 }
 
 // ValidateLicense validates a license key and returns license information
@@ -51,108 +60,73 @@ func (ls *LicenseService) ValidateLicense(licenseKey string) (*LicenseInfo, erro
 		}, nil
 	}
 
-	// Extract and validate the license key using the same algorithm from gen_key
-	array := arrayInit()
-
-	// Get offset from key
-	var offset int64
-	var str string
-	var err error
-
-	// Try to parse offset (numbers from 10 to 99)
-	if len(licenseKey) >= 3 {
-		offset, err = strconv.ParseInt(licenseKey[1:3], 10, 64)
-		if err != nil {
-			// Try single digit (1 to 9)
-			if len(licenseKey) >= 2 {
-				offset, err = strconv.ParseInt(licenseKey[1:2], 10, 64)
-				if err != nil {
-					return &LicenseInfo{
-						IsValid: false,
-						Message: "Invalid license key format",
-					}, nil
-				}
-			} else {
-				return &LicenseInfo{
-					IsValid: false,
-					Message: "Invalid license key format",
-				}, nil
-			}
-		}
-	} else {
-		return &LicenseInfo{
-			IsValid: false,
-			Message: "License key too short",
-		}, nil
-	}
-
-	// Extract date from key using fibonacci sequence
-	for _, v := range array {
-		if len(licenseKey) >= int(offset)+v+1 {
-			str += string(licenseKey[int(offset)+v+1])
-		}
+	if license.IsSigned(licenseKey) {
+		return ls.validateSigned(licenseKey), nil
 	}
+	return ls.validateLegacy(licenseKey), nil
+}
 
-	if len(str) == 0 {
+// validateSigned checks the signature and expiry of a signed key
+func (ls *LicenseService) validateSigned(licenseKey string) *LicenseInfo {
+	claims, err := license.Verify(licenseKey, ls.publicKey)
+	if err != nil {
 		return &LicenseInfo{
 			IsValid: false,
-			Message: "Could not extract date from license key",
-		}, nil
-	}
-
-	// Handle base64 padding - trim to exactly 11 characters if longer
-	if len(str) > 11 {
-		for len(str) > 11 {
-			str = str[:len(str)-1]
+			Message: "Invalid license key",
 		}
 	}
 
-	// Reverse the string and add padding
-	str = reverse(str)
-	str = str + "="
+	expiryDate, _ := claims.Expiry()
+	info := &LicenseInfo{
+		ExpiryDate: claims.ExpiresAt,
+		Clinic:     claims.Clinic,
+		Seats:      claims.Seats,
+		Features:   claims.Features,
+	}
+	info.IsValid, info.Message = expiryStatus(ls.now(), expiryDate)
+	return info
+}
 
-	// Decode base64
-	b64, err := base64.StdEncoding.DecodeString(str)
+// validateLegacy decodes an old-format key. These are accepted only during the migration period.
+func (ls *LicenseService) validateLegacy(licenseKey string) *LicenseInfo {
+	expiryDate, err := license.ParseLegacy(licenseKey)
 	if err != nil {
 		return &LicenseInfo{
 			IsValid: false,
-			Message: "Invalid license key encoding",
-		}, nil
+			Message: fmt.Sprintf("Invalid license key: %v", err),
+		}
 	}
 
-	dateStr := string(b64)
+	info := &LicenseInfo{
+		ExpiryDate: expiryDate.Format(license.DateLayout),
+		Legacy:     true,
+	}
 
-	// Parse the date
-	expiryDate, err := time.Parse(layout, dateStr)
-	if err != nil {
-		return &LicenseInfo{
-			IsValid: false,
-			Message: "Invalid date in license key",
-		}, nil
+	now := ls.now()
+	if now.After(ls.legacyUntil) {
+		info.Message = "This license key format is no longer supported, please request a new license key"
+		return info
 	}
 
-	// Check if license is expired
-	now := time.Now()
-	isValid := now.Before(expiryDate) || now.Equal(expiryDate)
-
-	var message string
-	if isValid {
-		daysLeft := int(expiryDate.Sub(now).Hours() / 24)
-		if daysLeft <= 7 {
-			message = fmt.Sprintf("License expires in %d days", daysLeft)
-		} else {
-			message = "License is valid"
-		}
-	} else {
+	info.IsValid, info.Message = expiryStatus(now, expiryDate)
+	if info.IsValid {
+		info.Message += fmt.Sprintf(". Old license key format, please request a new key before %s", legacyKeysAcceptedUntil)
+	}
+	return info
+}
+
+// expiryStatus reports whether a license expiring at expiryDate is still valid, with a user-facing message
+func expiryStatus(now, expiryDate time.Time) (bool, string) {
+	if now.After(expiryDate) {
 		daysExpired := int(now.Sub(expiryDate).Hours() / 24)
-		message = fmt.Sprintf("License expired %d days ago", daysExpired)
+		return false, fmt.Sprintf("License expired %d days ago", daysExpired)
 	}
 
-	return &LicenseInfo{
-		IsValid:    isValid,
-		ExpiryDate: expiryDate.Format("2006-01-02"),
-		Message:    message,
-	}, nil
+	daysLeft := int(expiryDate.Sub(now).Hours() / 24)
+	if daysLeft <= 7 {
+		return true, fmt.Sprintf("License expires in %d days", daysLeft)
+	}
+	return true, "License is valid"
 }
 
 // IsLicenseValid is a simplified method that returns just the validation status
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"DentistApp/license"
+)
+
+func newTestLicenseService(t *testing.T, now time.Time) (*LicenseService, func(license.Claims) string) {
+	t.Helper()
+	publicKey, privateKey, err := license.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := NewLicenseService()
+	service.publicKey = publicKey
+	service.now = func() time.Time { return now }
+
+	sign := func(claims license.Claims) string {
+		key, err := license.Sign(claims, privateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+	return service, sign
+}
+
+func TestValidateSignedLicense(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	service, sign := newTestLicenseService(t, now)
+
+	key := sign(license.Claims{Clinic: "Smile Clinic", ExpiresAt: "2026-12-31", Seats: 3, Features: []string{"backup"}})
+	info, err := service.ValidateLicense(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.IsValid || info.Clinic != "Smile Clinic" || info.Seats != 3 || info.Legacy ||
+		len(info.Features) != 1 || info.Features[0] != "backup" {
+		t.Errorf("unexpected license info: %+v", info)
+	}
+
+	// Valid through the whole expiry day
+	service.now = func() time.Time { return time.Date(2026, 12, 31, 23, 0, 0, 0, time.UTC) }
+	if !service.IsLicenseValid(key) {
+		t.Errorf("license should be valid on its expiry day")
+	}
+	service.now = func() time.Time { return time.Date(2027, 1, 1, 1, 0, 0, 0, time.UTC) }
+	if service.IsLicenseValid(key) {
+		t.Errorf("license should be expired after its expiry day")
+	}
+
+	// A key signed with another private key is rejected
+	_, otherSign := newTestLicenseService(t, now)
+	if service.IsLicenseValid(otherSign(license.Claims{Clinic: "Forged", ExpiresAt: "2099-12-31"})) {
+		t.Errorf("license signed with an unknown key was accepted")
+	}
+}
+
+// testLegacyKey was issued by the retired legacy generator and expires on 2028-01-01
+const testLegacyKey = "q543EcBmrWK2e50790PlftFrRPLv1lnOELy01JfYHo9wPWPZWbyY1JVa2gEsDuZMYhfXx9pDyKAmATzi3EtEbNHBKDxXGN7J" +
+	"rsi9ww0qjQm0ekOqb8tL7iQYw08Ibh3YAFd9fACpFjKP8Fggyuhvgu2oyp2wD3rUwHuA7F85OGW9ewcFjD9iXJxNwlDsO1fo" +
+	"8JY206zA1CXxxbx43llI7kYBI0qxT9BapyRrwhO4pNYzofBUQ4POt4WTgFQo0iXxGfEjTnpEeSOQ1nP6i3bhb2WEFCTw9LTF" +
+	"jCkjymucho2aAkue8GdPvI6YBbWHSlbWtRmHGgPd7yQ1fAUJDfkFnHPhdvmyKbhxrMNpBrpxyLMBwWGFBP8BLuB8NIHKHuqX" +
+	"MwU57yqVnQ20QhyFlETVB3LOq82FiLGQax79sEZ1VJTWk3FTMCb7rLjcfaC0dbgu5cAKFzFZaQVqwG0Edhqsh1SOawQSkKBF" +
+	"cRThIFVv96Qv05ur6p8lah6T8M8Y6YXm"
+
+func TestValidateLegacyLicense(t *testing.T) {
+	key := testLegacyKey
+	service, _ := newTestLicenseService(t, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC))
+	info, err := service.ValidateLicense(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.IsValid || !info.Legacy {
+		t.Errorf("legacy key should be accepted during the migration period: %+v", info)
+	}
+
+	service.now = func() time.Time { return time.Date(2027, 7, 1, 0, 0, 0, 0, time.UTC) }
+	if service.IsLicenseValid(key) {
+		t.Errorf("legacy key accepted after %s", legacyKeysAcceptedUntil)
+	}
+}
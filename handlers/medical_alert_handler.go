@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"DentistApp/models"
+)
+
+// MedicalAlertHandler handles patients' structured medical alerts
+type MedicalAlertHandler struct {
+	db *sql.DB
+}
+
+// NewMedicalAlertHandler creates new handler
+func NewMedicalAlertHandler(db *sql.DB) *MedicalAlertHandler {
+	return &MedicalAlertHandler{db: db}
+}
+
+const medicalAlertSelect = `SELECT ma.id, ma.patient_id, ma.alert_type, ma.severity, ma.description, ma.review_date,
+	                               ma.reviewed_at, COALESCE(u.username, ''), ma.created_at, ma.updated_at
+	                        FROM medical_alerts ma
+	                        LEFT JOIN users u ON ma.reviewed_by = u.id`
+
+// medicalAlertOrder puts the most severe alerts first
+const medicalAlertOrder = ` ORDER BY CASE ma.severity WHEN 'critical' THEN 0 WHEN 'warning' THEN 1 ELSE 2 END, ma.id`
+
+// queryMedicalAlerts returns the alerts matching where, flagging the ones whose review date has passed
+func queryMedicalAlerts(q queryRunner, where string, args ...any) ([]models.MedicalAlert, error) {
+	rows, err := q.Query(medicalAlertSelect+` WHERE `+where+medicalAlertOrder, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get medical alerts: %v", err)
+	}
+	defer rows.Close()
+
+	today := time.Now().Format("2006-01-02")
+	alerts := []models.MedicalAlert{}
+	for rows.Next() {
+		var a models.MedicalAlert
+		if err := rows.Scan(&a.ID, &a.PatientID, &a.AlertType, &a.Severity, &a.Description, &a.ReviewDate,
+			&a.ReviewedAt, &a.ReviewedByName, &a.CreatedAt, &a.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan medical alert: %v", err)
+		}
+		a.ReviewOverdue = a.ReviewDate != "" && a.ReviewDate < today
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
+
+// patientMedicalAlerts returns the alerts of each of the given patients, for attaching to sessions,
+// lab orders and appointments
+func patientMedicalAlerts(q queryRunner, patientIDs ...int) (map[int][]models.MedicalAlert, error) {
+	byPatient := map[int][]models.MedicalAlert{}
+	// Query in batches to stay under SQLite's limit on bound parameters
+	for len(patientIDs) > 0 {
+		batch := patientIDs[:min(len(patientIDs), 500)]
+		patientIDs = patientIDs[len(batch):]
+		args := make([]any, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+		alerts, err := queryMedicalAlerts(q, `ma.patient_id IN (?`+strings.Repeat(", ?", len(batch)-1)+`)`, args...)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range alerts {
+			byPatient[a.PatientID] = append(byPatient[a.PatientID], a)
+		}
+	}
+	return byPatient, nil
+}
+
+// attachAppointmentAlerts fills in the medical alerts of each appointment's patient
+func attachAppointmentAlerts(q queryRunner, appointments []models.Appointment) error {
+	var patientIDs []int
+	seen := map[int]bool{}
+	for _, appt := range appointments {
+		if !seen[appt.PatientID] {
+			seen[appt.PatientID] = true
+			patientIDs = append(patientIDs, appt.PatientID)
+		}
+	}
+	alerts, err := patientMedicalAlerts(q, patientIDs...)
+	if err != nil {
+		return err
+	}
+	for i := range appointments {
+		appointments[i].MedicalAlerts = alerts[appointments[i].PatientID]
+	}
+	return nil
+}
+
+// GetPatientMedicalAlerts returns the patient's medical alerts, most severe first
+func (h *MedicalAlertHandler) GetPatientMedicalAlerts(patientID int) ([]models.MedicalAlert, error) {
+	return queryMedicalAlerts(h.db, `ma.patient_id = ?`, patientID)
+}
+
+func validateMedicalAlert(a *models.MedicalAlert) error {
+	a.Description = strings.TrimSpace(a.Description)
+	a.ReviewDate = strings.TrimSpace(a.ReviewDate)
+	known := false
+	for _, t := range models.MedicalAlertTypes {
+		known = known || t == a.AlertType
+	}
+	if !known {
+		return fmt.Errorf("invalid medical alert type %q", a.AlertType)
+	}
+	if a.AlertType == models.AlertOther && a.Description == "" {
+		return fmt.Errorf("describe the alert when its type is other")
+	}
+	switch a.Severity {
+	case models.AlertSeverityInfo, models.AlertSeverityWarning, models.AlertSeverityCritical:
+	default:
+		return fmt.Errorf("invalid medical alert severity %q", a.Severity)
+	}
+	if a.ReviewDate != "" {
+		if _, err := time.Parse("2006-01-02", a.ReviewDate); err != nil {
+			return fmt.Errorf("invalid review date %q", a.ReviewDate)
+		}
+	}
+	return nil
+}
+
+// CreateMedicalAlert adds an alert to a patient
+func (h *MedicalAlertHandler) CreateMedicalAlert(a models.MedicalAlert, actorID int) (int64, error) {
+	if err := validateMedicalAlert(&a); err != nil {
+		return 0, err
+	}
+	var exists bool
+	if err := h.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM patients WHERE id = ?)`, a.PatientID).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("failed to check patient: %v", err)
+	} else if !exists {
+		return 0, fmt.Errorf("patient not found")
+	}
+	id, err := auditedInsert(h.db, actorID, auditMedicalAlert,
+		`INSERT INTO medical_alerts (patient_id, alert_type, severity, description, review_date) VALUES (?, ?, ?, ?, ?)`,
+		a.PatientID, a.AlertType, a.Severity, a.Description, a.ReviewDate)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create medical alert: %v", err)
+	}
+	return id, nil
+}
+
+// UpdateMedicalAlert saves an alert's type, severity, description and review date
+func (h *MedicalAlertHandler) UpdateMedicalAlert(a models.MedicalAlert, actorID int) error {
+	if err := validateMedicalAlert(&a); err != nil {
+		return err
+	}
+	result, err := auditedExec(h.db, actorID, auditMedicalAlert, int64(a.ID), AuditActionUpdate,
+		`UPDATE medical_alerts SET alert_type = ?, severity = ?, description = ?, review_date = ?, updated_at = datetime('now')
+		 WHERE id = ?`,
+		a.AlertType, a.Severity, a.Description, a.ReviewDate, a.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update medical alert: %v", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to get rows affected: %v", err)
+	} else if n == 0 {
+		return fmt.Errorf("medical alert not found")
+	}
+	return nil
+}
+
+// ReviewMedicalAlert records that the alert was checked with the patient today and sets the date of
+// the next review, which may be empty
+func (h *MedicalAlertHandler) ReviewMedicalAlert(id int, nextReviewDate string, actorID int) error {
+	nextReviewDate = strings.TrimSpace(nextReviewDate)
+	if nextReviewDate != "" {
+		next, err := time.Parse("2006-01-02", nextReviewDate)
+		if err != nil {
+			return fmt.Errorf("invalid review date %q", nextReviewDate)
+		}
+		if next.Format("2006-01-02") <= time.Now().Format("2006-01-02") {
+			return fmt.Errorf("the next review must be after today")
+		}
+	}
+	result, err := auditedExec(h.db, actorID, auditMedicalAlert, int64(id), AuditActionUpdate,
+		`UPDATE medical_alerts SET review_date = ?, reviewed_at = datetime('now'), reviewed_by = ?, updated_at = datetime('now')
+		 WHERE id = ?`,
+		nextReviewDate, nullableActor(actorID), id)
+	if err != nil {
+		return fmt.Errorf("failed to review medical alert: %v", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to get rows affected: %v", err)
+	} else if n == 0 {
+		return fmt.Errorf("medical alert not found")
+	}
+	return nil
+}
+
+// DeleteMedicalAlert removes an alert that no longer applies, e.g. after a pregnancy
+func (h *MedicalAlertHandler) DeleteMedicalAlert(id int, actorID int) error {
+	result, err := auditedExec(h.db, actorID, auditMedicalAlert, int64(id), AuditActionDelete,
+		`DELETE FROM medical_alerts WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete medical alert: %v", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to get rows affected: %v", err)
+	} else if n == 0 {
+		return fmt.Errorf("medical alert not found")
+	}
+	return nil
+}
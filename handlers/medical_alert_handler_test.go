@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"DentistApp/models"
+)
+
+func TestMedicalAlertsOnPatientFacingRecords(t *testing.T) {
+	db, admin := newTestAdmin(t)
+	patientID := newTestPatient(t, db, models.PatientForm{Name: "Jane Doe", Phone: "0100000000"})
+
+	alerts := NewMedicalAlertHandler(db)
+	if _, err := alerts.CreateMedicalAlert(models.MedicalAlert{PatientID: patientID, AlertType: "smoker", Severity: models.AlertSeverityInfo}, admin.ID); err == nil {
+		t.Errorf("CreateMedicalAlert accepted an unknown type")
+	}
+	if _, err := alerts.CreateMedicalAlert(models.MedicalAlert{PatientID: patientID, AlertType: models.AlertOther, Severity: models.AlertSeverityInfo}, admin.ID); err == nil {
+		t.Errorf("CreateMedicalAlert accepted an other alert without a description")
+	}
+	if _, err := alerts.CreateMedicalAlert(models.MedicalAlert{PatientID: 999, AlertType: models.AlertLatexAllergy, Severity: models.AlertSeverityWarning}, admin.ID); err == nil {
+		t.Errorf("CreateMedicalAlert accepted an unknown patient")
+	}
+
+	yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	nextYear := time.Now().AddDate(1, 0, 0).Format("2006-01-02")
+	latexID, err := alerts.CreateMedicalAlert(models.MedicalAlert{PatientID: patientID, AlertType: models.AlertLatexAllergy, Severity: models.AlertSeverityWarning}, admin.ID)
+	if err != nil {
+		t.Fatalf("CreateMedicalAlert failed: %v", err)
+	}
+	warfarinID, err := alerts.CreateMedicalAlert(models.MedicalAlert{PatientID: patientID, AlertType: models.AlertAnticoagulant,
+		Severity: models.AlertSeverityCritical, Description: "Warfarin, check INR", ReviewDate: yesterday}, admin.ID)
+	if err != nil {
+		t.Fatalf("CreateMedicalAlert failed: %v", err)
+	}
+
+	// The most severe alert comes first and its passed review date is flagged
+	check := func(name string, got []models.MedicalAlert) {
+		t.Helper()
+		if len(got) != 2 || got[0].ID != int(warfarinID) || !got[0].ReviewOverdue || got[1].ID != int(latexID) || got[1].ReviewOverdue {
+			t.Errorf("%s alerts = %+v", name, got)
+		}
+	}
+	list, err := alerts.GetPatientMedicalAlerts(patientID)
+	if err != nil {
+		t.Fatalf("GetPatientMedicalAlerts failed: %v", err)
+	}
+	check("patient", list)
+
+	sessionID, err := NewSessionHandler(db).CreateSession(models.SessionForm{
+		PatientID: patientID, DentistID: admin.ID, SessionDate: "2025-03-01", Status: "in-progress",
+	}, admin.ID)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	session, err := NewSessionHandler(db).GetSession(int(sessionID))
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	check("session", session.MedicalAlerts)
+
+	result, err := db.Exec(`INSERT INTO lab_orders (order_number, patient_id, lab_id, created_by, work_type_id, lab_cost)
+	                       VALUES ('ORDER-001', ?, 1, ?, 1, 0)`, patientID, admin.ID)
+	if err != nil {
+		t.Fatalf("failed to insert lab order: %v", err)
+	}
+	orderID, _ := result.LastInsertId()
+	order, err := NewLabOrderHandler(db).GetLabOrder(int(orderID))
+	if err != nil {
+		t.Fatalf("GetLabOrder failed: %v", err)
+	}
+	check("lab order", order.MedicalAlerts)
+
+	appointments := NewAppointmentHandler(db)
+	apptID, err := appointments.AddAppointment(models.Appointment{PatientID: patientID, DateTime: "2025-03-03T10:00:00Z", Duration: 30,
+		AllowOutsideHours: true}, admin.ID)
+	if err != nil {
+		t.Fatalf("AddAppointment failed: %v", err)
+	}
+	appt, err := appointments.GetAppointment(int(apptID))
+	if err != nil {
+		t.Fatalf("GetAppointment failed: %v", err)
+	}
+	check("appointment", appt.MedicalAlerts)
+	inRange, err := appointments.GetAppointmentsInRange("2025-03-01", "2025-03-07", 0, 0)
+	if err != nil || len(inRange) != 1 {
+		t.Fatalf("GetAppointmentsInRange = %+v, %v", inRange, err)
+	}
+	check("appointment range", inRange[0].MedicalAlerts)
+
+	// Reviewing moves the review date on and clears the flag
+	if err := alerts.ReviewMedicalAlert(int(warfarinID), yesterday, admin.ID); err == nil {
+		t.Errorf("ReviewMedicalAlert accepted a next review in the past")
+	}
+	if err := alerts.ReviewMedicalAlert(int(warfarinID), nextYear, admin.ID); err != nil {
+		t.Fatalf("ReviewMedicalAlert failed: %v", err)
+	}
+	list, _ = alerts.GetPatientMedicalAlerts(patientID)
+	if list[0].ReviewOverdue || list[0].ReviewDate != nextYear || list[0].ReviewedAt == "" || list[0].ReviewedByName != "admin" {
+		t.Errorf("reviewed alert = %+v", list[0])
+	}
+
+	if err := alerts.DeleteMedicalAlert(int(latexID), admin.ID); err != nil {
+		t.Fatalf("DeleteMedicalAlert failed: %v", err)
+	}
+	if list, _ := alerts.GetPatientMedicalAlerts(patientID); len(list) != 1 {
+		t.Errorf("alerts after delete = %+v", list)
+	}
+}
@@ -3,12 +3,15 @@ package handlers
 import (
 	"database/sql"
 	"fmt"
+	"net/mail"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
 	"strings"
+	"time"
 
 	"DentistApp/models"
 )
@@ -24,7 +27,7 @@ func NewPatientHandler(db *sql.DB) *PatientHandler {
 }
 
 // AddPatient adds a new patient to the database
-func (h *PatientHandler) AddPatient(patient models.PatientForm) (int64, error) {
+func (h *PatientHandler) AddPatient(patient models.PatientForm, actorID int) (int64, error) {
 	// Check for phone number uniqueness
 	var existingCount int
 	err := h.db.QueryRow("SELECT COUNT(*) FROM patients WHERE phone = ?", patient.Phone).Scan(&existingCount)
@@ -35,14 +38,21 @@ func (h *PatientHandler) AddPatient(patient models.PatientForm) (int64, error) {
 		return 0, fmt.Errorf("phone number already exists for another patient")
 	}
 
-	// Validate age range
-	if patient.Age < 6 || patient.Age > 100 {
-		return 0, fmt.Errorf("age must be between 6 and 100 years")
+	dateOfBirth, estimated, err := resolveDateOfBirth(patient.DateOfBirth, patient.Age)
+	if err != nil {
+		return 0, err
+	}
+	age := patientAge(dateOfBirth, 0)
+	if err := validatePatientAge(age); err != nil {
+		return 0, err
+	}
+	if err := validatePatientEmail(patient.Email); err != nil {
+		return 0, err
 	}
 
 	query := `
-	INSERT INTO patients (name, phone, age, gender, allergies, current_medications, medical_conditions, smoking_status, pregnancy_status, dental_history, special_notes)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	INSERT INTO patients (name, phone, email, age, date_of_birth, dob_estimated, gender, allergies, current_medications, medical_conditions, smoking_status, pregnancy_status, dental_history, special_notes)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	// Convert boolean to integer for SQLite (0 = false, 1 = true)
 	smokingStatus := 0
@@ -54,18 +64,13 @@ func (h *PatientHandler) AddPatient(patient models.PatientForm) (int64, error) {
 		pregnancyStatus = 1
 	}
 
-	result, err := h.db.Exec(query, patient.Name, patient.Phone, patient.Age, patient.Gender, 
-		patient.Allergies, patient.CurrentMedications, patient.MedicalConditions, 
+	id, err := auditedInsert(h.db, actorID, auditPatient, query, patient.Name, patient.Phone, strings.TrimSpace(patient.Email), age, dateOfBirth, estimated, patient.Gender,
+		patient.Allergies, patient.CurrentMedications, patient.MedicalConditions,
 		smokingStatus, pregnancyStatus, patient.DentalHistory, patient.SpecialNotes)
 	if err != nil {
 		return 0, err
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
-	}
-
 	// Create patient data directory
 	patientDir := filepath.Join("patient_data", fmt.Sprintf("%d", id))
 	err = os.MkdirAll(patientDir, 0755)
@@ -89,7 +94,7 @@ func (h *PatientHandler) AddPatient(patient models.PatientForm) (int64, error) {
 
 // GetPatients returns all patients from the database
 func (h *PatientHandler) GetPatients() ([]models.Patient, error) {
-	query := `SELECT id, name, phone, age, gender, total_required, allergies, current_medications, medical_conditions, smoking_status, pregnancy_status, dental_history, special_notes FROM patients ORDER BY name`
+	query := `SELECT id, name, phone, email, age, date_of_birth, dob_estimated, gender, total_required, allergies, current_medications, medical_conditions, smoking_status, pregnancy_status, dental_history, special_notes FROM patients ORDER BY name`
 	rows, err := h.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -100,7 +105,7 @@ func (h *PatientHandler) GetPatients() ([]models.Patient, error) {
 	for rows.Next() {
 		var patient models.Patient
 		var smokingStatus, pregnancyStatus int
-		err := rows.Scan(&patient.ID, &patient.Name, &patient.Phone, &patient.Age, &patient.Gender, &patient.TotalRequired,
+		err := rows.Scan(&patient.ID, &patient.Name, &patient.Phone, &patient.Email, &patient.Age, &patient.DateOfBirth, &patient.DOBEstimated, &patient.Gender, &patient.TotalRequired,
 			&patient.Allergies, &patient.CurrentMedications, &patient.MedicalConditions, 
 			&smokingStatus, &pregnancyStatus, &patient.DentalHistory, &patient.SpecialNotes)
 		if err != nil {
@@ -109,6 +114,7 @@ func (h *PatientHandler) GetPatients() ([]models.Patient, error) {
 		// Convert integer to boolean
 		patient.SmokingStatus = smokingStatus == 1
 		patient.PregnancyStatus = pregnancyStatus == 1
+		patient.Age = patientAge(patient.DateOfBirth, patient.Age)
 		patients = append(patients, patient)
 	}
 
@@ -119,21 +125,26 @@ func (h *PatientHandler) GetPatients() ([]models.Patient, error) {
 func (h *PatientHandler) GetPatient(id int) (models.Patient, error) {
 	var patient models.Patient
 	var smokingStatus, pregnancyStatus int
-	query := `SELECT id, name, phone, age, gender, total_required, allergies, current_medications, medical_conditions, smoking_status, pregnancy_status, dental_history, special_notes FROM patients WHERE id = ?`
-	err := h.db.QueryRow(query, id).Scan(&patient.ID, &patient.Name, &patient.Phone, &patient.Age, &patient.Gender, &patient.TotalRequired,
+	query := `SELECT id, name, phone, email, age, date_of_birth, dob_estimated, gender, total_required, allergies, current_medications, medical_conditions, smoking_status, pregnancy_status, dental_history, special_notes,
+	          (SELECT COUNT(*) FROM appointments WHERE patient_id = patients.id AND status = 'no_show'),
+	          (SELECT COUNT(*) FROM appointments WHERE patient_id = patients.id AND status = 'cancelled' AND late_cancellation = 1)
+	          FROM patients WHERE id = ?`
+	err := h.db.QueryRow(query, id).Scan(&patient.ID, &patient.Name, &patient.Phone, &patient.Email, &patient.Age, &patient.DateOfBirth, &patient.DOBEstimated, &patient.Gender, &patient.TotalRequired,
 		&patient.Allergies, &patient.CurrentMedications, &patient.MedicalConditions,
-		&smokingStatus, &pregnancyStatus, &patient.DentalHistory, &patient.SpecialNotes)
+		&smokingStatus, &pregnancyStatus, &patient.DentalHistory, &patient.SpecialNotes,
+		&patient.NoShowCount, &patient.LateCancellationCount)
 	if err != nil {
 		return patient, err
 	}
 	// Convert integer to boolean
 	patient.SmokingStatus = smokingStatus == 1
 	patient.PregnancyStatus = pregnancyStatus == 1
+	patient.Age = patientAge(patient.DateOfBirth, patient.Age)
 	return patient, nil
 }
 
 // UpdatePatient updates an existing patient
-func (h *PatientHandler) UpdatePatient(patient models.Patient) error {
+func (h *PatientHandler) UpdatePatient(patient models.Patient, actorID int) error {
 	// Check for phone number uniqueness (excluding current patient)
 	var existingCount int
 	err := h.db.QueryRow("SELECT COUNT(*) FROM patients WHERE phone = ? AND id != ?", patient.Phone, patient.ID).Scan(&existingCount)
@@ -144,14 +155,31 @@ func (h *PatientHandler) UpdatePatient(patient models.Patient) error {
 		return fmt.Errorf("phone number already exists for another patient")
 	}
 
-	// Validate age range
-	if patient.Age < 6 || patient.Age > 100 {
-		return fmt.Errorf("age must be between 6 and 100 years")
+	// A date of birth that is kept keeps its estimated flag and is not checked against the age
+	// limits again, so patients who grow older than them can still be edited
+	var currentDOB string
+	var estimated bool
+	err = h.db.QueryRow("SELECT date_of_birth, dob_estimated FROM patients WHERE id = ?", patient.ID).Scan(&currentDOB, &estimated)
+	if err != nil {
+		return fmt.Errorf("failed to get patient: %v", err)
+	}
+	dateOfBirth := strings.TrimSpace(patient.DateOfBirth)
+	if dateOfBirth == "" || dateOfBirth != currentDOB {
+		dateOfBirth, estimated, err = resolveDateOfBirth(dateOfBirth, patient.Age)
+		if err != nil {
+			return err
+		}
+		if err := validatePatientAge(patientAge(dateOfBirth, 0)); err != nil {
+			return err
+		}
+	}
+	if err := validatePatientEmail(patient.Email); err != nil {
+		return err
 	}
 
 	query := `
 	UPDATE patients 
-	SET name = ?, phone = ?, age = ?, gender = ?, total_required = ?, allergies = ?, current_medications = ?, medical_conditions = ?, smoking_status = ?, pregnancy_status = ?, dental_history = ?, special_notes = ?
+	SET name = ?, phone = ?, email = ?, date_of_birth = ?, dob_estimated = ?, gender = ?, total_required = ?, allergies = ?, current_medications = ?, medical_conditions = ?, smoking_status = ?, pregnancy_status = ?, dental_history = ?, special_notes = ?
 	WHERE id = ?`
 
 	// Convert boolean to integer for SQLite (0 = false, 1 = true)
@@ -164,14 +192,16 @@ func (h *PatientHandler) UpdatePatient(patient models.Patient) error {
 		pregnancyStatus = 1
 	}
 
-	_, err = h.db.Exec(query, patient.Name, patient.Phone, patient.Age, patient.Gender, patient.TotalRequired,
+	_, err = auditedExec(h.db, actorID, auditPatient, int64(patient.ID), AuditActionUpdate, query,
+		patient.Name, patient.Phone, strings.TrimSpace(patient.Email), dateOfBirth, estimated, patient.Gender, patient.TotalRequired,
 		patient.Allergies, patient.CurrentMedications, patient.MedicalConditions,
 		smokingStatus, pregnancyStatus, patient.DentalHistory, patient.SpecialNotes, patient.ID)
 	return err
 }
 
-// DeletePatient deletes a patient and their data directory
-func (h *PatientHandler) DeletePatient(id int) error {
+// DeletePatient deletes a patient and their data directory. Patients with a signed clinical note are
+// kept, since deleting them would remove the note with its sessions.
+func (h *PatientHandler) DeletePatient(id int, actorID int) error {
 	// Ensure foreign keys are enabled
 	_, err := h.db.Exec("PRAGMA foreign_keys = ON;")
 	if err != nil {
@@ -191,6 +221,21 @@ func (h *PatientHandler) DeletePatient(id int) error {
 		return fmt.Errorf("failed to enable foreign keys in transaction: %v", err)
 	}
 
+	var signed int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM clinical_notes cn JOIN sessions s ON cn.session_id = s.id
+	                   WHERE s.patient_id = ? AND cn.signed_at != ''`, id).Scan(&signed)
+	if err != nil {
+		return fmt.Errorf("failed to check clinical notes: %v", err)
+	}
+	if signed > 0 {
+		return fmt.Errorf("the patient has a signed clinical note and cannot be deleted")
+	}
+
+	before, err := auditSnapshot(tx, auditPatient, int64(id))
+	if err != nil {
+		return err
+	}
+
 	// Delete patient (should cascade to appointments and payments)
 	_, err = tx.Exec("DELETE FROM patients WHERE id = ?", id)
 	if err != nil {
@@ -222,6 +267,12 @@ func (h *PatientHandler) DeletePatient(id int) error {
 		}
 	}
 
+	if before != nil {
+		if err := recordAudit(tx, actorID, auditPatient, int64(id), AuditActionDelete, before); err != nil {
+			return err
+		}
+	}
+
 	// Commit the transaction
 	err = tx.Commit()
 	if err != nil {
@@ -233,16 +284,91 @@ func (h *PatientHandler) DeletePatient(id int) error {
 	return os.RemoveAll(patientDir)
 }
 
-// SearchPatients searches patients by name or phone
-func (h *PatientHandler) SearchPatients(searchTerm string) ([]models.Patient, error) {
+// validatePatientEmail accepts an empty email or a single plain address, as used for reminders
+func validatePatientEmail(email string) error {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return nil
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return fmt.Errorf("invalid email address %q", email)
+	}
+	return nil
+}
+
+// dateOfBirthLayout is the format dates of birth are stored in
+const dateOfBirthLayout = "2006-01-02"
+
+// ageOn returns the age in whole years of someone born on dob at the given date
+func ageOn(dob, at time.Time) int {
+	age := at.Year() - dob.Year()
+	if at.Month() < dob.Month() || (at.Month() == dob.Month() && at.Day() < dob.Day()) {
+		age--
+	}
+	return age
+}
+
+// patientAge returns the patient's current age from the date of birth, falling back to the stored
+// age for records without one
+func patientAge(dateOfBirth string, stored int) int {
+	dob, err := time.Parse(dateOfBirthLayout, dateOfBirth)
+	if err != nil {
+		return stored
+	}
+	return ageOn(dob, time.Now())
+}
+
+// resolveDateOfBirth validates the given date of birth, or estimates one from the age when the
+// date is not known. The estimate puts the birthday half a year ago, the middle of the possible range.
+func resolveDateOfBirth(dateOfBirth string, age int) (string, bool, error) {
+	dateOfBirth = strings.TrimSpace(dateOfBirth)
+	if dateOfBirth == "" {
+		if age <= 0 {
+			return "", false, fmt.Errorf("date of birth or age is required")
+		}
+		return time.Now().AddDate(-age, -6, 0).Format(dateOfBirthLayout), true, nil
+	}
+	dob, err := time.Parse(dateOfBirthLayout, dateOfBirth)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid date of birth %q", dateOfBirth)
+	}
+	if dob.After(time.Now()) {
+		return "", false, fmt.Errorf("date of birth cannot be in the future")
+	}
+	return dateOfBirth, false, nil
+}
+
+// validatePatientAge checks the age range the practice registers patients in
+func validatePatientAge(age int) error {
+	if age < 6 || age > 100 {
+		return fmt.Errorf("age must be between 6 and 100 years")
+	}
+	return nil
+}
+
+// SearchPatients searches patients by name or phone, optionally within an age band. An empty search
+// term matches every patient.
+func (h *PatientHandler) SearchPatients(searchTerm string, filters models.PatientFilters) ([]models.Patient, error) {
 	query := `
-	SELECT id, name, phone, age, gender, total_required, allergies, current_medications, medical_conditions, smoking_status, pregnancy_status, dental_history, special_notes
+	SELECT id, name, phone, email, age, date_of_birth, dob_estimated, gender, total_required, allergies, current_medications, medical_conditions, smoking_status, pregnancy_status, dental_history, special_notes
 	FROM patients 
-	WHERE name LIKE ? OR phone LIKE ?
-	ORDER BY name`
+	WHERE (name LIKE ? OR phone LIKE ?)`
 
 	searchPattern := "%" + searchTerm + "%"
-	rows, err := h.db.Query(query, searchPattern, searchPattern)
+	args := []any{searchPattern, searchPattern}
+	// A patient is at least MinAge when born on or before that many years ago, and at most MaxAge
+	// when born after MaxAge+1 years ago
+	today := time.Now()
+	if filters.MinAge != nil {
+		query += ` AND date_of_birth != '' AND date_of_birth <= ?`
+		args = append(args, today.AddDate(-*filters.MinAge, 0, 0).Format(dateOfBirthLayout))
+	}
+	if filters.MaxAge != nil {
+		query += ` AND date_of_birth > ?`
+		args = append(args, today.AddDate(-*filters.MaxAge-1, 0, 0).Format(dateOfBirthLayout))
+	}
+	rows, err := h.db.Query(query+` ORDER BY name`, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -252,7 +378,7 @@ func (h *PatientHandler) SearchPatients(searchTerm string) ([]models.Patient, er
 	for rows.Next() {
 		var patient models.Patient
 		var smokingStatus, pregnancyStatus int
-		err := rows.Scan(&patient.ID, &patient.Name, &patient.Phone, &patient.Age, &patient.Gender, &patient.TotalRequired,
+		err := rows.Scan(&patient.ID, &patient.Name, &patient.Phone, &patient.Email, &patient.Age, &patient.DateOfBirth, &patient.DOBEstimated, &patient.Gender, &patient.TotalRequired,
 			&patient.Allergies, &patient.CurrentMedications, &patient.MedicalConditions,
 			&smokingStatus, &pregnancyStatus, &patient.DentalHistory, &patient.SpecialNotes)
 		if err != nil {
@@ -261,6 +387,7 @@ func (h *PatientHandler) SearchPatients(searchTerm string) ([]models.Patient, er
 		// Convert integer to boolean
 		patient.SmokingStatus = smokingStatus == 1
 		patient.PregnancyStatus = pregnancyStatus == 1
+		patient.Age = patientAge(patient.DateOfBirth, patient.Age)
 		patients = append(patients, patient)
 	}
 
@@ -380,8 +507,9 @@ func (h *PatientHandler) OpenPatientFolder(id int) error {
 	return nil
 }
 
-// DeleteAllPatients deletes all patients and their data directories
-func (h *PatientHandler) DeleteAllPatients() error {
+// DeleteAllPatients deletes all patients and their data directories. Nothing is deleted while any
+// clinical note is signed.
+func (h *PatientHandler) DeleteAllPatients(actorID int) error {
 	// Ensure foreign keys are enabled
 	_, err := h.db.Exec("PRAGMA foreign_keys = ON;")
 	if err != nil {
@@ -401,6 +529,14 @@ func (h *PatientHandler) DeleteAllPatients() error {
 		return fmt.Errorf("failed to enable foreign keys in transaction: %v", err)
 	}
 
+	var signed int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM clinical_notes WHERE signed_at != ''`).Scan(&signed); err != nil {
+		return fmt.Errorf("failed to check clinical notes: %v", err)
+	}
+	if signed > 0 {
+		return fmt.Errorf("%d signed clinical note(s) would be deleted; patients with signed notes cannot be removed", signed)
+	}
+
 	// Delete all appointments first (defensive)
 	_, err = tx.Exec("DELETE FROM appointments")
 	if err != nil {
@@ -414,11 +550,21 @@ func (h *PatientHandler) DeleteAllPatients() error {
 	}
 
 	// Delete all patients
-	_, err = tx.Exec("DELETE FROM patients")
+	result, err := tx.Exec("DELETE FROM patients")
 	if err != nil {
 		return fmt.Errorf("failed to delete patients: %v", err)
 	}
 
+	// Individual rows are not snapshotted; the entry records how many patients were removed
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	err = writeAuditEntry(tx, actorID, auditPatient.name, 0, AuditActionDeleteAll, map[string]any{"deleted_count": deleted})
+	if err != nil {
+		return err
+	}
+
 	// Commit the transaction
 	err = tx.Commit()
 	if err != nil {
@@ -466,3 +612,73 @@ func cleanPatientName(name string) string {
 
 	return cleanName
 }
+
+// GetUpcomingBirthdays returns the patients whose birthday falls between from and to (inclusive,
+// YYYY-MM-DD, at most a year apart), ordered by birthday. Estimated dates of birth are left out since
+// their day is not known. Patients born on 29 February have their birthday on 28 February in other years.
+func (h *PatientHandler) GetUpcomingBirthdays(from, to string) ([]models.PatientBirthday, error) {
+	start, err := time.Parse(dateOfBirthLayout, from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from date %q", from)
+	}
+	end, err := time.Parse(dateOfBirthLayout, to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to date %q", to)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("the to date must not be before the from date")
+	}
+	if end.Sub(start) > 366*24*time.Hour {
+		return nil, fmt.Errorf("the date range cannot be longer than a year")
+	}
+
+	rows, err := h.db.Query(`SELECT id, name, phone, email, date_of_birth FROM patients
+	                         WHERE date_of_birth != '' AND dob_estimated = 0`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patients: %v", err)
+	}
+	defer rows.Close()
+
+	birthdays := []models.PatientBirthday{}
+	for rows.Next() {
+		var b models.PatientBirthday
+		if err := rows.Scan(&b.PatientID, &b.Name, &b.Phone, &b.Email, &b.DateOfBirth); err != nil {
+			return nil, fmt.Errorf("failed to scan patient: %v", err)
+		}
+		dob, err := time.Parse(dateOfBirthLayout, b.DateOfBirth)
+		if err != nil {
+			continue
+		}
+		// The range spans at most two calendar years, so the birthday in either can fall inside it
+		for year := start.Year(); year <= end.Year(); year++ {
+			birthday := birthdayIn(dob, year)
+			if birthday.Before(start) || birthday.After(end) || !birthday.After(dob) {
+				continue
+			}
+			b.Birthday = birthday.Format(dateOfBirthLayout)
+			b.Age = year - dob.Year()
+			birthdays = append(birthdays, b)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get patients: %v", err)
+	}
+
+	sort.Slice(birthdays, func(i, j int) bool {
+		if birthdays[i].Birthday != birthdays[j].Birthday {
+			return birthdays[i].Birthday < birthdays[j].Birthday
+		}
+		return birthdays[i].Name < birthdays[j].Name
+	})
+	return birthdays, nil
+}
+
+// birthdayIn returns the date of the birthday in the given year, moving 29 February to the 28th
+// outside leap years
+func birthdayIn(dob time.Time, year int) time.Time {
+	day := dob.Day()
+	if dob.Month() == time.February && day == 29 && time.Date(year, time.March, 0, 0, 0, 0, 0, time.UTC).Day() != 29 {
+		day = 28
+	}
+	return time.Date(year, dob.Month(), day, 0, 0, 0, 0, time.UTC)
+}
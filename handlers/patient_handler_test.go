@@ -5,6 +5,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"DentistApp/models"
 )
 
 func TestOpenPatientFolder(t *testing.T) {
@@ -159,3 +162,132 @@ func TestOpenPatientFolderWithExistingDirectory(t *testing.T) {
 	// Clean up test directory
 	os.RemoveAll(filepath.Join(cwd, "patient_data"))
 }
+
+func TestPatientDateOfBirthAndBirthdays(t *testing.T) {
+	db, admin := newTestAdmin(t)
+	patients := NewPatientHandler(db)
+	t.Cleanup(func() { os.RemoveAll("patient_data") })
+
+	future := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	if _, err := patients.AddPatient(models.PatientForm{Name: "Future", Phone: "0100000001", DateOfBirth: future, Gender: "male"}, admin.ID); err == nil {
+		t.Errorf("AddPatient accepted a date of birth in the future")
+	}
+	if _, err := patients.AddPatient(models.PatientForm{Name: "Nobody", Phone: "0100000002", Gender: "male"}, admin.ID); err == nil {
+		t.Errorf("AddPatient accepted a patient without date of birth or age")
+	}
+
+	// Fixtures are relative to today so the ages stay inside the bands tested below. Leap was born on
+	// the last 29 February at least 20 years ago.
+	now := time.Now()
+	isLeap := func(year int) bool { return time.Date(year, time.March, 0, 0, 0, 0, 0, time.UTC).Day() == 29 }
+	leapYear := now.Year() - 20
+	for !isLeap(leapYear) {
+		leapYear--
+	}
+
+	// The age is worked out from the date of birth and is one less on the day before the birthday
+	dob := now.AddDate(-30, 0, 1).Format("2006-01-02")
+	leapID, err := patients.AddPatient(models.PatientForm{Name: "Leap", Phone: "0100000003", DateOfBirth: fmt.Sprintf("%d-02-29", leapYear), Gender: "female"}, admin.ID)
+	if err != nil {
+		t.Fatalf("AddPatient failed: %v", err)
+	}
+	exactID, err := patients.AddPatient(models.PatientForm{Name: "Exact", Phone: "0100000004", DateOfBirth: dob, Gender: "female"}, admin.ID)
+	if err != nil {
+		t.Fatalf("AddPatient failed: %v", err)
+	}
+	exact, err := patients.GetPatient(int(exactID))
+	if err != nil {
+		t.Fatalf("GetPatient failed: %v", err)
+	}
+	if exact.Age != 29 || exact.DateOfBirth != dob || exact.DOBEstimated {
+		t.Errorf("patient with date of birth = %+v", exact)
+	}
+
+	// Without a date of birth one is estimated from the age
+	estimatedID, err := patients.AddPatient(models.PatientForm{Name: "Estimated", Phone: "0100000005", Age: 70, Gender: "male"}, admin.ID)
+	if err != nil {
+		t.Fatalf("AddPatient failed: %v", err)
+	}
+	estimated, _ := patients.GetPatient(int(estimatedID))
+	if estimated.Age != 70 || estimated.DateOfBirth == "" || !estimated.DOBEstimated {
+		t.Errorf("patient with estimated date of birth = %+v", estimated)
+	}
+	// Correcting it clears the estimated flag
+	corrected := time.Date(now.Year()-70, time.June, 15, 0, 0, 0, 0, time.UTC)
+	estimated.DateOfBirth = corrected.Format("2006-01-02")
+	if err := patients.UpdatePatient(estimated, admin.ID); err != nil {
+		t.Fatalf("UpdatePatient failed: %v", err)
+	}
+	estimated, _ = patients.GetPatient(int(estimatedID))
+	if estimated.DOBEstimated || estimated.Age != ageOn(corrected, now) {
+		t.Errorf("patient after correcting the date of birth = %+v", estimated)
+	}
+
+	// Age bands
+	band := func(min, max *int) []int {
+		t.Helper()
+		list, err := patients.SearchPatients("", models.PatientFilters{MinAge: min, MaxAge: max})
+		if err != nil {
+			t.Fatalf("SearchPatients failed: %v", err)
+		}
+		var ids []int
+		for _, p := range list {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+	thirty, sixtyFive := 30, 65
+	if ids := band(nil, nil); len(ids) != 3 {
+		t.Errorf("unfiltered search = %v", ids)
+	}
+	if ids := band(nil, &thirty); len(ids) != 2 || ids[0] != int(exactID) || ids[1] != int(leapID) {
+		t.Errorf("patients up to 30 = %v", ids)
+	}
+	if ids := band(&thirty, &sixtyFive); len(ids) != 0 {
+		t.Errorf("patients 30 to 65 = %v", ids)
+	}
+	if ids := band(&sixtyFive, nil); len(ids) != 1 || ids[0] != int(estimatedID) {
+		t.Errorf("patients over 65 = %v", ids)
+	}
+
+	// Exact's birthday moves with the date the test runs, so it is left out of the checks below
+	birthdaysIn := func(from, to string) []models.PatientBirthday {
+		t.Helper()
+		list, err := patients.GetUpcomingBirthdays(from, to)
+		if err != nil {
+			t.Fatalf("GetUpcomingBirthdays failed: %v", err)
+		}
+		var fixed []models.PatientBirthday
+		for _, b := range list {
+			if b.PatientID != int(exactID) {
+				fixed = append(fixed, b)
+			}
+		}
+		return fixed
+	}
+	nonLeap, nextLeap := now.Year()+1, now.Year()+1
+	for isLeap(nonLeap) {
+		nonLeap++
+	}
+	for !isLeap(nextLeap) {
+		nextLeap++
+	}
+
+	// 29 February birthdays fall on the 28th outside leap years
+	birthdays := birthdaysIn(fmt.Sprintf("%d-02-20", nonLeap), fmt.Sprintf("%d-03-05", nonLeap))
+	if len(birthdays) != 1 || birthdays[0].PatientID != int(leapID) || birthdays[0].Birthday != fmt.Sprintf("%d-02-28", nonLeap) || birthdays[0].Age != nonLeap-leapYear {
+		t.Errorf("birthdays = %+v", birthdays)
+	}
+	birthdays = birthdaysIn(fmt.Sprintf("%d-02-20", nextLeap), fmt.Sprintf("%d-03-05", nextLeap))
+	if len(birthdays) != 1 || birthdays[0].Birthday != fmt.Sprintf("%d-02-29", nextLeap) {
+		t.Errorf("leap year birthdays = %+v", birthdays)
+	}
+	// A range across the new year finds birthdays in both years
+	birthdays = birthdaysIn(fmt.Sprintf("%d-06-01", now.Year()+1), fmt.Sprintf("%d-03-01", now.Year()+2))
+	if len(birthdays) != 2 || birthdays[0].PatientID != int(estimatedID) || birthdays[1].PatientID != int(leapID) {
+		t.Errorf("birthdays across the new year = %+v", birthdays)
+	}
+	if _, err := patients.GetUpcomingBirthdays("2027-01-01", "2028-06-01"); err == nil {
+		t.Errorf("GetUpcomingBirthdays accepted a range longer than a year")
+	}
+}
@@ -17,7 +17,7 @@ func NewPaymentHandler(db *sql.DB) *PaymentHandler {
 }
 
 // AddPayment adds a new payment for a patient
-func (h *PaymentHandler) AddPayment(payment models.Payment) (int64, error) {
+func (h *PaymentHandler) AddPayment(payment models.Payment, actorID int) (int64, error) {
 	if payment.Amount <= 0 {
 		return 0, fmt.Errorf("payment amount must be greater than zero")
 	}
@@ -37,11 +37,7 @@ func (h *PaymentHandler) AddPayment(payment models.Payment) (int64, error) {
 
 	query := `INSERT INTO payments (invoice_id, patient_id, payment_code, amount, payment_date, note, payment_method, created_at, updated_at)
 			  VALUES (NULL, ?, ?, ?, ?, ?, 'cash', datetime('now'), datetime('now'))`
-	result, err := h.db.Exec(query, payment.PatientID, paymentCode, payment.Amount, paymentDate.Format("2006-01-02 15:04:05"), payment.Note)
-	if err != nil {
-		return 0, err
-	}
-	return result.LastInsertId()
+	return auditedInsert(h.db, actorID, auditPayment, query, payment.PatientID, paymentCode, payment.Amount, paymentDate.Format("2006-01-02 15:04:05"), payment.Note)
 }
 
 // GetPaymentsForPatient returns all payments for a patient, most recent first
@@ -128,9 +124,9 @@ func (h *PaymentHandler) GetLastPaymentForPatient(patientID int) (*models.Paymen
 }
 
 // UpdateTotalRequired sets the total required amount for a patient
-func (h *PaymentHandler) UpdateTotalRequired(patientID int, total int) error {
+func (h *PaymentHandler) UpdateTotalRequired(patientID int, total int, actorID int) error {
 	query := `UPDATE patients SET total_required = ? WHERE id = ?`
-	_, err := h.db.Exec(query, total, patientID)
+	_, err := auditedExec(h.db, actorID, auditPatient, int64(patientID), AuditActionUpdate, query, total, patientID)
 	return err
 }
 
@@ -139,6 +135,10 @@ type PatientBalance struct {
 	TotalRequired int `json:"total_required"`
 	TotalPaid     int `json:"total_paid"`
 	Remaining     int `json:"remaining"`
+	// PlanEstimate and PlanRemaining total the accepted items of accepted treatment plans, all of them
+	// and those not performed yet
+	PlanEstimate  int `json:"plan_estimate"`
+	PlanRemaining int `json:"plan_remaining"`
 }
 
 // GetPatientBalance returns the total required, total paid, and remaining for a patient
@@ -153,22 +153,31 @@ func (h *PaymentHandler) GetPatientBalance(patientID int) (*PatientBalance, erro
 		return nil, err
 	}
 	remaining := totalRequired - totalPaid
-	return &PatientBalance{
+	balance := &PatientBalance{
 		TotalRequired: totalRequired,
 		TotalPaid:     totalPaid,
 		Remaining:     remaining,
-	}, nil
+	}
+	plans, err := loadTreatmentPlans(h.db, `tp.patient_id = ? AND tp.accepted_at != ''`, patientID)
+	if err != nil {
+		return nil, err
+	}
+	for _, plan := range plans {
+		balance.PlanEstimate += plan.AcceptedTotal
+		balance.PlanRemaining += plan.Remaining
+	}
+	return balance, nil
 }
 
 // DeletePayment deletes a payment by ID
-func (h *PaymentHandler) DeletePayment(paymentID int) error {
+func (h *PaymentHandler) DeletePayment(paymentID int, actorID int) error {
 	query := `DELETE FROM payments WHERE id = ? AND invoice_id IS NULL`
-	_, err := h.db.Exec(query, paymentID)
+	_, err := auditedExec(h.db, actorID, auditPayment, int64(paymentID), AuditActionDelete, query, paymentID)
 	return err
 }
 
 // UpdatePayment updates a payment by ID
-func (h *PaymentHandler) UpdatePayment(payment models.Payment) error {
+func (h *PaymentHandler) UpdatePayment(payment models.Payment, actorID int) error {
 	if payment.Amount <= 0 {
 		return fmt.Errorf("payment amount must be greater than zero")
 	}
@@ -184,7 +193,8 @@ func (h *PaymentHandler) UpdatePayment(payment models.Payment) error {
 	query := `UPDATE payments
 	          SET amount = ?, payment_date = ?, note = ?, updated_at = datetime('now')
 	          WHERE id = ? AND invoice_id IS NULL`
-	_, err = h.db.Exec(query, payment.Amount, paymentDate.Format("2006-01-02 15:04:05"), payment.Note, payment.ID)
+	_, err = auditedExec(h.db, actorID, auditPayment, int64(payment.ID), AuditActionUpdate, query,
+		payment.Amount, paymentDate.Format("2006-01-02 15:04:05"), payment.Note, payment.ID)
 	return err
 }
 
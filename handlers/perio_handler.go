@@ -0,0 +1,442 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"DentistApp/models"
+)
+
+// PerioHandler handles periodontal exams
+type PerioHandler struct {
+	db *sql.DB
+}
+
+// NewPerioHandler creates new handler
+func NewPerioHandler(db *sql.DB) *PerioHandler {
+	return &PerioHandler{db: db}
+}
+
+// loadPerioExams returns the exams matching where with their teeth and summaries, newest first
+func (h *PerioHandler) loadPerioExams(where string, args ...any) ([]models.PerioExam, error) {
+	rows, err := h.db.Query(`SELECT e.id, e.patient_id, e.exam_date, e.examiner_id, COALESCE(u.username, ''), e.notes, e.created_at
+	                         FROM perio_exams e LEFT JOIN users u ON e.examiner_id = u.id
+	                         WHERE `+where+` ORDER BY e.exam_date DESC, e.id DESC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get periodontal exams: %v", err)
+	}
+	exams := []models.PerioExam{}
+	byID := map[int]int{}
+	for rows.Next() {
+		var exam models.PerioExam
+		var examinerID sql.NullInt64
+		if err := rows.Scan(&exam.ID, &exam.PatientID, &exam.ExamDate, &examinerID, &exam.ExaminerName, &exam.Notes, &exam.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan periodontal exam: %v", err)
+		}
+		exam.ExaminerID = nullableInt(examinerID)
+		exam.Teeth = []models.PerioTooth{}
+		byID[exam.ID] = len(exams)
+		exams = append(exams, exam)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(exams) == 0 {
+		return exams, nil
+	}
+
+	ids := make([]string, 0, len(exams))
+	for _, exam := range exams {
+		ids = append(ids, fmt.Sprint(exam.ID))
+	}
+	in := strings.Join(ids, ",")
+
+	// teeth[exam index][tooth] is the position of the tooth in that exam's Teeth
+	teeth := make([]map[int]int, len(exams))
+	toothRows, err := h.db.Query(`SELECT exam_id, tooth, mobility, furcation FROM perio_teeth WHERE exam_id IN (` + in + `) ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get periodontal exam teeth: %v", err)
+	}
+	for toothRows.Next() {
+		var examID int
+		var tooth models.PerioTooth
+		if err := toothRows.Scan(&examID, &tooth.Tooth, &tooth.Mobility, &tooth.Furcation); err != nil {
+			toothRows.Close()
+			return nil, fmt.Errorf("failed to scan periodontal exam tooth: %v", err)
+		}
+		i, ok := byID[examID]
+		if !ok {
+			continue
+		}
+		if teeth[i] == nil {
+			teeth[i] = map[int]int{}
+		}
+		tooth.Sites = []models.PerioSite{}
+		teeth[i][tooth.Tooth] = len(exams[i].Teeth)
+		exams[i].Teeth = append(exams[i].Teeth, tooth)
+	}
+	toothRows.Close()
+	if err := toothRows.Err(); err != nil {
+		return nil, err
+	}
+
+	siteRows, err := h.db.Query(`SELECT exam_id, tooth, site, probing_depth, recession, bleeding FROM perio_sites WHERE exam_id IN (` + in + `) ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get periodontal exam sites: %v", err)
+	}
+	defer siteRows.Close()
+	for siteRows.Next() {
+		var examID, tooth int
+		var site models.PerioSite
+		if err := siteRows.Scan(&examID, &tooth, &site.Site, &site.ProbingDepth, &site.Recession, &site.Bleeding); err != nil {
+			return nil, fmt.Errorf("failed to scan periodontal exam site: %v", err)
+		}
+		i, ok := byID[examID]
+		if !ok {
+			continue
+		}
+		if t, ok := teeth[i][tooth]; ok {
+			exams[i].Teeth[t].Sites = append(exams[i].Teeth[t].Sites, site)
+		}
+	}
+	if err := siteRows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range exams {
+		exams[i].Summary = summarizePerio(exams[i].Teeth)
+	}
+	return exams, nil
+}
+
+// summarizePerio calculates the mean probing depth, the share of bleeding sites and the number of
+// deep pockets
+func summarizePerio(teeth []models.PerioTooth) models.PerioSummary {
+	summary := models.PerioSummary{Teeth: len(teeth)}
+	depth, bleeding := 0, 0
+	for _, tooth := range teeth {
+		for _, site := range tooth.Sites {
+			summary.Sites++
+			depth += site.ProbingDepth
+			if site.Bleeding {
+				bleeding++
+			}
+			if site.ProbingDepth >= models.PerioDeepPocket {
+				summary.DeepSites++
+			}
+		}
+	}
+	if summary.Sites > 0 {
+		summary.MeanDepth = roundHundredths(float64(depth) / float64(summary.Sites))
+		summary.BleedingPercent = roundHundredths(float64(bleeding) * 100 / float64(summary.Sites))
+	}
+	return summary
+}
+
+func roundHundredths(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// GetPerioExams returns the patient's periodontal exams, newest first
+func (h *PerioHandler) GetPerioExams(patientID int) ([]models.PerioExam, error) {
+	return h.loadPerioExams(`e.patient_id = ?`, patientID)
+}
+
+// GetPerioExam returns one exam with its measurements and summary
+func (h *PerioHandler) GetPerioExam(id int) (models.PerioExam, error) {
+	exams, err := h.loadPerioExams(`e.id = ?`, id)
+	if err != nil {
+		return models.PerioExam{}, err
+	}
+	if len(exams) == 0 {
+		return models.PerioExam{}, fmt.Errorf("periodontal exam not found")
+	}
+	return exams[0], nil
+}
+
+// CreatePerioExam saves a new exam. Only one exam per patient and date is kept.
+func (h *PerioHandler) CreatePerioExam(exam models.PerioExam, actorID int) (int64, error) {
+	if err := validatePerioExam(&exam); err != nil {
+		return 0, err
+	}
+	if exam.ExaminerID == nil {
+		exam.ExaminerID = &actorID
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkPerioExamDate(tx, exam); err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec(`INSERT INTO perio_exams (patient_id, exam_date, examiner_id, notes, created_at) VALUES (?, ?, ?, ?, ?)`,
+		exam.PatientID, exam.ExamDate, exam.ExaminerID, exam.Notes, time.Now().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create periodontal exam: %v", err)
+	}
+	examID, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get periodontal exam ID: %v", err)
+	}
+	if err := insertPerioTeeth(tx, examID, exam.Teeth); err != nil {
+		return 0, err
+	}
+	if err := recordAudit(tx, actorID, auditPerioExam, examID, AuditActionCreate, nil); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return examID, nil
+}
+
+// UpdatePerioExam replaces the date, notes and measurements of an exam
+func (h *PerioHandler) UpdatePerioExam(exam models.PerioExam, actorID int) error {
+	current, err := h.GetPerioExam(exam.ID)
+	if err != nil {
+		return err
+	}
+	exam.PatientID = current.PatientID
+	if err := validatePerioExam(&exam); err != nil {
+		return err
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkPerioExamDate(tx, exam); err != nil {
+		return err
+	}
+	before, err := auditSnapshot(tx, auditPerioExam, int64(exam.ID))
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE perio_exams SET exam_date = ?, examiner_id = ?, notes = ? WHERE id = ?`,
+		exam.ExamDate, exam.ExaminerID, exam.Notes, exam.ID); err != nil {
+		return fmt.Errorf("failed to update periodontal exam: %v", err)
+	}
+	if err := deletePerioTeeth(tx, exam.ID); err != nil {
+		return err
+	}
+	if err := insertPerioTeeth(tx, int64(exam.ID), exam.Teeth); err != nil {
+		return err
+	}
+	if err := recordAudit(tx, actorID, auditPerioExam, int64(exam.ID), AuditActionUpdate, before); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// DeletePerioExam deletes an exam and its measurements
+func (h *PerioHandler) DeletePerioExam(id int, actorID int) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	before, err := auditSnapshot(tx, auditPerioExam, int64(id))
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return fmt.Errorf("periodontal exam not found")
+	}
+	if err := deletePerioTeeth(tx, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM perio_exams WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete periodontal exam: %v", err)
+	}
+	if err := recordAudit(tx, actorID, auditPerioExam, int64(id), AuditActionDelete, before); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// ComparePerioExams compares two exams of the same patient, the earlier one as the baseline
+func (h *PerioHandler) ComparePerioExams(firstID, secondID int) (models.PerioComparison, error) {
+	before, err := h.GetPerioExam(firstID)
+	if err != nil {
+		return models.PerioComparison{}, err
+	}
+	after, err := h.GetPerioExam(secondID)
+	if err != nil {
+		return models.PerioComparison{}, err
+	}
+	if before.PatientID != after.PatientID {
+		return models.PerioComparison{}, fmt.Errorf("exams belong to different patients")
+	}
+	if before.ExamDate > after.ExamDate {
+		before, after = after, before
+	}
+
+	comparison := models.PerioComparison{
+		BeforeID:        before.ID,
+		BeforeDate:      before.ExamDate,
+		AfterID:         after.ID,
+		AfterDate:       after.ExamDate,
+		Before:          before.Summary,
+		After:           after.Summary,
+		MeanDepthChange: roundHundredths(after.Summary.MeanDepth - before.Summary.MeanDepth),
+		BleedingChange:  roundHundredths(after.Summary.BleedingPercent - before.Summary.BleedingPercent),
+		DeepSitesChange: after.Summary.DeepSites - before.Summary.DeepSites,
+		Teeth:           []models.PerioToothChange{},
+	}
+
+	earlier := map[int]models.PerioTooth{}
+	for _, tooth := range before.Teeth {
+		earlier[tooth.Tooth] = tooth
+	}
+	for _, tooth := range after.Teeth {
+		prev, ok := earlier[tooth.Tooth]
+		if !ok {
+			continue
+		}
+		change := models.PerioToothChange{Tooth: tooth.Tooth}
+		depths := map[string]int{}
+		for _, site := range prev.Sites {
+			depths[site.Site] = site.ProbingDepth
+			change.MaxDepthBefore = max(change.MaxDepthBefore, site.ProbingDepth)
+			if site.Bleeding {
+				change.BleedingBefore++
+			}
+		}
+		for _, site := range tooth.Sites {
+			change.MaxDepthAfter = max(change.MaxDepthAfter, site.ProbingDepth)
+			if site.Bleeding {
+				change.BleedingAfter++
+			}
+			if d, ok := depths[site.Site]; ok {
+				if site.ProbingDepth < d {
+					change.SitesImproved++
+				} else if site.ProbingDepth > d {
+					change.SitesWorsened++
+				}
+			}
+		}
+		comparison.Teeth = append(comparison.Teeth, change)
+	}
+	sort.Slice(comparison.Teeth, func(i, j int) bool { return comparison.Teeth[i].Tooth < comparison.Teeth[j].Tooth })
+	return comparison, nil
+}
+
+// checkPerioExamDate returns an error if the patient already has another exam on the same date
+func checkPerioExamDate(tx *sql.Tx, exam models.PerioExam) error {
+	var count int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM perio_exams WHERE patient_id = ? AND exam_date = ? AND id != ?`,
+		exam.PatientID, exam.ExamDate, exam.ID).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to check periodontal exams: %v", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("the patient already has a periodontal exam on %s", exam.ExamDate)
+	}
+	return nil
+}
+
+func insertPerioTeeth(tx *sql.Tx, examID int64, teeth []models.PerioTooth) error {
+	for _, tooth := range teeth {
+		if _, err := tx.Exec(`INSERT INTO perio_teeth (exam_id, tooth, mobility, furcation) VALUES (?, ?, ?, ?)`,
+			examID, tooth.Tooth, tooth.Mobility, tooth.Furcation); err != nil {
+			return fmt.Errorf("failed to save periodontal exam tooth: %v", err)
+		}
+		for _, site := range tooth.Sites {
+			if _, err := tx.Exec(`INSERT INTO perio_sites (exam_id, tooth, site, probing_depth, recession, bleeding) VALUES (?, ?, ?, ?, ?, ?)`,
+				examID, tooth.Tooth, site.Site, site.ProbingDepth, site.Recession, site.Bleeding); err != nil {
+				return fmt.Errorf("failed to save periodontal exam site: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+func deletePerioTeeth(tx *sql.Tx, examID int) error {
+	if _, err := tx.Exec(`DELETE FROM perio_sites WHERE exam_id = ?`, examID); err != nil {
+		return fmt.Errorf("failed to delete periodontal exam sites: %v", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM perio_teeth WHERE exam_id = ?`, examID); err != nil {
+		return fmt.Errorf("failed to delete periodontal exam teeth: %v", err)
+	}
+	return nil
+}
+
+// validatePerioExam checks the exam and puts each tooth's sites in PerioSites order
+func validatePerioExam(exam *models.PerioExam) error {
+	exam.Notes = strings.TrimSpace(exam.Notes)
+	if exam.PatientID <= 0 {
+		return fmt.Errorf("patient is required")
+	}
+	if _, err := time.Parse("2006-01-02", exam.ExamDate); err != nil {
+		return fmt.Errorf("invalid exam date: %s", exam.ExamDate)
+	}
+	if len(exam.Teeth) == 0 {
+		return fmt.Errorf("record at least one tooth")
+	}
+
+	siteOrder := map[string]int{}
+	for i, site := range models.PerioSites {
+		siteOrder[site] = i
+	}
+	seen := map[int]bool{}
+	for i := range exam.Teeth {
+		tooth := &exam.Teeth[i]
+		if !validToothNumber(tooth.Tooth) {
+			return fmt.Errorf("%d is not an FDI tooth number (11-48 or 51-85)", tooth.Tooth)
+		}
+		if seen[tooth.Tooth] {
+			return fmt.Errorf("tooth %d is recorded twice", tooth.Tooth)
+		}
+		seen[tooth.Tooth] = true
+		if tooth.Mobility < 0 || tooth.Mobility > 3 {
+			return fmt.Errorf("tooth %d: mobility must be a grade from 0 to 3", tooth.Tooth)
+		}
+		if tooth.Furcation < 0 || tooth.Furcation > 3 {
+			return fmt.Errorf("tooth %d: furcation must be a grade from 0 to 3", tooth.Tooth)
+		}
+
+		sites := make([]models.PerioSite, len(models.PerioSites))
+		filled := map[string]bool{}
+		for _, site := range tooth.Sites {
+			site.Site = strings.ToUpper(strings.TrimSpace(site.Site))
+			pos, ok := siteOrder[site.Site]
+			if !ok {
+				return fmt.Errorf("tooth %d: unknown site %q", tooth.Tooth, site.Site)
+			}
+			if filled[site.Site] {
+				return fmt.Errorf("tooth %d: site %s is recorded twice", tooth.Tooth, site.Site)
+			}
+			if site.ProbingDepth < 0 || site.ProbingDepth > 15 {
+				return fmt.Errorf("tooth %d %s: probing depth must be between 0 and 15 mm", tooth.Tooth, site.Site)
+			}
+			if site.Recession < -5 || site.Recession > 15 {
+				return fmt.Errorf("tooth %d %s: recession must be between -5 and 15 mm", tooth.Tooth, site.Site)
+			}
+			filled[site.Site] = true
+			sites[pos] = site
+		}
+		if len(filled) != len(models.PerioSites) {
+			return fmt.Errorf("tooth %d: record all six sites", tooth.Tooth)
+		}
+		tooth.Sites = sites
+	}
+	return nil
+}
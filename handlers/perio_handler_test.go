@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"testing"
+
+	"DentistApp/models"
+)
+
+func perioTooth(tooth int, depths [6]int, bleeding [6]bool) models.PerioTooth {
+	result := models.PerioTooth{Tooth: tooth}
+	// Sites are given out of order to check that they are stored in chart order
+	for i := len(models.PerioSites) - 1; i >= 0; i-- {
+		result.Sites = append(result.Sites, models.PerioSite{Site: models.PerioSites[i], ProbingDepth: depths[i], Bleeding: bleeding[i]})
+	}
+	return result
+}
+
+func TestPerioExamSummaryAndComparison(t *testing.T) {
+	db, admin := newTestAdmin(t)
+	var patientIDs []int
+	for _, name := range []string{"Jane Doe", "John Roe"} {
+		patientIDs = append(patientIDs, newTestPatient(t, db, models.PatientForm{Name: name, Phone: "0100000000"}))
+	}
+
+	perio := NewPerioHandler(db)
+	invalid := []models.PerioExam{
+		{PatientID: patientIDs[0], ExamDate: "2025-13-01", Teeth: []models.PerioTooth{perioTooth(16, [6]int{}, [6]bool{})}},
+		{PatientID: patientIDs[0], ExamDate: "2025-01-10", Teeth: []models.PerioTooth{perioTooth(19, [6]int{}, [6]bool{})}},
+		{PatientID: patientIDs[0], ExamDate: "2025-01-10", Teeth: []models.PerioTooth{{Tooth: 16, Sites: []models.PerioSite{{Site: "MB", ProbingDepth: 3}}}}},
+		{PatientID: patientIDs[0], ExamDate: "2025-01-10", Teeth: []models.PerioTooth{perioTooth(16, [6]int{20}, [6]bool{})}},
+	}
+	for _, exam := range invalid {
+		if _, err := perio.CreatePerioExam(exam, admin.ID); err == nil {
+			t.Errorf("CreatePerioExam accepted %+v", exam)
+		}
+	}
+
+	baseline := models.PerioExam{
+		PatientID: patientIDs[0],
+		ExamDate:  "2025-01-10",
+		Teeth: []models.PerioTooth{
+			perioTooth(16, [6]int{3, 3, 6, 4, 3, 5}, [6]bool{true, false, true, false, false, true}),
+			perioTooth(21, [6]int{2, 2, 2, 2, 2, 2}, [6]bool{}),
+		},
+	}
+	baselineID, err := perio.CreatePerioExam(baseline, admin.ID)
+	if err != nil {
+		t.Fatalf("CreatePerioExam failed: %v", err)
+	}
+	if _, err := perio.CreatePerioExam(baseline, admin.ID); err == nil {
+		t.Errorf("CreatePerioExam accepted a second exam on the same date")
+	}
+
+	exam, err := perio.GetPerioExam(int(baselineID))
+	if err != nil {
+		t.Fatalf("GetPerioExam failed: %v", err)
+	}
+	if exam.Teeth[0].Sites[0].Site != models.PerioSites[0] || exam.Teeth[0].Sites[2].ProbingDepth != 6 {
+		t.Errorf("sites = %+v; expected them in chart order", exam.Teeth[0].Sites)
+	}
+	// 12 sites, 36 mm in total, 3 bleeding, 2 sites of 5 mm or more
+	if exam.Summary.Sites != 12 || exam.Summary.MeanDepth != 3 || exam.Summary.BleedingPercent != 25 || exam.Summary.DeepSites != 2 {
+		t.Errorf("summary = %+v; expected 3 mm mean, 25%% bleeding and 2 deep sites", exam.Summary)
+	}
+	if exam.ExaminerID == nil || *exam.ExaminerID != admin.ID {
+		t.Errorf("examiner = %v; expected the user recording the exam", exam.ExaminerID)
+	}
+
+	review := models.PerioExam{
+		PatientID: patientIDs[0],
+		ExamDate:  "2025-04-10",
+		Teeth: []models.PerioTooth{
+			perioTooth(16, [6]int{3, 3, 4, 3, 3, 4}, [6]bool{true}),
+			perioTooth(21, [6]int{2, 3, 2, 2, 2, 2}, [6]bool{}),
+		},
+	}
+	reviewID, err := perio.CreatePerioExam(review, admin.ID)
+	if err != nil {
+		t.Fatalf("CreatePerioExam failed: %v", err)
+	}
+
+	// The earlier exam is the baseline whichever order they are passed in
+	comparison, err := perio.ComparePerioExams(int(reviewID), int(baselineID))
+	if err != nil {
+		t.Fatalf("ComparePerioExams failed: %v", err)
+	}
+	if comparison.BeforeID != int(baselineID) || comparison.DeepSitesChange != -2 || comparison.MeanDepthChange != -0.25 || comparison.BleedingChange != -16.67 {
+		t.Errorf("comparison = %+v; expected fewer deep sites and less bleeding", comparison)
+	}
+	if len(comparison.Teeth) != 2 || comparison.Teeth[0].Tooth != 16 || comparison.Teeth[0].SitesImproved != 3 || comparison.Teeth[0].MaxDepthAfter != 4 || comparison.Teeth[1].SitesWorsened != 1 {
+		t.Errorf("tooth changes = %+v", comparison.Teeth)
+	}
+
+	other, err := perio.CreatePerioExam(models.PerioExam{PatientID: patientIDs[1], ExamDate: "2025-04-10", Teeth: review.Teeth}, admin.ID)
+	if err != nil {
+		t.Fatalf("CreatePerioExam failed: %v", err)
+	}
+	if _, err := perio.ComparePerioExams(int(baselineID), int(other)); err == nil {
+		t.Errorf("ComparePerioExams compared exams of different patients")
+	}
+
+	// Updating replaces the measurements; moving onto another exam's date is refused
+	exam.Teeth = exam.Teeth[:1]
+	exam.ExamDate = "2025-04-10"
+	if err := perio.UpdatePerioExam(exam, admin.ID); err == nil {
+		t.Errorf("UpdatePerioExam moved the exam onto a date that already has one")
+	}
+	exam.ExamDate = "2025-01-11"
+	if err := perio.UpdatePerioExam(exam, admin.ID); err != nil {
+		t.Fatalf("UpdatePerioExam failed: %v", err)
+	}
+	exams, err := perio.GetPerioExams(patientIDs[0])
+	if err != nil {
+		t.Fatalf("GetPerioExams failed: %v", err)
+	}
+	if len(exams) != 2 || exams[0].ID != int(reviewID) || exams[1].Summary.Teeth != 1 || exams[1].Summary.Sites != 6 {
+		t.Errorf("exams = %+v; expected the review first and the edited baseline with one tooth", exams)
+	}
+
+	if err := perio.DeletePerioExam(int(baselineID), admin.ID); err != nil {
+		t.Fatalf("DeletePerioExam failed: %v", err)
+	}
+	var sites int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM perio_sites WHERE exam_id = ?`, baselineID).Scan(&sites); err != nil || sites != 0 {
+		t.Errorf("deleted exam left %d sites (%v)", sites, err)
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"fmt"
+
+	"DentistApp/models"
+)
+
+// allPermissions lists every permission; Admin is granted all of them
+var allPermissions = []models.Permission{
+	models.PermPatientView, models.PermPatientCreate, models.PermPatientUpdate, models.PermPatientDelete, models.PermPatientDeleteAll,
+	models.PermMedicalAlertManage, models.PermTreatmentPlanAccept, models.PermClinicalNoteView, models.PermPrescriptionView,
+	models.PermAppointmentView, models.PermAppointmentManage,
+	models.PermSessionView, models.PermSessionCreate, models.PermSessionUpdate, models.PermSessionDelete,
+	models.PermInvoiceView, models.PermInvoiceCreate,
+	models.PermPaymentView, models.PermPaymentCreate, models.PermPaymentUpdate, models.PermPaymentDelete,
+	models.PermExpenseView, models.PermExpenseCreate, models.PermExpenseUpdate, models.PermExpenseDelete, models.PermExpenseApprove,
+	models.PermExpenseCategoryManage, models.PermExpenseCategoryDeletePermanent,
+	models.PermProcedureView, models.PermProcedureManage,
+	models.PermLabView, models.PermLabManage, models.PermLabOrderView, models.PermLabOrderCreate,
+	models.PermSettingsManage,
+	models.PermUserView, models.PermUserManage,
+	models.PermAuditView, models.PermBackupManage,
+}
+
+// rolePermissions maps each role to the permissions it is granted
+var rolePermissions = map[string][]models.Permission{
+	models.RoleAdmin: allPermissions,
+	models.RoleDentist: {
+		models.PermPatientView, models.PermPatientCreate, models.PermPatientUpdate, models.PermPatientDelete,
+		models.PermMedicalAlertManage, models.PermTreatmentPlanAccept, models.PermClinicalNoteView, models.PermPrescriptionView,
+		models.PermAppointmentView, models.PermAppointmentManage,
+		models.PermSessionView, models.PermSessionCreate, models.PermSessionUpdate, models.PermSessionDelete,
+		models.PermInvoiceView, models.PermInvoiceCreate,
+		models.PermPaymentView, models.PermPaymentCreate, models.PermPaymentUpdate,
+		models.PermProcedureView, models.PermProcedureManage,
+		models.PermLabView, models.PermLabManage, models.PermLabOrderView, models.PermLabOrderCreate,
+		models.PermUserView,
+	},
+	models.RoleReceptionist: {
+		models.PermPatientView, models.PermPatientCreate, models.PermPatientUpdate,
+		models.PermAppointmentView, models.PermAppointmentManage,
+		models.PermSessionView, models.PermSessionCreate,
+		models.PermInvoiceView, models.PermInvoiceCreate,
+		models.PermPaymentView, models.PermPaymentCreate,
+		models.PermProcedureView,
+		models.PermLabView, models.PermLabOrderView, models.PermLabOrderCreate,
+		models.PermUserView,
+	},
+	models.RoleAccountant: {
+		models.PermPatientView,
+		models.PermSessionView,
+		models.PermInvoiceView, models.PermInvoiceCreate,
+		models.PermPaymentView, models.PermPaymentCreate, models.PermPaymentUpdate, models.PermPaymentDelete,
+		models.PermExpenseView, models.PermExpenseCreate, models.PermExpenseUpdate, models.PermExpenseDelete, models.PermExpenseApprove,
+		models.PermExpenseCategoryManage,
+		models.PermProcedureView,
+		models.PermLabView, models.PermLabOrderView,
+		models.PermUserView,
+	},
+}
+
+// IsValidRole reports whether role is one of the known roles
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission reports whether the role grants the permission
+func HasPermission(role string, permission models.Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckPermission returns an error unless the user's role grants the permission
+func CheckPermission(user *models.User, permission models.Permission) error {
+	if user == nil || !HasPermission(user.Role, permission) {
+		return fmt.Errorf("permission denied: %s", permission)
+	}
+	return nil
+}
+
+// PermissionsForRole returns the names of the permissions granted to the role
+func PermissionsForRole(role string) []string {
+	granted := make([]string, 0, len(rolePermissions[role]))
+	for _, p := range rolePermissions[role] {
+		granted = append(granted, string(p))
+	}
+	return granted
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"testing"
+
+	"DentistApp/models"
+)
+
+func TestRolePermissions(t *testing.T) {
+	tests := []struct {
+		role       string
+		permission models.Permission
+		expected   bool
+	}{
+		{models.RoleAdmin, models.PermPatientDeleteAll, true},
+		{models.RoleAdmin, models.PermExpenseCategoryDeletePermanent, true},
+		{models.RoleDentist, models.PermSessionCreate, true},
+		{models.RoleDentist, models.PermPatientDeleteAll, false},
+		{models.RoleReceptionist, models.PermAppointmentManage, true},
+		{models.RoleReceptionist, models.PermPatientDeleteAll, false},
+		{models.RoleReceptionist, models.PermExpenseCategoryDeletePermanent, false},
+		{models.RoleReceptionist, models.PermPaymentDelete, false},
+		{models.RoleAccountant, models.PermExpenseApprove, true},
+		{models.RoleAccountant, models.PermPatientUpdate, false},
+		{models.RoleDentist, models.PermMedicalAlertManage, true},
+		{models.RoleAdmin, models.PermMedicalAlertManage, true},
+		{models.RoleReceptionist, models.PermMedicalAlertManage, false},
+		{models.RoleAccountant, models.PermMedicalAlertManage, false},
+		{models.RoleDentist, models.PermTreatmentPlanAccept, true},
+		{models.RoleAdmin, models.PermTreatmentPlanAccept, true},
+		{models.RoleReceptionist, models.PermTreatmentPlanAccept, false},
+		{models.RoleAccountant, models.PermTreatmentPlanAccept, false},
+		{models.RoleDentist, models.PermClinicalNoteView, true},
+		{models.RoleAdmin, models.PermClinicalNoteView, true},
+		{models.RoleReceptionist, models.PermClinicalNoteView, false},
+		{models.RoleAccountant, models.PermClinicalNoteView, false},
+		{models.RoleDentist, models.PermPrescriptionView, true},
+		{models.RoleAdmin, models.PermPrescriptionView, true},
+		{models.RoleReceptionist, models.PermPrescriptionView, false},
+		{models.RoleAccountant, models.PermPrescriptionView, false},
+		{"Assistant", models.PermPatientView, false},
+	}
+
+	for _, tt := range tests {
+		if got := HasPermission(tt.role, tt.permission); got != tt.expected {
+			t.Errorf("HasPermission(%q, %q) = %v; expected %v", tt.role, tt.permission, got, tt.expected)
+		}
+	}
+
+	if err := CheckPermission(&models.User{Role: models.RoleReceptionist}, models.PermPatientDeleteAll); err == nil {
+		t.Errorf("CheckPermission should deny patient.delete_all to Receptionist")
+	}
+	if err := CheckPermission(nil, models.PermPatientView); err == nil {
+		t.Errorf("CheckPermission should deny a nil user")
+	}
+	if len(PermissionsForRole(models.RoleAdmin)) != len(allPermissions) {
+		t.Errorf("Admin should be granted every permission")
+	}
+}
+
+func TestCreateUserRejectsUnknownRole(t *testing.T) {
+	db := newTestDB(t)
+	handler := NewAuthHandler(db)
+	if err := handler.InitializeAdmin(); err != nil {
+		t.Fatalf("InitializeAdmin failed: %v", err)
+	}
+	admin, err := handler.GetUserByUsername("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = handler.CreateUser(models.UserForm{Username: "front", Password: "secret1", Role: "Assistant"}, admin.ID)
+	if err == nil {
+		t.Errorf("CreateUser accepted an unknown role")
+	}
+
+	_, err = handler.CreateUser(models.UserForm{Username: "front", Password: "secret1", Role: models.RoleReceptionist}, admin.ID)
+	if err != nil {
+		t.Errorf("CreateUser failed for Receptionist: %v", err)
+	}
+}
@@ -0,0 +1,495 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"DentistApp/models"
+	"DentistApp/pdfdoc"
+)
+
+// PrescriptionHandler handles the drug catalogue, drug rules and prescriptions
+type PrescriptionHandler struct {
+	db *sql.DB
+}
+
+// NewPrescriptionHandler creates new handler
+func NewPrescriptionHandler(db *sql.DB) *PrescriptionHandler {
+	return &PrescriptionHandler{db: db}
+}
+
+// GetDrugs returns the drug catalogue ordered by name
+func (h *PrescriptionHandler) GetDrugs() ([]models.Drug, error) {
+	rows, err := h.db.Query(`SELECT id, name, strength, form, drug_class, default_dose, default_frequency, default_duration, default_instructions
+	                         FROM drugs ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get drugs: %v", err)
+	}
+	defer rows.Close()
+
+	drugs := make([]models.Drug, 0)
+	for rows.Next() {
+		var d models.Drug
+		if err := rows.Scan(&d.ID, &d.Name, &d.Strength, &d.Form, &d.DrugClass, &d.DefaultDose, &d.DefaultFrequency, &d.DefaultDuration, &d.DefaultInstructions); err != nil {
+			return nil, fmt.Errorf("failed to scan drug: %v", err)
+		}
+		drugs = append(drugs, d)
+	}
+	return drugs, rows.Err()
+}
+
+func validateDrug(d *models.Drug) error {
+	d.Name = strings.TrimSpace(d.Name)
+	d.DrugClass = strings.ToLower(strings.TrimSpace(d.DrugClass))
+	if d.Name == "" {
+		return fmt.Errorf("drug name is required")
+	}
+	return nil
+}
+
+// CreateDrug adds a drug to the catalogue
+func (h *PrescriptionHandler) CreateDrug(d models.Drug, actorID int) (int64, error) {
+	if err := validateDrug(&d); err != nil {
+		return 0, err
+	}
+	id, err := auditedInsert(h.db, actorID, auditDrug,
+		`INSERT INTO drugs (name, strength, form, drug_class, default_dose, default_frequency, default_duration, default_instructions)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		d.Name, d.Strength, d.Form, d.DrugClass, d.DefaultDose, d.DefaultFrequency, d.DefaultDuration, d.DefaultInstructions)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return 0, fmt.Errorf("%s is already in the catalogue", d.Name)
+		}
+		return 0, fmt.Errorf("failed to create drug: %v", err)
+	}
+	return id, nil
+}
+
+// UpdateDrug saves a catalogue entry. Prescriptions already written keep the old name and strength.
+func (h *PrescriptionHandler) UpdateDrug(d models.Drug, actorID int) error {
+	if err := validateDrug(&d); err != nil {
+		return err
+	}
+	result, err := auditedExec(h.db, actorID, auditDrug, int64(d.ID), AuditActionUpdate,
+		`UPDATE drugs SET name = ?, strength = ?, form = ?, drug_class = ?, default_dose = ?, default_frequency = ?,
+		 default_duration = ?, default_instructions = ? WHERE id = ?`,
+		d.Name, d.Strength, d.Form, d.DrugClass, d.DefaultDose, d.DefaultFrequency, d.DefaultDuration, d.DefaultInstructions, d.ID)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return fmt.Errorf("%s is already in the catalogue", d.Name)
+		}
+		return fmt.Errorf("failed to update drug: %v", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("drug not found")
+	}
+	return nil
+}
+
+// DeleteDrug removes a drug from the catalogue
+func (h *PrescriptionHandler) DeleteDrug(id int, actorID int) error {
+	result, err := auditedExec(h.db, actorID, auditDrug, int64(id), AuditActionDelete, `DELETE FROM drugs WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete drug: %v", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("drug not found")
+	}
+	return nil
+}
+
+// GetDrugRules returns the allergy and interaction rules ordered by kind, class and term
+func (h *PrescriptionHandler) GetDrugRules() ([]models.DrugRule, error) {
+	return queryDrugRules(h.db, `SELECT id, kind, drug_class, term, severity, message FROM drug_rules ORDER BY kind, drug_class, term`)
+}
+
+func queryDrugRules(q queryRunner, query string, args ...any) ([]models.DrugRule, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get drug rules: %v", err)
+	}
+	defer rows.Close()
+
+	rules := make([]models.DrugRule, 0)
+	for rows.Next() {
+		var r models.DrugRule
+		if err := rows.Scan(&r.ID, &r.Kind, &r.DrugClass, &r.Term, &r.Severity, &r.Message); err != nil {
+			return nil, fmt.Errorf("failed to scan drug rule: %v", err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+func validateDrugRule(r *models.DrugRule) error {
+	r.DrugClass = strings.ToLower(strings.TrimSpace(r.DrugClass))
+	r.Term = strings.ToLower(strings.TrimSpace(r.Term))
+	r.Message = strings.TrimSpace(r.Message)
+	if r.Kind != models.DrugRuleAllergy && r.Kind != models.DrugRuleInteraction {
+		return fmt.Errorf("invalid rule kind: %s", r.Kind)
+	}
+	if r.Severity != models.DrugRuleWarning && r.Severity != models.DrugRuleContraindicated {
+		return fmt.Errorf("invalid rule severity: %s", r.Severity)
+	}
+	if r.DrugClass == "" || r.Term == "" {
+		return fmt.Errorf("drug class and term are required")
+	}
+	return nil
+}
+
+// CreateDrugRule adds an allergy or interaction rule
+func (h *PrescriptionHandler) CreateDrugRule(r models.DrugRule, actorID int) (int64, error) {
+	if err := validateDrugRule(&r); err != nil {
+		return 0, err
+	}
+	id, err := auditedInsert(h.db, actorID, auditDrugRule,
+		`INSERT INTO drug_rules (kind, drug_class, term, severity, message) VALUES (?, ?, ?, ?, ?)`,
+		r.Kind, r.DrugClass, r.Term, r.Severity, r.Message)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return 0, fmt.Errorf("a %s rule for %s and %q already exists", r.Kind, r.DrugClass, r.Term)
+		}
+		return 0, fmt.Errorf("failed to create drug rule: %v", err)
+	}
+	return id, nil
+}
+
+// UpdateDrugRule saves an allergy or interaction rule
+func (h *PrescriptionHandler) UpdateDrugRule(r models.DrugRule, actorID int) error {
+	if err := validateDrugRule(&r); err != nil {
+		return err
+	}
+	result, err := auditedExec(h.db, actorID, auditDrugRule, int64(r.ID), AuditActionUpdate,
+		`UPDATE drug_rules SET kind = ?, drug_class = ?, term = ?, severity = ?, message = ? WHERE id = ?`,
+		r.Kind, r.DrugClass, r.Term, r.Severity, r.Message, r.ID)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return fmt.Errorf("a %s rule for %s and %q already exists", r.Kind, r.DrugClass, r.Term)
+		}
+		return fmt.Errorf("failed to update drug rule: %v", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("drug rule not found")
+	}
+	return nil
+}
+
+// DeleteDrugRule deletes an allergy or interaction rule
+func (h *PrescriptionHandler) DeleteDrugRule(id int, actorID int) error {
+	result, err := auditedExec(h.db, actorID, auditDrugRule, int64(id), AuditActionDelete, `DELETE FROM drug_rules WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete drug rule: %v", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("drug rule not found")
+	}
+	return nil
+}
+
+// CheckPrescription returns the alerts the prescription lines raise for the session's patient
+func (h *PrescriptionHandler) CheckPrescription(sessionID int, items []models.PrescriptionItem) ([]models.PrescriptionAlert, error) {
+	var patientID int
+	if err := h.db.QueryRow(`SELECT patient_id FROM sessions WHERE id = ?`, sessionID).Scan(&patientID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("session not found")
+		}
+		return nil, fmt.Errorf("failed to get session: %v", err)
+	}
+	return checkPrescription(h.db, patientID, items)
+}
+
+// checkPrescription matches each line's drug class against the rules: allergy rules against the
+// patient's allergies and interaction rules against their current medications. A drug named in the
+// allergies is always contraindicated.
+func checkPrescription(q queryRunner, patientID int, items []models.PrescriptionItem) ([]models.PrescriptionAlert, error) {
+	var allergies, medications string
+	err := q.QueryRow(`SELECT COALESCE(allergies, ''), COALESCE(current_medications, '') FROM patients WHERE id = ?`, patientID).
+		Scan(&allergies, &medications)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("patient not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient: %v", err)
+	}
+	allergies, medications = strings.ToLower(allergies), strings.ToLower(medications)
+
+	alerts := make([]models.PrescriptionAlert, 0)
+	for _, item := range items {
+		name := strings.TrimSpace(item.DrugName)
+		if name != "" && strings.Contains(allergies, strings.ToLower(name)) {
+			alerts = append(alerts, models.PrescriptionAlert{
+				DrugName: name,
+				Kind:     models.DrugRuleAllergy,
+				Severity: models.DrugRuleContraindicated,
+				Term:     strings.ToLower(name),
+				Message:  fmt.Sprintf("The patient is allergic to %s", name),
+			})
+		}
+
+		var class string
+		err := q.QueryRow(`SELECT drug_class FROM drugs WHERE id = ? OR name = ? COLLATE NOCASE ORDER BY id = ? DESC LIMIT 1`,
+			item.DrugID, name, item.DrugID).Scan(&class)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to get drug class: %v", err)
+		}
+		if class == "" {
+			continue
+		}
+		rules, err := queryDrugRules(q, `SELECT id, kind, drug_class, term, severity, message FROM drug_rules WHERE drug_class = ? ORDER BY kind, term`, class)
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range rules {
+			text := medications
+			if rule.Kind == models.DrugRuleAllergy {
+				text = allergies
+			}
+			if !strings.Contains(text, rule.Term) {
+				continue
+			}
+			// The drug's own name was already reported above
+			if rule.Kind == models.DrugRuleAllergy && rule.Term == strings.ToLower(name) {
+				continue
+			}
+			alerts = append(alerts, models.PrescriptionAlert{DrugName: name, Kind: rule.Kind, Severity: rule.Severity, Term: rule.Term, Message: rule.Message})
+		}
+	}
+	return alerts, nil
+}
+
+func validatePrescriptionItems(items []models.PrescriptionItem) error {
+	if len(items) == 0 {
+		return fmt.Errorf("add at least one drug")
+	}
+	for i := range items {
+		item := &items[i]
+		item.DrugName = strings.TrimSpace(item.DrugName)
+		item.Dose = strings.TrimSpace(item.Dose)
+		item.Frequency = strings.TrimSpace(item.Frequency)
+		if item.DrugName == "" {
+			return fmt.Errorf("drug name is required")
+		}
+		if item.Dose == "" || item.Frequency == "" {
+			return fmt.Errorf("%s: dose and frequency are required", item.DrugName)
+		}
+	}
+	return nil
+}
+
+// CreatePrescription saves a prescription for the session's patient. If the allergy and
+// interaction check raises alerts, an override reason is required.
+func (h *PrescriptionHandler) CreatePrescription(p models.Prescription, actorID int) (int64, error) {
+	if err := validatePrescriptionItems(p.Items); err != nil {
+		return 0, err
+	}
+	p.OverrideReason = strings.TrimSpace(p.OverrideReason)
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRow(`SELECT patient_id FROM sessions WHERE id = ?`, p.SessionID).Scan(&p.PatientID); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("session not found")
+		}
+		return 0, fmt.Errorf("failed to get session: %v", err)
+	}
+	alerts, err := checkPrescription(tx, p.PatientID, p.Items)
+	if err != nil {
+		return 0, err
+	}
+	if len(alerts) > 0 && p.OverrideReason == "" {
+		messages := make([]string, 0, len(alerts))
+		for _, alert := range alerts {
+			messages = append(messages, alert.DrugName+": "+alert.Message)
+		}
+		return 0, fmt.Errorf("the prescription needs a reason to override: %s", strings.Join(messages, "; "))
+	}
+
+	result, err := tx.Exec(`INSERT INTO prescriptions (session_id, patient_id, prescriber_id, prescribed_at, notes, override_reason) VALUES (?, ?, ?, ?, ?, ?)`,
+		p.SessionID, p.PatientID, nullableActor(actorID), time.Now().Format("2006-01-02 15:04:05"), strings.TrimSpace(p.Notes), p.OverrideReason)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create prescription: %v", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get prescription ID: %v", err)
+	}
+	for _, item := range p.Items {
+		if _, err := tx.Exec(`INSERT INTO prescription_items (prescription_id, drug_id, drug_name, strength, dose, frequency, duration, instructions)
+		                      VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			id, item.DrugID, item.DrugName, strings.TrimSpace(item.Strength), item.Dose, item.Frequency,
+			strings.TrimSpace(item.Duration), strings.TrimSpace(item.Instructions)); err != nil {
+			return 0, fmt.Errorf("failed to save prescription item: %v", err)
+		}
+	}
+	if err := recordAudit(tx, actorID, auditPrescription, id, AuditActionCreate, nil); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return id, nil
+}
+
+// DeletePrescription deletes a prescription and its lines
+func (h *PrescriptionHandler) DeletePrescription(id int, actorID int) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	before, err := auditSnapshot(tx, auditPrescription, int64(id))
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return fmt.Errorf("prescription not found")
+	}
+	if _, err := tx.Exec(`DELETE FROM prescription_items WHERE prescription_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete prescription items: %v", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM prescriptions WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete prescription: %v", err)
+	}
+	if err := recordAudit(tx, actorID, auditPrescription, int64(id), AuditActionDelete, before); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// GetSessionPrescriptions returns the prescriptions written during a session
+func (h *PrescriptionHandler) GetSessionPrescriptions(sessionID int) ([]models.Prescription, error) {
+	return h.loadPrescriptions(`rx.session_id = ?`, sessionID)
+}
+
+// GetPatientPrescriptions returns every prescription of a patient, newest first
+func (h *PrescriptionHandler) GetPatientPrescriptions(patientID int) ([]models.Prescription, error) {
+	return h.loadPrescriptions(`rx.patient_id = ?`, patientID)
+}
+
+func (h *PrescriptionHandler) loadPrescriptions(where string, args ...any) ([]models.Prescription, error) {
+	rows, err := h.db.Query(`SELECT rx.id, rx.session_id, rx.patient_id, COALESCE(p.name, ''), rx.prescriber_id, COALESCE(u.username, ''),
+	                                rx.prescribed_at, rx.notes, rx.override_reason
+	                         FROM prescriptions rx
+	                         JOIN sessions s ON rx.session_id = s.id
+	                         LEFT JOIN patients p ON rx.patient_id = p.id
+	                         LEFT JOIN users u ON rx.prescriber_id = u.id
+	                         WHERE `+where+` ORDER BY rx.prescribed_at DESC, rx.id DESC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prescriptions: %v", err)
+	}
+	prescriptions := make([]models.Prescription, 0)
+	for rows.Next() {
+		var p models.Prescription
+		var prescriberID sql.NullInt64
+		if err := rows.Scan(&p.ID, &p.SessionID, &p.PatientID, &p.PatientName, &prescriberID, &p.PrescriberName,
+			&p.PrescribedAt, &p.Notes, &p.OverrideReason); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan prescription: %v", err)
+		}
+		p.PrescriberID = nullableInt(prescriberID)
+		prescriptions = append(prescriptions, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range prescriptions {
+		items, err := h.prescriptionItems(prescriptions[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		prescriptions[i].Items = items
+	}
+	return prescriptions, nil
+}
+
+func (h *PrescriptionHandler) prescriptionItems(prescriptionID int) ([]models.PrescriptionItem, error) {
+	rows, err := h.db.Query(`SELECT id, drug_id, drug_name, strength, dose, frequency, duration, instructions
+	                         FROM prescription_items WHERE prescription_id = ? ORDER BY id`, prescriptionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prescription items: %v", err)
+	}
+	defer rows.Close()
+
+	items := make([]models.PrescriptionItem, 0)
+	for rows.Next() {
+		var item models.PrescriptionItem
+		var drugID sql.NullInt64
+		if err := rows.Scan(&item.ID, &drugID, &item.DrugName, &item.Strength, &item.Dose, &item.Frequency, &item.Duration, &item.Instructions); err != nil {
+			return nil, fmt.Errorf("failed to scan prescription item: %v", err)
+		}
+		item.DrugID = nullableInt(drugID)
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// ExportPrescriptionPDF renders a prescription as a PDF in the patient's patient_data/<id> folder
+// and returns the file path
+func (h *PrescriptionHandler) ExportPrescriptionPDF(id int, clinic pdfdoc.Clinic) (string, error) {
+	var doc pdfdoc.Prescription
+	var prescribedAt, dateOfBirth string
+	err := h.db.QueryRow(`SELECT rx.patient_id, COALESCE(p.name, ''), COALESCE(p.age, 0), COALESCE(p.date_of_birth, ''), COALESCE(p.allergies, ''),
+	                             COALESCE(u.username, ''), rx.prescribed_at, rx.notes
+	                      FROM prescriptions rx
+	                      LEFT JOIN patients p ON rx.patient_id = p.id
+	                      LEFT JOIN users u ON rx.prescriber_id = u.id
+	                      WHERE rx.id = ?`, id).Scan(
+		&doc.PatientID, &doc.PatientName, &doc.PatientAge, &dateOfBirth, &doc.Allergies, &doc.Prescriber, &prescribedAt, &doc.Notes)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("prescription not found")
+	} else if err != nil {
+		return "", fmt.Errorf("failed to get prescription: %v", err)
+	}
+	doc.PatientAge = patientAge(dateOfBirth, doc.PatientAge)
+	doc.Number = fmt.Sprintf("RX-%05d", id)
+	doc.Date = prescribedAt
+	if len(doc.Date) > 10 {
+		doc.Date = doc.Date[:10]
+	}
+	if doc.Items, err = h.prescriptionItems(id); err != nil {
+		return "", err
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current working directory: %v", err)
+	}
+	folderPath := filepath.Join(cwd, "patient_data", fmt.Sprintf("%d", doc.PatientID))
+	if err := os.MkdirAll(folderPath, 0755); err != nil {
+		return "", fmt.Errorf("failed to create patient folder: %v", err)
+	}
+
+	// Render to a temporary file first so a failed export never leaves a half-written PDF
+	pdfPath := filepath.Join(folderPath, fmt.Sprintf("Prescription-%s.pdf", doc.Number))
+	tmp, err := os.CreateTemp(folderPath, ".prescription-*.pdf")
+	if err != nil {
+		return "", fmt.Errorf("failed to create prescription file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := pdfdoc.RenderPrescription(tmp, clinic, doc); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write prescription file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), pdfPath); err != nil {
+		return "", fmt.Errorf("failed to save prescription file: %v", err)
+	}
+	return pdfPath, nil
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"DentistApp/models"
+	"DentistApp/pdfdoc"
+)
+
+func TestPrescriptionAllergyChecks(t *testing.T) {
+	db, admin := newTestAdmin(t)
+
+	// The PDF is written under patient_data in the working directory
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(cwd) })
+
+	patientID := newTestPatient(t, db, models.PatientForm{Name: "Jane Doe", Phone: "0100000000",
+		Allergies: "Penicillin (rash)", CurrentMedications: "Warfarin 5 mg daily"})
+	sessionID, err := NewSessionHandler(db).CreateSession(models.SessionForm{
+		PatientID: patientID, DentistID: admin.ID, SessionDate: "2025-03-01", Status: "completed",
+	}, admin.ID)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	rx := NewPrescriptionHandler(db)
+	drugs, err := rx.GetDrugs()
+	if err != nil {
+		t.Fatalf("GetDrugs failed: %v", err)
+	}
+	byName := map[string]models.Drug{}
+	for _, d := range drugs {
+		byName[d.Name] = d
+	}
+	line := func(name string) models.PrescriptionItem {
+		d, ok := byName[name]
+		if !ok {
+			t.Fatalf("%s is not in the seeded catalogue", name)
+		}
+		return models.PrescriptionItem{DrugID: &d.ID, DrugName: d.Name, Strength: d.Strength, Dose: d.DefaultDose, Frequency: d.DefaultFrequency, Duration: d.DefaultDuration}
+	}
+
+	// Amoxicillin is a penicillin; ibuprofen is an NSAID and the patient takes warfarin
+	alerts, err := rx.CheckPrescription(int(sessionID), []models.PrescriptionItem{line("Amoxicillin"), line("Ibuprofen"), line("Paracetamol")})
+	if err != nil {
+		t.Fatalf("CheckPrescription failed: %v", err)
+	}
+	var allergy, interaction bool
+	for _, alert := range alerts {
+		switch {
+		case alert.DrugName == "Amoxicillin" && alert.Kind == models.DrugRuleAllergy && alert.Term == "penicillin":
+			allergy = true
+		case alert.DrugName == "Ibuprofen" && alert.Kind == models.DrugRuleInteraction && alert.Term == "warfarin":
+			interaction = true
+		case alert.DrugName == "Paracetamol":
+			t.Errorf("paracetamol raised %+v", alert)
+		}
+	}
+	if !allergy || !interaction {
+		t.Errorf("alerts = %+v; expected the penicillin allergy and the warfarin interaction", alerts)
+	}
+
+	// A rule added by the clinic is applied, and a drug named in the allergies is always flagged
+	if _, err := rx.CreateDrugRule(models.DrugRule{Kind: models.DrugRuleInteraction, DrugClass: "Paracetamol", Term: "Warfarin", Severity: models.DrugRuleWarning, Message: "Regular paracetamol raises the INR"}, admin.ID); err != nil {
+		t.Fatalf("CreateDrugRule failed: %v", err)
+	}
+	if _, err := rx.CreateDrugRule(models.DrugRule{Kind: "food", DrugClass: "nsaid", Term: "x", Severity: models.DrugRuleWarning}, admin.ID); err == nil {
+		t.Errorf("CreateDrugRule accepted an unknown kind")
+	}
+	alerts, _ = rx.CheckPrescription(int(sessionID), []models.PrescriptionItem{line("Paracetamol"), {DrugName: "penicillin V", Dose: "1", Frequency: "daily"}})
+	if len(alerts) != 1 || alerts[0].Message != "Regular paracetamol raises the INR" {
+		t.Errorf("alerts = %+v; expected only the clinic's paracetamol rule", alerts)
+	}
+	if _, err := db.Exec(`UPDATE patients SET allergies = 'Penicillin V, latex' WHERE id = ?`, patientID); err != nil {
+		t.Fatal(err)
+	}
+	alerts, _ = rx.CheckPrescription(int(sessionID), []models.PrescriptionItem{{DrugName: "Penicillin V", Dose: "1", Frequency: "daily"}})
+	if len(alerts) != 1 || alerts[0].Severity != models.DrugRuleContraindicated {
+		t.Errorf("alerts = %+v; expected the named allergy", alerts)
+	}
+
+	// Saving with alerts needs an override reason
+	form := models.Prescription{SessionID: int(sessionID), Items: []models.PrescriptionItem{line("Ibuprofen")}}
+	if _, err := rx.CreatePrescription(form, admin.ID); err == nil || !strings.Contains(err.Error(), "warfarin") {
+		t.Errorf("CreatePrescription err = %v; expected the warfarin interaction", err)
+	}
+	form.OverrideReason = "Short course agreed with the cardiologist"
+	id, err := rx.CreatePrescription(form, admin.ID)
+	if err != nil {
+		t.Fatalf("CreatePrescription failed: %v", err)
+	}
+	if _, err := rx.CreatePrescription(models.Prescription{SessionID: int(sessionID), Items: []models.PrescriptionItem{{DrugName: "Paracetamol"}}}, admin.ID); err == nil {
+		t.Errorf("CreatePrescription accepted a line without dose and frequency")
+	}
+
+	prescriptions, err := rx.GetPatientPrescriptions(patientID)
+	if err != nil {
+		t.Fatalf("GetPatientPrescriptions failed: %v", err)
+	}
+	if len(prescriptions) != 1 || prescriptions[0].OverrideReason == "" || len(prescriptions[0].Items) != 1 || prescriptions[0].Items[0].Strength != "400 mg" {
+		t.Fatalf("prescriptions = %+v", prescriptions)
+	}
+
+	path, err := rx.ExportPrescriptionPDF(int(id), pdfdoc.Clinic{Name: "Smile Clinic"})
+	if err != nil {
+		t.Fatalf("ExportPrescriptionPDF failed: %v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read PDF: %v", err)
+	}
+	if !bytes.HasPrefix(content, []byte("%PDF-")) {
+		t.Errorf("exported file is not a PDF")
+	}
+
+	if err := rx.DeletePrescription(int(id), admin.ID); err != nil {
+		t.Fatalf("DeletePrescription failed: %v", err)
+	}
+	if prescriptions, _ := rx.GetSessionPrescriptions(int(sessionID)); len(prescriptions) != 0 {
+		t.Errorf("prescriptions after delete = %+v", prescriptions)
+	}
+}
@@ -19,7 +19,7 @@ func NewProcedureHandler(db *sql.DB) *ProcedureHandler {
 }
 
 // CreateProcedure inserts new procedure
-func (h *ProcedureHandler) CreateProcedure(procedure models.ProcedureForm) (int64, error) {
+func (h *ProcedureHandler) CreateProcedure(procedure models.ProcedureForm, actorID int) (int64, error) {
 	log.Printf("[ProcedureHandler] CreateProcedure called with name: %s, price: %d", procedure.Name, procedure.Price)
 	
 	if procedure.Name == "" {
@@ -34,17 +34,11 @@ func (h *ProcedureHandler) CreateProcedure(procedure models.ProcedureForm) (int6
 	query := `INSERT INTO dental_procedures (name, price) VALUES (?, ?)`
 	log.Printf("[ProcedureHandler] Executing query: %s with values: name=%s, price=%d", query, procedure.Name, procedure.Price)
 	
-	result, err := h.db.Exec(query, procedure.Name, procedure.Price)
+	id, err := auditedInsert(h.db, actorID, auditProcedure, query, procedure.Name, procedure.Price)
 	if err != nil {
 		log.Printf("[ProcedureHandler] Database error: %v", err)
 		return 0, err
 	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		log.Printf("[ProcedureHandler] Error getting last insert ID: %v", err)
-		return 0, err
-	}
 	
 	log.Printf("[ProcedureHandler] Procedure created successfully with ID: %d", id)
 	return id, nil
@@ -129,7 +123,7 @@ func (h *ProcedureHandler) GetProceduresPaginated(page, pageSize int) (*models.P
 }
 
 // UpdateProcedure updates procedure by id
-func (h *ProcedureHandler) UpdateProcedure(procedure models.Procedure) error {
+func (h *ProcedureHandler) UpdateProcedure(procedure models.Procedure, actorID int) error {
 	if procedure.Name == "" {
 		return fmt.Errorf("procedure name is required")
 	}
@@ -138,13 +132,14 @@ func (h *ProcedureHandler) UpdateProcedure(procedure models.Procedure) error {
 	}
 
 	query := `UPDATE dental_procedures SET name = ?, price = ? WHERE id = ?`
-	_, err := h.db.Exec(query, procedure.Name, procedure.Price, procedure.ID)
+	_, err := auditedExec(h.db, actorID, auditProcedure, int64(procedure.ID), AuditActionUpdate, query,
+		procedure.Name, procedure.Price, procedure.ID)
 	return err
 }
 
 // DeleteProcedure deletes procedure by id
-func (h *ProcedureHandler) DeleteProcedure(id int) error {
+func (h *ProcedureHandler) DeleteProcedure(id int, actorID int) error {
 	query := `DELETE FROM dental_procedures WHERE id = ?`
-	_, err := h.db.Exec(query, id)
+	_, err := auditedExec(h.db, actorID, auditProcedure, int64(id), AuditActionDelete, query, id)
 	return err
 }
@@ -0,0 +1,417 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"DentistApp/models"
+	"DentistApp/reminders"
+)
+
+// outboxTimeLayout is the format of outbox timestamps; it sorts as text
+const outboxTimeLayout = "2006-01-02 15:04:05"
+
+// reminderClaimLease is how long a claimed message is reserved for the scheduler sending it
+const reminderClaimLease = 10 * time.Minute
+
+// reminderTestLog receives the messages sent while reminders are in test mode
+const reminderTestLog = "reminder_test_messages.log"
+
+// ReminderHandler handles the reminder settings and the outbox of reminder messages. It implements
+// reminders.Outbox.
+type ReminderHandler struct {
+	db *sql.DB
+}
+
+// NewReminderHandler creates new handler
+func NewReminderHandler(db *sql.DB) *ReminderHandler {
+	return &ReminderHandler{db: db}
+}
+
+// GetReminderSettings returns the reminder settings without the SMS API key and SMTP password
+func (h *ReminderHandler) GetReminderSettings() (*models.ReminderSettings, error) {
+	settings, err := h.loadSettings()
+	if err != nil {
+		return nil, err
+	}
+	settings.HasSMSAPIKey = settings.SMSAPIKey != ""
+	settings.HasSMTPPassword = settings.SMTPPassword != ""
+	settings.SMSAPIKey = ""
+	settings.SMTPPassword = ""
+	return settings, nil
+}
+
+// SaveReminderSettings updates the reminder settings. An empty API key or password keeps the current one.
+func (h *ReminderHandler) SaveReminderSettings(settings models.ReminderSettings, actorID int) error {
+	if settings.LeadHours < 1 || settings.LeadHours > 168 {
+		return fmt.Errorf("reminders must be sent between 1 hour and 7 days before the appointment")
+	}
+	if settings.MaxAttempts < 1 || settings.MaxAttempts > 10 {
+		return fmt.Errorf("delivery attempts must be between 1 and 10")
+	}
+	if settings.Enabled && !settings.SMSEnabled && !settings.EmailEnabled {
+		return fmt.Errorf("choose SMS, email or both to send reminders")
+	}
+	for _, tmpl := range []string{settings.SMSTemplate, settings.EmailSubject, settings.EmailTemplate} {
+		if err := reminders.ValidateTemplate(tmpl); err != nil {
+			return err
+		}
+	}
+
+	current, err := h.loadSettings()
+	if err != nil {
+		return err
+	}
+	if settings.SMSAPIKey == "" {
+		settings.SMSAPIKey = current.SMSAPIKey
+	}
+	if settings.SMTPPassword == "" {
+		settings.SMTPPassword = current.SMTPPassword
+	}
+	if settings.SMTPPort == 0 {
+		settings.SMTPPort = 587
+	}
+	if settings.SMTPPort < 1 || settings.SMTPPort > 65535 {
+		return fmt.Errorf("invalid SMTP port %d", settings.SMTPPort)
+	}
+	if settings.Enabled && !settings.TestMode {
+		if settings.SMSEnabled {
+			if u, err := url.Parse(settings.SMSURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("enter the SMS gateway URL, starting with https://")
+			}
+		}
+		if settings.EmailEnabled {
+			if strings.TrimSpace(settings.SMTPHost) == "" {
+				return fmt.Errorf("enter the SMTP server to send email reminders")
+			}
+			if _, err := mail.ParseAddress(settings.SMTPFrom); err != nil {
+				return fmt.Errorf("invalid sender email address %q", settings.SMTPFrom)
+			}
+		}
+	}
+
+	query := `UPDATE reminder_settings SET enabled = ?, lead_hours = ?, sms_enabled = ?, email_enabled = ?,
+	          sms_template = ?, email_subject = ?, email_template = ?, test_mode = ?, max_attempts = ?,
+	          sms_url = ?, sms_api_key = ?, sms_from = ?, smtp_host = ?, smtp_port = ?, smtp_username = ?,
+	          smtp_password = ?, smtp_from = ?, updated_at = ?
+	          WHERE id = 1`
+	_, err = auditedExec(h.db, actorID, auditReminderSettings, 1, AuditActionUpdate, query,
+		settings.Enabled, settings.LeadHours, settings.SMSEnabled, settings.EmailEnabled,
+		settings.SMSTemplate, settings.EmailSubject, settings.EmailTemplate, settings.TestMode, settings.MaxAttempts,
+		strings.TrimSpace(settings.SMSURL), settings.SMSAPIKey, strings.TrimSpace(settings.SMSFrom),
+		strings.TrimSpace(settings.SMTPHost), settings.SMTPPort, settings.SMTPUsername,
+		settings.SMTPPassword, strings.TrimSpace(settings.SMTPFrom), time.Now().Format(outboxTimeLayout))
+	if err != nil {
+		return fmt.Errorf("failed to save reminder settings: %v", err)
+	}
+	return nil
+}
+
+// Config returns the settings in the form used by reminders.Scheduler. In test mode every channel
+// writes to the test log instead of sending.
+func (h *ReminderHandler) Config() (reminders.Config, error) {
+	settings, err := h.loadSettings()
+	if err != nil {
+		return reminders.Config{}, err
+	}
+	config := reminders.Config{
+		Enabled:     settings.Enabled,
+		MaxAttempts: settings.MaxAttempts,
+		Senders:     map[string]reminders.Sender{},
+	}
+	if settings.TestMode {
+		sender := &reminders.FileSender{Path: reminderTestLog}
+		config.Senders[reminders.ChannelSMS] = sender
+		config.Senders[reminders.ChannelEmail] = sender
+		return config, nil
+	}
+	if settings.SMSURL != "" {
+		config.Senders[reminders.ChannelSMS] = &reminders.HTTPSMSSender{URL: settings.SMSURL, APIKey: settings.SMSAPIKey, From: settings.SMSFrom}
+	}
+	if settings.SMTPHost != "" {
+		config.Senders[reminders.ChannelEmail] = &reminders.SMTPSender{
+			Host:     settings.SMTPHost,
+			Port:     settings.SMTPPort,
+			Username: settings.SMTPUsername,
+			Password: settings.SMTPPassword,
+			From:     settings.SMTPFrom,
+		}
+	}
+	return config, nil
+}
+
+// QueueDue queues a reminder on each enabled channel for every scheduled or confirmed appointment
+// starting within the lead time after now. Each appointment time is reminded once per channel, so
+// a rescheduled appointment gets a new reminder.
+func (h *ReminderHandler) QueueDue(now time.Time) (int, error) {
+	settings, err := h.loadSettings()
+	if err != nil {
+		return 0, err
+	}
+	if !settings.Enabled {
+		return 0, nil
+	}
+	clinic, err := loadClinicSettings(h.db)
+	if err != nil {
+		return 0, err
+	}
+
+	end := now.Add(time.Duration(settings.LeadHours) * time.Hour)
+	lower, upper := appointmentScanBounds(now, end)
+	rows, err := h.db.Query(`SELECT a.id, a.patient_id, a.datetime, p.name, COALESCE(p.phone, ''), p.email, COALESCE(u.username, '')
+	                         FROM appointments a
+	                         JOIN patients p ON a.patient_id = p.id
+	                         LEFT JOIN users u ON a.dentist_id = u.id
+	                         WHERE a.status IN (?, ?) AND a.datetime >= ? AND a.datetime < ?`,
+		models.AppointmentScheduled, models.AppointmentConfirmed, lower, upper)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find appointments to remind: %v", err)
+	}
+	type reminder struct {
+		appointmentID, patientID int
+		datetime                 string
+		channel, recipient       string
+		subject, body            string
+	}
+	var queue []reminder
+	for rows.Next() {
+		var id, patientID int
+		var datetime, name, phone, email, dentist string
+		if err := rows.Scan(&id, &patientID, &datetime, &name, &phone, &email, &dentist); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan appointment: %v", err)
+		}
+		start, err := parseAppointmentTime(datetime)
+		if err != nil || !start.After(now) || start.After(end) {
+			continue
+		}
+		data := reminders.TemplateData{Patient: name, Dentist: dentist, Clinic: clinic.Name, Start: start}
+		if settings.SMSEnabled && strings.TrimSpace(phone) != "" {
+			queue = append(queue, reminder{id, patientID, datetime, reminders.ChannelSMS, strings.TrimSpace(phone),
+				"", reminders.Render(settings.SMSTemplate, data)})
+		}
+		if settings.EmailEnabled && email != "" {
+			queue = append(queue, reminder{id, patientID, datetime, reminders.ChannelEmail, email,
+				reminders.Render(settings.EmailSubject, data), reminders.Render(settings.EmailTemplate, data)})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	stamp := now.Format(outboxTimeLayout)
+	for _, r := range queue {
+		res, err := h.db.Exec(`INSERT OR IGNORE INTO outbound_messages
+		                       (appointment_id, patient_id, appointment_datetime, channel, recipient, subject, body, status, next_attempt_at, dedupe_key, created_at)
+		                       VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			r.appointmentID, r.patientID, r.datetime, r.channel, r.recipient, r.subject, r.body, models.MessagePending, stamp,
+			fmt.Sprintf("reminder:%d:%s:%s", r.appointmentID, r.channel, r.datetime), stamp)
+		if err != nil {
+			return queued, fmt.Errorf("failed to queue reminder: %v", err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			queued++
+		}
+	}
+	return queued, nil
+}
+
+// Claim returns up to limit pending messages that are due at now and reserves each for
+// reminderClaimLease. Reminders for appointments that were cancelled, moved, deleted or have already
+// started are cancelled instead.
+func (h *ReminderHandler) Claim(now time.Time, limit int) ([]reminders.Message, error) {
+	_, err := h.db.Exec(`UPDATE outbound_messages SET status = ?, last_error = 'appointment was cancelled or moved'
+	                     WHERE status = ? AND appointment_datetime != '' AND NOT EXISTS (
+	                         SELECT 1 FROM appointments a
+	                         WHERE a.id = outbound_messages.appointment_id AND a.status IN (?, ?)
+	                           AND a.datetime = outbound_messages.appointment_datetime)`,
+		models.MessageCancelled, models.MessagePending, models.AppointmentScheduled, models.AppointmentConfirmed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel outdated reminders: %v", err)
+	}
+
+	stamp := now.Format(outboxTimeLayout)
+	rows, err := h.db.Query(`SELECT id, channel, recipient, subject, body, attempts, next_attempt_at, appointment_datetime
+	                         FROM outbound_messages
+	                         WHERE status = ? AND next_attempt_at <= ?
+	                         ORDER BY next_attempt_at, id LIMIT ?`, models.MessagePending, stamp, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pending messages: %v", err)
+	}
+	type candidate struct {
+		msg                reminders.Message
+		due, appointmentAt string
+	}
+	var candidates []candidate
+	for rows.Next() {
+		var c candidate
+		if err := rows.Scan(&c.msg.ID, &c.msg.Channel, &c.msg.Recipient, &c.msg.Subject, &c.msg.Body, &c.msg.Attempts, &c.due, &c.appointmentAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan message: %v", err)
+		}
+		candidates = append(candidates, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	lease := now.Add(reminderClaimLease).Format(outboxTimeLayout)
+	messages := []reminders.Message{}
+	for _, c := range candidates {
+		if start, err := parseAppointmentTime(c.appointmentAt); err == nil && !start.After(now) {
+			if _, err := h.db.Exec(`UPDATE outbound_messages SET status = ?, last_error = 'appointment has already started' WHERE id = ? AND status = ?`,
+				models.MessageCancelled, c.msg.ID, models.MessagePending); err != nil {
+				return nil, fmt.Errorf("failed to cancel message: %v", err)
+			}
+			continue
+		}
+		res, err := h.db.Exec(`UPDATE outbound_messages SET next_attempt_at = ? WHERE id = ? AND status = ? AND next_attempt_at = ?`,
+			lease, c.msg.ID, models.MessagePending, c.due)
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim message: %v", err)
+		}
+		if n, _ := res.RowsAffected(); n == 1 {
+			messages = append(messages, c.msg)
+		}
+	}
+	return messages, nil
+}
+
+// MarkSent records a delivered message
+func (h *ReminderHandler) MarkSent(id int64, at time.Time) error {
+	_, err := h.db.Exec(`UPDATE outbound_messages SET status = ?, sent_at = ?, last_error = '' WHERE id = ?`,
+		models.MessageSent, at.Format(outboxTimeLayout), id)
+	return err
+}
+
+// MarkFailed records a failed attempt and schedules the retry, or marks the message failed when
+// retryAt is zero
+func (h *ReminderHandler) MarkFailed(id int64, at time.Time, reason string, retryAt time.Time) error {
+	status, next := models.MessagePending, retryAt.Format(outboxTimeLayout)
+	if retryAt.IsZero() {
+		status, next = models.MessageFailed, at.Format(outboxTimeLayout)
+	}
+	_, err := h.db.Exec(`UPDATE outbound_messages SET status = ?, attempts = attempts + 1, last_error = ?, next_attempt_at = ? WHERE id = ?`,
+		status, reason, next, id)
+	return err
+}
+
+// GetOutboundMessages returns a page of the outbox, newest first, optionally with one status
+func (h *ReminderHandler) GetOutboundMessages(status string, page, pageSize int) (*models.OutboundMessageList, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	where := ""
+	var args []any
+	if status != "" {
+		where = "WHERE m.status = ?"
+		args = append(args, status)
+	}
+
+	var totalCount int
+	if err := h.db.QueryRow(`SELECT COUNT(*) FROM outbound_messages m `+where, args...).Scan(&totalCount); err != nil {
+		return nil, fmt.Errorf("failed to count messages: %v", err)
+	}
+	totalPages := int(math.Ceil(float64(totalCount) / float64(pageSize)))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+	if page > totalPages {
+		page = totalPages
+	}
+
+	rows, err := h.db.Query(`SELECT m.id, m.appointment_id, m.patient_id, COALESCE(p.name, ''), m.channel, m.recipient, m.subject, m.body,
+	                         m.status, m.attempts, m.next_attempt_at, m.last_error, m.created_at, COALESCE(m.sent_at, '')
+	                         FROM outbound_messages m LEFT JOIN patients p ON m.patient_id = p.id `+where+`
+	                         ORDER BY m.id DESC LIMIT ? OFFSET ?`, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages: %v", err)
+	}
+	defer rows.Close()
+
+	list := &models.OutboundMessageList{Messages: []models.OutboundMessage{}, CurrentPage: page, TotalPages: totalPages, TotalCount: totalCount, PageSize: pageSize}
+	for rows.Next() {
+		var m models.OutboundMessage
+		var appointmentID, patientID sql.NullInt64
+		if err := rows.Scan(&m.ID, &appointmentID, &patientID, &m.PatientName, &m.Channel, &m.Recipient, &m.Subject, &m.Body,
+			&m.Status, &m.Attempts, &m.NextAttemptAt, &m.LastError, &m.CreatedAt, &m.SentAt); err != nil {
+			return nil, fmt.Errorf("failed to scan message: %v", err)
+		}
+		if appointmentID.Valid {
+			id := int(appointmentID.Int64)
+			m.AppointmentID = &id
+		}
+		if patientID.Valid {
+			id := int(patientID.Int64)
+			m.PatientID = &id
+		}
+		list.Messages = append(list.Messages, m)
+	}
+	return list, rows.Err()
+}
+
+// RetryOutboundMessage sends a failed or cancelled message again on the next run
+func (h *ReminderHandler) RetryOutboundMessage(id int, actorID int) error {
+	res, err := auditedExec(h.db, actorID, auditOutboundMessage, int64(id), AuditActionUpdate,
+		`UPDATE outbound_messages SET status = ?, attempts = 0, next_attempt_at = ? WHERE id = ? AND status IN (?, ?)`,
+		models.MessagePending, time.Now().Format(outboxTimeLayout), id, models.MessageFailed, models.MessageCancelled)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("only failed or cancelled messages can be retried")
+	}
+	return nil
+}
+
+// CancelOutboundMessage stops a pending message from being sent
+func (h *ReminderHandler) CancelOutboundMessage(id int, actorID int) error {
+	res, err := auditedExec(h.db, actorID, auditOutboundMessage, int64(id), AuditActionUpdate,
+		`UPDATE outbound_messages SET status = ?, last_error = 'cancelled by staff' WHERE id = ? AND status = ?`,
+		models.MessageCancelled, id, models.MessagePending)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("only pending messages can be cancelled")
+	}
+	return nil
+}
+
+// loadSettings returns the stored settings with the default templates filled in where none are set
+func (h *ReminderHandler) loadSettings() (*models.ReminderSettings, error) {
+	var s models.ReminderSettings
+	var updatedAt sql.NullString
+	err := h.db.QueryRow(`SELECT enabled, lead_hours, sms_enabled, email_enabled, sms_template, email_subject, email_template,
+	                             test_mode, max_attempts, sms_url, sms_api_key, sms_from, smtp_host, smtp_port, smtp_username,
+	                             smtp_password, smtp_from, updated_at
+	                      FROM reminder_settings WHERE id = 1`).Scan(
+		&s.Enabled, &s.LeadHours, &s.SMSEnabled, &s.EmailEnabled, &s.SMSTemplate, &s.EmailSubject, &s.EmailTemplate,
+		&s.TestMode, &s.MaxAttempts, &s.SMSURL, &s.SMSAPIKey, &s.SMSFrom, &s.SMTPHost, &s.SMTPPort, &s.SMTPUsername,
+		&s.SMTPPassword, &s.SMTPFrom, &updatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load reminder settings: %v", err)
+	}
+	if s.SMSTemplate == "" {
+		s.SMSTemplate = reminders.DefaultSMSTemplate
+	}
+	if s.EmailSubject == "" {
+		s.EmailSubject = reminders.DefaultEmailSubject
+	}
+	if s.EmailTemplate == "" {
+		s.EmailTemplate = reminders.DefaultEmailTemplate
+	}
+	s.UpdatedAt = updatedAt.String
+	return &s, nil
+}
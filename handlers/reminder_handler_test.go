@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"DentistApp/models"
+	"DentistApp/reminders"
+)
+
+func TestReminderOutbox(t *testing.T) {
+	db, admin := newTestAdmin(t)
+	newTestPatient(t, db, models.PatientForm{Name: "Jane Doe", Phone: "0100000000", Email: "jane@example.com"})
+
+	handler := NewReminderHandler(db)
+	settings, err := handler.GetReminderSettings()
+	if err != nil {
+		t.Fatalf("GetReminderSettings failed: %v", err)
+	}
+	if settings.Enabled || settings.SMSTemplate != reminders.DefaultSMSTemplate {
+		t.Errorf("expected reminders to be off with default templates, got %+v", settings)
+	}
+	settings.Enabled = true
+	settings.EmailEnabled = true
+	settings.TestMode = false
+	if err := handler.SaveReminderSettings(*settings, admin.ID); err == nil {
+		t.Errorf("expected sending without a configured gateway to be rejected")
+	}
+	settings.SMSURL = "https://sms.example.com/send"
+	settings.SMSAPIKey = "key-123"
+	settings.SMTPHost = "smtp.example.com"
+	settings.SMTPFrom = "clinic@example.com"
+	settings.SMTPPassword = "hunter2"
+	if err := handler.SaveReminderSettings(*settings, admin.ID); err != nil {
+		t.Fatalf("SaveReminderSettings failed: %v", err)
+	}
+	saved, err := handler.GetReminderSettings()
+	if err != nil {
+		t.Fatalf("GetReminderSettings failed: %v", err)
+	}
+	if saved.SMTPPassword != "" || saved.SMSAPIKey != "" || !saved.HasSMTPPassword || !saved.HasSMSAPIKey {
+		t.Errorf("expected secrets to be hidden but flagged, got %+v", saved)
+	}
+	// Saving without the secrets keeps them
+	saved.TestMode = true
+	if err := handler.SaveReminderSettings(*saved, admin.ID); err != nil {
+		t.Fatalf("SaveReminderSettings failed: %v", err)
+	}
+	if config, err := handler.Config(); err != nil || len(config.Senders) != 2 {
+		t.Errorf("expected test mode to log both channels, got %+v %v", config, err)
+	}
+	if again, _ := handler.GetReminderSettings(); !again.HasSMTPPassword || !again.HasSMSAPIKey {
+		t.Errorf("expected secrets to be kept when left empty")
+	}
+
+	now := time.Now().Truncate(time.Minute)
+	soon := now.Add(3 * time.Hour).Format(seriesTimeLayout)
+	later := now.Add(72 * time.Hour).Format(seriesTimeLayout)
+	appointments := NewAppointmentHandler(db)
+	soonID, err := appointments.AddAppointment(models.Appointment{PatientID: 1, DateTime: soon, Duration: 30}, admin.ID)
+	if err != nil {
+		t.Fatalf("AddAppointment failed: %v", err)
+	}
+	if _, err := appointments.AddAppointment(models.Appointment{PatientID: 1, DateTime: later, Duration: 30}, admin.ID); err != nil {
+		t.Fatalf("AddAppointment failed: %v", err)
+	}
+
+	queued, err := handler.QueueDue(now)
+	if err != nil || queued != 2 {
+		t.Fatalf("expected an SMS and an email for the appointment within 24 hours, got %d %v", queued, err)
+	}
+	if queued, err := handler.QueueDue(now); err != nil || queued != 0 {
+		t.Errorf("expected reminders to be queued once, got %d %v", queued, err)
+	}
+
+	messages, err := handler.Claim(now, 10)
+	if err != nil || len(messages) != 2 {
+		t.Fatalf("expected 2 messages to be claimed, got %v %v", messages, err)
+	}
+	if claimed, _ := handler.Claim(now, 10); len(claimed) != 0 {
+		t.Errorf("expected claimed messages not to be handed out again, got %v", claimed)
+	}
+	var sms, email reminders.Message
+	for _, m := range messages {
+		if m.Channel == reminders.ChannelSMS {
+			sms = m
+		} else {
+			email = m
+		}
+	}
+	if sms.Recipient != "0100000000" || email.Recipient != "jane@example.com" || email.Subject == "" {
+		t.Errorf("unexpected messages %+v %+v", sms, email)
+	}
+	if err := handler.MarkSent(sms.ID, now); err != nil {
+		t.Fatalf("MarkSent failed: %v", err)
+	}
+	retryAt := now.Add(5 * time.Minute)
+	if err := handler.MarkFailed(email.ID, now, "connection refused", retryAt); err != nil {
+		t.Fatalf("MarkFailed failed: %v", err)
+	}
+	if claimed, _ := handler.Claim(now, 10); len(claimed) != 0 {
+		t.Errorf("expected the failed email to wait for its retry, got %v", claimed)
+	}
+	if claimed, _ := handler.Claim(retryAt, 10); len(claimed) != 1 || claimed[0].Attempts != 1 {
+		t.Errorf("expected the email to be retried with 1 attempt, got %v", claimed)
+	}
+	if err := handler.MarkFailed(email.ID, retryAt, "connection refused", retryAt.Add(10*time.Minute)); err != nil {
+		t.Fatalf("MarkFailed failed: %v", err)
+	}
+
+	// Moving the appointment cancels the pending reminder and queues a new one for the new time
+	moved := now.Add(5 * time.Hour).Format(seriesTimeLayout)
+	if _, err := db.Exec(`UPDATE appointments SET datetime = ? WHERE id = ?`, moved, soonID); err != nil {
+		t.Fatal(err)
+	}
+	if claimed, _ := handler.Claim(retryAt.Add(time.Hour), 10); len(claimed) != 0 {
+		t.Errorf("expected the reminder for the old time to be cancelled, got %v", claimed)
+	}
+	if queued, err := handler.QueueDue(now); err != nil || queued != 2 {
+		t.Errorf("expected new reminders for the moved appointment, got %d %v", queued, err)
+	}
+
+	list, err := handler.GetOutboundMessages(models.MessageCancelled, 1, 20)
+	if err != nil || list.TotalCount != 1 || list.Messages[0].PatientName != "Jane Doe" {
+		t.Fatalf("expected one cancelled message, got %+v %v", list, err)
+	}
+	cancelled := list.Messages[0].ID
+	if err := handler.CancelOutboundMessage(cancelled, admin.ID); err == nil {
+		t.Errorf("expected cancelling a cancelled message to fail")
+	}
+	if err := handler.RetryOutboundMessage(cancelled, admin.ID); err != nil {
+		t.Errorf("RetryOutboundMessage failed: %v", err)
+	}
+}
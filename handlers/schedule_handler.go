@@ -0,0 +1,434 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"DentistApp/models"
+)
+
+// maxFreeSlots caps the number of slots FindFreeSlots returns
+const maxFreeSlots = 200
+
+// ScheduleHandler handles dentists' working hours, clinic holidays and time off
+type ScheduleHandler struct {
+	db *sql.DB
+}
+
+// NewScheduleHandler creates new handler
+func NewScheduleHandler(db *sql.DB) *ScheduleHandler {
+	return &ScheduleHandler{db: db}
+}
+
+// OutsideWorkingHoursError is returned when an appointment falls outside its dentist's working hours,
+// on a clinic holiday or during the dentist's time off
+type OutsideWorkingHoursError struct {
+	Reason string
+}
+
+func (e *OutsideWorkingHoursError) Error() string {
+	return "appointment is outside working hours: " + e.Reason
+}
+
+// period is a span of wall-clock time
+type period struct {
+	start, end time.Time
+}
+
+// GetWorkingHours returns a user's weekly schedule ordered by weekday and start time
+func (h *ScheduleHandler) GetWorkingHours(userID int) ([]models.WorkingHours, error) {
+	rows, err := h.db.Query(`SELECT id, user_id, weekday, start_time, end_time FROM working_hours
+	                         WHERE user_id = ? ORDER BY weekday, start_time`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load working hours: %v", err)
+	}
+	defer rows.Close()
+
+	hours := []models.WorkingHours{}
+	for rows.Next() {
+		var wh models.WorkingHours
+		if err := rows.Scan(&wh.ID, &wh.UserID, &wh.Weekday, &wh.StartTime, &wh.EndTime); err != nil {
+			return nil, fmt.Errorf("failed to scan working hours: %v", err)
+		}
+		hours = append(hours, wh)
+	}
+	return hours, rows.Err()
+}
+
+// SetWorkingHours replaces a user's weekly schedule. Blocks on the same day must not overlap.
+// An empty schedule means the user's bookings are not checked against working hours.
+func (h *ScheduleHandler) SetWorkingHours(userID int, hours []models.WorkingHours, actorID int) error {
+	var exists bool
+	if err := h.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM users WHERE id = ?)`, userID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check user: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("user not found")
+	}
+
+	byDay := map[int][]period{}
+	for _, wh := range hours {
+		if wh.Weekday < 0 || wh.Weekday > 6 {
+			return fmt.Errorf("invalid weekday %d", wh.Weekday)
+		}
+		start, err := time.Parse("15:04", wh.StartTime)
+		if err != nil {
+			return fmt.Errorf("invalid start time %q", wh.StartTime)
+		}
+		end, err := time.Parse("15:04", wh.EndTime)
+		if err != nil {
+			return fmt.Errorf("invalid end time %q", wh.EndTime)
+		}
+		if !end.After(start) {
+			return fmt.Errorf("working hours must end after they start (%s-%s)", wh.StartTime, wh.EndTime)
+		}
+		for _, other := range byDay[wh.Weekday] {
+			if start.Before(other.end) && other.start.Before(end) {
+				return fmt.Errorf("working hours overlap on %s", time.Weekday(wh.Weekday))
+			}
+		}
+		byDay[wh.Weekday] = append(byDay[wh.Weekday], period{start, end})
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT id FROM working_hours WHERE user_id = ?`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to load working hours: %v", err)
+	}
+	var oldIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan working hours: %v", err)
+		}
+		oldIDs = append(oldIDs, id)
+	}
+	rows.Close()
+
+	for _, id := range oldIDs {
+		before, err := auditSnapshot(tx, auditWorkingHours, id)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM working_hours WHERE id = ?`, id); err != nil {
+			return fmt.Errorf("failed to clear working hours: %v", err)
+		}
+		if err := recordAudit(tx, actorID, auditWorkingHours, id, AuditActionDelete, before); err != nil {
+			return err
+		}
+	}
+	for _, wh := range hours {
+		result, err := tx.Exec(`INSERT INTO working_hours (user_id, weekday, start_time, end_time) VALUES (?, ?, ?, ?)`,
+			userID, wh.Weekday, wh.StartTime, wh.EndTime)
+		if err != nil {
+			return fmt.Errorf("failed to save working hours: %v", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		if err := recordAudit(tx, actorID, auditWorkingHours, id, AuditActionCreate, nil); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetClinicHolidays returns all clinic holidays ordered by date
+func (h *ScheduleHandler) GetClinicHolidays() ([]models.ClinicHoliday, error) {
+	rows, err := h.db.Query(`SELECT id, date, name FROM clinic_holidays ORDER BY date`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load holidays: %v", err)
+	}
+	defer rows.Close()
+
+	holidays := []models.ClinicHoliday{}
+	for rows.Next() {
+		var holiday models.ClinicHoliday
+		if err := rows.Scan(&holiday.ID, &holiday.Date, &holiday.Name); err != nil {
+			return nil, fmt.Errorf("failed to scan holiday: %v", err)
+		}
+		holidays = append(holidays, holiday)
+	}
+	return holidays, rows.Err()
+}
+
+// AddClinicHoliday marks a day as closed for the whole clinic
+func (h *ScheduleHandler) AddClinicHoliday(holiday models.ClinicHoliday, actorID int) (int64, error) {
+	if _, err := time.Parse("2006-01-02", holiday.Date); err != nil {
+		return 0, fmt.Errorf("invalid holiday date %q", holiday.Date)
+	}
+	holiday.Name = strings.TrimSpace(holiday.Name)
+	id, err := auditedInsert(h.db, actorID, auditClinicHoliday, `INSERT INTO clinic_holidays (date, name) VALUES (?, ?)`,
+		holiday.Date, holiday.Name)
+	if err != nil && strings.Contains(err.Error(), "UNIQUE") {
+		return 0, fmt.Errorf("%s is already a holiday", holiday.Date)
+	}
+	return id, err
+}
+
+// DeleteClinicHoliday removes a clinic holiday
+func (h *ScheduleHandler) DeleteClinicHoliday(id int, actorID int) error {
+	_, err := auditedExec(h.db, actorID, auditClinicHoliday, int64(id), AuditActionDelete, `DELETE FROM clinic_holidays WHERE id = ?`, id)
+	return err
+}
+
+// GetTimeOff returns time off ordered by start, for one user or for everyone when userID is 0
+func (h *ScheduleHandler) GetTimeOff(userID int) ([]models.TimeOff, error) {
+	query := `SELECT t.id, t.user_id, COALESCE(u.username, ''), t.start_at, t.end_at, t.reason
+	          FROM time_off t LEFT JOIN users u ON t.user_id = u.id`
+	var args []any
+	if userID > 0 {
+		query += ` WHERE t.user_id = ?`
+		args = append(args, userID)
+	}
+	rows, err := h.db.Query(query+` ORDER BY t.start_at`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load time off: %v", err)
+	}
+	defer rows.Close()
+
+	entries := []models.TimeOff{}
+	for rows.Next() {
+		var entry models.TimeOff
+		if err := rows.Scan(&entry.ID, &entry.UserID, &entry.Username, &entry.Start, &entry.End, &entry.Reason); err != nil {
+			return nil, fmt.Errorf("failed to scan time off: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// AddTimeOff records a period a dentist is unavailable
+func (h *ScheduleHandler) AddTimeOff(entry models.TimeOff, actorID int) (int64, error) {
+	start, err := parseAppointmentTime(entry.Start)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time off start %q", entry.Start)
+	}
+	end, err := parseAppointmentTime(entry.End)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time off end %q", entry.End)
+	}
+	if !end.After(start) {
+		return 0, fmt.Errorf("time off must end after it starts")
+	}
+	var exists bool
+	if err := h.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM users WHERE id = ?)`, entry.UserID).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("failed to check user: %v", err)
+	}
+	if !exists {
+		return 0, fmt.Errorf("user not found")
+	}
+	return auditedInsert(h.db, actorID, auditTimeOff, `INSERT INTO time_off (user_id, start_at, end_at, reason) VALUES (?, ?, ?, ?)`,
+		entry.UserID, start.Format(seriesTimeLayout), end.Format(seriesTimeLayout), strings.TrimSpace(entry.Reason))
+}
+
+// DeleteTimeOff removes a time off entry
+func (h *ScheduleHandler) DeleteTimeOff(id int, actorID int) error {
+	_, err := auditedExec(h.db, actorID, auditTimeOff, int64(id), AuditActionDelete, `DELETE FROM time_off WHERE id = ?`, id)
+	return err
+}
+
+// FindFreeSlots returns the open slots of duration minutes in a dentist's schedule on the days from
+// through to (YYYY-MM-DD, inclusive). Slots follow the working hours, skip holidays, time off and
+// booked appointments, and start no earlier than now.
+func (h *ScheduleHandler) FindFreeSlots(dentistID, duration int, from, to string) ([]models.FreeSlot, error) {
+	if duration < 5 || duration > maxAppointmentDuration {
+		return nil, fmt.Errorf("duration must be between 5 and %d minutes", maxAppointmentDuration)
+	}
+	start, err := time.ParseInLocation("2006-01-02", from, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date %q", from)
+	}
+	end, err := time.ParseInLocation("2006-01-02", to, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end date %q", to)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end date is before start date")
+	}
+	if end.Sub(start) > 62*24*time.Hour {
+		return nil, fmt.Errorf("free slots can be searched at most two months ahead")
+	}
+	end = end.AddDate(0, 0, 1)
+
+	free, hasSchedule, err := dentistAvailability(h.db, dentistID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if !hasSchedule {
+		return nil, fmt.Errorf("dentist has no working hours set")
+	}
+
+	booked, err := NewAppointmentHandler(h.db).appointmentsInRange(from, to, dentistID, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, appt := range booked {
+		if appt.Status == models.AppointmentCancelled || appt.Status == models.AppointmentNoShow {
+			continue
+		}
+		apptStart, err := parseAppointmentTime(appt.DateTime)
+		if err != nil {
+			continue
+		}
+		free = subtractPeriod(free, period{apptStart, apptStart.Add(time.Duration(appt.Duration) * time.Minute)})
+	}
+
+	length := time.Duration(duration) * time.Minute
+	now := time.Now()
+	slots := []models.FreeSlot{}
+	for _, p := range free {
+		// Start on a 5 minute boundary
+		slot := p.start.Truncate(5 * time.Minute)
+		if slot.Before(p.start) {
+			slot = slot.Add(5 * time.Minute)
+		}
+		for ; !slot.Add(length).After(p.end); slot = slot.Add(length) {
+			if slot.Before(now) {
+				continue
+			}
+			slots = append(slots, models.FreeSlot{Start: slot.Format(seriesTimeLayout), End: slot.Add(length).Format(seriesTimeLayout)})
+			if len(slots) >= maxFreeSlots {
+				return slots, nil
+			}
+		}
+	}
+	return slots, nil
+}
+
+// dentistAvailability returns the periods between from and to that a dentist is scheduled to work,
+// less clinic holidays and the dentist's time off, in order. hasSchedule is false when the dentist
+// has no working hours at all.
+func dentistAvailability(q queryRunner, dentistID int, from, to time.Time) (available []period, hasSchedule bool, err error) {
+	rows, err := q.Query(`SELECT weekday, start_time, end_time FROM working_hours WHERE user_id = ?`, dentistID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load working hours: %v", err)
+	}
+	blocks := map[time.Weekday][][2]string{}
+	for rows.Next() {
+		var weekday int
+		var startTime, endTime string
+		if err := rows.Scan(&weekday, &startTime, &endTime); err != nil {
+			rows.Close()
+			return nil, false, fmt.Errorf("failed to scan working hours: %v", err)
+		}
+		blocks[time.Weekday(weekday)] = append(blocks[time.Weekday(weekday)], [2]string{startTime, endTime})
+		hasSchedule = true
+	}
+	rows.Close()
+	if !hasSchedule {
+		return nil, false, nil
+	}
+
+	holidays := map[string]bool{}
+	rows, err = q.Query(`SELECT date FROM clinic_holidays WHERE date >= ? AND date <= ?`,
+		from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load holidays: %v", err)
+	}
+	for rows.Next() {
+		var date string
+		if err := rows.Scan(&date); err != nil {
+			rows.Close()
+			return nil, false, fmt.Errorf("failed to scan holiday: %v", err)
+		}
+		holidays[date] = true
+	}
+	rows.Close()
+
+	for day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.Local); day.Before(to); day = day.AddDate(0, 0, 1) {
+		if holidays[day.Format("2006-01-02")] {
+			continue
+		}
+		for _, block := range blocks[day.Weekday()] {
+			blockStart, _ := time.ParseInLocation("2006-01-02 15:04", day.Format("2006-01-02 ")+block[0], time.Local)
+			blockEnd, _ := time.ParseInLocation("2006-01-02 15:04", day.Format("2006-01-02 ")+block[1], time.Local)
+			available = append(available, period{blockStart, blockEnd})
+		}
+	}
+	sort.Slice(available, func(i, j int) bool { return available[i].start.Before(available[j].start) })
+
+	rows, err = q.Query(`SELECT start_at, end_at FROM time_off WHERE user_id = ? AND start_at < ? AND end_at > ?`,
+		dentistID, to.Format(seriesTimeLayout), from.Format(seriesTimeLayout))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load time off: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var startAt, endAt string
+		if err := rows.Scan(&startAt, &endAt); err != nil {
+			return nil, false, fmt.Errorf("failed to scan time off: %v", err)
+		}
+		offStart, err1 := parseAppointmentTime(startAt)
+		offEnd, err2 := parseAppointmentTime(endAt)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		available = subtractPeriod(available, period{offStart, offEnd})
+	}
+	return available, true, rows.Err()
+}
+
+// subtractPeriod removes cut from each period, splitting periods it falls inside
+func subtractPeriod(periods []period, cut period) []period {
+	out := make([]period, 0, len(periods)+1)
+	for _, p := range periods {
+		if !cut.start.Before(p.end) || !p.start.Before(cut.end) {
+			out = append(out, p)
+			continue
+		}
+		if p.start.Before(cut.start) {
+			out = append(out, period{p.start, cut.start})
+		}
+		if cut.end.Before(p.end) {
+			out = append(out, period{cut.end, p.end})
+		}
+	}
+	return out
+}
+
+// checkWorkingHours returns an OutsideWorkingHoursError if start to end is not within one of the
+// dentist's working periods. Dentists without working hours are not checked.
+func checkWorkingHours(q queryRunner, dentistID int, start, end time.Time) error {
+	dayStart := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.Local)
+	available, hasSchedule, err := dentistAvailability(q, dentistID, dayStart, dayStart.AddDate(0, 0, 1))
+	if err != nil || !hasSchedule {
+		return err
+	}
+	for _, p := range available {
+		if !start.Before(p.start) && !end.After(p.end) {
+			return nil
+		}
+	}
+
+	var holiday string
+	err = q.QueryRow(`SELECT CASE WHEN name = '' THEN 'holiday' ELSE name END FROM clinic_holidays WHERE date = ?`,
+		dayStart.Format("2006-01-02")).Scan(&holiday)
+	if err == nil {
+		return &OutsideWorkingHoursError{Reason: "the clinic is closed on " + dayStart.Format("2006-01-02") + " (" + holiday + ")"}
+	} else if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check holidays: %v", err)
+	}
+	var reason string
+	err = q.QueryRow(`SELECT reason FROM time_off WHERE user_id = ? AND start_at < ? AND end_at > ? LIMIT 1`,
+		dentistID, end.Format(seriesTimeLayout), start.Format(seriesTimeLayout)).Scan(&reason)
+	if err == nil {
+		if reason == "" {
+			reason = "no reason given"
+		}
+		return &OutsideWorkingHoursError{Reason: "the dentist is on time off (" + reason + ")"}
+	} else if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check time off: %v", err)
+	}
+	return &OutsideWorkingHoursError{Reason: fmt.Sprintf("the dentist does not work at that time on %s", start.Weekday())}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"DentistApp/models"
+)
+
+func TestWorkingHoursAndFreeSlots(t *testing.T) {
+	db, admin := newTestAdmin(t)
+	auth := NewAuthHandler(db)
+	dentistID, err := auth.CreateUser(models.UserForm{Username: "dr.smith", Password: "secret1", Role: models.RoleDentist}, admin.ID)
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	dentist := int(dentistID)
+	newTestPatient(t, db, models.PatientForm{Name: "Jane Doe", Phone: "0100000000"})
+
+	schedule := NewScheduleHandler(db)
+	if err := schedule.SetWorkingHours(dentist, []models.WorkingHours{
+		{Weekday: int(time.Monday), StartTime: "09:00", EndTime: "12:00"},
+		{Weekday: int(time.Monday), StartTime: "11:00", EndTime: "13:00"},
+	}, admin.ID); err == nil {
+		t.Errorf("expected overlapping working hours to be rejected")
+	}
+	if err := schedule.SetWorkingHours(dentist, []models.WorkingHours{
+		{Weekday: int(time.Monday), StartTime: "09:00", EndTime: "12:00"},
+		{Weekday: int(time.Monday), StartTime: "13:00", EndTime: "15:00"},
+	}, admin.ID); err != nil {
+		t.Fatalf("SetWorkingHours failed: %v", err)
+	}
+
+	// Work on Mondays well in the future so no slot is in the past
+	monday := time.Now().AddDate(0, 0, 14)
+	for monday.Weekday() != time.Monday {
+		monday = monday.AddDate(0, 0, 1)
+	}
+	day := monday.Format("2006-01-02")
+	nextMonday := monday.AddDate(0, 0, 7).Format("2006-01-02")
+
+	if _, err := schedule.AddClinicHoliday(models.ClinicHoliday{Date: nextMonday, Name: "Spring holiday"}, admin.ID); err != nil {
+		t.Fatalf("AddClinicHoliday failed: %v", err)
+	}
+	if _, err := schedule.AddTimeOff(models.TimeOff{UserID: dentist, Start: day + "T14:00", End: day + "T15:00", Reason: "training"}, admin.ID); err != nil {
+		t.Fatalf("AddTimeOff failed: %v", err)
+	}
+
+	appointments := NewAppointmentHandler(db)
+	if _, err := appointments.AddAppointment(models.Appointment{PatientID: 1, DateTime: day + "T10:00", Duration: 30, DentistID: &dentist}, admin.ID); err != nil {
+		t.Fatalf("AddAppointment within working hours failed: %v", err)
+	}
+
+	slots, err := schedule.FindFreeSlots(dentist, 60, day, nextMonday)
+	if err != nil {
+		t.Fatalf("FindFreeSlots failed: %v", err)
+	}
+	var starts []string
+	for _, slot := range slots {
+		starts = append(starts, strings.TrimPrefix(slot.Start, day+"T"))
+	}
+	if got := strings.Join(starts, " "); got != "09:00 10:30 13:00" {
+		t.Errorf("unexpected free slots: %s", got)
+	}
+
+	outside := []struct {
+		datetime string
+		reason   string
+	}{
+		{monday.AddDate(0, 0, 1).Format("2006-01-02") + "T10:00", "does not work"},
+		{day + "T11:45", "does not work"},
+		{day + "T14:00", "training"},
+		{nextMonday + "T10:00", "Spring holiday"},
+	}
+	for _, tt := range outside {
+		_, err := appointments.AddAppointment(models.Appointment{PatientID: 1, DateTime: tt.datetime, Duration: 30, DentistID: &dentist}, admin.ID)
+		var hoursErr *OutsideWorkingHoursError
+		if !errors.As(err, &hoursErr) || !strings.Contains(hoursErr.Reason, tt.reason) {
+			t.Errorf("%s: expected an outside working hours error mentioning %q, got %v", tt.datetime, tt.reason, err)
+		}
+	}
+	if _, err := appointments.AddAppointment(models.Appointment{PatientID: 1, DateTime: nextMonday + "T10:00", Duration: 30, DentistID: &dentist, AllowOutsideHours: true}, admin.ID); err != nil {
+		t.Errorf("expected AllowOutsideHours to override the check: %v", err)
+	}
+
+	// Dentists without a schedule are not checked
+	if _, err := appointments.AddAppointment(models.Appointment{PatientID: 1, DateTime: nextMonday + "T10:00", Duration: 30, DentistID: &admin.ID}, admin.ID); err != nil {
+		t.Errorf("expected a booking for a dentist without working hours to pass: %v", err)
+	}
+	if _, err := schedule.FindFreeSlots(admin.ID, 30, day, day); err == nil {
+		t.Errorf("expected an error for a dentist without working hours")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 )
 
 // SessionHandler handles session-related database operations
@@ -17,8 +18,13 @@ func NewSessionHandler(db *sql.DB) *SessionHandler {
 	return &SessionHandler{db: db}
 }
 
-// CreateSession creates a new session with items
-func (h *SessionHandler) CreateSession(session models.SessionForm) (int64, error) {
+// CreateSession creates a new session with items. If it is completed, the conditions its items
+// leave behind are charted on their teeth.
+func (h *SessionHandler) CreateSession(session models.SessionForm, actorID int) (int64, error) {
+	if err := validateSessionItems(session.Items); err != nil {
+		return 0, err
+	}
+
 	// Start transaction
 	tx, err := h.db.Begin()
 	if err != nil {
@@ -47,17 +53,16 @@ func (h *SessionHandler) CreateSession(session models.SessionForm) (int64, error
 	}
 
 	// Insert session items
-	for _, item := range session.Items {
-		itemQuery := `INSERT INTO session_items (session_id, procedure_id, item_name, amount)
-		              VALUES (?, ?, ?, ?)`
-		var procedureID interface{}
-		if item.ProcedureID != nil {
-			procedureID = *item.ProcedureID
-		}
-		_, err := tx.Exec(itemQuery, sessionID, procedureID, item.ItemName, item.Amount)
-		if err != nil {
-			return 0, fmt.Errorf("failed to create session item: %v", err)
-		}
+	if err := insertSessionItems(tx, sessionID, session.PatientID, session.Items); err != nil {
+		return 0, err
+	}
+
+	if err := chartSession(tx, sessionID, session.PatientID, session.Status, session.Items, actorID); err != nil {
+		return 0, err
+	}
+
+	if err := recordAudit(tx, actorID, auditSession, sessionID, AuditActionCreate, nil); err != nil {
+		return 0, err
 	}
 
 	// Commit transaction
@@ -199,7 +204,7 @@ func (h *SessionHandler) GetSessions(page int, filters *models.SessionFilters) (
 	}, nil
 }
 
-// GetSession returns a specific session by ID with all items
+// GetSession returns a specific session by ID with all items and the patient's medical alerts
 func (h *SessionHandler) GetSession(id int) (models.Session, error) {
 	var session models.Session
 
@@ -223,7 +228,7 @@ func (h *SessionHandler) GetSession(id int) (models.Session, error) {
 	}
 
 	// Get session items
-	itemsQuery := `SELECT id, session_id, procedure_id, item_name, amount
+	itemsQuery := `SELECT id, session_id, procedure_id, item_name, amount, chart_condition, plan_item_id
 	               FROM session_items WHERE session_id = ? ORDER BY id`
 	itemRows, err := h.db.Query(itemsQuery, id)
 	if err != nil {
@@ -234,8 +239,8 @@ func (h *SessionHandler) GetSession(id int) (models.Session, error) {
 	items := make([]models.SessionItem, 0)
 	for itemRows.Next() {
 		var item models.SessionItem
-		var procedureID sql.NullInt64
-		err := itemRows.Scan(&item.ID, &item.SessionID, &procedureID, &item.ItemName, &item.Amount)
+		var procedureID, planItemID sql.NullInt64
+		err := itemRows.Scan(&item.ID, &item.SessionID, &procedureID, &item.ItemName, &item.Amount, &item.ChartCondition, &planItemID)
 		if err != nil {
 			return session, fmt.Errorf("failed to scan session item: %v", err)
 		}
@@ -243,15 +248,48 @@ func (h *SessionHandler) GetSession(id int) (models.Session, error) {
 			procID := int(procedureID.Int64)
 			item.ProcedureID = &procID
 		}
+		item.PlanItemID = nullableInt(planItemID)
+		item.Teeth = []models.ToothRef{}
 		items = append(items, item)
 	}
+	itemRows.Close()
+
+	// Get the teeth each item was performed on
+	teethRows, err := h.db.Query(`SELECT session_item_id, tooth, surfaces FROM session_item_teeth WHERE session_id = ? ORDER BY id`, id)
+	if err != nil {
+		return session, fmt.Errorf("failed to get session item teeth: %v", err)
+	}
+	defer teethRows.Close()
+	for teethRows.Next() {
+		var itemID int
+		var ref models.ToothRef
+		if err := teethRows.Scan(&itemID, &ref.Tooth, &ref.Surfaces); err != nil {
+			return session, fmt.Errorf("failed to scan session item tooth: %v", err)
+		}
+		for i := range items {
+			if items[i].ID == itemID {
+				items[i].Teeth = append(items[i].Teeth, ref)
+			}
+		}
+	}
 	session.Items = items
 
+	alerts, err := patientMedicalAlerts(h.db, session.PatientID)
+	if err != nil {
+		return session, err
+	}
+	session.MedicalAlerts = alerts[session.PatientID]
+
 	return session, nil
 }
 
-// UpdateSession updates an existing session and its items
-func (h *SessionHandler) UpdateSession(session models.Session, items []models.SessionItemForm) error {
+// UpdateSession updates an existing session and its items. The tooth chart is only updated the
+// first time the session is saved as completed; later edits to its items do not chart again.
+func (h *SessionHandler) UpdateSession(session models.Session, items []models.SessionItemForm, actorID int) error {
+	if err := validateSessionItems(items); err != nil {
+		return err
+	}
+
 	// Start transaction
 	tx, err := h.db.Begin()
 	if err != nil {
@@ -259,6 +297,11 @@ func (h *SessionHandler) UpdateSession(session models.Session, items []models.Se
 	}
 	defer tx.Rollback()
 
+	before, err := auditSnapshot(tx, auditSession, int64(session.ID))
+	if err != nil {
+		return err
+	}
+
 	// Calculate total amount from items
 	totalAmount := 0
 	for _, item := range items {
@@ -279,19 +322,22 @@ func (h *SessionHandler) UpdateSession(session models.Session, items []models.Se
 	if err != nil {
 		return fmt.Errorf("failed to delete session items: %v", err)
 	}
+	_, err = tx.Exec("DELETE FROM session_item_teeth WHERE session_id = ?", session.ID)
+	if err != nil {
+		return fmt.Errorf("failed to delete session item teeth: %v", err)
+	}
 
 	// Insert new items
-	for _, item := range items {
-		itemQuery := `INSERT INTO session_items (session_id, procedure_id, item_name, amount)
-		              VALUES (?, ?, ?, ?)`
-		var procedureID interface{}
-		if item.ProcedureID != nil {
-			procedureID = *item.ProcedureID
-		}
-		_, err := tx.Exec(itemQuery, session.ID, procedureID, item.ItemName, item.Amount)
-		if err != nil {
-			return fmt.Errorf("failed to create session item: %v", err)
-		}
+	if err := insertSessionItems(tx, int64(session.ID), session.PatientID, items); err != nil {
+		return err
+	}
+
+	if err := chartSession(tx, int64(session.ID), session.PatientID, session.Status, items, actorID); err != nil {
+		return err
+	}
+
+	if err := recordAudit(tx, actorID, auditSession, int64(session.ID), AuditActionUpdate, before); err != nil {
+		return err
 	}
 
 	// Commit transaction
@@ -302,10 +348,19 @@ func (h *SessionHandler) UpdateSession(session models.Session, items []models.Se
 	return nil
 }
 
-// DeleteSession deletes a session and its items (cascade)
-func (h *SessionHandler) DeleteSession(id int) error {
+// DeleteSession deletes a session and its items (cascade). Sessions with a signed clinical note are
+// kept.
+func (h *SessionHandler) DeleteSession(id int, actorID int) error {
+	var signed int
+	if err := h.db.QueryRow(`SELECT COUNT(*) FROM clinical_notes WHERE session_id = ? AND signed_at != ''`, id).Scan(&signed); err != nil {
+		return fmt.Errorf("failed to check clinical notes: %v", err)
+	}
+	if signed > 0 {
+		return fmt.Errorf("the session has a signed clinical note and cannot be deleted")
+	}
+
 	query := `DELETE FROM sessions WHERE id = ?`
-	result, err := h.db.Exec(query, id)
+	result, err := auditedExec(h.db, actorID, auditSession, int64(id), AuditActionDelete, query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete session: %v", err)
 	}
@@ -321,3 +376,120 @@ func (h *SessionHandler) DeleteSession(id int) error {
 
 	return nil
 }
+
+// validateSessionItems checks the teeth and chart condition of each item, normalizing their surfaces
+func validateSessionItems(items []models.SessionItemForm) error {
+	for i := range items {
+		item := &items[i]
+		if item.ChartCondition != "" && len(item.Teeth) == 0 {
+			return fmt.Errorf("select the teeth %q was performed on", item.ItemName)
+		}
+		for j := range item.Teeth {
+			var err error
+			if item.ChartCondition != "" {
+				err = validateChartEntry(&item.Teeth[j], item.ChartCondition)
+			} else {
+				err = validateToothRef(&item.Teeth[j])
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %v", item.ItemName, err)
+			}
+		}
+	}
+	return nil
+}
+
+// insertSessionItems inserts the items of a session together with the teeth they reference. Items
+// performing a treatment plan item must come from one of the patient's plans and may only be
+// performed once.
+func insertSessionItems(tx *sql.Tx, sessionID int64, patientID int, items []models.SessionItemForm) error {
+	for _, item := range items {
+		var planItemID interface{}
+		if item.PlanItemID != nil {
+			if err := checkPlanItemForSession(tx, *item.PlanItemID, patientID); err != nil {
+				return err
+			}
+			planItemID = *item.PlanItemID
+		}
+
+		itemQuery := `INSERT INTO session_items (session_id, procedure_id, item_name, amount, chart_condition, plan_item_id)
+		              VALUES (?, ?, ?, ?, ?, ?)`
+		var procedureID interface{}
+		if item.ProcedureID != nil {
+			procedureID = *item.ProcedureID
+		}
+		result, err := tx.Exec(itemQuery, sessionID, procedureID, item.ItemName, item.Amount, item.ChartCondition, planItemID)
+		if err != nil {
+			return fmt.Errorf("failed to create session item: %v", err)
+		}
+		itemID, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get session item ID: %v", err)
+		}
+		for _, ref := range item.Teeth {
+			_, err := tx.Exec(`INSERT INTO session_item_teeth (session_id, session_item_id, tooth, surfaces) VALUES (?, ?, ?, ?)`,
+				sessionID, itemID, ref.Tooth, ref.Surfaces)
+			if err != nil {
+				return fmt.Errorf("failed to save session item tooth: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkPlanItemForSession returns an error unless the plan item belongs to the patient and is still
+// waiting to be performed
+func checkPlanItemForSession(tx *sql.Tx, planItemID, patientID int) error {
+	var name, status string
+	var planPatientID, sessionID int
+	err := tx.QueryRow(`SELECT i.item_name, i.status, tp.patient_id,
+	                           COALESCE((SELECT s.id FROM session_items si JOIN sessions s ON si.session_id = s.id
+	                                     WHERE si.plan_item_id = i.id LIMIT 1), 0)
+	                    FROM treatment_plan_items i JOIN treatment_plans tp ON i.plan_id = tp.id
+	                    WHERE i.id = ?`, planItemID).Scan(&name, &status, &planPatientID, &sessionID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("treatment plan item not found")
+	} else if err != nil {
+		return fmt.Errorf("failed to get treatment plan item: %v", err)
+	}
+	switch {
+	case planPatientID != patientID:
+		return fmt.Errorf("%s belongs to another patient's treatment plan", name)
+	case sessionID != 0:
+		return fmt.Errorf("%s was already performed in session %d", name, sessionID)
+	case status == models.PlanItemDeclined:
+		return fmt.Errorf("%s was declined by the patient", name)
+	}
+	return nil
+}
+
+// chartSession charts the conditions left by the items of a completed session, once per session
+func chartSession(tx *sql.Tx, sessionID int64, patientID int, status string, items []models.SessionItemForm, actorID int) error {
+	if status != "completed" {
+		return nil
+	}
+	var chartedAt string
+	if err := tx.QueryRow(`SELECT charted_at FROM sessions WHERE id = ?`, sessionID).Scan(&chartedAt); err != nil {
+		return fmt.Errorf("failed to get session: %v", err)
+	}
+	if chartedAt != "" {
+		return nil
+	}
+
+	id := int(sessionID)
+	for _, item := range items {
+		if item.ChartCondition == "" {
+			continue
+		}
+		for _, ref := range item.Teeth {
+			if _, err := chartCondition(tx, patientID, ref, item.ChartCondition, item.ItemName, &id, actorID); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := tx.Exec(`UPDATE sessions SET charted_at = ? WHERE id = ?`, time.Now().Format(chartTimeLayout), sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to update session: %v", err)
+	}
+	return nil
+}
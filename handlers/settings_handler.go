@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"regexp"
+	"strings"
+	"time"
+
+	"DentistApp/models"
+)
+
+// maxClinicLogoSize is the largest logo accepted, in bytes
+const maxClinicLogoSize = 1024 * 1024
+
+// numberPrefixPattern limits invoice and lab order prefixes to characters that are safe in
+// file names and SQL comparisons
+var numberPrefixPattern = regexp.MustCompile(`^[A-Za-z0-9/-]{1,12}$`)
+
+// SettingsHandler handles the clinic profile and shared preferences
+type SettingsHandler struct {
+	db *sql.DB
+}
+
+// NewSettingsHandler creates a new SettingsHandler
+func NewSettingsHandler(db *sql.DB) *SettingsHandler {
+	return &SettingsHandler{db: db}
+}
+
+// GetClinicSettings returns the clinic settings, including the logo
+func (h *SettingsHandler) GetClinicSettings() (*models.ClinicSettings, error) {
+	settings, err := loadClinicSettings(h.db)
+	if err != nil {
+		return nil, err
+	}
+	if len(settings.Logo) > 0 {
+		settings.LogoDataURL = fmt.Sprintf("data:image/%s;base64,%s", settings.LogoType, base64.StdEncoding.EncodeToString(settings.Logo))
+	}
+	return settings, nil
+}
+
+// SaveClinicSettings updates the clinic profile and preferences. The logo is changed with SetClinicLogo.
+func (h *SettingsHandler) SaveClinicSettings(settings models.ClinicSettings, actorID int) error {
+	settings.Name = strings.TrimSpace(settings.Name)
+	settings.Address = strings.TrimSpace(settings.Address)
+	settings.Phone = strings.TrimSpace(settings.Phone)
+	settings.TaxNumber = strings.TrimSpace(settings.TaxNumber)
+	settings.CurrencySymbol = strings.TrimSpace(settings.CurrencySymbol)
+	settings.InvoiceFooter = strings.TrimSpace(settings.InvoiceFooter)
+
+	if len(settings.Name) > 200 || len(settings.Address) > 500 || len(settings.Phone) > 50 || len(settings.TaxNumber) > 50 {
+		return fmt.Errorf("clinic details are too long")
+	}
+	if settings.CurrencySymbol == "" || len(settings.CurrencySymbol) > 10 {
+		return fmt.Errorf("currency symbol must be 1 to 10 characters")
+	}
+	if len(settings.InvoiceFooter) > 500 {
+		return fmt.Errorf("invoice footer must be at most 500 characters")
+	}
+	if !numberPrefixPattern.MatchString(settings.InvoicePrefix) {
+		return fmt.Errorf("invoice prefix must be 1 to 12 letters, digits, '-' or '/'")
+	}
+	if !numberPrefixPattern.MatchString(settings.LabOrderPrefix) {
+		return fmt.Errorf("lab order prefix must be 1 to 12 letters, digits, '-' or '/'")
+	}
+	if settings.DefaultAppointmentDuration < 5 || settings.DefaultAppointmentDuration > 480 {
+		return fmt.Errorf("default appointment duration must be between 5 and 480 minutes")
+	}
+	if err := validateTheme(settings.Theme); err != nil {
+		return err
+	}
+
+	query := `UPDATE clinic_settings SET name = ?, address = ?, phone = ?, tax_number = ?, currency_symbol = ?,
+	          invoice_footer = ?, invoice_prefix = ?, lab_order_prefix = ?, default_appointment_duration = ?,
+	          theme = ?, updated_at = ?
+	          WHERE id = 1`
+	_, err := auditedExec(h.db, actorID, auditClinicSettings, 1, AuditActionUpdate, query,
+		settings.Name, settings.Address, settings.Phone, settings.TaxNumber, settings.CurrencySymbol,
+		settings.InvoiceFooter, settings.InvoicePrefix, settings.LabOrderPrefix, settings.DefaultAppointmentDuration,
+		settings.Theme, time.Now().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return fmt.Errorf("failed to save clinic settings: %v", err)
+	}
+	return nil
+}
+
+// SetClinicLogo replaces the logo with a PNG or JPEG given as a data: URL. An empty value removes it.
+func (h *SettingsHandler) SetClinicLogo(dataURL string, actorID int) error {
+	var logo []byte
+	logoType := ""
+	if dataURL != "" {
+		header, encoded, ok := strings.Cut(dataURL, ",")
+		if !ok || !strings.HasPrefix(header, "data:") || !strings.HasSuffix(header, ";base64") {
+			return fmt.Errorf("invalid logo data")
+		}
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("invalid logo data")
+		}
+		if len(data) > maxClinicLogoSize {
+			return fmt.Errorf("logo must be at most 1 MB")
+		}
+		_, format, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil || (format != "png" && format != "jpeg") {
+			return fmt.Errorf("logo must be a PNG or JPEG image")
+		}
+		logo, logoType = data, format
+	}
+
+	query := `UPDATE clinic_settings SET logo = ?, logo_type = ?, updated_at = ? WHERE id = 1`
+	_, err := auditedExec(h.db, actorID, auditClinicSettings, 1, AuditActionUpdate, query,
+		logo, logoType, time.Now().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return fmt.Errorf("failed to save clinic logo: %v", err)
+	}
+	return nil
+}
+
+// SetTheme changes the shared colour theme ("light" or "dark")
+func (h *SettingsHandler) SetTheme(theme string, actorID int) error {
+	if err := validateTheme(theme); err != nil {
+		return err
+	}
+	query := `UPDATE clinic_settings SET theme = ?, updated_at = ? WHERE id = 1`
+	_, err := auditedExec(h.db, actorID, auditClinicSettings, 1, AuditActionUpdate, query,
+		theme, time.Now().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return fmt.Errorf("failed to save theme: %v", err)
+	}
+	return nil
+}
+
+func validateTheme(theme string) error {
+	if theme != "light" && theme != "dark" {
+		return fmt.Errorf("theme must be light or dark")
+	}
+	return nil
+}
+
+// loadClinicSettings reads the settings row. Other handlers use it for numbering prefixes and defaults.
+func loadClinicSettings(runner queryRunner) (*models.ClinicSettings, error) {
+	var settings models.ClinicSettings
+	var updatedAt sql.NullString
+	err := runner.QueryRow(`SELECT name, address, phone, tax_number, logo, logo_type, currency_symbol, invoice_footer,
+	                               invoice_prefix, lab_order_prefix, default_appointment_duration, theme, updated_at
+	                        FROM clinic_settings WHERE id = 1`).Scan(
+		&settings.Name, &settings.Address, &settings.Phone, &settings.TaxNumber, &settings.Logo, &settings.LogoType,
+		&settings.CurrencySymbol, &settings.InvoiceFooter, &settings.InvoicePrefix, &settings.LabOrderPrefix,
+		&settings.DefaultAppointmentDuration, &settings.Theme, &updatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load clinic settings: %v", err)
+	}
+	settings.UpdatedAt = updatedAt.String
+	return &settings, nil
+}
+
+// nextSequenceNumber returns the next number in a prefixed sequence such as INV-001, INV-002.
+// table and column are fixed by the caller, never user input.
+func nextSequenceNumber(runner queryRunner, table, column, prefix string) (string, error) {
+	query := fmt.Sprintf(`SELECT MAX(CAST(SUBSTR(%[2]s, ? + 1) AS INTEGER)) FROM %[1]s
+	                      WHERE SUBSTR(%[2]s, 1, ?) = ?`, table, column)
+	var last sql.NullInt64
+	if err := runner.QueryRow(query, len(prefix), len(prefix), prefix).Scan(&last); err != nil {
+		return "", fmt.Errorf("failed to get last %s: %v", strings.ReplaceAll(column, "_", " "), err)
+	}
+	return fmt.Sprintf("%s%03d", prefix, last.Int64+1), nil
+}
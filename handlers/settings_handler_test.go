@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"DentistApp/models"
+)
+
+func TestClinicSettingsDriveNumbering(t *testing.T) {
+	db := newTestDB(t)
+	settings := NewSettingsHandler(db)
+	invoices := NewInvoiceHandler(db)
+
+	number, err := invoices.GenerateInvoiceNumber()
+	if err != nil || number != "INV-001" {
+		t.Fatalf("GenerateInvoiceNumber = %q, %v; expected INV-001", number, err)
+	}
+
+	current, err := settings.GetClinicSettings()
+	if err != nil {
+		t.Fatalf("GetClinicSettings failed: %v", err)
+	}
+	current.Name = "  Smile Clinic "
+	current.InvoicePrefix = "SC/"
+	current.DefaultAppointmentDuration = 45
+	if err := settings.SaveClinicSettings(*current, 1); err != nil {
+		t.Fatalf("SaveClinicSettings failed: %v", err)
+	}
+
+	// Existing numbers with the new prefix continue the sequence
+	newTestPatient(t, db, models.PatientForm{Name: "Jane Doe", Phone: "0100000000"})
+	if _, err := db.Exec(`INSERT INTO sessions (patient_id, dentist_id, session_date, total_amount, status) VALUES (1, 1, '2025-03-01', 100, 'completed')`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO invoices (session_id, patient_id, invoice_number, total_amount, status) VALUES (1, 1, 'SC/009', 100, 'issued')`); err != nil {
+		t.Fatal(err)
+	}
+	number, err = invoices.GenerateInvoiceNumber()
+	if err != nil || number != "SC/010" {
+		t.Errorf("GenerateInvoiceNumber = %q, %v; expected SC/010", number, err)
+	}
+
+	id, err := NewAppointmentHandler(db).AddAppointment(models.Appointment{PatientID: 1, DateTime: "2025-03-02 10:00"}, 1)
+	if err != nil {
+		t.Fatalf("AddAppointment failed: %v", err)
+	}
+	appt, err := NewAppointmentHandler(db).GetAppointment(int(id))
+	if err != nil || appt.Duration != 45 {
+		t.Errorf("appointment duration = %d, %v; expected the default of 45", appt.Duration, err)
+	}
+
+	saved, err := settings.GetClinicSettings()
+	if err != nil || saved.Name != "Smile Clinic" {
+		t.Errorf("clinic name = %q, %v; expected it trimmed", saved.Name, err)
+	}
+
+	invalid := []func(s *models.ClinicSettings){
+		func(s *models.ClinicSettings) { s.InvoicePrefix = "INV%" },
+		func(s *models.ClinicSettings) { s.LabOrderPrefix = "" },
+		func(s *models.ClinicSettings) { s.CurrencySymbol = " " },
+		func(s *models.ClinicSettings) { s.DefaultAppointmentDuration = 0 },
+		func(s *models.ClinicSettings) { s.Theme = "blue" },
+	}
+	for i, change := range invalid {
+		s := *saved
+		change(&s)
+		if err := settings.SaveClinicSettings(s, 1); err == nil {
+			t.Errorf("invalid settings %d were accepted", i)
+		}
+	}
+}
+
+func TestSetClinicLogo(t *testing.T) {
+	db := newTestDB(t)
+	settings := NewSettingsHandler(db)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	dataURL := "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+	if err := settings.SetClinicLogo(dataURL, 1); err != nil {
+		t.Fatalf("SetClinicLogo failed: %v", err)
+	}
+
+	saved, err := settings.GetClinicSettings()
+	if err != nil {
+		t.Fatalf("GetClinicSettings failed: %v", err)
+	}
+	if saved.LogoDataURL != dataURL || saved.LogoType != "png" {
+		t.Errorf("logo was not stored as given")
+	}
+
+	notAnImage := "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("hello"))
+	if err := settings.SetClinicLogo(notAnImage, 1); err == nil || !strings.Contains(err.Error(), "PNG or JPEG") {
+		t.Errorf("expected a non-image logo to be rejected, got %v", err)
+	}
+
+	if err := settings.SetClinicLogo("", 1); err != nil {
+		t.Fatalf("removing the logo failed: %v", err)
+	}
+	if saved, _ := settings.GetClinicSettings(); saved.LogoDataURL != "" {
+		t.Errorf("logo was not removed")
+	}
+}
@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"DentistApp/models"
+)
+
+// ToothChartHandler handles the per-tooth dental chart
+type ToothChartHandler struct {
+	db *sql.DB
+}
+
+// NewToothChartHandler creates new handler
+func NewToothChartHandler(db *sql.DB) *ToothChartHandler {
+	return &ToothChartHandler{db: db}
+}
+
+const chartTimeLayout = "2006-01-02 15:04:05"
+
+const toothChartSelect = `SELECT tc.id, tc.patient_id, tc.tooth, tc.surfaces, tc.condition, tc.notes, tc.session_id,
+	                             tc.recorded_at, tc.recorded_by, COALESCE(u.username, ''), tc.resolved_at, tc.resolved_by
+	                      FROM tooth_chart tc
+	                      LEFT JOIN users u ON tc.recorded_by = u.id`
+
+func scanToothCondition(scanner interface{ Scan(...any) error }) (models.ToothCondition, error) {
+	var c models.ToothCondition
+	var sessionID, recordedBy, resolvedBy sql.NullInt64
+	err := scanner.Scan(&c.ID, &c.PatientID, &c.Tooth, &c.Surfaces, &c.Condition, &c.Notes, &sessionID,
+		&c.RecordedAt, &recordedBy, &c.RecordedByName, &c.ResolvedAt, &resolvedBy)
+	if err != nil {
+		return c, err
+	}
+	c.SessionID = nullableInt(sessionID)
+	c.RecordedBy = nullableInt(recordedBy)
+	c.ResolvedBy = nullableInt(resolvedBy)
+	return c, nil
+}
+
+func (h *ToothChartHandler) queryConditions(query string, args ...any) ([]models.ToothCondition, error) {
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tooth chart: %v", err)
+	}
+	defer rows.Close()
+
+	conditions := []models.ToothCondition{}
+	for rows.Next() {
+		c, err := scanToothCondition(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tooth chart entry: %v", err)
+		}
+		conditions = append(conditions, c)
+	}
+	return conditions, rows.Err()
+}
+
+// GetToothChart returns the patient's current chart: every condition that has not been resolved
+func (h *ToothChartHandler) GetToothChart(patientID int) ([]models.ToothCondition, error) {
+	return h.queryConditions(toothChartSelect+` WHERE tc.patient_id = ? AND tc.resolved_at = '' ORDER BY tc.tooth, tc.id`, patientID)
+}
+
+// GetToothHistory returns every condition ever charted on one of the patient's teeth, or on all of
+// them when tooth is 0, newest first
+func (h *ToothChartHandler) GetToothHistory(patientID, tooth int) ([]models.ToothCondition, error) {
+	if tooth == 0 {
+		return h.queryConditions(toothChartSelect+` WHERE tc.patient_id = ? ORDER BY tc.recorded_at DESC, tc.id DESC`, patientID)
+	}
+	return h.queryConditions(toothChartSelect+` WHERE tc.patient_id = ? AND tc.tooth = ? ORDER BY tc.recorded_at DESC, tc.id DESC`, patientID, tooth)
+}
+
+// AddToothCondition charts a condition by hand, resolving the conditions it replaces
+func (h *ToothChartHandler) AddToothCondition(form models.ToothConditionForm, actorID int) (int64, error) {
+	ref := models.ToothRef{Tooth: form.Tooth, Surfaces: form.Surfaces}
+	if err := validateChartEntry(&ref, form.Condition); err != nil {
+		return 0, err
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	id, err := chartCondition(tx, form.PatientID, ref, form.Condition, strings.TrimSpace(form.Notes), nil, actorID)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return id, nil
+}
+
+// ResolveToothCondition takes a condition off the current chart, keeping it in the tooth's history
+func (h *ToothChartHandler) ResolveToothCondition(id int, actorID int) error {
+	result, err := auditedExec(h.db, actorID, auditToothCondition, int64(id), AuditActionUpdate,
+		`UPDATE tooth_chart SET resolved_at = ?, resolved_by = ? WHERE id = ? AND resolved_at = ''`,
+		time.Now().Format(chartTimeLayout), nullableActor(actorID), id)
+	if err != nil {
+		return fmt.Errorf("failed to resolve tooth condition: %v", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to get rows affected: %v", err)
+	} else if n == 0 {
+		return fmt.Errorf("tooth condition not found or already resolved")
+	}
+	return nil
+}
+
+// validToothNumber reports whether n is an FDI tooth number: quadrants 1-4 hold permanent teeth 1-8,
+// quadrants 5-8 primary teeth 1-5
+func validToothNumber(n int) bool {
+	quadrant, position := n/10, n%10
+	switch {
+	case quadrant >= 1 && quadrant <= 4:
+		return position >= 1 && position <= 8
+	case quadrant >= 5 && quadrant <= 8:
+		return position >= 1 && position <= 5
+	}
+	return false
+}
+
+// normalizeSurfaces upper-cases the surface letters and puts them in MODBL order without duplicates
+func normalizeSurfaces(surfaces string) (string, error) {
+	surfaces = strings.ToUpper(strings.ReplaceAll(surfaces, " ", ""))
+	for _, r := range surfaces {
+		if !strings.ContainsRune(models.ToothSurfaces, r) {
+			return "", fmt.Errorf("unknown tooth surface %q; use M, O, D, B or L", r)
+		}
+	}
+	var b strings.Builder
+	for _, r := range models.ToothSurfaces {
+		if strings.ContainsRune(surfaces, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String(), nil
+}
+
+// validateToothRef checks the tooth number and normalizes its surfaces
+func validateToothRef(ref *models.ToothRef) error {
+	if !validToothNumber(ref.Tooth) {
+		return fmt.Errorf("%d is not an FDI tooth number (11-48 or 51-85)", ref.Tooth)
+	}
+	surfaces, err := normalizeSurfaces(ref.Surfaces)
+	if err != nil {
+		return err
+	}
+	ref.Surfaces = surfaces
+	return nil
+}
+
+// surfaceCondition reports whether the condition applies to individual surfaces rather than the whole tooth
+func surfaceCondition(condition string) bool {
+	return condition == models.ToothCaries || condition == models.ToothFilling
+}
+
+// validateChartEntry checks that the condition can be charted on the tooth and surfaces given
+func validateChartEntry(ref *models.ToothRef, condition string) error {
+	if err := validateToothRef(ref); err != nil {
+		return err
+	}
+	known := false
+	for _, c := range models.ToothConditions {
+		if c == condition {
+			known = true
+		}
+	}
+	if !known {
+		return fmt.Errorf("unknown tooth condition %q", condition)
+	}
+	if surfaceCondition(condition) && ref.Surfaces == "" {
+		return fmt.Errorf("select the surfaces of tooth %d", ref.Tooth)
+	}
+	if !surfaceCondition(condition) && ref.Surfaces != "" {
+		return fmt.Errorf("%s applies to the whole tooth, not to surfaces", strings.ReplaceAll(condition, "_", " "))
+	}
+	return nil
+}
+
+// removeSurfaces returns the surfaces of s not in remove, in MODBL order
+func removeSurfaces(s, remove string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if !strings.ContainsRune(remove, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func nullableActor(actorID int) any {
+	if actorID > 0 {
+		return actorID
+	}
+	return nil
+}
+
+// chartCondition records a validated condition inside tx and resolves the active conditions it
+// replaces: a missing tooth or implant replaces everything, a crown replaces caries, fillings and an
+// earlier crown, and a filling replaces caries and fillings on the same surfaces (what is left of them
+// stays charted). Charting a condition that is already active on the same surfaces changes nothing.
+func chartCondition(tx *sql.Tx, patientID int, ref models.ToothRef, condition, notes string, sessionID *int, actorID int) (int64, error) {
+	rows, err := tx.Query(`SELECT id, surfaces, condition, notes, session_id FROM tooth_chart
+	                       WHERE patient_id = ? AND tooth = ? AND resolved_at = '' ORDER BY id`, patientID, ref.Tooth)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get tooth chart: %v", err)
+	}
+	type active struct {
+		id                        int64
+		surfaces, condition, note string
+		sessionID                 sql.NullInt64
+	}
+	var current []active
+	for rows.Next() {
+		var a active
+		if err := rows.Scan(&a.id, &a.surfaces, &a.condition, &a.note, &a.sessionID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan tooth chart entry: %v", err)
+		}
+		current = append(current, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, a := range current {
+		if a.condition == condition && a.surfaces == ref.Surfaces {
+			return a.id, nil
+		}
+		if a.condition == models.ToothMissing && condition != models.ToothImplant {
+			return 0, fmt.Errorf("tooth %d is charted as missing", ref.Tooth)
+		}
+	}
+
+	now := time.Now().Format(chartTimeLayout)
+	insert := func(surfaces, condition, notes string, sessionID any) (int64, error) {
+		result, err := tx.Exec(`INSERT INTO tooth_chart (patient_id, tooth, surfaces, condition, notes, session_id, recorded_at, recorded_by)
+		                        VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			patientID, ref.Tooth, surfaces, condition, notes, sessionID, now, nullableActor(actorID))
+		if err != nil {
+			return 0, fmt.Errorf("failed to chart tooth %d: %v", ref.Tooth, err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get tooth chart ID: %v", err)
+		}
+		return id, recordAudit(tx, actorID, auditToothCondition, id, AuditActionCreate, nil)
+	}
+
+	for _, a := range current {
+		replaced := false
+		remaining := ""
+		switch condition {
+		case models.ToothMissing, models.ToothImplant:
+			replaced = true
+		case models.ToothCrown:
+			replaced = surfaceCondition(a.condition) || a.condition == models.ToothCrown
+		case models.ToothFilling:
+			remaining = removeSurfaces(a.surfaces, ref.Surfaces)
+			replaced = surfaceCondition(a.condition) && remaining != a.surfaces
+		}
+		if !replaced {
+			continue
+		}
+
+		before, err := auditSnapshot(tx, auditToothCondition, a.id)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`UPDATE tooth_chart SET resolved_at = ?, resolved_by = ? WHERE id = ?`, now, nullableActor(actorID), a.id); err != nil {
+			return 0, fmt.Errorf("failed to resolve tooth condition: %v", err)
+		}
+		if err := recordAudit(tx, actorID, auditToothCondition, a.id, AuditActionUpdate, before); err != nil {
+			return 0, err
+		}
+		if remaining != "" {
+			var previousSession any
+			if a.sessionID.Valid {
+				previousSession = a.sessionID.Int64
+			}
+			if _, err := insert(remaining, a.condition, a.note, previousSession); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	var session any
+	if sessionID != nil {
+		session = *sessionID
+	}
+	return insert(ref.Surfaces, condition, notes, session)
+}
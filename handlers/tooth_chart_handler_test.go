@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"strconv"
+	"testing"
+
+	"DentistApp/models"
+)
+
+func TestToothChartFromSessions(t *testing.T) {
+	db, admin := newTestAdmin(t)
+	patientID := newTestPatient(t, db, models.PatientForm{Name: "Jane Doe", Phone: "0100000000"})
+
+	chart := NewToothChartHandler(db)
+	sessions := NewSessionHandler(db)
+
+	for _, form := range []models.ToothConditionForm{
+		{PatientID: patientID, Tooth: 19, Condition: models.ToothMissing},
+		{PatientID: patientID, Tooth: 86, Condition: models.ToothMissing},
+		{PatientID: patientID, Tooth: 16, Condition: models.ToothCaries},
+		{PatientID: patientID, Tooth: 16, Surfaces: "OX", Condition: models.ToothCaries},
+		{PatientID: patientID, Tooth: 16, Surfaces: "O", Condition: models.ToothCrown},
+	} {
+		if _, err := chart.AddToothCondition(form, admin.ID); err == nil {
+			t.Errorf("AddToothCondition accepted tooth %d %q %s", form.Tooth, form.Surfaces, form.Condition)
+		}
+	}
+
+	if _, err := chart.AddToothCondition(models.ToothConditionForm{PatientID: patientID, Tooth: 16, Surfaces: "dom", Condition: models.ToothCaries}, admin.ID); err != nil {
+		t.Fatalf("AddToothCondition failed: %v", err)
+	}
+	if _, err := chart.AddToothCondition(models.ToothConditionForm{PatientID: patientID, Tooth: 55, Condition: models.ToothMissing}, admin.ID); err != nil {
+		t.Fatalf("AddToothCondition failed for a primary tooth: %v", err)
+	}
+	if _, err := chart.AddToothCondition(models.ToothConditionForm{PatientID: patientID, Tooth: 55, Condition: models.ToothCrown}, admin.ID); err == nil {
+		t.Errorf("AddToothCondition crowned a missing tooth")
+	}
+
+	// An in-progress session references the teeth but does not chart them yet
+	form := models.SessionForm{
+		PatientID:   patientID,
+		DentistID:   admin.ID,
+		SessionDate: "2025-03-01",
+		Status:      "in-progress",
+		Items: []models.SessionItemForm{
+			{ItemName: "Composite filling", Amount: 250000, ChartCondition: models.ToothFilling, Teeth: []models.ToothRef{{Tooth: 16, Surfaces: "o"}}},
+			{ItemName: "Crown", Amount: 900000, ChartCondition: models.ToothCrown, Teeth: []models.ToothRef{{Tooth: 36}}},
+		},
+	}
+	sessionID, err := sessions.CreateSession(form, admin.ID)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	current, err := chart.GetToothChart(patientID)
+	if err != nil {
+		t.Fatalf("GetToothChart failed: %v", err)
+	}
+	if len(current) != 2 || current[0].Surfaces != "MOD" {
+		t.Fatalf("chart before completion = %+v; expected MOD caries on 16 and missing 55", current)
+	}
+
+	session, err := sessions.GetSession(int(sessionID))
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if len(session.Items[0].Teeth) != 1 || session.Items[0].Teeth[0].Surfaces != "O" || session.Items[0].ChartCondition != models.ToothFilling {
+		t.Errorf("session item teeth = %+v; expected a filling on 16 O", session.Items[0])
+	}
+
+	// Completing the session fills the occlusal surface and crowns 36, leaving caries on M and D
+	session.Status = "completed"
+	if err := sessions.UpdateSession(session, form.Items, admin.ID); err != nil {
+		t.Fatalf("UpdateSession failed: %v", err)
+	}
+	current, err = chart.GetToothChart(patientID)
+	if err != nil {
+		t.Fatalf("GetToothChart failed: %v", err)
+	}
+	got := map[string]string{}
+	for _, c := range current {
+		got[c.Condition+":"+strconv.Itoa(c.Tooth)] = c.Surfaces
+		fromSession := c.Condition == models.ToothFilling || c.Condition == models.ToothCrown
+		if fromSession && (c.SessionID == nil || *c.SessionID != int(sessionID)) {
+			t.Errorf("%s on %d not linked to session %d", c.Condition, c.Tooth, sessionID)
+		}
+	}
+	expected := map[string]string{"caries:16": "MD", "filling:16": "O", "crown:36": "", "missing:55": ""}
+	if len(got) != len(expected) {
+		t.Fatalf("chart after completion = %v; expected %v", got, expected)
+	}
+	for k, v := range expected {
+		if got[k] != v {
+			t.Errorf("chart[%s] = %q; expected %q", k, got[k], v)
+		}
+	}
+
+	// Saving the completed session again does not chart twice
+	if err := sessions.UpdateSession(session, form.Items, admin.ID); err != nil {
+		t.Fatalf("UpdateSession failed: %v", err)
+	}
+	history, err := chart.GetToothHistory(patientID, 16)
+	if err != nil {
+		t.Fatalf("GetToothHistory failed: %v", err)
+	}
+	if len(history) != 3 {
+		t.Errorf("tooth 16 history has %d entries; expected the original caries, the filling and the remaining caries", len(history))
+	}
+
+	for _, c := range current {
+		if c.Condition == models.ToothCaries {
+			if err := chart.ResolveToothCondition(c.ID, admin.ID); err != nil {
+				t.Fatalf("ResolveToothCondition failed: %v", err)
+			}
+			if err := chart.ResolveToothCondition(c.ID, admin.ID); err == nil {
+				t.Errorf("ResolveToothCondition resolved a condition twice")
+			}
+		}
+	}
+
+	if _, err := sessions.CreateSession(models.SessionForm{
+		PatientID: patientID, DentistID: admin.ID, SessionDate: "2025-03-02", Status: "completed",
+		Items: []models.SessionItemForm{{ItemName: "Filling", Amount: 1, ChartCondition: models.ToothFilling}},
+	}, admin.ID); err == nil {
+		t.Errorf("CreateSession accepted a charted item without teeth")
+	}
+}
@@ -0,0 +1,395 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"DentistApp/models"
+)
+
+// TreatmentPlanHandler handles treatment plans of proposed future work
+type TreatmentPlanHandler struct {
+	db *sql.DB
+}
+
+// NewTreatmentPlanHandler creates new handler
+func NewTreatmentPlanHandler(db *sql.DB) *TreatmentPlanHandler {
+	return &TreatmentPlanHandler{db: db}
+}
+
+const treatmentPlanSelect = `SELECT tp.id, tp.patient_id, p.name, tp.dentist_id, COALESCE(u.username, ''), tp.title, tp.notes,
+	                                tp.accepted_at, tp.created_at
+	                         FROM treatment_plans tp
+	                         JOIN patients p ON tp.patient_id = p.id
+	                         LEFT JOIN users u ON tp.dentist_id = u.id`
+
+// planItemSelect derives done and scheduled from the session an item was performed in, so editing or
+// deleting that session keeps the plan up to date
+const planItemSelect = `SELECT i.id, i.plan_id, i.phase, i.procedure_id, i.item_name, i.tooth, i.surfaces, i.price,
+	                           CASE
+	                               WHEN EXISTS (SELECT 1 FROM session_items si JOIN sessions s ON si.session_id = s.id
+	                                            WHERE si.plan_item_id = i.id AND s.status = 'completed') THEN 'done'
+	                               WHEN EXISTS (SELECT 1 FROM session_items si JOIN sessions s ON si.session_id = s.id
+	                                            WHERE si.plan_item_id = i.id) THEN 'scheduled'
+	                               ELSE i.status
+	                           END,
+	                           (SELECT s.id FROM session_items si JOIN sessions s ON si.session_id = s.id
+	                            WHERE si.plan_item_id = i.id ORDER BY si.id LIMIT 1)
+	                    FROM treatment_plan_items i`
+
+// loadTreatmentPlans returns the plans matching where, with their items and totals
+func loadTreatmentPlans(q queryRunner, where string, args ...any) ([]models.TreatmentPlan, error) {
+	rows, err := q.Query(treatmentPlanSelect+` WHERE `+where+` ORDER BY tp.created_at DESC, tp.id DESC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get treatment plans: %v", err)
+	}
+	plans := []models.TreatmentPlan{}
+	byID := map[int]int{}
+	for rows.Next() {
+		var plan models.TreatmentPlan
+		var dentistID sql.NullInt64
+		if err := rows.Scan(&plan.ID, &plan.PatientID, &plan.PatientName, &dentistID, &plan.DentistName,
+			&plan.Title, &plan.Notes, &plan.AcceptedAt, &plan.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan treatment plan: %v", err)
+		}
+		plan.DentistID = nullableInt(dentistID)
+		plan.Items = []models.TreatmentPlanItem{}
+		byID[plan.ID] = len(plans)
+		plans = append(plans, plan)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(plans) == 0 {
+		return plans, nil
+	}
+
+	ids := make([]string, 0, len(plans))
+	for _, plan := range plans {
+		ids = append(ids, fmt.Sprint(plan.ID))
+	}
+	items, err := q.Query(planItemSelect + ` WHERE i.plan_id IN (` + strings.Join(ids, ",") + `) ORDER BY i.phase, i.id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get treatment plan items: %v", err)
+	}
+	defer items.Close()
+	for items.Next() {
+		item, err := scanPlanItem(items)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan treatment plan item: %v", err)
+		}
+		plan := &plans[byID[item.PlanID]]
+		plan.Items = append(plan.Items, item)
+		addPlanTotals(plan, item)
+	}
+	return plans, items.Err()
+}
+
+func scanPlanItem(scanner interface{ Scan(...any) error }) (models.TreatmentPlanItem, error) {
+	var item models.TreatmentPlanItem
+	var procedureID, sessionID sql.NullInt64
+	err := scanner.Scan(&item.ID, &item.PlanID, &item.Phase, &procedureID, &item.ItemName, &item.Tooth, &item.Surfaces,
+		&item.Price, &item.Status, &sessionID)
+	item.ProcedureID = nullableInt(procedureID)
+	item.SessionID = nullableInt(sessionID)
+	return item, err
+}
+
+func addPlanTotals(plan *models.TreatmentPlan, item models.TreatmentPlanItem) {
+	switch item.Status {
+	case models.PlanItemDeclined:
+		return
+	case models.PlanItemDone:
+		plan.DoneTotal += item.Price
+		plan.AcceptedTotal += item.Price
+	case models.PlanItemAccepted, models.PlanItemScheduled:
+		plan.AcceptedTotal += item.Price
+		plan.Remaining += item.Price
+	}
+	plan.Estimate += item.Price
+}
+
+// GetTreatmentPlans returns the patient's treatment plans, newest first
+func (h *TreatmentPlanHandler) GetTreatmentPlans(patientID int) ([]models.TreatmentPlan, error) {
+	return loadTreatmentPlans(h.db, `tp.patient_id = ?`, patientID)
+}
+
+// GetTreatmentPlan returns one plan with its items and totals
+func (h *TreatmentPlanHandler) GetTreatmentPlan(id int) (models.TreatmentPlan, error) {
+	plans, err := loadTreatmentPlans(h.db, `tp.id = ?`, id)
+	if err != nil {
+		return models.TreatmentPlan{}, err
+	}
+	if len(plans) == 0 {
+		return models.TreatmentPlan{}, fmt.Errorf("treatment plan not found")
+	}
+	return plans[0], nil
+}
+
+// CreateTreatmentPlan saves a new plan with its items, all proposed unless given another status
+func (h *TreatmentPlanHandler) CreateTreatmentPlan(plan models.TreatmentPlan, actorID int) (int64, error) {
+	if err := h.validatePlan(&plan); err != nil {
+		return 0, err
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`INSERT INTO treatment_plans (patient_id, dentist_id, title, notes, created_at) VALUES (?, ?, ?, ?, ?)`,
+		plan.PatientID, plan.DentistID, plan.Title, plan.Notes, time.Now().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create treatment plan: %v", err)
+	}
+	planID, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get treatment plan ID: %v", err)
+	}
+	for _, item := range plan.Items {
+		if err := insertPlanItem(tx, planID, item); err != nil {
+			return 0, err
+		}
+	}
+	if err := recordAudit(tx, actorID, auditTreatmentPlan, planID, AuditActionCreate, nil); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return planID, nil
+}
+
+func insertPlanItem(tx *sql.Tx, planID int64, item models.TreatmentPlanItem) error {
+	_, err := tx.Exec(`INSERT INTO treatment_plan_items (plan_id, phase, procedure_id, item_name, tooth, surfaces, price, status)
+	                   VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		planID, item.Phase, item.ProcedureID, item.ItemName, item.Tooth, item.Surfaces, item.Price, item.Status)
+	if err != nil {
+		return fmt.Errorf("failed to create treatment plan item: %v", err)
+	}
+	return nil
+}
+
+// UpdateTreatmentPlan saves the plan and its items: items without an ID are added and items left out
+// are removed. Items already performed in a session cannot be removed.
+func (h *TreatmentPlanHandler) UpdateTreatmentPlan(plan models.TreatmentPlan, actorID int) error {
+	current, err := h.GetTreatmentPlan(plan.ID)
+	if err != nil {
+		return err
+	}
+	plan.PatientID = current.PatientID
+	if err := h.validatePlan(&plan); err != nil {
+		return err
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	before, err := auditSnapshot(tx, auditTreatmentPlan, int64(plan.ID))
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE treatment_plans SET dentist_id = ?, title = ?, notes = ? WHERE id = ?`,
+		plan.DentistID, plan.Title, plan.Notes, plan.ID); err != nil {
+		return fmt.Errorf("failed to update treatment plan: %v", err)
+	}
+
+	kept := map[int]bool{}
+	for _, item := range plan.Items {
+		if item.ID == 0 {
+			if err := insertPlanItem(tx, int64(plan.ID), item); err != nil {
+				return err
+			}
+			continue
+		}
+		kept[item.ID] = true
+		result, err := tx.Exec(`UPDATE treatment_plan_items SET phase = ?, procedure_id = ?, item_name = ?, tooth = ?, surfaces = ?, price = ?, status = ?
+		                        WHERE id = ? AND plan_id = ?`,
+			item.Phase, item.ProcedureID, item.ItemName, item.Tooth, item.Surfaces, item.Price, item.Status, item.ID, plan.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update treatment plan item: %v", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return fmt.Errorf("treatment plan item %d is not part of this plan", item.ID)
+		}
+	}
+	for _, item := range current.Items {
+		if kept[item.ID] {
+			continue
+		}
+		if item.SessionID != nil {
+			return fmt.Errorf("%s was already performed and cannot be removed from the plan", item.ItemName)
+		}
+		if _, err := tx.Exec(`DELETE FROM treatment_plan_items WHERE id = ?`, item.ID); err != nil {
+			return fmt.Errorf("failed to remove treatment plan item: %v", err)
+		}
+	}
+
+	if err := recordAudit(tx, actorID, auditTreatmentPlan, int64(plan.ID), AuditActionUpdate, before); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// DeleteTreatmentPlan deletes a plan and its items. Sessions that performed its items keep their lines.
+func (h *TreatmentPlanHandler) DeleteTreatmentPlan(id int, actorID int) error {
+	result, err := auditedExec(h.db, actorID, auditTreatmentPlan, int64(id), AuditActionDelete, `DELETE FROM treatment_plans WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete treatment plan: %v", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to get rows affected: %v", err)
+	} else if n == 0 {
+		return fmt.Errorf("treatment plan not found")
+	}
+	return nil
+}
+
+// AcceptTreatmentPlan records the patient's acceptance: the proposed items listed are accepted, the
+// other proposed items are declined, and the acceptance date is set
+func (h *TreatmentPlanHandler) AcceptTreatmentPlan(id int, acceptedItemIDs []int, actorID int) error {
+	if len(acceptedItemIDs) == 0 {
+		return fmt.Errorf("select at least one item the patient accepted")
+	}
+	plan, err := h.GetTreatmentPlan(id)
+	if err != nil {
+		return err
+	}
+	accepted := map[int]bool{}
+	for _, itemID := range acceptedItemIDs {
+		accepted[itemID] = true
+	}
+	for _, item := range plan.Items {
+		delete(accepted, item.ID)
+	}
+	if len(accepted) > 0 {
+		return fmt.Errorf("accepted items must belong to the plan")
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	before, err := auditSnapshot(tx, auditTreatmentPlan, int64(id))
+	if err != nil {
+		return err
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(acceptedItemIDs)), ",")
+	args := []any{}
+	for _, itemID := range acceptedItemIDs {
+		args = append(args, itemID)
+	}
+	args = append(args, models.PlanItemAccepted, models.PlanItemDeclined, id, models.PlanItemProposed)
+	if _, err := tx.Exec(`UPDATE treatment_plan_items SET status = CASE WHEN id IN (`+placeholders+`) THEN ? ELSE ? END
+	                      WHERE plan_id = ? AND status = ?`, args...); err != nil {
+		return fmt.Errorf("failed to accept treatment plan: %v", err)
+	}
+	if _, err := tx.Exec(`UPDATE treatment_plans SET accepted_at = ? WHERE id = ?`, time.Now().Format("2006-01-02"), id); err != nil {
+		return fmt.Errorf("failed to accept treatment plan: %v", err)
+	}
+	if err := recordAudit(tx, actorID, auditTreatmentPlan, int64(id), AuditActionUpdate, before); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// PlanSessionItems turns accepted or scheduled plan items into session lines that record the work
+// against the plan once the session is saved
+func (h *TreatmentPlanHandler) PlanSessionItems(itemIDs []int) ([]models.SessionItemForm, error) {
+	forms := []models.SessionItemForm{}
+	for _, itemID := range itemIDs {
+		item, err := scanPlanItem(h.db.QueryRow(planItemSelect+` WHERE i.id = ?`, itemID))
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("treatment plan item not found")
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to get treatment plan item: %v", err)
+		}
+		if item.Status != models.PlanItemAccepted && item.Status != models.PlanItemScheduled {
+			return nil, fmt.Errorf("%s is %s; only accepted items can be performed", item.ItemName, item.Status)
+		}
+		id := item.ID
+		form := models.SessionItemForm{ProcedureID: item.ProcedureID, ItemName: item.ItemName, Amount: item.Price, PlanItemID: &id}
+		if item.Tooth != 0 {
+			form.Teeth = []models.ToothRef{{Tooth: item.Tooth, Surfaces: item.Surfaces}}
+		}
+		forms = append(forms, form)
+	}
+	return forms, nil
+}
+
+// validatePlan checks the plan and fills item names from their procedures
+func (h *TreatmentPlanHandler) validatePlan(plan *models.TreatmentPlan) error {
+	plan.Title = strings.TrimSpace(plan.Title)
+	plan.Notes = strings.TrimSpace(plan.Notes)
+	if plan.Title == "" {
+		return fmt.Errorf("treatment plan title is required")
+	}
+	if plan.PatientID <= 0 {
+		return fmt.Errorf("patient is required")
+	}
+	if len(plan.Items) == 0 {
+		return fmt.Errorf("add at least one procedure to the plan")
+	}
+	for i := range plan.Items {
+		item := &plan.Items[i]
+		if item.Phase < 1 {
+			item.Phase = 1
+		}
+		if item.Price < 0 {
+			return fmt.Errorf("price cannot be negative")
+		}
+		switch item.Status {
+		case "":
+			item.Status = models.PlanItemProposed
+		case models.PlanItemProposed, models.PlanItemAccepted, models.PlanItemScheduled, models.PlanItemDeclined:
+		case models.PlanItemDone:
+			// Done follows from the session the item was performed in
+			item.Status = models.PlanItemAccepted
+		default:
+			return fmt.Errorf("invalid treatment plan item status: %s", item.Status)
+		}
+		if item.Tooth != 0 {
+			ref := models.ToothRef{Tooth: item.Tooth, Surfaces: item.Surfaces}
+			if err := validateToothRef(&ref); err != nil {
+				return err
+			}
+			item.Surfaces = ref.Surfaces
+		} else {
+			item.Surfaces = ""
+		}
+		item.ItemName = strings.TrimSpace(item.ItemName)
+		if item.ProcedureID == nil {
+			if item.ItemName == "" {
+				return fmt.Errorf("select a procedure for every plan item")
+			}
+			continue
+		}
+		var name string
+		err := h.db.QueryRow(`SELECT name FROM dental_procedures WHERE id = ?`, *item.ProcedureID).Scan(&name)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("procedure not found")
+		} else if err != nil {
+			return fmt.Errorf("failed to get procedure: %v", err)
+		}
+		if item.ItemName == "" {
+			item.ItemName = name
+		}
+	}
+	return nil
+}
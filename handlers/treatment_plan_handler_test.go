@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"testing"
+
+	"DentistApp/models"
+)
+
+func TestTreatmentPlanProgress(t *testing.T) {
+	db, admin := newTestAdmin(t)
+	var patientIDs []int
+	for _, name := range []string{"Jane Doe", "John Roe"} {
+		patientIDs = append(patientIDs, newTestPatient(t, db, models.PatientForm{Name: name, Phone: "0100000000"}))
+	}
+	result, err := db.Exec(`INSERT INTO dental_procedures (name, price) VALUES ('Root canal', 500000)`)
+	if err != nil {
+		t.Fatalf("failed to insert procedure: %v", err)
+	}
+	procedureID64, _ := result.LastInsertId()
+	procedureID := int(procedureID64)
+
+	plans := NewTreatmentPlanHandler(db)
+	if _, err := plans.CreateTreatmentPlan(models.TreatmentPlan{PatientID: patientIDs[0], Title: "Empty"}, admin.ID); err == nil {
+		t.Errorf("CreateTreatmentPlan accepted a plan without items")
+	}
+	planID, err := plans.CreateTreatmentPlan(models.TreatmentPlan{
+		PatientID: patientIDs[0],
+		Title:     "Upper right",
+		Items: []models.TreatmentPlanItem{
+			{Phase: 1, ProcedureID: &procedureID, Tooth: 16, Price: 500000},
+			{Phase: 1, ItemName: "Filling", Tooth: 15, Surfaces: "od", Price: 200000},
+			{Phase: 2, ItemName: "Whitening", Price: 300000},
+		},
+	}, admin.ID)
+	if err != nil {
+		t.Fatalf("CreateTreatmentPlan failed: %v", err)
+	}
+	plan, err := plans.GetTreatmentPlan(int(planID))
+	if err != nil {
+		t.Fatalf("GetTreatmentPlan failed: %v", err)
+	}
+	if plan.Estimate != 1000000 || plan.AcceptedTotal != 0 || plan.Items[0].ItemName != "Root canal" || plan.Items[1].Surfaces != "OD" {
+		t.Fatalf("new plan = %+v; expected a 1000000 estimate with nothing accepted", plan)
+	}
+
+	// The patient accepts phase 1 and declines whitening
+	if err := plans.AcceptTreatmentPlan(plan.ID, []int{plan.Items[0].ID, plan.Items[1].ID}, admin.ID); err != nil {
+		t.Fatalf("AcceptTreatmentPlan failed: %v", err)
+	}
+	plan, _ = plans.GetTreatmentPlan(plan.ID)
+	if plan.AcceptedAt == "" || plan.Items[2].Status != models.PlanItemDeclined || plan.Estimate != 700000 || plan.Remaining != 700000 {
+		t.Fatalf("accepted plan = %+v; expected 700000 remaining and whitening declined", plan)
+	}
+
+	if _, err := plans.PlanSessionItems([]int{plan.Items[2].ID}); err == nil {
+		t.Errorf("PlanSessionItems accepted a declined item")
+	}
+	lines, err := plans.PlanSessionItems([]int{plan.Items[0].ID})
+	if err != nil {
+		t.Fatalf("PlanSessionItems failed: %v", err)
+	}
+	if len(lines) != 1 || lines[0].Amount != 500000 || len(lines[0].Teeth) != 1 || lines[0].Teeth[0].Tooth != 16 {
+		t.Fatalf("session lines = %+v; expected the root canal on 16", lines)
+	}
+
+	sessions := NewSessionHandler(db)
+	form := models.SessionForm{PatientID: patientIDs[1], DentistID: admin.ID, SessionDate: "2025-03-01", Status: "in-progress", Items: lines}
+	if _, err := sessions.CreateSession(form, admin.ID); err == nil {
+		t.Errorf("CreateSession performed another patient's plan item")
+	}
+	form.PatientID = patientIDs[0]
+	sessionID, err := sessions.CreateSession(form, admin.ID)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	plan, _ = plans.GetTreatmentPlan(plan.ID)
+	if plan.Items[0].Status != models.PlanItemScheduled || plan.Items[0].SessionID == nil || *plan.Items[0].SessionID != int(sessionID) {
+		t.Errorf("item in an in-progress session = %+v; expected scheduled", plan.Items[0])
+	}
+	if _, err := sessions.CreateSession(form, admin.ID); err == nil {
+		t.Errorf("CreateSession performed a plan item twice")
+	}
+
+	session, err := sessions.GetSession(int(sessionID))
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	session.Status = "completed"
+	if err := sessions.UpdateSession(session, lines, admin.ID); err != nil {
+		t.Fatalf("UpdateSession failed: %v", err)
+	}
+	plan, _ = plans.GetTreatmentPlan(plan.ID)
+	if plan.Items[0].Status != models.PlanItemDone || plan.DoneTotal != 500000 || plan.Remaining != 200000 {
+		t.Errorf("plan after the session = %+v; expected the root canal done and 200000 remaining", plan)
+	}
+
+	balance, err := NewPaymentHandler(db).GetPatientBalance(patientIDs[0])
+	if err != nil {
+		t.Fatalf("GetPatientBalance failed: %v", err)
+	}
+	if balance.PlanEstimate != 700000 || balance.PlanRemaining != 200000 {
+		t.Errorf("balance = %+v; expected a 700000 plan estimate with 200000 remaining", balance)
+	}
+
+	// Performed items cannot be dropped from the plan, and deleting the session reopens them
+	plan.Items = plan.Items[1:]
+	if err := plans.UpdateTreatmentPlan(plan, admin.ID); err == nil {
+		t.Errorf("UpdateTreatmentPlan removed a performed item")
+	}
+	if err := sessions.DeleteSession(int(sessionID), admin.ID); err != nil {
+		t.Fatalf("DeleteSession failed: %v", err)
+	}
+	plan, _ = plans.GetTreatmentPlan(plan.ID)
+	if plan.Items[0].Status != models.PlanItemAccepted || plan.Remaining != 700000 {
+		t.Errorf("plan after deleting the session = %+v; expected the root canal accepted again", plan)
+	}
+}
@@ -0,0 +1,542 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"DentistApp/models"
+)
+
+// WaitlistHandler handles the waiting list of patients who want an earlier appointment
+type WaitlistHandler struct {
+	db *sql.DB
+}
+
+// NewWaitlistHandler creates new handler
+func NewWaitlistHandler(db *sql.DB) *WaitlistHandler {
+	return &WaitlistHandler{db: db}
+}
+
+const waitlistSelect = `SELECT w.id, w.patient_id, p.name, COALESCE(p.phone, ''), w.dentist_id, COALESCE(u.username, ''),
+	                           w.procedure_id, COALESCE(dp.name, ''), w.appointment_id, COALESCE(a.datetime, ''),
+	                           w.duration, w.priority, w.earliest_date, w.latest_date, w.notes, w.status,
+	                           w.offered_datetime, w.offered_dentist_id, w.offered_chair_id, w.offered_at,
+	                           w.booked_appointment_id, w.created_at
+	                    FROM waitlist w
+	                    JOIN patients p ON w.patient_id = p.id
+	                    LEFT JOIN users u ON w.dentist_id = u.id
+	                    LEFT JOIN dental_procedures dp ON w.procedure_id = dp.id
+	                    LEFT JOIN appointments a ON w.appointment_id = a.id`
+
+func scanWaitlistEntry(scanner interface{ Scan(...any) error }) (models.WaitlistEntry, error) {
+	var e models.WaitlistEntry
+	var dentistID, procedureID, appointmentID, offeredDentistID, offeredChairID, bookedID sql.NullInt64
+	err := scanner.Scan(&e.ID, &e.PatientID, &e.PatientName, &e.PatientPhone, &dentistID, &e.DentistName,
+		&procedureID, &e.ProcedureName, &appointmentID, &e.AppointmentDateTime,
+		&e.Duration, &e.Priority, &e.EarliestDate, &e.LatestDate, &e.Notes, &e.Status,
+		&e.OfferedDateTime, &offeredDentistID, &offeredChairID, &e.OfferedAt, &bookedID, &e.CreatedAt)
+	if err != nil {
+		return e, err
+	}
+	e.DentistID = nullableInt(dentistID)
+	e.ProcedureID = nullableInt(procedureID)
+	e.AppointmentID = nullableInt(appointmentID)
+	e.OfferedDentistID = nullableInt(offeredDentistID)
+	e.OfferedChairID = nullableInt(offeredChairID)
+	e.BookedAppointmentID = nullableInt(bookedID)
+	e.Windows = []models.WaitlistWindow{}
+	return e, nil
+}
+
+func nullableInt(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	i := int(v.Int64)
+	return &i
+}
+
+// GetWaitlist returns the entries with the given status, or those still waiting or offered a slot
+// when status is empty, highest priority and longest waiting first
+func (h *WaitlistHandler) GetWaitlist(status string) ([]models.WaitlistEntry, error) {
+	query := waitlistSelect + ` WHERE w.status IN (?, ?) ORDER BY w.priority, w.created_at, w.id`
+	args := []any{models.WaitlistWaiting, models.WaitlistOffered}
+	if status != "" {
+		query = waitlistSelect + ` WHERE w.status = ? ORDER BY w.priority, w.created_at, w.id`
+		args = []any{status}
+	}
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get waiting list: %v", err)
+	}
+	entries := []models.WaitlistEntry{}
+	byID := map[int]int{}
+	for rows.Next() {
+		e, err := scanWaitlistEntry(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan waiting list entry: %v", err)
+		}
+		byID[e.ID] = len(entries)
+		entries = append(entries, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return entries, nil
+	}
+
+	windows, err := h.db.Query(`SELECT id, waitlist_id, weekday, start_time, end_time FROM waitlist_windows ORDER BY weekday, start_time`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get waiting list times: %v", err)
+	}
+	defer windows.Close()
+	for windows.Next() {
+		var w models.WaitlistWindow
+		var entryID int
+		if err := windows.Scan(&w.ID, &entryID, &w.Weekday, &w.StartTime, &w.EndTime); err != nil {
+			return nil, fmt.Errorf("failed to scan waiting list time: %v", err)
+		}
+		if i, ok := byID[entryID]; ok {
+			entries[i].Windows = append(entries[i].Windows, w)
+		}
+	}
+	return entries, windows.Err()
+}
+
+// getEntry returns one entry with its windows
+func (h *WaitlistHandler) getEntry(id int) (models.WaitlistEntry, error) {
+	e, err := scanWaitlistEntry(h.db.QueryRow(waitlistSelect+` WHERE w.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return e, fmt.Errorf("waiting list entry not found")
+	} else if err != nil {
+		return e, fmt.Errorf("failed to get waiting list entry: %v", err)
+	}
+	rows, err := h.db.Query(`SELECT id, weekday, start_time, end_time FROM waitlist_windows WHERE waitlist_id = ? ORDER BY weekday, start_time`, id)
+	if err != nil {
+		return e, fmt.Errorf("failed to get waiting list times: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var w models.WaitlistWindow
+		if err := rows.Scan(&w.ID, &w.Weekday, &w.StartTime, &w.EndTime); err != nil {
+			return e, fmt.Errorf("failed to scan waiting list time: %v", err)
+		}
+		e.Windows = append(e.Windows, w)
+	}
+	return e, rows.Err()
+}
+
+// AddWaitlistEntry puts a patient on the waiting list. A duration of 0 uses the linked appointment's
+// duration, or the clinic's default.
+func (h *WaitlistHandler) AddWaitlistEntry(entry models.WaitlistEntry, actorID int) (int64, error) {
+	if err := h.validateEntry(&entry); err != nil {
+		return 0, err
+	}
+	tx, err := h.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`INSERT INTO waitlist (patient_id, dentist_id, procedure_id, appointment_id, duration, priority, earliest_date, latest_date, notes)
+	                        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.PatientID, entry.DentistID, entry.ProcedureID, entry.AppointmentID, entry.Duration, entry.Priority,
+		entry.EarliestDate, entry.LatestDate, entry.Notes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add waiting list entry: %v", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if err := insertWaitlistWindows(tx, id, entry.Windows); err != nil {
+		return 0, err
+	}
+	if err := recordAudit(tx, actorID, auditWaitlist, id, AuditActionCreate, nil); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// UpdateWaitlistEntry changes an entry that is still waiting or offered a slot, replacing its windows
+func (h *WaitlistHandler) UpdateWaitlistEntry(entry models.WaitlistEntry, actorID int) error {
+	if err := h.validateEntry(&entry); err != nil {
+		return err
+	}
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	before, err := auditSnapshot(tx, auditWaitlist, int64(entry.ID))
+	if err != nil {
+		return err
+	}
+	result, err := tx.Exec(`UPDATE waitlist SET patient_id = ?, dentist_id = ?, procedure_id = ?, appointment_id = ?, duration = ?,
+	                        priority = ?, earliest_date = ?, latest_date = ?, notes = ?
+	                        WHERE id = ? AND status IN (?, ?)`,
+		entry.PatientID, entry.DentistID, entry.ProcedureID, entry.AppointmentID, entry.Duration,
+		entry.Priority, entry.EarliestDate, entry.LatestDate, entry.Notes,
+		entry.ID, models.WaitlistWaiting, models.WaitlistOffered)
+	if err != nil {
+		return fmt.Errorf("failed to update waiting list entry: %v", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("waiting list entry not found or already closed")
+	}
+	if _, err := tx.Exec(`DELETE FROM waitlist_windows WHERE waitlist_id = ?`, entry.ID); err != nil {
+		return fmt.Errorf("failed to clear waiting list times: %v", err)
+	}
+	if err := insertWaitlistWindows(tx, int64(entry.ID), entry.Windows); err != nil {
+		return err
+	}
+	if err := recordAudit(tx, actorID, auditWaitlist, int64(entry.ID), AuditActionUpdate, before); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertWaitlistWindows(tx *sql.Tx, entryID int64, windows []models.WaitlistWindow) error {
+	for _, w := range windows {
+		if _, err := tx.Exec(`INSERT INTO waitlist_windows (waitlist_id, weekday, start_time, end_time) VALUES (?, ?, ?, ?)`,
+			entryID, w.Weekday, w.StartTime, w.EndTime); err != nil {
+			return fmt.Errorf("failed to save waiting list time: %v", err)
+		}
+	}
+	return nil
+}
+
+// RemoveWaitlistEntry takes a patient off the waiting list
+func (h *WaitlistHandler) RemoveWaitlistEntry(id int, actorID int) error {
+	return h.setStatus(id, actorID, `UPDATE waitlist SET status = ? WHERE id = ? AND status IN (?, ?)`,
+		models.WaitlistRemoved, id, models.WaitlistWaiting, models.WaitlistOffered)
+}
+
+// OfferWaitlistSlot records that the patient has been offered slot and is deciding
+func (h *WaitlistHandler) OfferWaitlistSlot(id int, slot models.WaitlistSlot, actorID int) error {
+	if _, err := parseAppointmentTime(slot.DateTime); err != nil {
+		return err
+	}
+	return h.setStatus(id, actorID, `UPDATE waitlist SET status = ?, offered_datetime = ?, offered_dentist_id = ?, offered_chair_id = ?, offered_at = ?
+	                                 WHERE id = ? AND status IN (?, ?)`,
+		models.WaitlistOffered, slot.DateTime, slot.DentistID, slot.ChairID, time.Now().Format("2006-01-02 15:04:05"),
+		id, models.WaitlistWaiting, models.WaitlistOffered)
+}
+
+// DeclineWaitlistOffer puts a patient who turned down an offered slot back on the waiting list
+func (h *WaitlistHandler) DeclineWaitlistOffer(id int, actorID int) error {
+	return h.setStatus(id, actorID, `UPDATE waitlist SET status = ?, offered_datetime = '', offered_dentist_id = NULL, offered_chair_id = NULL, offered_at = ''
+	                                 WHERE id = ? AND status = ?`,
+		models.WaitlistWaiting, id, models.WaitlistOffered)
+}
+
+func (h *WaitlistHandler) setStatus(id int, actorID int, query string, args ...any) error {
+	result, err := auditedExec(h.db, actorID, auditWaitlist, int64(id), AuditActionUpdate, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update waiting list entry: %v", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("waiting list entry not found or already closed")
+	}
+	return nil
+}
+
+// FindWaitlistMatches returns the waiting patients who fit slot, best first: by priority, then those
+// who asked for the slot's dentist, then by how much sooner the slot is than their current booking,
+// then by how long they have waited. A patient fits if the slot is long enough, within their dates and
+// windows, with their preferred dentist, earlier than their current booking, not overlapping another
+// of their appointments and the slot's dentist and chair are still free for their visit.
+func (h *WaitlistHandler) FindWaitlistMatches(slot models.WaitlistSlot) ([]models.WaitlistMatch, error) {
+	start, err := parseAppointmentTime(slot.DateTime)
+	if err != nil {
+		return nil, err
+	}
+	matches := []models.WaitlistMatch{}
+	if !start.After(time.Now()) || slot.Duration <= 0 {
+		return matches, nil
+	}
+	entries, err := h.GetWaitlist(models.WaitlistWaiting)
+	if err != nil {
+		return nil, err
+	}
+
+	appointments := NewAppointmentHandler(h.db)
+	day := start.Format("2006-01-02")
+	for _, e := range entries {
+		if e.Duration > slot.Duration {
+			continue
+		}
+		if e.DentistID != nil && (slot.DentistID == nil || *e.DentistID != *slot.DentistID) {
+			continue
+		}
+		if (e.EarliestDate != "" && day < e.EarliestDate) || (e.LatestDate != "" && day > e.LatestDate) {
+			continue
+		}
+		end := start.Add(time.Duration(e.Duration) * time.Minute)
+		if !fitsWaitlistWindows(e.Windows, start, end) {
+			continue
+		}
+		match := models.WaitlistMatch{Entry: e}
+		if e.AppointmentID != nil && e.AppointmentDateTime != "" {
+			current, err := parseAppointmentTime(e.AppointmentDateTime)
+			if err == nil {
+				if !current.After(start) {
+					continue
+				}
+				match.DaysEarlier = int(dateOnly(current).Sub(dateOnly(start)).Hours() / 24)
+			}
+		}
+		busy, err := h.patientBusy(e.PatientID, e.AppointmentID, start, end)
+		if err != nil {
+			return nil, err
+		}
+		if busy {
+			continue
+		}
+		candidate := models.Appointment{DateTime: slot.DateTime, Duration: e.Duration, DentistID: slot.DentistID, ChairID: slot.ChairID}
+		if e.AppointmentID != nil {
+			candidate.ID = *e.AppointmentID
+		}
+		conflicts, err := appointments.FindConflicts(candidate)
+		if err != nil {
+			return nil, err
+		}
+		if len(conflicts) > 0 {
+			continue
+		}
+		matches = append(matches, match)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Entry.Priority != b.Entry.Priority {
+			return a.Entry.Priority < b.Entry.Priority
+		}
+		if aPrefers, bPrefers := a.Entry.DentistID != nil, b.Entry.DentistID != nil; aPrefers != bPrefers {
+			return aPrefers
+		}
+		if a.DaysEarlier != b.DaysEarlier {
+			return a.DaysEarlier > b.DaysEarlier
+		}
+		return a.Entry.CreatedAt < b.Entry.CreatedAt
+	})
+	return matches, nil
+}
+
+// fitsWaitlistWindows reports whether start-end lies within one of the windows; no windows means any time
+func fitsWaitlistWindows(windows []models.WaitlistWindow, start, end time.Time) bool {
+	if len(windows) == 0 {
+		return true
+	}
+	if end.YearDay() != start.YearDay() {
+		return false
+	}
+	from, to := start.Format("15:04"), end.Format("15:04")
+	for _, w := range windows {
+		if (w.Weekday == -1 || w.Weekday == int(start.Weekday())) && w.StartTime <= from && to <= w.EndTime {
+			return true
+		}
+	}
+	return false
+}
+
+// patientBusy reports whether the patient has another appointment overlapping start-end, ignoring
+// the booking with id except (the one that would be moved)
+func (h *WaitlistHandler) patientBusy(patientID int, except *int, start, end time.Time) (bool, error) {
+	lower, upper := appointmentScanBounds(start, start)
+	rows, err := h.db.Query(`SELECT id, datetime, COALESCE(duration, 0) FROM appointments
+	                         WHERE patient_id = ? AND status NOT IN ('cancelled', 'no_show')
+	                           AND datetime >= ? AND datetime < ?`, patientID, lower, upper)
+	if err != nil {
+		return false, fmt.Errorf("failed to check the patient's appointments: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, duration int
+		var datetime string
+		if err := rows.Scan(&id, &datetime, &duration); err != nil {
+			return false, fmt.Errorf("failed to scan appointment: %v", err)
+		}
+		if except != nil && id == *except {
+			continue
+		}
+		other, err := parseAppointmentTime(datetime)
+		if err != nil {
+			continue
+		}
+		if start.Before(other.Add(time.Duration(duration)*time.Minute)) && other.Before(end) {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// BookWaitlistSlot books the patient into slot and closes their entry. A patient with a current
+// booking has it moved to the slot; otherwise a new appointment is made. The booking is checked for
+// overlaps and working hours like any other. It returns the appointment ID.
+func (h *WaitlistHandler) BookWaitlistSlot(id int, slot models.WaitlistSlot, actorID int) (int64, error) {
+	entry, err := h.getEntry(id)
+	if err != nil {
+		return 0, err
+	}
+	if entry.Status != models.WaitlistWaiting && entry.Status != models.WaitlistOffered {
+		return 0, fmt.Errorf("waiting list entry is already closed")
+	}
+
+	appointments := NewAppointmentHandler(h.db)
+	appt := models.Appointment{
+		PatientID: entry.PatientID,
+		DateTime:  slot.DateTime,
+		Duration:  entry.Duration,
+		DentistID: slot.DentistID,
+		ChairID:   slot.ChairID,
+	}
+	var notes []string
+	for _, s := range []string{entry.ProcedureName, entry.Notes} {
+		if s != "" {
+			notes = append(notes, s)
+		}
+	}
+	appt.Notes = strings.Join(notes, "\n")
+	if entry.AppointmentID != nil {
+		current, err := appointments.GetAppointment(*entry.AppointmentID)
+		if err == nil && (current.Status == models.AppointmentScheduled || current.Status == models.AppointmentConfirmed) {
+			appt.ID = current.ID
+			appt.Notes = current.Notes
+		} else if err != nil && err != sql.ErrNoRows {
+			return 0, fmt.Errorf("failed to get appointment: %v", err)
+		}
+	}
+	tx, err := h.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := validateAppointment(tx, appt); err != nil {
+		return 0, err
+	}
+
+	apptID := int64(appt.ID)
+	if appt.ID != 0 {
+		before, err := auditSnapshot(tx, auditAppointment, apptID)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`UPDATE appointments SET datetime = ?, duration = ?, dentist_id = ?, chair_id = ? WHERE id = ?`,
+			appt.DateTime, appt.Duration, appt.DentistID, appt.ChairID, appt.ID); err != nil {
+			return 0, fmt.Errorf("failed to move appointment: %v", err)
+		}
+		if err := recordAudit(tx, actorID, auditAppointment, apptID, AuditActionUpdate, before); err != nil {
+			return 0, err
+		}
+	} else {
+		result, err := tx.Exec(`INSERT INTO appointments (patient_id, datetime, duration, notes, dentist_id, chair_id) VALUES (?, ?, ?, ?, ?, ?)`,
+			appt.PatientID, appt.DateTime, appt.Duration, appt.Notes, appt.DentistID, appt.ChairID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to add appointment: %v", err)
+		}
+		if apptID, err = result.LastInsertId(); err != nil {
+			return 0, err
+		}
+		if err := recordAudit(tx, actorID, auditAppointment, apptID, AuditActionCreate, nil); err != nil {
+			return 0, err
+		}
+	}
+
+	before, err := auditSnapshot(tx, auditWaitlist, int64(id))
+	if err != nil {
+		return 0, err
+	}
+	// The status guard stops two workstations booking the same entry
+	result, err := tx.Exec(`UPDATE waitlist SET status = ?, booked_appointment_id = ? WHERE id = ? AND status = ?`,
+		models.WaitlistBooked, apptID, id, entry.Status)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update waiting list entry: %v", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return 0, fmt.Errorf("waiting list entry was changed by someone else, please reload")
+	}
+	if err := recordAudit(tx, actorID, auditWaitlist, int64(id), AuditActionUpdate, before); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to book appointment: %v", err)
+	}
+	return apptID, nil
+}
+
+// validateEntry checks an entry and fills in its duration and priority defaults
+func (h *WaitlistHandler) validateEntry(entry *models.WaitlistEntry) error {
+	var exists bool
+	if err := h.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM patients WHERE id = ?)`, entry.PatientID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check patient: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("patient not found")
+	}
+	if entry.AppointmentID != nil {
+		var patientID int
+		var datetime string
+		var duration int
+		err := h.db.QueryRow(`SELECT patient_id, datetime, COALESCE(duration, 0) FROM appointments WHERE id = ? AND status IN (?, ?)`,
+			*entry.AppointmentID, models.AppointmentScheduled, models.AppointmentConfirmed).Scan(&patientID, &datetime, &duration)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("the appointment to move is not an upcoming booking")
+		} else if err != nil {
+			return fmt.Errorf("failed to check appointment: %v", err)
+		}
+		if patientID != entry.PatientID {
+			return fmt.Errorf("the appointment to move belongs to another patient")
+		}
+		if entry.Duration <= 0 {
+			entry.Duration = duration
+		}
+	}
+	if entry.Duration <= 0 {
+		settings, err := loadClinicSettings(h.db)
+		if err != nil {
+			return err
+		}
+		entry.Duration = settings.DefaultAppointmentDuration
+	}
+	if entry.Priority == 0 {
+		entry.Priority = models.WaitlistPriorityNormal
+	}
+	if entry.Priority < models.WaitlistPriorityUrgent || entry.Priority > models.WaitlistPriorityNormal {
+		return fmt.Errorf("invalid priority %d", entry.Priority)
+	}
+	for _, date := range []string{entry.EarliestDate, entry.LatestDate} {
+		if _, err := time.Parse("2006-01-02", date); date != "" && err != nil {
+			return fmt.Errorf("invalid date %q", date)
+		}
+	}
+	if entry.EarliestDate != "" && entry.LatestDate != "" && entry.LatestDate < entry.EarliestDate {
+		return fmt.Errorf("the latest date is before the earliest date")
+	}
+	for _, w := range entry.Windows {
+		if w.Weekday < -1 || w.Weekday > 6 {
+			return fmt.Errorf("invalid weekday %d", w.Weekday)
+		}
+		start, err := time.Parse("15:04", w.StartTime)
+		if err != nil {
+			return fmt.Errorf("invalid start time %q", w.StartTime)
+		}
+		end, err := time.Parse("15:04", w.EndTime)
+		if err != nil {
+			return fmt.Errorf("invalid end time %q", w.EndTime)
+		}
+		if !end.After(start) {
+			return fmt.Errorf("available times must end after they start (%s-%s)", w.StartTime, w.EndTime)
+		}
+	}
+	entry.Notes = strings.TrimSpace(entry.Notes)
+	return nil
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"DentistApp/models"
+)
+
+func TestWaitlistMatchesAndBooking(t *testing.T) {
+	db, admin := newTestAdmin(t)
+	auth := NewAuthHandler(db)
+	dentistID, err := auth.CreateUser(models.UserForm{Username: "dr.smith", Password: "secret1", Role: models.RoleDentist}, admin.ID)
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	dentist := int(dentistID)
+	for _, name := range []string{"Ann", "Bob", "Cid", "Dee"} {
+		newTestPatient(t, db, models.PatientForm{Name: name})
+	}
+
+	day := time.Now().AddDate(0, 0, 7)
+	slot := models.WaitlistSlot{DateTime: day.Format("2006-01-02") + "T10:00", Duration: 60, DentistID: &dentist}
+	appointments := NewAppointmentHandler(db)
+	freed, err := appointments.AddAppointment(models.Appointment{PatientID: 1, DateTime: slot.DateTime, Duration: 60, DentistID: &dentist}, admin.ID)
+	if err != nil {
+		t.Fatalf("AddAppointment failed: %v", err)
+	}
+	later := day.AddDate(0, 0, 14).Format("2006-01-02") + "T09:00"
+	bobsBooking, err := appointments.AddAppointment(models.Appointment{PatientID: 2, DateTime: later, Duration: 30}, admin.ID)
+	if err != nil {
+		t.Fatalf("AddAppointment failed: %v", err)
+	}
+
+	waitlist := NewWaitlistHandler(db)
+	bookingID := int(bobsBooking)
+	add := func(entry models.WaitlistEntry) int {
+		t.Helper()
+		id, err := waitlist.AddWaitlistEntry(entry, admin.ID)
+		if err != nil {
+			t.Fatalf("AddWaitlistEntry failed: %v", err)
+		}
+		return int(id)
+	}
+	// Ann already holds the slot; Bob wants to move his booking two weeks earlier; Cid is urgent for
+	// this dentist; Dee can only come in the afternoon
+	add(models.WaitlistEntry{PatientID: 1, Duration: 30})
+	bob := add(models.WaitlistEntry{PatientID: 2, AppointmentID: &bookingID})
+	cid := add(models.WaitlistEntry{PatientID: 3, Duration: 45, DentistID: &dentist, Priority: models.WaitlistPriorityUrgent})
+	add(models.WaitlistEntry{PatientID: 4, Duration: 30, Windows: []models.WaitlistWindow{{Weekday: -1, StartTime: "13:00", EndTime: "17:00"}}})
+	if _, err := waitlist.AddWaitlistEntry(models.WaitlistEntry{PatientID: 1, AppointmentID: &bookingID}, admin.ID); err == nil {
+		t.Errorf("expected moving another patient's appointment to be rejected")
+	}
+
+	if err := appointments.SetAppointmentStatus(int(freed), models.AppointmentCancelled, "patient is ill", admin.ID); err != nil {
+		t.Fatalf("SetAppointmentStatus failed: %v", err)
+	}
+	matches, err := waitlist.FindWaitlistMatches(slot)
+	if err != nil {
+		t.Fatalf("FindWaitlistMatches failed: %v", err)
+	}
+	var ranked []string
+	for _, m := range matches {
+		ranked = append(ranked, m.Entry.PatientName)
+	}
+	// Ann's cancelled visit no longer blocks her
+	if len(ranked) != 3 || ranked[0] != "Cid" || ranked[1] != "Bob" || ranked[2] != "Ann" {
+		t.Fatalf("unexpected ranking %v", ranked)
+	}
+	if matches[1].DaysEarlier != 14 || matches[1].Entry.Duration != 30 {
+		t.Errorf("expected Bob's 30 minute booking to move 14 days earlier, got %+v", matches[1])
+	}
+
+	if err := waitlist.OfferWaitlistSlot(cid, slot, admin.ID); err != nil {
+		t.Fatalf("OfferWaitlistSlot failed: %v", err)
+	}
+	if err := waitlist.DeclineWaitlistOffer(cid, admin.ID); err != nil {
+		t.Fatalf("DeclineWaitlistOffer failed: %v", err)
+	}
+
+	movedID, err := waitlist.BookWaitlistSlot(bob, slot, admin.ID)
+	if err != nil {
+		t.Fatalf("BookWaitlistSlot failed: %v", err)
+	}
+	moved, err := appointments.GetAppointment(int(movedID))
+	if err != nil || movedID != bobsBooking || moved.DateTime != slot.DateTime || moved.DentistID == nil {
+		t.Errorf("expected Bob's booking to move into the slot, got %+v %v", moved, err)
+	}
+	if _, err := waitlist.BookWaitlistSlot(bob, slot, admin.ID); err == nil {
+		t.Errorf("expected booking a closed entry to fail")
+	}
+	if _, err := waitlist.BookWaitlistSlot(cid, slot, admin.ID); err == nil {
+		t.Errorf("expected booking over Bob's new appointment to conflict")
+	}
+	if matches, _ := waitlist.FindWaitlistMatches(slot); len(matches) != 0 {
+		t.Errorf("expected nobody to fit the filled slot, got %v", matches)
+	}
+
+	active, err := waitlist.GetWaitlist("")
+	if err != nil || len(active) != 3 {
+		t.Fatalf("expected 3 patients still waiting, got %d %v", len(active), err)
+	}
+	for _, e := range active {
+		if e.PatientName == "Dee" && len(e.Windows) != 1 {
+			t.Errorf("expected Dee's afternoon window, got %v", e.Windows)
+		}
+	}
+}
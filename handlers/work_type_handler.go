@@ -24,17 +24,7 @@ func (h *WorkTypeHandler) CreateWorkType(workType models.WorkTypeForm, userID in
 	}
 
 	query := `INSERT INTO work_types (name, description, created_by) VALUES (?, ?, ?)`
-	result, err := h.db.Exec(query, workType.Name, workType.Description, userID)
-	if err != nil {
-		return 0, err
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
-	}
-
-	return id, nil
+	return auditedInsert(h.db, userID, auditWorkType, query, workType.Name, workType.Description, userID)
 }
 
 // GetWorkTypesPaginated returns paginated work types ordered by name
@@ -106,14 +96,14 @@ func (h *WorkTypeHandler) UpdateWorkType(id int, workType models.WorkTypeForm, u
 	}
 
 	query := `UPDATE work_types SET name = ?, description = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
-	_, err := h.db.Exec(query, workType.Name, workType.Description, id)
+	_, err := auditedExec(h.db, userID, auditWorkType, int64(id), AuditActionUpdate, query, workType.Name, workType.Description, id)
 	return err
 }
 
 // DeleteWorkType deletes work type by id
-func (h *WorkTypeHandler) DeleteWorkType(id int) error {
+func (h *WorkTypeHandler) DeleteWorkType(id int, userID int) error {
 	query := `DELETE FROM work_types WHERE id = ?`
-	_, err := h.db.Exec(query, id)
+	_, err := auditedExec(h.db, userID, auditWorkType, int64(id), AuditActionDelete, query, id)
 	return err
 }
 
@@ -0,0 +1,422 @@
+// Package ical reads and writes appointment calendars in the RFC 5545 iCalendar format.
+//
+// Only VEVENT components are handled. Written events use UTC times; when reading, times with a
+// TZID are converted from that zone (falling back to local time when the zone is unknown) and
+// floating times are taken as local time.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Event statuses from RFC 5545
+const (
+	StatusTentative = "TENTATIVE"
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+const (
+	utcLayout      = "20060102T150405Z"
+	localLayout    = "20060102T150405"
+	dateLayout     = "20060102"
+	maxLineOctets  = 75
+	productID      = "-//DentistApp//Appointments//EN"
+	maxCalendarLen = 16 << 20
+)
+
+// Attendee is an event participant. Address is the calendar user address, such as
+// "tel:+15550100" or "mailto:jane@example.com".
+type Attendee struct {
+	Name    string
+	Address string
+}
+
+// Phone returns the number of a tel: address, or "" for other addresses
+func (a Attendee) Phone() string {
+	if len(a.Address) > 4 && strings.EqualFold(a.Address[:4], "tel:") {
+		return a.Address[4:]
+	}
+	return ""
+}
+
+// Event is a calendar entry
+type Event struct {
+	UID         string
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Location    string
+	Status      string
+	Attendees   []Attendee
+	// Set when reading only
+	AllDay    bool
+	Recurring bool
+	// Invalid explains why an event read from a file could not be understood; its other fields may be incomplete
+	Invalid string
+	// Line is the line of the event's BEGIN:VEVENT in the file it was read from
+	Line int
+}
+
+// Calendar is a named list of events
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+// Write writes cal as an iCalendar file with CRLF line endings. stamp is used as every event's DTSTAMP.
+func Write(w io.Writer, cal Calendar, stamp time.Time) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeFolded(bw, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", productID)
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if cal.Name != "" {
+		line("X-WR-CALNAME", escapeText(cal.Name))
+	}
+	for _, e := range cal.Events {
+		if e.UID == "" {
+			return fmt.Errorf("calendar event has no UID")
+		}
+		line("BEGIN", "VEVENT")
+		line("UID", escapeText(e.UID))
+		line("DTSTAMP", stamp.UTC().Format(utcLayout))
+		line("DTSTART", e.Start.UTC().Format(utcLayout))
+		line("DTEND", e.End.UTC().Format(utcLayout))
+		line("SUMMARY", escapeText(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION", escapeText(e.Description))
+		}
+		if e.Location != "" {
+			line("LOCATION", escapeText(e.Location))
+		}
+		if e.Status != "" {
+			line("STATUS", e.Status)
+		}
+		for _, a := range e.Attendees {
+			name := "ATTENDEE"
+			if a.Name != "" {
+				name += ";CN=" + quoteParam(a.Name)
+			}
+			line(name, a.Address)
+		}
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// writeFolded writes a content line, folding it into continuation lines of at most 75 octets
+// without splitting UTF-8 characters
+func writeFolded(w *bufio.Writer, s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// Continuation lines start with a space, which counts toward the limit
+		limit = maxLineOctets - 1
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+func unescapeText(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// quoteParam quotes a parameter value when it contains characters that would end it. Double quotes
+// cannot appear in parameter values and are dropped.
+func quoteParam(s string) string {
+	s = strings.ReplaceAll(s, `"`, "")
+	if strings.ContainsAny(s, ";:,") {
+		return `"` + s + `"`
+	}
+	return s
+}
+
+// Parse reads the events of an iCalendar file. Problems with a single event are reported in its
+// Invalid field; an error is returned only when the input is not a calendar at all.
+func Parse(r io.Reader) (Calendar, error) {
+	var cal Calendar
+	data, err := io.ReadAll(io.LimitReader(r, maxCalendarLen+1))
+	if err != nil {
+		return cal, fmt.Errorf("failed to read calendar: %v", err)
+	}
+	if len(data) > maxCalendarLen {
+		return cal, fmt.Errorf("calendar file is larger than %d MB", maxCalendarLen>>20)
+	}
+
+	lines, starts := unfold(string(data))
+	var stack []string
+	var event *Event
+	var duration time.Duration
+	seenCalendar := false
+
+	finish := func() {
+		e := *event
+		if e.Invalid == "" {
+			switch {
+			case e.Start.IsZero():
+				e.Invalid = "event has no start time"
+			case e.End.IsZero() && duration > 0:
+				e.End = e.Start.Add(duration)
+			case e.End.IsZero() && e.AllDay:
+				e.End = e.Start.AddDate(0, 0, 1)
+			case e.End.IsZero():
+				e.End = e.Start
+			case e.End.Before(e.Start):
+				e.Invalid = "event ends before it starts"
+			}
+		}
+		cal.Events = append(cal.Events, e)
+		event = nil
+	}
+
+	for i, raw := range lines {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		name, params, value, err := splitLine(raw)
+		if err != nil {
+			if event != nil && event.Invalid == "" {
+				event.Invalid = err.Error()
+			}
+			continue
+		}
+
+		switch name {
+		case "BEGIN":
+			component := strings.ToUpper(value)
+			if component == "VCALENDAR" {
+				seenCalendar = true
+			}
+			if component == "VEVENT" && len(stack) == 1 && stack[0] == "VCALENDAR" {
+				event = &Event{Line: starts[i]}
+				duration = 0
+			}
+			stack = append(stack, component)
+			continue
+		case "END":
+			if len(stack) == 0 {
+				return cal, fmt.Errorf("line %d: END:%s without BEGIN", starts[i], value)
+			}
+			if stack[len(stack)-1] == "VEVENT" && event != nil && len(stack) == 2 {
+				finish()
+			}
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		if len(stack) == 1 && stack[0] == "VCALENDAR" && name == "X-WR-CALNAME" {
+			cal.Name = unescapeText(value)
+			continue
+		}
+		// Properties of nested components such as VALARM do not belong to the event
+		if event == nil || len(stack) != 2 {
+			continue
+		}
+		if err := setProperty(event, &duration, name, params, value); err != nil && event.Invalid == "" {
+			event.Invalid = err.Error()
+		}
+	}
+
+	if !seenCalendar {
+		return cal, fmt.Errorf("file is not an iCalendar calendar")
+	}
+	if event != nil {
+		event.Invalid = "event is not closed with END:VEVENT"
+		finish()
+	}
+	return cal, nil
+}
+
+func setProperty(e *Event, duration *time.Duration, name string, params map[string]string, value string) error {
+	switch name {
+	case "UID":
+		e.UID = unescapeText(value)
+	case "SUMMARY":
+		e.Summary = unescapeText(value)
+	case "DESCRIPTION":
+		e.Description = unescapeText(value)
+	case "LOCATION":
+		e.Location = unescapeText(value)
+	case "STATUS":
+		e.Status = strings.ToUpper(value)
+	case "RRULE", "RDATE":
+		e.Recurring = true
+	case "ATTENDEE":
+		e.Attendees = append(e.Attendees, Attendee{Name: params["CN"], Address: value})
+	case "DTSTART":
+		t, allDay, err := parseTime(value, params)
+		if err != nil {
+			return fmt.Errorf("invalid start time: %v", err)
+		}
+		e.Start, e.AllDay = t, allDay
+	case "DTEND":
+		t, _, err := parseTime(value, params)
+		if err != nil {
+			return fmt.Errorf("invalid end time: %v", err)
+		}
+		e.End = t
+	case "DURATION":
+		d, err := parseDuration(value)
+		if err != nil {
+			return err
+		}
+		*duration = d
+	}
+	return nil
+}
+
+// unfold joins continuation lines and returns the logical lines with the file line each starts on
+func unfold(data string) ([]string, []int) {
+	data = strings.TrimPrefix(data, "\ufeff")
+	physical := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	var lines []string
+	var starts []int
+	for i, l := range physical {
+		l = strings.TrimSuffix(l, "\r")
+		if len(lines) > 0 && (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		lines = append(lines, l)
+		starts = append(starts, i+1)
+	}
+	return lines, starts
+}
+
+// splitLine splits a content line into its upper-cased name, parameters (names upper-cased,
+// quotes removed) and value
+func splitLine(line string) (string, map[string]string, string, error) {
+	inQuotes := false
+	colon := -1
+	for i := 0; i < len(line) && colon < 0; i++ {
+		switch line[i] {
+		case '"':
+			inQuotes = !inQuotes
+		case ':':
+			if !inQuotes {
+				colon = i
+			}
+		}
+	}
+	if colon < 0 {
+		return "", nil, "", fmt.Errorf("invalid calendar line %q", line)
+	}
+
+	head, value := line[:colon], line[colon+1:]
+	parts := splitOutsideQuotes(head, ';')
+	name := strings.ToUpper(strings.TrimSpace(parts[0]))
+	params := map[string]string{}
+	for _, p := range parts[1:] {
+		key, val, _ := strings.Cut(p, "=")
+		params[strings.ToUpper(strings.TrimSpace(key))] = strings.Trim(val, `"`)
+	}
+	return name, params, value, nil
+}
+
+func splitOutsideQuotes(s string, sep byte) []string {
+	var parts []string
+	inQuotes := false
+	last := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			inQuotes = !inQuotes
+		case sep:
+			if !inQuotes {
+				parts = append(parts, s[last:i])
+				last = i + 1
+			}
+		}
+	}
+	return append(parts, s[last:])
+}
+
+// parseTime reads a DATE or DATE-TIME value, reporting whether it was a date only
+func parseTime(value string, params map[string]string) (time.Time, bool, error) {
+	if params["VALUE"] == "DATE" || len(value) == len(dateLayout) {
+		t, err := time.ParseInLocation(dateLayout, value, time.Local)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(utcLayout, value)
+		return t.Local(), false, err
+	}
+	loc := time.Local
+	if tzid := params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation(localLayout, value, loc)
+	return t.Local(), false, err
+}
+
+var durationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseDuration reads a DURATION value such as PT45M or P1DT2H
+func parseDuration(value string) (time.Duration, error) {
+	m := durationPattern.FindStringSubmatch(strings.ToUpper(value))
+	if m == nil || value == "P" || strings.HasSuffix(value, "T") {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, unit := range units {
+		if m[i+2] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+2])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		d += time.Duration(n) * unit
+	}
+	if m[1] == "-" {
+		return 0, fmt.Errorf("negative duration %q", value)
+	}
+	return d, nil
+}
@@ -0,0 +1,113 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteAndParse(t *testing.T) {
+	start := time.Date(2025, 3, 4, 10, 30, 0, 0, time.Local)
+	cal := Calendar{
+		Name: "Smile Clinic",
+		Events: []Event{{
+			UID:         "appointment-7@dentistapp",
+			Start:       start,
+			End:         start.Add(45 * time.Minute),
+			Summary:     "Jane Doe",
+			Description: "Crown fitting; bring x-rays, please\nSecond line " + strings.Repeat("é", 60),
+			Location:    "Chair 1",
+			Status:      StatusConfirmed,
+			Attendees:   []Attendee{{Name: "Doe, Jane", Address: "tel:+15550100"}},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, cal, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	out := buf.String()
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+	}
+	for _, want := range []string{"UID:appointment-7@dentistapp\r\n", `ATTENDEE;CN="Doe, Jane":tel:+15550100`, `bring x-rays\, please\n`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+
+	parsed, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if parsed.Name != cal.Name || len(parsed.Events) != 1 {
+		t.Fatalf("unexpected calendar: %+v", parsed)
+	}
+	got, want := parsed.Events[0], cal.Events[0]
+	if got.UID != want.UID || !got.Start.Equal(want.Start) || !got.End.Equal(want.End) ||
+		got.Summary != want.Summary || got.Description != want.Description || got.Location != want.Location ||
+		got.Status != want.Status || len(got.Attendees) != 1 || got.Attendees[0] != want.Attendees[0] || got.Invalid != "" {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", got, want)
+	}
+	if phone := got.Attendees[0].Phone(); phone != "+15550100" {
+		t.Errorf("Phone() = %q", phone)
+	}
+}
+
+func TestParseVariants(t *testing.T) {
+	input := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"UID:a",
+		"DTSTART;TZID=UTC:20250304T090000",
+		"DURATION:PT1H30M",
+		"SUMMARY:Check-",
+		" up",
+		"BEGIN:VALARM",
+		"DESCRIPTION:Reminder",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:b",
+		"DTSTART;VALUE=DATE:20250305",
+		"RRULE:FREQ=WEEKLY;COUNT=3",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:c",
+		"DTSTART:2025-03-06 09:00",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\n")
+
+	cal, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(cal.Events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(cal.Events))
+	}
+
+	first := cal.Events[0]
+	wantStart := time.Date(2025, 3, 4, 9, 0, 0, 0, time.UTC)
+	if !first.Start.Equal(wantStart) || first.End.Sub(first.Start) != 90*time.Minute {
+		t.Errorf("unexpected times: %v - %v", first.Start, first.End)
+	}
+	if first.Summary != "Check-up" || first.Description != "" {
+		t.Errorf("unexpected text: %q / %q", first.Summary, first.Description)
+	}
+
+	second := cal.Events[1]
+	if !second.AllDay || !second.Recurring || second.End.Sub(second.Start) != 24*time.Hour {
+		t.Errorf("unexpected all-day event: %+v", second)
+	}
+	if cal.Events[2].Invalid == "" || cal.Events[2].Line != 17 {
+		t.Errorf("expected the third event to be invalid at line 17: %+v", cal.Events[2])
+	}
+
+	if _, err := Parse(strings.NewReader("BEGIN:VCARD\nEND:VCARD\n")); err == nil {
+		t.Errorf("expected a non-calendar file to be rejected")
+	}
+}
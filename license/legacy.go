@@ -0,0 +1,74 @@
+package license
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// Legacy keys hide a base64 expiry date (YYYYMMDD) at Fibonacci offsets inside a random string.
+// Anyone who knows the scheme can produce one, so no new ones are issued and existing keys are
+// only accepted during the migration to signed keys.
+
+const legacyLayout = "20060102"
+
+// legacyOffsets returns the fibonacci sequence used to place the date characters
+func legacyOffsets() []int {
+	return []int{3, 5, 8, 13, 21, 34, 55, 89, 144, 233, 377, 610, 987}
+}
+
+// ParseLegacy extracts the expiry date from a legacy key
+func ParseLegacy(key string) (time.Time, error) {
+	if len(key) < 3 {
+		return time.Time{}, fmt.Errorf("license key too short")
+	}
+
+	// Offset is a number from 10 to 99, or 1 to 9 for very old keys
+	offset, err := strconv.ParseInt(key[1:3], 10, 64)
+	if err != nil {
+		offset, err = strconv.ParseInt(key[1:2], 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid license key format")
+		}
+	}
+
+	var str string
+	for _, v := range legacyOffsets() {
+		if len(key) >= int(offset)+v+1 {
+			str += string(key[int(offset)+v+1])
+		}
+	}
+	if len(str) == 0 {
+		return time.Time{}, fmt.Errorf("could not extract date from license key")
+	}
+
+	// The encoded date is 11 characters once its padding is removed
+	if len(str) > 11 {
+		str = str[:11]
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(reverse(str) + "=")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid license key encoding")
+	}
+
+	expiry, err := time.Parse(legacyLayout, string(decoded))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date in license key")
+	}
+	return expiry, nil
+}
+
+// reverse reverses a string
+func reverse(s string) string {
+	size := len(s)
+	buf := make([]byte, size)
+	for start := 0; start < size; {
+		r, n := utf8.DecodeRuneInString(s[start:])
+		start += n
+		utf8.EncodeRune(buf[size-start:], r)
+	}
+	return string(buf)
+}
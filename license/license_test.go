@@ -0,0 +1,118 @@
+package license
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	publicKey, privateKey, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := Claims{
+		LicenseID: "LIC-1",
+		Clinic:    "Smile Clinic",
+		IssuedAt:  "2026-01-01",
+		ExpiresAt: "2027-12-31",
+		Seats:     5,
+		Features:  []string{"backup", "reminders"},
+	}
+	key, err := Sign(claims, privateKey)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if !IsSigned(key) {
+		t.Fatalf("signed key %q does not have the signed prefix", key)
+	}
+
+	got, err := Verify(key, publicKey)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if got.Clinic != claims.Clinic || got.Seats != 5 || !reflect.DeepEqual(got.Features, claims.Features) ||
+		!got.HasFeature("reminders") || got.HasFeature("sms") {
+		t.Errorf("claims did not round trip: %+v", got)
+	}
+
+	// Changing the payload must break the signature
+	parts := strings.Split(strings.TrimPrefix(key, SignedPrefix), ".")
+	forged, _ := Sign(Claims{Clinic: "Other", ExpiresAt: "2099-12-31"}, privateKey)
+	forgedParts := strings.Split(strings.TrimPrefix(forged, SignedPrefix), ".")
+	if _, err := Verify(SignedPrefix+forgedParts[0]+"."+parts[1], publicKey); err != ErrBadSignature {
+		t.Errorf("Verify with swapped payload = %v; expected ErrBadSignature", err)
+	}
+
+	otherPublic, _, _ := GenerateKeyPair()
+	if _, err := Verify(key, otherPublic); err != ErrBadSignature {
+		t.Errorf("Verify with another public key = %v; expected ErrBadSignature", err)
+	}
+	if _, err := Verify("DL1.garbage", publicKey); err != ErrMalformed {
+		t.Errorf("Verify of malformed key = %v; expected ErrMalformed", err)
+	}
+}
+
+func TestLegacyRoundTrip(t *testing.T) {
+	expiry := time.Date(2027, 3, 15, 0, 0, 0, 0, time.UTC)
+	for _, offset := range []int{10, 54, 99} {
+		key, err := generateLegacy(expiry, offset)
+		if err != nil {
+			t.Fatalf("generateLegacy(%d) failed: %v", offset, err)
+		}
+		got, err := ParseLegacy(key)
+		if err != nil {
+			t.Fatalf("ParseLegacy failed for offset %d: %v", offset, err)
+		}
+		if !got.Equal(expiry) {
+			t.Errorf("offset %d: expiry = %v; expected %v", offset, got, expiry)
+		}
+	}
+
+	if _, err := generateLegacy(expiry, 5); err == nil {
+		t.Errorf("generateLegacy accepted an offset below 10")
+	}
+}
+
+const legacyFillerLength = 510
+
+// generateLegacy builds a legacy key the way the retired issuer did expiring on the given date. offset must be between 10 and 99.
+func generateLegacy(expiry time.Time, offset int) (string, error) {
+	if offset < 10 || offset > 99 {
+		return "", fmt.Errorf("offset must be between 10 and 99")
+	}
+
+	fib := legacyOffsets()
+	b64 := strings.ReplaceAll(base64.StdEncoding.EncodeToString([]byte(expiry.Format(legacyLayout))), "=", "")
+	rb64 := reverse(b64)
+
+	strOffset := strconv.Itoa(offset)
+	filler := randomString(legacyFillerLength)
+	file := filler[:1] + strOffset + filler[1:]
+
+	var key string
+	for i, v := range rb64 {
+		key += file[len(key):len(strOffset)+offset+fib[i]-1] + string(v)
+	}
+	key += file[len(key):]
+
+	return key, nil
+}
+
+// randomString returns alphanumeric filler for legacy keys
+func randomString(length int) string {
+	chars := []rune("ABCDEFGHIJKLMNOPQRSTUVWXYZ" +
+		"abcdefghijklmnopqrstuvwxyz" +
+		"0123456789")
+	var b strings.Builder
+	for i := 0; i < length; i++ {
+		b.WriteRune(chars[rand.Intn(len(chars))])
+	}
+	return b.String()
+}
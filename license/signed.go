@@ -0,0 +1,150 @@
+// Package license signs and verifies DentistApp license keys.
+//
+// Signed keys have the form "DL1.<payload>.<signature>", where payload is the base64url encoded
+// JSON Claims and signature is an Ed25519 signature over the encoded payload. The app only
+// embeds the public key; keys are issued with cmd/licgen, which holds the private key.
+package license
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SignedPrefix marks a signed license key and its format version
+const SignedPrefix = "DL1."
+
+// DateLayout is the format of Claims.ExpiresAt and Claims.IssuedAt
+const DateLayout = "2006-01-02"
+
+var (
+	// ErrBadSignature is returned when a signed key was not issued with the matching private key
+	ErrBadSignature = errors.New("license signature is invalid")
+	// ErrMalformed is returned for keys that do not have the signed key structure
+	ErrMalformed = errors.New("license key is malformed")
+)
+
+// Claims is the content of a signed license
+type Claims struct {
+	LicenseID string   `json:"license_id"`
+	Clinic    string   `json:"clinic"`
+	IssuedAt  string   `json:"issued_at"`
+	ExpiresAt string   `json:"expires_at"`
+	Seats     int      `json:"seats"`
+	Features  []string `json:"features"`
+}
+
+// Expiry returns the last day the license is valid, as the end of that day
+func (c *Claims) Expiry() (time.Time, error) {
+	day, err := time.Parse(DateLayout, c.ExpiresAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid expiry date %q", c.ExpiresAt)
+	}
+	return day.Add(24*time.Hour - time.Nanosecond), nil
+}
+
+// HasFeature reports whether the license enables a feature flag
+func (c *Claims) HasFeature(feature string) bool {
+	for _, f := range c.Features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSigned reports whether key uses the signed format (as opposed to a legacy key)
+func IsSigned(key string) bool {
+	return strings.HasPrefix(key, SignedPrefix)
+}
+
+// Sign produces a signed license key for the claims
+func Sign(claims Claims, privateKey ed25519.PrivateKey) (string, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return "", fmt.Errorf("invalid private key size")
+	}
+	if claims.Clinic == "" {
+		return "", fmt.Errorf("clinic is required")
+	}
+	if _, err := claims.Expiry(); err != nil {
+		return "", err
+	}
+	if claims.Seats < 0 {
+		return "", fmt.Errorf("seats cannot be negative")
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode claims: %v", err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	signature := ed25519.Sign(privateKey, []byte(encoded))
+	return SignedPrefix + encoded + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Verify checks the signature of a signed key and returns its claims.
+// It does not check expiry; callers decide how to treat an expired license.
+func Verify(key string, publicKey ed25519.PublicKey) (*Claims, error) {
+	if !IsSigned(key) {
+		return nil, ErrMalformed
+	}
+	parts := strings.Split(strings.TrimPrefix(key, SignedPrefix), ".")
+	if len(parts) != 2 {
+		return nil, ErrMalformed
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	if len(publicKey) != ed25519.PublicKeySize || !ed25519.Verify(publicKey, []byte(parts[0]), signature) {
+		return nil, ErrBadSignature
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrMalformed
+	}
+	if _, err := claims.Expiry(); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+// GenerateKeyPair creates a new signing key pair
+func GenerateKeyPair() (ed25519.PublicKey, ed25519.PrivateKey, error) {
+	return ed25519.GenerateKey(rand.Reader)
+}
+
+// EncodeKey encodes a public or private key for embedding or storage
+func EncodeKey(key []byte) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// DecodePublicKey decodes a key produced by EncodeKey
+func DecodePublicKey(encoded string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// DecodePrivateKey decodes a key produced by EncodeKey
+func DecodePrivateKey(encoded string) (ed25519.PrivateKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key")
+	}
+	return ed25519.PrivateKey(key), nil
+}
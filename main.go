@@ -5,8 +5,10 @@ import (
 	"embed"
 	"log"
 
+	"DentistApp/backup"
 	"DentistApp/database"
 	"DentistApp/handlers"
+	"DentistApp/reminders"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -30,11 +32,37 @@ func main() {
 	sessionHandler := handlers.NewSessionHandler(db)
 	invoiceHandler := handlers.NewInvoiceHandler(db)
 	expenseCategoryHandler := handlers.NewExpenseCategoryHandler(db)
+	expenseHandler := handlers.NewExpenseHandler(db)
 	workTypeHandler := handlers.NewWorkTypeHandler(db)
 	colorShadeHandler := handlers.NewColorShadeHandler(db)
 	dentalLabHandler := handlers.NewDentalLabHandler(db)
 	labOrderHandler := handlers.NewLabOrderHandler(db)
 	authHandler := handlers.NewAuthHandler(db)
+	auditHandler := handlers.NewAuditHandler(db)
+	passphraseFile, err := backup.DefaultPassphraseFile()
+	if err != nil {
+		log.Fatal(err)
+	}
+	backupHandler := handlers.NewBackupHandler(db, passphraseFile)
+	settingsHandler := handlers.NewSettingsHandler(db)
+	chairHandler := handlers.NewChairHandler(db)
+	scheduleHandler := handlers.NewScheduleHandler(db)
+	backupManager := backup.NewManager(db, "backups", "patient_data")
+	backupScheduler := backup.NewScheduler(backupManager, backupHandler.Schedule)
+	reminderHandler := handlers.NewReminderHandler(db)
+	reminderScheduler := reminders.NewScheduler(reminderHandler, reminderHandler.Config)
+	waitlistHandler := handlers.NewWaitlistHandler(db)
+	toothChartHandler := handlers.NewToothChartHandler(db)
+	treatmentPlanHandler := handlers.NewTreatmentPlanHandler(db)
+	perioHandler := handlers.NewPerioHandler(db)
+	clinicalNoteHandler := handlers.NewClinicalNoteHandler(db)
+	prescriptionHandler := handlers.NewPrescriptionHandler(db)
+	medicalAlertHandler := handlers.NewMedicalAlertHandler(db)
+
+	// Earlier versions kept the backup passphrase in the database, so every backup carried it
+	if err := backupHandler.MoveStoredPassphrase(); err != nil {
+		log.Printf("Warning: Failed to move the backup passphrase out of the database: %v", err)
+	}
 
 	// Initialize admin user if it doesn't exist
 	err = authHandler.InitializeAdmin()
@@ -43,7 +71,7 @@ func main() {
 	}
 
 	// Create an instance of the app structure
-	app := NewApp(patientHandler, appointmentHandler, paymentHandler, procedureHandler, sessionHandler, invoiceHandler, expenseCategoryHandler, workTypeHandler, colorShadeHandler, dentalLabHandler, labOrderHandler, authHandler)
+	app := NewApp(patientHandler, appointmentHandler, paymentHandler, procedureHandler, sessionHandler, invoiceHandler, expenseCategoryHandler, expenseHandler, workTypeHandler, colorShadeHandler, dentalLabHandler, labOrderHandler, authHandler, auditHandler, backupHandler, backupManager, backupScheduler, settingsHandler, chairHandler, scheduleHandler, reminderHandler, reminderScheduler, waitlistHandler, toothChartHandler, treatmentPlanHandler, perioHandler, clinicalNoteHandler, prescriptionHandler, medicalAlertHandler)
 
 	// Create application with options
 	err = wails.Run(&options.App{
@@ -0,0 +1,21 @@
+package models
+
+// CalendarImportResult reports what an iCalendar import booked and which events it left out
+type CalendarImportResult struct {
+	Imported int `json:"imported"`
+	// AlreadyImported counts events booked by an earlier import or exported from this clinic
+	AlreadyImported int `json:"already_imported"`
+	// Unmatched events name no existing patient, or more than one; no patients are created for them
+	Unmatched []CalendarImportIssue `json:"unmatched"`
+	// Skipped events could not be read or are not single visits (all-day, recurring, cancelled)
+	Skipped []CalendarImportIssue `json:"skipped"`
+}
+
+// CalendarImportIssue describes an event that was not imported
+type CalendarImportIssue struct {
+	UID      string `json:"uid"`
+	Summary  string `json:"summary"`
+	DateTime string `json:"datetime"`
+	Attendee string `json:"attendee,omitempty"`
+	Reason   string `json:"reason"`
+}
@@ -0,0 +1,32 @@
+package models
+
+// AppointmentSeriesForm creates a recurring series. Appointment is the first visit; the others repeat
+// it according to RRule (an RFC 5545 RRULE subset, e.g. "FREQ=WEEKLY;INTERVAL=2;COUNT=8").
+type AppointmentSeriesForm struct {
+	Appointment Appointment `json:"appointment"`
+	RRule       string      `json:"rrule"`
+	// SkipConflicts books the visits that are free and reports the rest instead of booking nothing
+	SkipConflicts bool `json:"skip_conflicts"`
+}
+
+// AppointmentSeriesResult reports the visits booked for a series and any skipped for conflicts
+type AppointmentSeriesResult struct {
+	SeriesID       int64                `json:"series_id"`
+	AppointmentIDs []int64              `json:"appointment_ids"`
+	Skipped        []SkippedAppointment `json:"skipped"`
+}
+
+// SkippedAppointment is a series visit that was not booked because it overlaps other appointments
+// or, when Reason is set, falls outside the dentist's working hours
+type SkippedAppointment struct {
+	DateTime  string        `json:"datetime"`
+	Conflicts []Appointment `json:"conflicts"`
+	Reason    string        `json:"reason,omitempty"`
+}
+
+// Scopes for changing one visit of a series
+const (
+	SeriesScopeThis      = "this"
+	SeriesScopeFollowing = "following"
+	SeriesScopeAll       = "series"
+)
@@ -0,0 +1,33 @@
+package models
+
+// AuditEntry represents one recorded create/update/delete
+type AuditEntry struct {
+	ID         int    `json:"id"`
+	UserID     *int   `json:"user_id"`
+	Username   string `json:"username"`
+	CreatedAt  string `json:"created_at"`
+	EntityType string `json:"entity_type"`
+	EntityID   int    `json:"entity_id"`
+	Action     string `json:"action"`
+	// Changes is a JSON object of changed fields: {"field": {"old": ..., "new": ...}}
+	Changes string `json:"changes"`
+}
+
+// AuditLogFilters represents filter criteria for the audit log
+type AuditLogFilters struct {
+	UserID     *int    `json:"user_id,omitempty"`     // Optional acting user filter
+	EntityType *string `json:"entity_type,omitempty"` // Optional entity filter (e.g. "invoice")
+	EntityID   *int    `json:"entity_id,omitempty"`   // Optional entity ID filter, used with EntityType
+	Action     *string `json:"action,omitempty"`      // Optional action filter ("create", "update", "delete")
+	DateFrom   *string `json:"date_from,omitempty"`   // Optional start date (YYYY-MM-DD)
+	DateTo     *string `json:"date_to,omitempty"`     // Optional end date (YYYY-MM-DD)
+}
+
+// AuditLogResponse represents paginated audit log entries
+type AuditLogResponse struct {
+	Entries     []AuditEntry `json:"entries"`
+	CurrentPage int          `json:"current_page"`
+	TotalPages  int          `json:"total_pages"`
+	TotalCount  int          `json:"total_count"`
+	PageSize    int          `json:"page_size"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// BackupSettings represents the automatic backup schedule
+type BackupSettings struct {
+	Enabled       bool `json:"enabled"`
+	IntervalHours int  `json:"interval_hours"`
+	KeepCount     int  `json:"keep_count"`
+	Encrypt       bool `json:"encrypt"`
+	// Passphrase is only sent when changing it; it is never returned. Leave empty to keep the current one.
+	Passphrase    string `json:"passphrase,omitempty"`
+	HasPassphrase bool   `json:"has_passphrase"`
+	UpdatedAt     string `json:"updated_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// Chair represents a treatment chair (operatory) that appointments are booked into
+type Chair struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	IsActive  bool   `json:"is_active"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// ChairForm represents data needed to create/update a chair
+type ChairForm struct {
+	Name     string `json:"name"`
+	IsActive bool   `json:"is_active"`
+}
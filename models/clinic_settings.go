@@ -0,0 +1,22 @@
+package models
+
+// ClinicSettings represents the clinic profile and preferences shared by every workstation
+type ClinicSettings struct {
+	Name           string `json:"name"`
+	Address        string `json:"address"`
+	Phone          string `json:"phone"`
+	TaxNumber      string `json:"tax_number"`
+	CurrencySymbol string `json:"currency_symbol"`
+	InvoiceFooter  string `json:"invoice_footer"`
+	InvoicePrefix  string `json:"invoice_prefix"`
+	LabOrderPrefix string `json:"lab_order_prefix"`
+	// DefaultAppointmentDuration is in minutes
+	DefaultAppointmentDuration int    `json:"default_appointment_duration"`
+	Theme                      string `json:"theme"` // "light" or "dark"
+	// LogoDataURL is the logo as a data: URL for display; it is set with SetClinicLogo, not saved with the rest
+	LogoDataURL string `json:"logo_data_url,omitempty"`
+	UpdatedAt   string `json:"updated_at"`
+
+	Logo     []byte `json:"-"`
+	LogoType string `json:"-"` // "png" or "jpeg"
+}
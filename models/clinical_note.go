@@ -0,0 +1,63 @@
+package models
+
+// NoteTemplate is reusable SOAP text. Its sections may contain the placeholders {patient}, {date},
+// {dentist}, {teeth}, {procedure}, {anaesthetic} and {lot}, filled in from the session.
+type NoteTemplate struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Subjective string `json:"subjective"`
+	Objective  string `json:"objective"`
+	Assessment string `json:"assessment"`
+	Plan       string `json:"plan"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// NoteTemplateValues fills the placeholders that cannot be read from the session
+type NoteTemplateValues struct {
+	Anaesthetic string `json:"anaesthetic"`
+	LotNumber   string `json:"lot_number"`
+}
+
+// ClinicalNote is the structured SOAP note of a session. Each save keeps the previous text as a
+// version; once signed the note cannot be changed.
+type ClinicalNote struct {
+	ID            int    `json:"id"` // 0 while the session has no note yet
+	SessionID     int    `json:"session_id"`
+	PatientID     int    `json:"patient_id"`
+	TemplateID    *int   `json:"template_id,omitempty"`
+	Subjective    string `json:"subjective"`
+	Objective     string `json:"objective"`
+	Assessment    string `json:"assessment"`
+	Plan          string `json:"plan"`
+	Version       int    `json:"version"`
+	UpdatedAt     string `json:"updated_at,omitempty"`
+	UpdatedBy     *int   `json:"updated_by,omitempty"`
+	UpdatedByName string `json:"updated_by_name,omitempty"`
+	SignedAt      string `json:"signed_at,omitempty"` // empty while the note can still be edited
+	SignedBy      *int   `json:"signed_by,omitempty"`
+	SignedByName  string `json:"signed_by_name,omitempty"`
+}
+
+// ClinicalNoteVersion is one saved revision of a clinical note
+type ClinicalNoteVersion struct {
+	Version     int    `json:"version"`
+	Subjective  string `json:"subjective"`
+	Objective   string `json:"objective"`
+	Assessment  string `json:"assessment"`
+	Plan        string `json:"plan"`
+	SavedAt     string `json:"saved_at"`
+	SavedBy     *int   `json:"saved_by,omitempty"`
+	SavedByName string `json:"saved_by_name,omitempty"`
+}
+
+// ClinicalNoteMatch is a note found by a search, with an excerpt around the first match
+type ClinicalNoteMatch struct {
+	NoteID      int    `json:"note_id"`
+	SessionID   int    `json:"session_id"`
+	PatientID   int    `json:"patient_id"`
+	PatientName string `json:"patient_name"`
+	SessionDate string `json:"session_date"`
+	Section     string `json:"section"` // subjective, objective, assessment or plan
+	Excerpt     string `json:"excerpt"`
+	Signed      bool   `json:"signed"`
+}
@@ -0,0 +1,85 @@
+package models
+
+// Expense represents a clinic expense
+type Expense struct {
+	ID              int    `json:"id"`
+	ExpenseCode     string `json:"expense_code"`
+	ExpenseDate     string `json:"expense_date"`
+	Description     string `json:"description"`
+	Amount          int    `json:"amount"`
+	CategoryID      int    `json:"category_id"`
+	CategoryName    string `json:"category_name"`
+	PaymentStatus   string `json:"payment_status"` // "unpaid", "partially_paid", "paid"
+	PaymentMethod   string `json:"payment_method"`
+	VendorName      string `json:"vendor_name"`
+	VendorContact   string `json:"vendor_contact"`
+	ReceiptNumber   string `json:"receipt_number"`
+	Notes           string `json:"notes"`
+	ReceiptFilePath string `json:"receipt_file_path"`
+	ReportingPeriod string `json:"reporting_period"`
+	IsRecurring     bool   `json:"is_recurring"`
+	RecurringPeriod string `json:"recurring_period"`
+	TotalPaid       int    `json:"total_paid"`
+	CreatedBy       *int   `json:"created_by,omitempty"`
+	UpdatedBy       *int   `json:"updated_by,omitempty"`
+	CreatedAt       string `json:"created_at"`
+	UpdatedAt       string `json:"updated_at"`
+}
+
+// ExpenseForm represents the data needed to create/update an expense
+type ExpenseForm struct {
+	ExpenseDate     string `json:"expense_date"`
+	Description     string `json:"description"`
+	Amount          int    `json:"amount"`
+	CategoryID      int    `json:"category_id"`
+	PaymentMethod   string `json:"payment_method"`
+	VendorName      string `json:"vendor_name"`
+	VendorContact   string `json:"vendor_contact"`
+	ReceiptNumber   string `json:"receipt_number"`
+	Notes           string `json:"notes"`
+	ReceiptFilePath string `json:"receipt_file_path"`
+	IsRecurring     bool   `json:"is_recurring"`
+	RecurringPeriod string `json:"recurring_period"`
+}
+
+// ExpenseFilters represents filter criteria for expenses
+type ExpenseFilters struct {
+	CategoryID    *int    `json:"category_id,omitempty"`    // Optional category filter
+	DateFrom      *string `json:"date_from,omitempty"`      // Optional start date (YYYY-MM-DD)
+	DateTo        *string `json:"date_to,omitempty"`        // Optional end date (YYYY-MM-DD)
+	VendorName    *string `json:"vendor_name,omitempty"`    // Optional vendor search (partial match)
+	PaymentStatus *string `json:"payment_status,omitempty"` // Optional status filter ("unpaid", "partially_paid", "paid")
+}
+
+// ExpensesResponse represents paginated expenses response
+type ExpensesResponse struct {
+	Expenses    []Expense `json:"expenses"`
+	CurrentPage int       `json:"current_page"`
+	TotalPages  int       `json:"total_pages"`
+	TotalCount  int       `json:"total_count"`
+	TotalAmount int       `json:"total_amount"`
+	PageSize    int       `json:"page_size"`
+}
+
+// ExpensePayment represents a (partial) payment made against an expense
+type ExpensePayment struct {
+	ID            int    `json:"id"`
+	ExpenseID     int    `json:"expense_id"`
+	PaymentCode   string `json:"payment_code"`
+	Amount        int    `json:"amount"`
+	PaymentDate   string `json:"payment_date"`
+	PaymentMethod string `json:"payment_method"`
+	Note          string `json:"note"`
+	CreatedBy     *int   `json:"created_by,omitempty"`
+	CreatedAt     string `json:"created_at"`
+	UpdatedAt     string `json:"updated_at"`
+}
+
+// ExpensePaymentDetails represents an expense together with its payment history and totals
+type ExpensePaymentDetails struct {
+	Expense       Expense          `json:"expense"`
+	Payments      []ExpensePayment `json:"payments"`
+	TotalPaid     int              `json:"total_paid"`
+	Remaining     int              `json:"remaining"`
+	AllowPayments bool             `json:"allow_payments"`
+}
@@ -38,6 +38,8 @@ type LabOrderDetail struct {
 	Notes         string `json:"notes"`
 	CreatedAt     string `json:"created_at"`
 	UpdatedAt     string `json:"updated_at"`
+	// MedicalAlerts are the patient's, filled in by GetLabOrder
+	MedicalAlerts []MedicalAlert `json:"medical_alerts,omitempty"`
 }
 
 // LabOrdersResponse provides paginated lab orders
@@ -0,0 +1,42 @@
+package models
+
+// Medical alert types. AlertOther relies on the description to say what the alert is about.
+const (
+	AlertAnticoagulant           = "anticoagulant"
+	AlertPenicillinAllergy       = "penicillin_allergy"
+	AlertLatexAllergy            = "latex_allergy"
+	AlertLocalAnaestheticAllergy = "local_anaesthetic_allergy"
+	AlertPregnancy               = "pregnancy"
+	AlertBisphosphonate          = "bisphosphonate"
+	AlertDiabetes                = "diabetes"
+	AlertCardiac                 = "cardiac"
+	AlertOther                   = "other"
+)
+
+// MedicalAlertTypes lists every medical alert type
+var MedicalAlertTypes = []string{AlertAnticoagulant, AlertPenicillinAllergy, AlertLatexAllergy, AlertLocalAnaestheticAllergy,
+	AlertPregnancy, AlertBisphosphonate, AlertDiabetes, AlertCardiac, AlertOther}
+
+// Medical alert severities
+const (
+	AlertSeverityInfo     = "info"
+	AlertSeverityWarning  = "warning"
+	AlertSeverityCritical = "critical"
+)
+
+// MedicalAlert is a structured warning about a patient that is shown wherever the patient is treated
+type MedicalAlert struct {
+	ID          int    `json:"id"`
+	PatientID   int    `json:"patient_id"`
+	AlertType   string `json:"alert_type"`
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+	// ReviewDate (YYYY-MM-DD) is when the alert should next be checked with the patient; empty for never.
+	// ReviewOverdue is set when it has passed.
+	ReviewDate     string `json:"review_date"`
+	ReviewOverdue  bool   `json:"review_overdue"`
+	ReviewedAt     string `json:"reviewed_at,omitempty"`
+	ReviewedByName string `json:"reviewed_by_name,omitempty"`
+	CreatedAt      string `json:"created_at"`
+	UpdatedAt      string `json:"updated_at"`
+}
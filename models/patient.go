@@ -5,7 +5,11 @@ type Patient struct {
 	ID                int    `json:"id"`
 	Name              string `json:"name"`
 	Phone             string `json:"phone"`
-	Age               int    `json:"age"`
+	Email             string `json:"email"`
+	Age               int    `json:"age"` // computed from DateOfBirth
+	// DateOfBirth is YYYY-MM-DD. DOBEstimated marks one worked out from the age alone.
+	DateOfBirth       string `json:"date_of_birth"`
+	DOBEstimated      bool   `json:"dob_estimated"`
 	Gender            string `json:"gender"`
 	TotalRequired     int    `json:"total_required"`
 	Allergies         string `json:"allergies"`
@@ -15,12 +19,18 @@ type Patient struct {
 	PregnancyStatus   bool   `json:"pregnancy_status"`
 	DentalHistory     string `json:"dental_history"`
 	SpecialNotes      string `json:"special_notes"`
+	// Attendance history, filled in by GetPatient
+	NoShowCount           int `json:"no_show_count"`
+	LateCancellationCount int `json:"late_cancellation_count"`
 }
 
 // PatientForm represents the data needed to create/update a patient
 type PatientForm struct {
 	Name              string `json:"name"`
 	Phone             string `json:"phone"`
+	Email             string `json:"email"`
+	// DateOfBirth is YYYY-MM-DD. When it is not known, it is estimated from Age.
+	DateOfBirth       string `json:"date_of_birth"`
 	Age               int    `json:"age"`
 	Gender            string `json:"gender"`
 	Allergies         string `json:"allergies"`
@@ -32,12 +42,73 @@ type PatientForm struct {
 	SpecialNotes      string `json:"special_notes"`
 }
 
+// PatientFilters narrows a patient search to an age band in whole years. Patients whose date of
+// birth is not recorded never match an age filter.
+type PatientFilters struct {
+	MinAge *int `json:"min_age,omitempty"`
+	MaxAge *int `json:"max_age,omitempty"`
+}
+
+// PatientBirthday is a patient's birthday falling in a queried date range
+type PatientBirthday struct {
+	PatientID   int    `json:"patient_id"`
+	Name        string `json:"name"`
+	Phone       string `json:"phone"`
+	Email       string `json:"email"`
+	DateOfBirth string `json:"date_of_birth"`
+	Birthday    string `json:"birthday"` // YYYY-MM-DD; 29 February birthdays fall on 28 February in other years
+	Age         int    `json:"age"`      // the age the patient turns
+}
+
 // Appointment struct represents an appointment in the system
 // DateTime is in RFC3339 format (e.g., "2024-06-01T14:00:00Z")
 type Appointment struct {
-	ID        int    `json:"id"`
-	PatientID int    `json:"patient_id"`
-	DateTime  string `json:"datetime"`
-	Duration  int    `json:"duration"` // in minutes
-	Notes     string `json:"notes"`
+	ID          int    `json:"id"`
+	PatientID   int    `json:"patient_id"`
+	DateTime    string `json:"datetime"`
+	Duration    int    `json:"duration"` // in minutes
+	Notes       string `json:"notes"`
+	DentistID   *int   `json:"dentist_id,omitempty"` // nullable
+	ChairID     *int   `json:"chair_id,omitempty"`   // nullable
+	PatientName string `json:"patient_name,omitempty"`
+	DentistName string `json:"dentist_name,omitempty"`
+	ChairName   string `json:"chair_name,omitempty"`
+	// AllowConflict saves the appointment even if it overlaps another for the same dentist or chair
+	AllowConflict bool `json:"allow_conflict,omitempty"`
+	// AllowOutsideHours saves the appointment even if it falls outside the dentist's working hours
+	AllowOutsideHours bool `json:"allow_outside_hours,omitempty"`
+	// Status is changed with SetAppointmentStatus; each state records when it was entered
+	Status             string `json:"status"`
+	CancellationReason string `json:"cancellation_reason,omitempty"`
+	LateCancellation   bool   `json:"late_cancellation,omitempty"`
+	ConfirmedAt        string `json:"confirmed_at,omitempty"`
+	CheckedInAt        string `json:"checked_in_at,omitempty"`
+	InChairAt          string `json:"in_chair_at,omitempty"`
+	CompletedAt        string `json:"completed_at,omitempty"`
+	CancelledAt        string `json:"cancelled_at,omitempty"`
+	NoShowAt           string `json:"no_show_at,omitempty"`
+	// SeriesID links the visits of a recurring appointment series
+	SeriesID *int `json:"series_id,omitempty"`
+	// MedicalAlerts are the patient's, filled in when appointments are fetched for display
+	MedicalAlerts []MedicalAlert `json:"medical_alerts,omitempty"`
 }
+
+// AppointmentDaySummary totals one day's bookings for the calendar month view. Cancelled and
+// no-show appointments are not counted.
+type AppointmentDaySummary struct {
+	Date          string `json:"date"` // YYYY-MM-DD
+	Count         int    `json:"count"`
+	BookedMinutes int    `json:"booked_minutes"`
+}
+
+// Appointment statuses
+const (
+	AppointmentScheduled = "scheduled"
+	AppointmentConfirmed = "confirmed"
+	AppointmentCheckedIn = "checked_in"
+	AppointmentInChair   = "in_chair"
+	AppointmentCompleted = "completed"
+	AppointmentCancelled = "cancelled"
+	AppointmentNoShow    = "no_show"
+)
+
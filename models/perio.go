@@ -0,0 +1,71 @@
+package models
+
+// PerioSites are the six sites probed around each tooth: distobuccal, buccal and mesiobuccal, then
+// distolingual, lingual and mesiolingual
+var PerioSites = []string{"DB", "B", "MB", "DL", "L", "ML"}
+
+// PerioDeepPocket is the probing depth, in millimetres, from which a site counts as a deep pocket
+const PerioDeepPocket = 5
+
+// PerioSite is the measurement taken at one site of a tooth
+type PerioSite struct {
+	Site         string `json:"site"`
+	ProbingDepth int    `json:"probing_depth"` // in mm
+	Recession    int    `json:"recession"`     // in mm; negative when the gingival margin is above the CEJ
+	Bleeding     bool   `json:"bleeding"`
+}
+
+// PerioTooth is one tooth of a periodontal exam. Teeth that were not examined are left out.
+type PerioTooth struct {
+	Tooth     int         `json:"tooth"`     // FDI number
+	Mobility  int         `json:"mobility"`  // grade 0-3
+	Furcation int         `json:"furcation"` // grade 0-3
+	Sites     []PerioSite `json:"sites"`
+}
+
+// PerioSummary holds the indices of one exam
+type PerioSummary struct {
+	Teeth           int     `json:"teeth"`
+	Sites           int     `json:"sites"`
+	MeanDepth       float64 `json:"mean_depth"`       // mean probing depth in mm
+	BleedingPercent float64 `json:"bleeding_percent"` // share of sites bleeding on probing
+	DeepSites       int     `json:"deep_sites"`       // sites probing PerioDeepPocket mm or deeper
+}
+
+// PerioExam is a periodontal exam of a patient on one date
+type PerioExam struct {
+	ID           int          `json:"id"`
+	PatientID    int          `json:"patient_id"`
+	ExamDate     string       `json:"exam_date"` // YYYY-MM-DD
+	ExaminerID   *int         `json:"examiner_id,omitempty"`
+	ExaminerName string       `json:"examiner_name,omitempty"`
+	Notes        string       `json:"notes"`
+	CreatedAt    string       `json:"created_at"`
+	Teeth        []PerioTooth `json:"teeth"`
+	Summary      PerioSummary `json:"summary"`
+}
+
+// PerioToothChange compares one tooth examined in both exams
+type PerioToothChange struct {
+	Tooth          int `json:"tooth"`
+	MaxDepthBefore int `json:"max_depth_before"`
+	MaxDepthAfter  int `json:"max_depth_after"`
+	SitesImproved  int `json:"sites_improved"` // sites probing shallower than before
+	SitesWorsened  int `json:"sites_worsened"` // sites probing deeper than before
+	BleedingBefore int `json:"bleeding_before"`
+	BleedingAfter  int `json:"bleeding_after"`
+}
+
+// PerioComparison shows the progress between an earlier and a later exam
+type PerioComparison struct {
+	BeforeID        int                `json:"before_id"`
+	BeforeDate      string             `json:"before_date"`
+	AfterID         int                `json:"after_id"`
+	AfterDate       string             `json:"after_date"`
+	Before          PerioSummary       `json:"before"`
+	After           PerioSummary       `json:"after"`
+	MeanDepthChange float64            `json:"mean_depth_change"` // negative is an improvement
+	BleedingChange  float64            `json:"bleeding_change"`
+	DeepSitesChange int                `json:"deep_sites_change"`
+	Teeth           []PerioToothChange `json:"teeth"`
+}
@@ -0,0 +1,92 @@
+package models
+
+// User roles stored in users.role
+const (
+	RoleAdmin        = "Admin"
+	RoleDentist      = "Dentist"
+	RoleReceptionist = "Receptionist"
+	RoleAccountant   = "Accountant"
+)
+
+// Roles lists every assignable role
+var Roles = []string{RoleAdmin, RoleDentist, RoleReceptionist, RoleAccountant}
+
+// Permission is a named action a role may be allowed to perform
+type Permission string
+
+// Patient permissions
+const (
+	PermPatientView      Permission = "patient.view"
+	PermPatientCreate    Permission = "patient.create"
+	PermPatientUpdate    Permission = "patient.update"
+	PermPatientDelete    Permission = "patient.delete"
+	PermPatientDeleteAll Permission = "patient.delete_all"
+)
+
+// Clinical permissions for records that feed safety checks and treatment decisions, and for
+// reading clinical notes and prescriptions (dentist and admin)
+const (
+	PermMedicalAlertManage  Permission = "medical_alert.manage"
+	PermTreatmentPlanAccept Permission = "treatment_plan.accept"
+	PermClinicalNoteView    Permission = "clinical_note.view"
+	PermPrescriptionView    Permission = "prescription.view"
+)
+
+// Appointment permissions
+const (
+	PermAppointmentView   Permission = "appointment.view"
+	PermAppointmentManage Permission = "appointment.manage"
+)
+
+// Session (treatment visit) permissions
+const (
+	PermSessionView   Permission = "session.view"
+	PermSessionCreate Permission = "session.create"
+	PermSessionUpdate Permission = "session.update"
+	PermSessionDelete Permission = "session.delete"
+)
+
+// Invoice and payment permissions
+const (
+	PermInvoiceView   Permission = "invoice.view"
+	PermInvoiceCreate Permission = "invoice.create"
+	PermPaymentView   Permission = "payment.view"
+	PermPaymentCreate Permission = "payment.create"
+	PermPaymentUpdate Permission = "payment.update"
+	PermPaymentDelete Permission = "payment.delete"
+)
+
+// Expense permissions. expense.approve covers paying out an expense.
+const (
+	PermExpenseView                    Permission = "expense.view"
+	PermExpenseCreate                  Permission = "expense.create"
+	PermExpenseUpdate                  Permission = "expense.update"
+	PermExpenseDelete                  Permission = "expense.delete"
+	PermExpenseApprove                 Permission = "expense.approve"
+	PermExpenseCategoryManage          Permission = "expense_category.manage"
+	PermExpenseCategoryDeletePermanent Permission = "expense_category.delete_permanent"
+)
+
+// Clinic configuration and lab permissions
+const (
+	PermProcedureView   Permission = "procedure.view"
+	PermProcedureManage Permission = "procedure.manage"
+	PermLabView         Permission = "lab.view"
+	PermLabManage       Permission = "lab.manage"
+	PermLabOrderView    Permission = "lab_order.view"
+	PermLabOrderCreate  Permission = "lab_order.create"
+	// PermSettingsManage covers the clinic profile, numbering and defaults (admin only)
+	PermSettingsManage Permission = "settings.manage"
+)
+
+// User management permissions. user.view lists staff (e.g. for dentist filters); user.manage is admin only.
+const (
+	PermUserView   Permission = "user.view"
+	PermUserManage Permission = "user.manage"
+)
+
+// Audit log and backup permissions (admin only)
+const (
+	PermAuditView    Permission = "audit.view"
+	PermBackupManage Permission = "backup.manage"
+)
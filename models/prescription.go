@@ -0,0 +1,74 @@
+package models
+
+// Drug rule kinds: an allergy rule matches the patient's allergies, an interaction rule their
+// current medications
+const (
+	DrugRuleAllergy     = "allergy"
+	DrugRuleInteraction = "interaction"
+)
+
+// Drug rule severities
+const (
+	DrugRuleWarning         = "warning"
+	DrugRuleContraindicated = "contraindicated"
+)
+
+// Drug is an entry of the clinic's drug catalogue. The defaults fill a new prescription line.
+type Drug struct {
+	ID                  int    `json:"id"`
+	Name                string `json:"name"`
+	Strength            string `json:"strength"`
+	Form                string `json:"form"`
+	DrugClass           string `json:"drug_class"` // matched by drug rules, e.g. penicillin or nsaid
+	DefaultDose         string `json:"default_dose"`
+	DefaultFrequency    string `json:"default_frequency"`
+	DefaultDuration     string `json:"default_duration"`
+	DefaultInstructions string `json:"default_instructions"`
+}
+
+// DrugRule flags drugs of a class when Term appears in the patient's allergies or current
+// medications
+type DrugRule struct {
+	ID        int    `json:"id"`
+	Kind      string `json:"kind"` // DrugRuleAllergy or DrugRuleInteraction
+	DrugClass string `json:"drug_class"`
+	Term      string `json:"term"`
+	Severity  string `json:"severity"` // DrugRuleWarning or DrugRuleContraindicated
+	Message   string `json:"message"`
+}
+
+// PrescriptionItem is one drug on a prescription. The drug's name and strength are copied so the
+// prescription reads the same after the catalogue changes.
+type PrescriptionItem struct {
+	ID           int    `json:"id"`
+	DrugID       *int   `json:"drug_id,omitempty"`
+	DrugName     string `json:"drug_name"`
+	Strength     string `json:"strength"`
+	Dose         string `json:"dose"`
+	Frequency    string `json:"frequency"`
+	Duration     string `json:"duration"`
+	Instructions string `json:"instructions"`
+}
+
+// Prescription is written during a session
+type Prescription struct {
+	ID             int                `json:"id"`
+	SessionID      int                `json:"session_id"`
+	PatientID      int                `json:"patient_id"`
+	PatientName    string             `json:"patient_name,omitempty"`
+	PrescriberID   *int               `json:"prescriber_id,omitempty"`
+	PrescriberName string             `json:"prescriber_name,omitempty"`
+	PrescribedAt   string             `json:"prescribed_at"`
+	Notes          string             `json:"notes"`
+	OverrideReason string             `json:"override_reason"` // why the prescriber went ahead despite the alerts
+	Items          []PrescriptionItem `json:"items"`
+}
+
+// PrescriptionAlert is a rule a prescription line triggers for the patient
+type PrescriptionAlert struct {
+	DrugName string `json:"drug_name"`
+	Kind     string `json:"kind"`
+	Severity string `json:"severity"`
+	Term     string `json:"term"` // what was found in the patient's allergies or medications
+	Message  string `json:"message"`
+}
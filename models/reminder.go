@@ -0,0 +1,67 @@
+package models
+
+// ReminderSettings controls appointment reminders. Templates may use {patient}, {date}, {time},
+// {dentist} and {clinic}.
+type ReminderSettings struct {
+	Enabled bool `json:"enabled"`
+	// LeadHours is how long before an appointment its reminder is sent
+	LeadHours     int    `json:"lead_hours"`
+	SMSEnabled    bool   `json:"sms_enabled"`
+	EmailEnabled  bool   `json:"email_enabled"`
+	SMSTemplate   string `json:"sms_template"`
+	EmailSubject  string `json:"email_subject"`
+	EmailTemplate string `json:"email_template"`
+	// TestMode writes messages to a file instead of sending them
+	TestMode    bool `json:"test_mode"`
+	MaxAttempts int  `json:"max_attempts"`
+
+	SMSURL string `json:"sms_url"`
+	// SMSAPIKey and SMTPPassword are only sent when changing them; they are never returned
+	SMSAPIKey    string `json:"sms_api_key,omitempty"`
+	HasSMSAPIKey bool   `json:"has_sms_api_key"`
+	SMSFrom      string `json:"sms_from"`
+
+	SMTPHost        string `json:"smtp_host"`
+	SMTPPort        int    `json:"smtp_port"`
+	SMTPUsername    string `json:"smtp_username"`
+	SMTPPassword    string `json:"smtp_password,omitempty"`
+	HasSMTPPassword bool   `json:"has_smtp_password"`
+	SMTPFrom        string `json:"smtp_from"`
+
+	UpdatedAt string `json:"updated_at"`
+}
+
+// Outbound message statuses
+const (
+	MessagePending   = "pending"
+	MessageSent      = "sent"
+	MessageFailed    = "failed"
+	MessageCancelled = "cancelled"
+)
+
+// OutboundMessage is a reminder waiting in, or delivered from, the outbox
+type OutboundMessage struct {
+	ID            int    `json:"id"`
+	AppointmentID *int   `json:"appointment_id,omitempty"`
+	PatientID     *int   `json:"patient_id,omitempty"`
+	PatientName   string `json:"patient_name"`
+	Channel       string `json:"channel"`
+	Recipient     string `json:"recipient"`
+	Subject       string `json:"subject"`
+	Body          string `json:"body"`
+	Status        string `json:"status"`
+	Attempts      int    `json:"attempts"`
+	NextAttemptAt string `json:"next_attempt_at"`
+	LastError     string `json:"last_error"`
+	CreatedAt     string `json:"created_at"`
+	SentAt        string `json:"sent_at,omitempty"`
+}
+
+// OutboundMessageList represents a page of the outbox
+type OutboundMessageList struct {
+	Messages    []OutboundMessage `json:"messages"`
+	CurrentPage int               `json:"current_page"`
+	TotalPages  int               `json:"total_pages"`
+	TotalCount  int               `json:"total_count"`
+	PageSize    int               `json:"page_size"`
+}
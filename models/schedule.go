@@ -0,0 +1,34 @@
+package models
+
+// WorkingHours is one block of a dentist's weekly schedule. A day may have several blocks,
+// e.g. a morning and an afternoon shift.
+type WorkingHours struct {
+	ID        int    `json:"id"`
+	UserID    int    `json:"user_id"`
+	Weekday   int    `json:"weekday"`    // 0 = Sunday ... 6 = Saturday
+	StartTime string `json:"start_time"` // HH:MM
+	EndTime   string `json:"end_time"`   // HH:MM
+}
+
+// ClinicHoliday is a day the whole clinic is closed
+type ClinicHoliday struct {
+	ID   int    `json:"id"`
+	Date string `json:"date"` // YYYY-MM-DD
+	Name string `json:"name"`
+}
+
+// TimeOff is a period a single dentist is unavailable
+type TimeOff struct {
+	ID       int    `json:"id"`
+	UserID   int    `json:"user_id"`
+	Username string `json:"username,omitempty"`
+	Start    string `json:"start"` // YYYY-MM-DDTHH:MM
+	End      string `json:"end"`   // YYYY-MM-DDTHH:MM
+	Reason   string `json:"reason"`
+}
+
+// FreeSlot is an open period in a dentist's schedule
+type FreeSlot struct {
+	Start string `json:"start"` // YYYY-MM-DDTHH:MM
+	End   string `json:"end"`
+}
@@ -14,6 +14,8 @@ type Session struct {
 	DentistName   string        `json:"dentist_name,omitempty"`
 	InvoiceNumber string        `json:"invoice_number,omitempty"`
 	Items         []SessionItem `json:"items,omitempty"`
+	// MedicalAlerts are the patient's, filled in by GetSession
+	MedicalAlerts []MedicalAlert `json:"medical_alerts,omitempty"`
 }
 
 // SessionItem struct represents a procedure/item in a session
@@ -23,6 +25,11 @@ type SessionItem struct {
 	ProcedureID *int   `json:"procedure_id,omitempty"` // nullable
 	ItemName    string `json:"item_name"`
 	Amount      int    `json:"amount"`
+	// Teeth the item was performed on and the condition it leaves them in, charted once the session is completed
+	Teeth          []ToothRef `json:"teeth"`
+	ChartCondition string     `json:"chart_condition"`
+	// PlanItemID is the treatment plan item the line performs
+	PlanItemID *int `json:"plan_item_id,omitempty"`
 }
 
 // SessionForm represents the data needed to create/update a session
@@ -37,9 +44,12 @@ type SessionForm struct {
 
 // SessionItemForm represents the data needed to create/update a session item
 type SessionItemForm struct {
-	ProcedureID *int   `json:"procedure_id,omitempty"` // nullable
-	ItemName    string `json:"item_name"`
-	Amount      int    `json:"amount"`
+	ProcedureID    *int       `json:"procedure_id,omitempty"` // nullable
+	ItemName       string     `json:"item_name"`
+	Amount         int        `json:"amount"`
+	Teeth          []ToothRef `json:"teeth,omitempty"`
+	ChartCondition string     `json:"chart_condition,omitempty"`
+	PlanItemID     *int       `json:"plan_item_id,omitempty"`
 }
 
 // SessionsResponse represents the response for GetSessions (sessions + pagination info)
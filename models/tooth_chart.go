@@ -0,0 +1,50 @@
+package models
+
+// Tooth conditions recorded on the chart
+const (
+	ToothCaries    = "caries"
+	ToothFilling   = "filling"
+	ToothCrown     = "crown"
+	ToothMissing   = "missing"
+	ToothImplant   = "implant"
+	ToothRootCanal = "root_canal"
+)
+
+// ToothConditions lists every chartable condition
+var ToothConditions = []string{ToothCaries, ToothFilling, ToothCrown, ToothMissing, ToothImplant, ToothRootCanal}
+
+// ToothSurfaces are the surface letters in the order they are stored: mesial, occlusal, distal, buccal, lingual
+const ToothSurfaces = "MODBL"
+
+// ToothCondition is one entry of a patient's tooth chart. Entries are never edited; a newer condition
+// resolves the ones it replaces, so resolved entries form the tooth's history.
+type ToothCondition struct {
+	ID        int    `json:"id"`
+	PatientID int    `json:"patient_id"`
+	Tooth     int    `json:"tooth"`    // FDI number, 11-48 permanent or 51-85 primary
+	Surfaces  string `json:"surfaces"` // subset of MODBL; empty for the whole tooth
+	Condition string `json:"condition"`
+	Notes     string `json:"notes"`
+	// SessionID is the session whose completed item recorded the condition, if any
+	SessionID      *int   `json:"session_id,omitempty"`
+	RecordedAt     string `json:"recorded_at"`
+	RecordedBy     *int   `json:"recorded_by,omitempty"`
+	RecordedByName string `json:"recorded_by_name,omitempty"`
+	ResolvedAt     string `json:"resolved_at,omitempty"`
+	ResolvedBy     *int   `json:"resolved_by,omitempty"`
+}
+
+// ToothConditionForm represents the data needed to chart a condition by hand
+type ToothConditionForm struct {
+	PatientID int    `json:"patient_id"`
+	Tooth     int    `json:"tooth"`
+	Surfaces  string `json:"surfaces"`
+	Condition string `json:"condition"`
+	Notes     string `json:"notes"`
+}
+
+// ToothRef is a tooth, and optionally some of its surfaces, that a session item was performed on
+type ToothRef struct {
+	Tooth    int    `json:"tooth"`
+	Surfaces string `json:"surfaces"`
+}
@@ -0,0 +1,45 @@
+package models
+
+// Treatment plan item statuses. Done and scheduled are also derived from the session an item was
+// performed in: completed sessions make it done, in-progress ones scheduled.
+const (
+	PlanItemProposed  = "proposed"
+	PlanItemAccepted  = "accepted"
+	PlanItemScheduled = "scheduled"
+	PlanItemDone      = "done"
+	PlanItemDeclined  = "declined"
+)
+
+// TreatmentPlan is proposed future work for a patient, made of phased items
+type TreatmentPlan struct {
+	ID          int                 `json:"id"`
+	PatientID   int                 `json:"patient_id"`
+	PatientName string              `json:"patient_name,omitempty"`
+	DentistID   *int                `json:"dentist_id,omitempty"`
+	DentistName string              `json:"dentist_name,omitempty"`
+	Title       string              `json:"title"`
+	Notes       string              `json:"notes"`
+	AcceptedAt  string              `json:"accepted_at"` // when the patient accepted the plan; empty while proposed
+	CreatedAt   string              `json:"created_at"`
+	Items       []TreatmentPlanItem `json:"items"`
+	// Totals, in the same unit as procedure prices
+	Estimate      int `json:"estimate"`       // every item not declined
+	AcceptedTotal int `json:"accepted_total"` // items accepted, scheduled or done
+	DoneTotal     int `json:"done_total"`
+	Remaining     int `json:"remaining"` // accepted or scheduled items not done yet
+}
+
+// TreatmentPlanItem is one procedure of a treatment plan
+type TreatmentPlanItem struct {
+	ID          int    `json:"id"`
+	PlanID      int    `json:"plan_id"`
+	Phase       int    `json:"phase"`
+	ProcedureID *int   `json:"procedure_id,omitempty"`
+	ItemName    string `json:"item_name"`
+	Tooth       int    `json:"tooth"` // FDI number; 0 when the item is not for one tooth
+	Surfaces    string `json:"surfaces"`
+	Price       int    `json:"price"`
+	Status      string `json:"status"`
+	// SessionID is the session the item was performed in, if any
+	SessionID *int `json:"session_id,omitempty"`
+}
@@ -6,6 +6,9 @@ type User struct {
 	Username     string `json:"username"`
 	PasswordHash string `json:"-"` // Never serialize password hash to JSON
 	Role         string `json:"role"`
+	IsActive     bool   `json:"is_active"`
+	// MustChangePassword is set for the default admin password and after an admin password reset
+	MustChangePassword bool `json:"must_change_password"`
 }
 
 // UserForm represents the data needed to create/update a user
@@ -15,6 +18,18 @@ type UserForm struct {
 	Role     string `json:"role"`
 }
 
+// UserUpdateForm represents the fields an admin can change on an existing user
+type UserUpdateForm struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// ChangePasswordForm represents a user changing their own password
+type ChangePasswordForm struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
 // LoginRequest represents login credentials
 type LoginRequest struct {
 	Username string `json:"username"`
@@ -23,9 +38,10 @@ type LoginRequest struct {
 
 // LoginResponse represents the response after successful login
 type LoginResponse struct {
-	Success bool   `json:"success"`
-	Token   string `json:"token"`
-	User    User   `json:"user"`
-	Message string `json:"message"`
+	Success   bool   `json:"success"`
+	Token     string `json:"token"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+	User      User   `json:"user"`
+	Message   string `json:"message"`
 }
 
@@ -0,0 +1,69 @@
+package models
+
+// Waiting list statuses
+const (
+	WaitlistWaiting = "waiting"
+	WaitlistOffered = "offered"
+	WaitlistBooked  = "booked"
+	WaitlistRemoved = "removed"
+)
+
+// Waiting list priorities; lower values are offered a slot first
+const (
+	WaitlistPriorityUrgent = 1
+	WaitlistPriorityHigh   = 2
+	WaitlistPriorityNormal = 3
+)
+
+// WaitlistWindow is a time of day a waitlisted patient can come in
+type WaitlistWindow struct {
+	ID        int    `json:"id"`
+	Weekday   int    `json:"weekday"`    // 0 = Sunday ... 6 = Saturday, -1 = any day
+	StartTime string `json:"start_time"` // HH:MM
+	EndTime   string `json:"end_time"`   // HH:MM
+}
+
+// WaitlistEntry is a patient waiting to be called if an earlier slot opens. Without windows the
+// patient can come at any time.
+type WaitlistEntry struct {
+	ID            int    `json:"id"`
+	PatientID     int    `json:"patient_id"`
+	PatientName   string `json:"patient_name,omitempty"`
+	PatientPhone  string `json:"patient_phone,omitempty"`
+	DentistID     *int   `json:"dentist_id,omitempty"` // preferred dentist; nil means any
+	DentistName   string `json:"dentist_name,omitempty"`
+	ProcedureID   *int   `json:"procedure_id,omitempty"`
+	ProcedureName string `json:"procedure_name,omitempty"`
+	// AppointmentID is the patient's existing booking, moved to the new slot instead of booking another
+	AppointmentID       *int   `json:"appointment_id,omitempty"`
+	AppointmentDateTime string `json:"appointment_datetime,omitempty"`
+	Duration            int    `json:"duration"` // in minutes
+	Priority            int    `json:"priority"`
+	EarliestDate        string `json:"earliest_date"` // YYYY-MM-DD, optional
+	LatestDate          string `json:"latest_date"`   // YYYY-MM-DD, optional
+	Notes               string `json:"notes"`
+	Status              string `json:"status"`
+	// OfferedDateTime, OfferedDentistID and OfferedChairID describe the slot the patient is deciding on
+	OfferedDateTime     string           `json:"offered_datetime,omitempty"`
+	OfferedDentistID    *int             `json:"offered_dentist_id,omitempty"`
+	OfferedChairID      *int             `json:"offered_chair_id,omitempty"`
+	OfferedAt           string           `json:"offered_at,omitempty"`
+	BookedAppointmentID *int             `json:"booked_appointment_id,omitempty"`
+	CreatedAt           string           `json:"created_at"`
+	Windows             []WaitlistWindow `json:"windows"`
+}
+
+// WaitlistSlot is a free period to fill from the waiting list, such as the time of a cancelled appointment
+type WaitlistSlot struct {
+	DateTime  string `json:"datetime"`
+	Duration  int    `json:"duration"` // in minutes
+	DentistID *int   `json:"dentist_id,omitempty"`
+	ChairID   *int   `json:"chair_id,omitempty"`
+}
+
+// WaitlistMatch is a waitlisted patient who fits a slot
+type WaitlistMatch struct {
+	Entry WaitlistEntry `json:"entry"`
+	// DaysEarlier is how much sooner the slot is than the patient's existing booking, if any
+	DaysEarlier int `json:"days_earlier"`
+}
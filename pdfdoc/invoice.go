@@ -0,0 +1,169 @@
+package pdfdoc
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"DentistApp/models"
+)
+
+// Invoice is everything printed on one invoice
+type Invoice struct {
+	Number      string
+	Date        string
+	Status      string
+	PatientID   int
+	PatientName string
+	SessionDate string
+	Items       []models.SessionItem
+	Payments    []models.Payment
+	Total       int
+	Paid        int
+}
+
+// Remaining returns the balance still owed, never below zero
+func (inv Invoice) Remaining() int {
+	if inv.Paid >= inv.Total {
+		return 0
+	}
+	return inv.Total - inv.Paid
+}
+
+// RenderInvoice writes the invoice as a PDF to w
+func RenderInvoice(w io.Writer, clinic Clinic, inv Invoice) error {
+	r := newRenderer(clinic, "Invoice "+inv.Number)
+	r.header(clinic, "INVOICE", []string{inv.Number, labelled("Date:", inv.Date), labelled("Status:", statusLabel(inv.Status))})
+	r.billTo(inv)
+	r.items(inv)
+	r.totals(inv)
+	r.payments(inv)
+
+	if err := r.pdf.Error(); err != nil {
+		return fmt.Errorf("failed to render invoice: %v", err)
+	}
+	return r.pdf.Output(w)
+}
+
+func (r *renderer) billTo(inv Invoice) {
+	pdf := r.pdf
+	pdf.SetFont(fontFamily, "B", 9)
+	pdf.SetTextColor(107, 114, 128)
+	pdf.CellFormat(contentWidth, 5, "BILL TO", "", 1, "L", false, 0, "")
+
+	pdf.SetFont(fontFamily, "B", 11)
+	pdf.SetTextColor(17, 24, 39)
+	pdf.CellFormat(contentWidth, 6, inv.PatientName, "", 1, "L", false, 0, "")
+	pdf.SetFont(fontFamily, "", 9)
+	pdf.SetTextColor(75, 85, 99)
+	pdf.CellFormat(contentWidth, 4.5, fmt.Sprintf("Patient #%d", inv.PatientID), "", 1, "L", false, 0, "")
+	if inv.SessionDate != "" {
+		pdf.CellFormat(contentWidth, 4.5, "Visit date: "+inv.SessionDate, "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(6)
+}
+
+func (r *renderer) items(inv Invoice) {
+	pdf := r.pdf
+	widths := []float64{12, contentWidth - 12 - 45, 45}
+	aligns := []string{"C", "L", "R"}
+	r.tableHeader(widths, []string{"#", "Procedure", "Amount"}, aligns)
+
+	_, pageHeight := pdf.GetPageSize()
+	for i, item := range inv.Items {
+		// Long procedure names wrap inside their column and the row grows to fit them
+		lines := pdf.SplitText(item.ItemName, widths[1])
+		if len(lines) == 0 {
+			lines = []string{""}
+		}
+		height := rowHeight + float64(len(lines)-1)*itemLineHeight
+		if pdf.GetY()+height > pageHeight-bottomMargin {
+			pdf.AddPage()
+			r.tableHeader(widths, []string{"#", "Procedure", "Amount"}, aligns)
+		}
+
+		x, y := pdf.GetXY()
+		pdf.CellFormat(widths[0], height, strconv.Itoa(i+1), "B", 0, aligns[0], false, 0, "")
+		pdf.CellFormat(widths[1], height, "", "B", 0, aligns[1], false, 0, "")
+		pdf.CellFormat(widths[2], height, r.amount(item.Amount), "B", 0, aligns[2], false, 0, "")
+		for j, line := range lines {
+			pdf.SetXY(x+widths[0], y+(rowHeight-itemLineHeight)/2+float64(j)*itemLineHeight)
+			pdf.CellFormat(widths[1], itemLineHeight, line, "", 0, aligns[1], false, 0, "")
+		}
+		pdf.SetXY(x, y+height)
+	}
+	if len(inv.Items) == 0 {
+		pdf.CellFormat(contentWidth, rowHeight, "No procedures recorded", "B", 1, "C", false, 0, "")
+	}
+	pdf.Ln(4)
+}
+
+func (r *renderer) totals(inv Invoice) {
+	pdf := r.pdf
+	labelWidth, valueWidth := 45.0, 45.0
+	x := pageWidth - margin - labelWidth - valueWidth
+
+	rows := []struct {
+		label string
+		value int
+		bold  bool
+	}{
+		{"Total", inv.Total, false},
+		{"Paid", inv.Paid, false},
+		{"Balance due", inv.Remaining(), true},
+	}
+	for _, row := range rows {
+		style := ""
+		if row.bold {
+			style = "B"
+		}
+		pdf.SetX(x)
+		pdf.SetFont(fontFamily, style, 10)
+		pdf.CellFormat(labelWidth, rowHeight, row.label, "", 0, "L", false, 0, "")
+		pdf.CellFormat(valueWidth, rowHeight, r.amount(row.value), "", 1, "R", false, 0, "")
+	}
+	pdf.Ln(6)
+}
+
+func (r *renderer) payments(inv Invoice) {
+	pdf := r.pdf
+	pdf.SetFont(fontFamily, "B", 11)
+	pdf.SetTextColor(17, 24, 39)
+	pdf.CellFormat(contentWidth, 7, "Payments", "", 1, "L", false, 0, "")
+
+	if len(inv.Payments) == 0 {
+		pdf.SetFont(fontFamily, "", 9)
+		pdf.SetTextColor(107, 114, 128)
+		pdf.CellFormat(contentWidth, rowHeight, "No payments recorded yet", "", 1, "L", false, 0, "")
+		return
+	}
+
+	widths := []float64{30, 30, 25, contentWidth - 30 - 30 - 25 - 35, 35}
+	aligns := []string{"L", "L", "L", "L", "R"}
+	r.tableHeader(widths, []string{"Date", "Receipt", "Method", "Note", "Amount"}, aligns)
+	for _, payment := range inv.Payments {
+		cells := []string{
+			dateOnly(payment.PaymentDate),
+			payment.PaymentCode,
+			payment.PaymentMethod,
+			truncate(pdf, payment.Note, widths[3]-2),
+			r.amount(payment.Amount),
+		}
+		for j, cell := range cells {
+			pdf.CellFormat(widths[j], rowHeight, cell, "B", 0, aligns[j], false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+}
+
+func statusLabel(status string) string {
+	switch status {
+	case "partially_paid":
+		return "Partially paid"
+	case "":
+		return ""
+	default:
+		return strings.ToUpper(status[:1]) + status[1:]
+	}
+}
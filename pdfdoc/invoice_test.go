@@ -0,0 +1,93 @@
+package pdfdoc
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"DentistApp/models"
+)
+
+// pageCount reads the page count from the document's page tree
+func pageCount(t *testing.T, pdf []byte) int {
+	t.Helper()
+	match := regexp.MustCompile(`/Type /Pages\s*/Kids \[[^\]]*\]\s*/Count (\d+)`).FindSubmatch(pdf)
+	if match == nil {
+		t.Fatalf("no page tree in the output")
+	}
+	count, _ := strconv.Atoi(string(match[1]))
+	return count
+}
+
+func TestRenderInvoice(t *testing.T) {
+	clinic := Clinic{Name: "عيادة الابتسامة", Address: "Damascus", Currency: "SYP", Footer: "شكراً لزيارتكم"}
+	longName := "Root canal treatment, three canals, with rubber dam isolation, " +
+		"rotary instrumentation and warm vertical obturation (tooth 36)"
+
+	tests := []struct {
+		name  string
+		items []models.SessionItem
+		pages int
+	}{
+		{"known invoice", []models.SessionItem{
+			{ItemName: "تنظيف الأسنان", Amount: 150000},
+			{ItemName: longName, Amount: 250000},
+		}, 1},
+		{"no items", nil, 1},
+		{"multi-page item list", func() []models.SessionItem {
+			items := make([]models.SessionItem, 0, 60)
+			for i := 0; i < 60; i++ {
+				items = append(items, models.SessionItem{ItemName: fmt.Sprintf("%d. %s", i+1, longName), Amount: 1000})
+			}
+			return items
+		}(), 3},
+	}
+	for _, tt := range tests {
+		inv := Invoice{
+			Number:      "INV-001",
+			Date:        "2025-03-01",
+			Status:      "partially_paid",
+			PatientID:   7,
+			PatientName: "جين دو",
+			SessionDate: "2025-03-01",
+			Items:       tt.items,
+			Payments:    []models.Payment{{PaymentCode: "Payment-001", PaymentDate: "2025-03-01 10:00:00", PaymentMethod: "cash", Note: strings.Repeat("دفعة أولى ", 10), Amount: 100000}},
+			Total:       400000,
+			Paid:        100000,
+		}
+
+		var buf bytes.Buffer
+		if err := RenderInvoice(&buf, clinic, inv); err != nil {
+			t.Fatalf("%s: RenderInvoice failed: %v", tt.name, err)
+		}
+		out := buf.Bytes()
+		if len(out) == 0 || !bytes.HasPrefix(out, []byte("%PDF-")) {
+			t.Fatalf("%s: output is not a PDF: %.20q", tt.name, out)
+		}
+		if !bytes.Contains(out, []byte("/FontFile2")) {
+			t.Errorf("%s: the UTF-8 font was not embedded", tt.name)
+		}
+		if got := pageCount(t, out); got < tt.pages {
+			t.Errorf("%s: %d page(s); expected at least %d", tt.name, got, tt.pages)
+		}
+	}
+}
+
+func TestItemNamesWrap(t *testing.T) {
+	r := newRenderer(Clinic{}, "test")
+	r.pdf.SetFont(fontFamily, "", 9)
+	width := contentWidth - 12 - 45
+	name := strings.Repeat("Porcelain fused to metal crown ", 6)
+	lines := r.pdf.SplitText(name, width)
+	if len(lines) < 2 {
+		t.Fatalf("a %.0fmm name fits on one line of a %.0fmm column", r.pdf.GetStringWidth(name), width)
+	}
+	for _, line := range lines {
+		if r.pdf.GetStringWidth(line) > width {
+			t.Errorf("wrapped line %q is wider than the column", line)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Package pdfdoc renders clinic documents, such as invoices and prescriptions, as printable A4 PDFs.
+//
+// Text is drawn with the embedded DejaVu Sans Condensed font, so Arabic as well as Latin names print.
+// fpdf does no contextual shaping, so Arabic letters appear in their isolated forms.
+package pdfdoc
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// Clinic is the branding printed in the document header and footer
+type Clinic struct {
+	Name      string
+	Address   string
+	Phone     string
+	TaxNumber string
+	// Logo is a PNG or JPEG image, as named by LogoType ("png" or "jpeg"). An unreadable logo is left out.
+	Logo     []byte
+	LogoType string
+	Currency string
+	Footer   string
+}
+
+//go:embed fonts/DejaVuSansCondensed.ttf
+var regularFont []byte
+
+//go:embed fonts/DejaVuSansCondensed-Bold.ttf
+var boldFont []byte
+
+// fontFamily is the embedded UTF-8 font every document is set in
+const fontFamily = "DejaVu"
+
+const (
+	margin         = 15.0
+	pageWidth      = 210.0
+	contentWidth   = pageWidth - 2*margin
+	logoHeight     = 22.0
+	rowHeight      = 7.0
+	itemLineHeight = 4.5
+	bottomMargin   = margin + 10
+)
+
+// newRenderer starts an A4 document with the clinic footer and page numbers on every page
+func newRenderer(clinic Clinic, title string) *renderer {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(true, bottomMargin)
+	pdf.SetTitle(title, true)
+	pdf.SetCreator("DentistApp", true)
+
+	pdf.AddUTF8FontFromBytes(fontFamily, "", regularFont)
+	pdf.AddUTF8FontFromBytes(fontFamily, "B", boldFont)
+	// The oblique face has no Arabic glyphs, so italic text uses the regular one
+	pdf.AddUTF8FontFromBytes(fontFamily, "I", regularFont)
+	r := &renderer{pdf: pdf, currency: clinic.Currency}
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-margin - 5)
+		pdf.SetFont(fontFamily, "", 8)
+		pdf.SetTextColor(107, 114, 128)
+		if clinic.Footer != "" {
+			pdf.CellFormat(contentWidth, 4, clinic.Footer, "", 1, "C", false, 0, "")
+		}
+		pdf.CellFormat(contentWidth, 4, fmt.Sprintf("Page %d of {nb}", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AliasNbPages("")
+	pdf.AddPage()
+	return r
+}
+
+type renderer struct {
+	pdf      *fpdf.Fpdf
+	currency string
+}
+
+// header prints the clinic on the left and the document title with its details on the right
+func (r *renderer) header(clinic Clinic, title string, details []string) {
+	pdf := r.pdf
+	textX := margin
+	if r.logo(clinic.Logo, clinic.LogoType) {
+		textX = margin + logoHeight + 5
+	}
+
+	pdf.SetXY(textX, margin)
+	pdf.SetFont(fontFamily, "B", 16)
+	pdf.SetTextColor(17, 24, 39)
+	name := clinic.Name
+	if name == "" {
+		name = "Dental Clinic"
+	}
+	pdf.CellFormat(100, 8, name, "", 2, "L", false, 0, "")
+
+	pdf.SetFont(fontFamily, "", 9)
+	pdf.SetTextColor(75, 85, 99)
+	for _, line := range []string{clinic.Address, clinic.Phone, labelled("Tax No.", clinic.TaxNumber)} {
+		if line != "" {
+			pdf.CellFormat(100, 4.5, line, "", 2, "L", false, 0, "")
+		}
+	}
+
+	pdf.SetXY(pageWidth-margin-70, margin)
+	pdf.SetFont(fontFamily, "B", 20)
+	pdf.SetTextColor(37, 99, 235)
+	pdf.CellFormat(70, 9, title, "", 2, "R", false, 0, "")
+	pdf.SetFont(fontFamily, "", 10)
+	pdf.SetTextColor(17, 24, 39)
+	for _, line := range details {
+		pdf.CellFormat(70, 5.5, line, "", 2, "R", false, 0, "")
+	}
+
+	pdf.SetY(margin + logoHeight + 8)
+	pdf.SetDrawColor(229, 231, 235)
+	pdf.Line(margin, pdf.GetY(), pageWidth-margin, pdf.GetY())
+	pdf.Ln(5)
+}
+
+// logo draws the clinic logo and reports whether it was drawn
+func (r *renderer) logo(data []byte, logoType string) bool {
+	if len(data) == 0 {
+		return false
+	}
+	imageType := strings.ToUpper(logoType)
+	if imageType == "JPEG" {
+		imageType = "JPG"
+	}
+	if imageType != "PNG" && imageType != "JPG" {
+		return false
+	}
+
+	options := fpdf.ImageOptions{ImageType: imageType}
+	info := r.pdf.RegisterImageOptionsReader("logo", options, bytes.NewReader(data))
+	if r.pdf.Err() || info == nil {
+		// The logo is decoration; an unreadable image must not stop the document
+		r.pdf.ClearError()
+		return false
+	}
+	r.pdf.ImageOptions("logo", margin, margin, 0, logoHeight, false, options, 0, "")
+	return true
+}
+
+func (r *renderer) tableHeader(widths []float64, titles []string, aligns []string) {
+	pdf := r.pdf
+	pdf.SetFont(fontFamily, "B", 9)
+	pdf.SetFillColor(243, 244, 246)
+	pdf.SetTextColor(55, 65, 81)
+	for i, title := range titles {
+		pdf.CellFormat(widths[i], rowHeight, title, "B", 0, aligns[i], true, 0, "")
+	}
+	pdf.Ln(-1)
+	pdf.SetFont(fontFamily, "", 9)
+	pdf.SetTextColor(17, 24, 39)
+}
+
+func (r *renderer) amount(value int) string {
+	formatted := formatThousands(value)
+	if r.currency == "" {
+		return formatted
+	}
+	return formatted + " " + r.currency
+}
+
+// formatThousands formats an amount with comma thousands separators
+func formatThousands(value int) string {
+	digits := strconv.Itoa(value)
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(d)
+	}
+	return sign + b.String()
+}
+
+func labelled(label, value string) string {
+	if value == "" {
+		return ""
+	}
+	return label + " " + value
+}
+
+// dateOnly drops the time from a "YYYY-MM-DD HH:MM:SS" value
+func dateOnly(value string) string {
+	if len(value) > 10 {
+		return value[:10]
+	}
+	return value
+}
+
+// truncate shortens text so it fits in width, marking the cut with "..."
+func truncate(pdf *fpdf.Fpdf, text string, width float64) string {
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && pdf.GetStringWidth(string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
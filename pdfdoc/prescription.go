@@ -0,0 +1,107 @@
+package pdfdoc
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"DentistApp/models"
+)
+
+// Prescription is everything printed on one prescription
+type Prescription struct {
+	Number      string
+	Date        string
+	PatientID   int
+	PatientName string
+	PatientAge  int
+	Allergies   string
+	Prescriber  string
+	Notes       string
+	Items       []models.PrescriptionItem
+}
+
+// RenderPrescription writes the prescription as a PDF to w
+func RenderPrescription(w io.Writer, clinic Clinic, rx Prescription) error {
+	r := newRenderer(clinic, "Prescription "+rx.Number)
+	r.header(clinic, "PRESCRIPTION", []string{rx.Number, labelled("Date:", rx.Date)})
+	r.patient(rx)
+	r.drugs(rx)
+	r.signature(rx)
+
+	if err := r.pdf.Error(); err != nil {
+		return fmt.Errorf("failed to render prescription: %v", err)
+	}
+	return r.pdf.Output(w)
+}
+
+func (r *renderer) patient(rx Prescription) {
+	pdf := r.pdf
+	pdf.SetFont(fontFamily, "B", 9)
+	pdf.SetTextColor(107, 114, 128)
+	pdf.CellFormat(contentWidth, 5, "PATIENT", "", 1, "L", false, 0, "")
+
+	pdf.SetFont(fontFamily, "B", 11)
+	pdf.SetTextColor(17, 24, 39)
+	pdf.CellFormat(contentWidth, 6, rx.PatientName, "", 1, "L", false, 0, "")
+	pdf.SetFont(fontFamily, "", 9)
+	pdf.SetTextColor(75, 85, 99)
+	details := fmt.Sprintf("Patient #%d", rx.PatientID)
+	if rx.PatientAge > 0 {
+		details += fmt.Sprintf(" - %d years", rx.PatientAge)
+	}
+	pdf.CellFormat(contentWidth, 4.5, details, "", 1, "L", false, 0, "")
+	allergies := strings.TrimSpace(rx.Allergies)
+	if allergies == "" {
+		allergies = "none recorded"
+	}
+	pdf.MultiCell(contentWidth, 4.5, "Allergies: "+allergies, "", "L", false)
+	pdf.Ln(6)
+}
+
+func (r *renderer) drugs(rx Prescription) {
+	pdf := r.pdf
+	for i, item := range rx.Items {
+		pdf.SetFont(fontFamily, "B", 11)
+		pdf.SetTextColor(17, 24, 39)
+		name := strings.TrimSpace(item.DrugName + " " + item.Strength)
+		pdf.CellFormat(contentWidth, 6.5, strconv.Itoa(i+1)+". "+name, "", 1, "L", false, 0, "")
+
+		pdf.SetFont(fontFamily, "", 10)
+		directions := item.Dose + ", " + item.Frequency
+		if item.Duration != "" {
+			directions += ", for " + item.Duration
+		}
+		pdf.SetX(margin + 5)
+		pdf.MultiCell(contentWidth-5, 5, directions, "", "L", false)
+		if item.Instructions != "" {
+			pdf.SetX(margin + 5)
+			pdf.SetTextColor(75, 85, 99)
+			pdf.MultiCell(contentWidth-5, 5, item.Instructions, "", "L", false)
+		}
+		pdf.Ln(3)
+	}
+	if rx.Notes != "" {
+		pdf.SetFont(fontFamily, "I", 9)
+		pdf.SetTextColor(75, 85, 99)
+		pdf.MultiCell(contentWidth, 4.5, rx.Notes, "", "L", false)
+	}
+	pdf.Ln(10)
+}
+
+func (r *renderer) signature(rx Prescription) {
+	pdf := r.pdf
+	x := pageWidth - margin - 70
+	pdf.SetDrawColor(156, 163, 175)
+	pdf.Line(x, pdf.GetY()+10, pageWidth-margin, pdf.GetY()+10)
+	pdf.SetY(pdf.GetY() + 11)
+	pdf.SetX(x)
+	pdf.SetFont(fontFamily, "", 9)
+	pdf.SetTextColor(75, 85, 99)
+	pdf.CellFormat(70, 5, "Prescriber's signature", "", 1, "C", false, 0, "")
+	if rx.Prescriber != "" {
+		pdf.SetX(x)
+		pdf.CellFormat(70, 5, rx.Prescriber, "", 1, "C", false, 0, "")
+	}
+}
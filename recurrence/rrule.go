@@ -0,0 +1,238 @@
+// Package recurrence expands recurring appointment rules.
+//
+// Rules use a subset of RFC 5545 RRULE: FREQ (DAILY, WEEKLY or MONTHLY), INTERVAL, BYDAY (weekly
+// rules only, plain weekdays such as MO,TH) and one of COUNT or UNTIL. Every series must end, and a
+// series is capped at MaxOccurrences visits.
+package recurrence
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxOccurrences is the largest number of visits a rule may produce
+const MaxOccurrences = 104
+
+// Frequencies supported in FREQ
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+)
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// Rule is a parsed recurrence rule
+type Rule struct {
+	Freq     string
+	Interval int
+	ByDay    []time.Weekday
+	Count    int
+	// Until is the last moment an occurrence may start; zero when Count is used
+	Until time.Time
+}
+
+// Parse reads an RRULE value such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU;COUNT=10". A leading
+// "RRULE:" is accepted. A date-only UNTIL includes the whole day.
+func Parse(value string) (Rule, error) {
+	rule := Rule{Interval: 1}
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return rule, fmt.Errorf("recurrence rule is empty")
+	}
+
+	seen := map[string]bool{}
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		key = strings.ToUpper(strings.TrimSpace(key))
+		val = strings.ToUpper(strings.TrimSpace(val))
+		if !ok || val == "" {
+			return rule, fmt.Errorf("invalid recurrence rule part %q", part)
+		}
+		if seen[key] {
+			return rule, fmt.Errorf("recurrence rule repeats %s", key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "FREQ":
+			if val != Daily && val != Weekly && val != Monthly {
+				return rule, fmt.Errorf("unsupported recurrence frequency %s", val)
+			}
+			rule.Freq = val
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 || n > 52 {
+				return rule, fmt.Errorf("recurrence interval must be between 1 and 52")
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 || n > MaxOccurrences {
+				return rule, fmt.Errorf("recurrence count must be between 1 and %d", MaxOccurrences)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := parseUntil(val)
+			if err != nil {
+				return rule, err
+			}
+			rule.Until = until
+		case "BYDAY":
+			for _, code := range strings.Split(val, ",") {
+				day, ok := weekdayCodes[code]
+				if !ok {
+					return rule, fmt.Errorf("unsupported recurrence weekday %q", code)
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		case "WKST":
+			// Weeks always start on Monday here; accepted for compatibility with other tools
+		default:
+			return rule, fmt.Errorf("unsupported recurrence rule part %s", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return rule, fmt.Errorf("recurrence rule needs FREQ")
+	}
+	if rule.Count == 0 && rule.Until.IsZero() {
+		return rule, fmt.Errorf("recurrence rule needs COUNT or UNTIL")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return rule, fmt.Errorf("recurrence rule cannot have both COUNT and UNTIL")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != Weekly {
+		return rule, fmt.Errorf("BYDAY is only supported for weekly rules")
+	}
+	return rule, nil
+}
+
+// parseUntil reads a date (20250301) or date-time (20250301T170000, optionally with Z) in local time
+func parseUntil(value string) (time.Time, error) {
+	if t, err := time.ParseInLocation("20060102", value, time.Local); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t.Local(), nil
+	}
+	if t, err := time.ParseInLocation("20060102T150405", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid recurrence end date %q", value)
+}
+
+// String formats the rule as an RRULE value
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			for code, d := range weekdayCodes {
+				if d == day {
+					codes[i] = code
+				}
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	} else {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102T150405"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Occurrences returns the start of every visit in the series beginning at start, in order.
+// Weekly rules with BYDAY visit the listed weekdays of every INTERVAL-th week, on or after start and
+// at start's time of day; other rules begin with start itself. Monthly rules skip months that lack
+// start's day of the month.
+func (r Rule) Occurrences(start time.Time) ([]time.Time, error) {
+	var out []time.Time
+	add := func(t time.Time) bool {
+		if !r.Until.IsZero() && t.After(r.Until) {
+			return false
+		}
+		out = append(out, t)
+		return r.Count == 0 || len(out) < r.Count
+	}
+	done := func() bool {
+		return r.Count > 0 && len(out) >= r.Count
+	}
+
+	switch {
+	case r.Freq == Weekly && len(r.ByDay) > 0:
+		// Walk week by week from the Monday of start's week
+		offset := (int(start.Weekday()) + 6) % 7
+		weekStart := start.AddDate(0, 0, -offset)
+		for week := 0; !done(); week += r.Interval {
+			if week/r.Interval > MaxOccurrences {
+				break
+			}
+			monday := weekStart.AddDate(0, 0, 7*week)
+			stop := false
+			for i := 0; i < 7 && !stop; i++ {
+				day := monday.AddDate(0, 0, i)
+				if day.Before(start) || !r.hasDay(day.Weekday()) {
+					continue
+				}
+				if !add(day) {
+					stop = true
+				}
+			}
+			if stop || len(out) > MaxOccurrences {
+				break
+			}
+		}
+	default:
+		for i := 0; !done() && i <= MaxOccurrences*2; i++ {
+			var next time.Time
+			switch r.Freq {
+			case Daily:
+				next = start.AddDate(0, 0, i*r.Interval)
+			case Weekly:
+				next = start.AddDate(0, 0, 7*i*r.Interval)
+			case Monthly:
+				next = time.Date(start.Year(), start.Month()+time.Month(i*r.Interval), start.Day(),
+					start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+				if next.Day() != start.Day() {
+					continue
+				}
+			}
+			if !add(next) {
+				break
+			}
+		}
+	}
+
+	if len(out) == 0 {
+		return nil, fmt.Errorf("recurrence rule produces no visits")
+	}
+	if len(out) > MaxOccurrences {
+		return nil, fmt.Errorf("recurrence rule produces more than %d visits", MaxOccurrences)
+	}
+	return out, nil
+}
+
+func (r Rule) hasDay(day time.Weekday) bool {
+	for _, d := range r.ByDay {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
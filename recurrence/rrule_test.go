@@ -0,0 +1,83 @@
+package recurrence
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	rule, err := Parse("RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH;COUNT=6")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if rule.Freq != Weekly || rule.Interval != 2 || rule.Count != 6 || len(rule.ByDay) != 2 {
+		t.Errorf("unexpected rule: %+v", rule)
+	}
+	if got := rule.String(); got != "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH;COUNT=6" {
+		t.Errorf("String() = %q", got)
+	}
+
+	invalid := []string{
+		"",
+		"FREQ=YEARLY;COUNT=3",
+		"FREQ=WEEKLY",
+		"FREQ=WEEKLY;COUNT=3;UNTIL=20250601",
+		"FREQ=DAILY;BYDAY=MO;COUNT=3",
+		"FREQ=WEEKLY;BYDAY=1MO;COUNT=3",
+		"FREQ=WEEKLY;COUNT=500",
+		"FREQ=WEEKLY;BYSETPOS=1;COUNT=3",
+	}
+	for _, value := range invalid {
+		if _, err := Parse(value); err == nil {
+			t.Errorf("expected Parse(%q) to fail", value)
+		}
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	start := time.Date(2025, 3, 4, 10, 30, 0, 0, time.Local) // a Tuesday
+	format := func(times []time.Time) string {
+		out := make([]string, len(times))
+		for i, t := range times {
+			out[i] = t.Format("01-02 15:04")
+		}
+		return strings.Join(out, " ")
+	}
+
+	tests := []struct {
+		rule string
+		want string
+	}{
+		{"FREQ=WEEKLY;INTERVAL=3;COUNT=3", "03-04 10:30 03-25 10:30 04-15 10:30"},
+		{"FREQ=WEEKLY;BYDAY=TU,FR;COUNT=4", "03-04 10:30 03-07 10:30 03-11 10:30 03-14 10:30"},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;UNTIL=20250320", "03-05 10:30 03-17 10:30 03-19 10:30"},
+		{"FREQ=DAILY;INTERVAL=10;UNTIL=20250325T000000", "03-04 10:30 03-14 10:30 03-24 10:30"},
+		{"FREQ=MONTHLY;COUNT=3", "03-04 10:30 04-04 10:30 05-04 10:30"},
+	}
+	for _, tt := range tests {
+		rule, err := Parse(tt.rule)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.rule, err)
+		}
+		times, err := rule.Occurrences(start)
+		if err != nil {
+			t.Fatalf("Occurrences(%q) failed: %v", tt.rule, err)
+		}
+		if got := format(times); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.rule, got, tt.want)
+		}
+	}
+
+	// Months without the 31st are skipped
+	rule, _ := Parse("FREQ=MONTHLY;COUNT=3")
+	times, _ := rule.Occurrences(time.Date(2025, 1, 31, 9, 0, 0, 0, time.Local))
+	if got := format(times); got != "01-31 09:00 03-31 09:00 05-31 09:00" {
+		t.Errorf("monthly on the 31st: got %s", got)
+	}
+
+	rule, _ = Parse("FREQ=DAILY;UNTIL=20261231")
+	if _, err := rule.Occurrences(start); err == nil {
+		t.Errorf("expected an error for a series longer than %d visits", MaxOccurrences)
+	}
+}
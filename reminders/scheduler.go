@@ -0,0 +1,174 @@
+// Package reminders queues appointment reminders and delivers them over SMS or email.
+//
+// Messages wait in an outbox (the outbound_messages table, see handlers.ReminderHandler) so that
+// delivery survives restarts and failed sends are retried with exponential backoff.
+package reminders
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// checkInterval is how often the scheduler queues new reminders and sends due messages
+const checkInterval = time.Minute
+
+// batchSize is the most messages sent in one run
+const batchSize = 50
+
+// Backoff delays after a failed delivery: 5 minutes, doubling up to 6 hours
+const (
+	firstRetryDelay = 5 * time.Minute
+	maxRetryDelay   = 6 * time.Hour
+)
+
+// Outbox stores queued messages
+type Outbox interface {
+	// QueueDue adds reminders for appointments that start within the lead time and returns how many were queued
+	QueueDue(now time.Time) (int, error)
+	// Claim returns up to limit pending messages due at now and reserves them so no other
+	// scheduler sends them at the same time
+	Claim(now time.Time, limit int) ([]Message, error)
+	MarkSent(id int64, at time.Time) error
+	// MarkFailed records a failed attempt. A zero retryAt gives up on the message.
+	MarkFailed(id int64, at time.Time, reason string, retryAt time.Time) error
+}
+
+// Config controls delivery. It is loaded on every run so settings apply without a restart.
+type Config struct {
+	Enabled     bool
+	MaxAttempts int
+	// Senders maps a channel to the sender for it; messages for other channels fail and are retried
+	Senders map[string]Sender
+}
+
+// Scheduler queues reminders and sends due messages in the background
+type Scheduler struct {
+	outbox Outbox
+	config func() (Config, error)
+	cancel context.CancelFunc
+	done   chan struct{}
+	mu     sync.Mutex
+}
+
+// NewScheduler creates a scheduler
+func NewScheduler(outbox Outbox, config func() (Config, error)) *Scheduler {
+	return &Scheduler{outbox: outbox, config: config}
+}
+
+// Start runs the scheduler in the background until Stop is called
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go func(done chan struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		s.RunDue(ctx, time.Now())
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.RunDue(ctx, now)
+			}
+		}
+	}(s.done)
+}
+
+// Stop stops the scheduler, abandoning a delivery in progress, and waits for it to exit
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel, s.done = nil, nil
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// RunDue queues reminders that are due at now and sends pending messages. It returns the number of
+// messages sent and the number that failed.
+func (s *Scheduler) RunDue(ctx context.Context, now time.Time) (sent, failed int) {
+	config, err := s.config()
+	if err != nil {
+		log.Printf("[Reminders] Failed to load reminder settings: %v", err)
+		return 0, 0
+	}
+	if !config.Enabled {
+		return 0, 0
+	}
+
+	if queued, err := s.outbox.QueueDue(now); err != nil {
+		log.Printf("[Reminders] Failed to queue reminders: %v", err)
+	} else if queued > 0 {
+		log.Printf("[Reminders] Queued %d reminder(s)", queued)
+	}
+
+	messages, err := s.outbox.Claim(now, batchSize)
+	if err != nil {
+		log.Printf("[Reminders] Failed to load pending messages: %v", err)
+		return 0, 0
+	}
+	for _, msg := range messages {
+		if ctx.Err() != nil {
+			break
+		}
+		var err error
+		if sender, ok := config.Senders[msg.Channel]; ok {
+			err = sender.Send(ctx, msg)
+		} else {
+			err = fmt.Errorf("no sender is configured for %s", msg.Channel)
+		}
+		if err == nil {
+			if err := s.outbox.MarkSent(msg.ID, time.Now()); err != nil {
+				log.Printf("[Reminders] Failed to mark message %d as sent: %v", msg.ID, err)
+			}
+			sent++
+			continue
+		}
+		failed++
+		attempts := msg.Attempts + 1
+		var retryAt time.Time
+		if !IsPermanent(err) && attempts < config.MaxAttempts {
+			retryAt = now.Add(Backoff(attempts))
+		}
+		s.fail(msg, now, err.Error(), retryAt)
+	}
+	return sent, failed
+}
+
+func (s *Scheduler) fail(msg Message, now time.Time, reason string, retryAt time.Time) {
+	if retryAt.IsZero() {
+		log.Printf("[Reminders] Giving up on message %d to %s: %s", msg.ID, msg.Recipient, reason)
+	} else {
+		log.Printf("[Reminders] Message %d to %s failed, retrying at %s: %s", msg.ID, msg.Recipient, retryAt.Format("15:04"), reason)
+	}
+	if err := s.outbox.MarkFailed(msg.ID, now, reason, retryAt); err != nil {
+		log.Printf("[Reminders] Failed to record failure of message %d: %v", msg.ID, err)
+	}
+}
+
+// Backoff returns how long to wait after the given number of failed attempts
+func Backoff(attempts int) time.Duration {
+	delay := firstRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
@@ -0,0 +1,112 @@
+package reminders
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeOutbox struct {
+	pending []Message
+	sent    []int64
+	retries map[int64]time.Time
+	failed  []int64
+}
+
+func (o *fakeOutbox) QueueDue(now time.Time) (int, error) { return 0, nil }
+
+func (o *fakeOutbox) Claim(now time.Time, limit int) ([]Message, error) {
+	claimed := o.pending
+	o.pending = nil
+	return claimed, nil
+}
+
+func (o *fakeOutbox) MarkSent(id int64, at time.Time) error {
+	o.sent = append(o.sent, id)
+	return nil
+}
+
+func (o *fakeOutbox) MarkFailed(id int64, at time.Time, reason string, retryAt time.Time) error {
+	if retryAt.IsZero() {
+		o.failed = append(o.failed, id)
+	} else {
+		o.retries[id] = retryAt
+	}
+	return nil
+}
+
+type fakeSender struct {
+	errs map[int64]error
+}
+
+func (s *fakeSender) Send(ctx context.Context, msg Message) error { return s.errs[msg.ID] }
+
+func TestRunDueRetriesAndGivesUp(t *testing.T) {
+	outbox := &fakeOutbox{retries: map[int64]time.Time{}, pending: []Message{
+		{ID: 1, Channel: ChannelSMS},
+		{ID: 2, Channel: ChannelSMS},
+		{ID: 3, Channel: ChannelSMS, Attempts: 2},
+		{ID: 4, Channel: ChannelSMS},
+		{ID: 5, Channel: ChannelEmail},
+	}}
+	sender := &fakeSender{errs: map[int64]error{
+		2: errors.New("gateway unreachable"),
+		3: errors.New("gateway unreachable"),
+		4: Permanent(errors.New("invalid number")),
+	}}
+	config := func() (Config, error) {
+		return Config{Enabled: true, MaxAttempts: 3, Senders: map[string]Sender{ChannelSMS: sender}}, nil
+	}
+
+	now := time.Date(2024, 3, 4, 9, 0, 0, 0, time.Local)
+	sent, failed := NewScheduler(outbox, config).RunDue(context.Background(), now)
+	if sent != 1 || failed != 4 {
+		t.Errorf("expected 1 sent and 4 failed, got %d and %d", sent, failed)
+	}
+	if len(outbox.sent) != 1 || outbox.sent[0] != 1 {
+		t.Errorf("expected message 1 to be sent, got %v", outbox.sent)
+	}
+	// 2 is retried after its first failure; 5 has no sender yet and is retried too
+	if len(outbox.retries) != 2 || !outbox.retries[2].Equal(now.Add(5*time.Minute)) || outbox.retries[5].IsZero() {
+		t.Errorf("unexpected retries %v", outbox.retries)
+	}
+	// 3 reached MaxAttempts and 4 failed permanently
+	if len(outbox.failed) != 2 || outbox.failed[0] != 3 || outbox.failed[1] != 4 {
+		t.Errorf("expected messages 3 and 4 to be given up, got %v", outbox.failed)
+	}
+
+	outbox.pending = []Message{{ID: 6, Channel: ChannelSMS}}
+	disabled := func() (Config, error) { return Config{}, nil }
+	if sent, failed := NewScheduler(outbox, disabled).RunDue(context.Background(), now); sent != 0 || failed != 0 || len(outbox.pending) != 1 {
+		t.Errorf("expected a disabled scheduler to leave the outbox alone")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	want := []time.Duration{5 * time.Minute, 10 * time.Minute, 20 * time.Minute, 40 * time.Minute}
+	for i, w := range want {
+		if got := Backoff(i + 1); got != w {
+			t.Errorf("Backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+	if got := Backoff(20); got != 6*time.Hour {
+		t.Errorf("expected backoff to be capped at 6 hours, got %v", got)
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	data := TemplateData{Patient: "Jane Doe", Clinic: "Smile Clinic", Start: time.Date(2024, 3, 4, 9, 30, 0, 0, time.Local)}
+	got := Render(DefaultSMSTemplate, data)
+	want := "Hi Jane Doe, this is a reminder of your dental appointment on Mon 4 Mar 2024 at 09:30 with your dentist. Smile Clinic"
+	if got != want {
+		t.Errorf("Render = %q, want %q", got, want)
+	}
+	if err := ValidateTemplate("See you {when}"); err == nil || !strings.Contains(err.Error(), "{when}") {
+		t.Errorf("expected unknown placeholder to be rejected, got %v", err)
+	}
+	if err := ValidateTemplate(DefaultEmailTemplate); err != nil {
+		t.Errorf("default template rejected: %v", err)
+	}
+}
@@ -0,0 +1,253 @@
+package reminders
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Channels a message can be sent through
+const (
+	ChannelSMS   = "sms"
+	ChannelEmail = "email"
+)
+
+// sendTimeout bounds a single delivery attempt
+const sendTimeout = 30 * time.Second
+
+// Message is an outbound message taken from the outbox
+type Message struct {
+	ID        int64
+	Channel   string
+	Recipient string
+	// Subject is used for email only
+	Subject string
+	Body    string
+	// Attempts is the number of failed deliveries so far
+	Attempts int
+}
+
+// Sender delivers messages over one channel. Errors wrapped with Permanent are not retried.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// PermanentError is a delivery failure that retrying will not fix, such as a rejected recipient
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// Permanent marks err as not worth retrying
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// SMTPSender sends email through an SMTP server. Port 465 uses implicit TLS; other ports upgrade
+// with STARTTLS when the server offers it.
+type SMTPSender struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Send delivers msg to msg.Recipient
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	to, err := mail.ParseAddress(msg.Recipient)
+	if err != nil {
+		return Permanent(fmt.Errorf("invalid email address %q", msg.Recipient))
+	}
+	from, err := mail.ParseAddress(s.From)
+	if err != nil {
+		return Permanent(fmt.Errorf("invalid sender address %q", s.From))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	tlsConfig := &tls.Config{ServerName: s.Host}
+	var conn net.Conn
+	if s.Port == 465 {
+		dialer := &tls.Dialer{Config: tlsConfig}
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %v", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %v", err)
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok && s.Port != 465 {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("failed to start TLS: %v", err)
+		}
+	}
+	if s.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return smtpError("SMTP login failed", err)
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return smtpError("sender rejected", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return smtpError("recipient rejected", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return smtpError("failed to send message", err)
+	}
+	if _, err := w.Write(buildEmail(from, to, msg)); err != nil {
+		return fmt.Errorf("failed to send message: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return smtpError("message rejected", err)
+	}
+	return client.Quit()
+}
+
+// smtpError wraps an SMTP failure, marking 5xx replies as permanent
+func smtpError(what string, err error) error {
+	wrapped := fmt.Errorf("%s: %v", what, err)
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return Permanent(wrapped)
+	}
+	return wrapped
+}
+
+// buildEmail formats a plain-text UTF-8 email
+func buildEmail(from, to *mail.Address, msg Message) []byte {
+	subject := strings.Join(strings.Fields(msg.Subject), " ")
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from.String())
+	fmt.Fprintf(&b, "To: %s\r\n", to.String())
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(&b)
+	qp.Write([]byte(strings.ReplaceAll(msg.Body, "\n", "\r\n")))
+	qp.Close()
+	return b.Bytes()
+}
+
+// HTTPSMSSender sends SMS through a gateway that accepts a JSON POST of
+// {"to": ..., "from": ..., "message": ...}. APIKey, when set, is sent as a bearer token.
+// 4xx replies other than 408 and 429 are treated as permanent failures.
+type HTTPSMSSender struct {
+	URL    string
+	APIKey string
+	From   string
+	Client *http.Client
+}
+
+// Send delivers msg to msg.Recipient
+func (s *HTTPSMSSender) Send(ctx context.Context, msg Message) error {
+	payload, err := json.Marshal(map[string]string{"to": msg.Recipient, "from": s.From, "message": msg.Body})
+	if err != nil {
+		return Permanent(err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(payload))
+	if err != nil {
+		return Permanent(fmt.Errorf("invalid SMS gateway URL: %v", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.APIKey)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("SMS gateway unreachable: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+	err = fmt.Errorf("SMS gateway returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return Permanent(err)
+	}
+	return err
+}
+
+// FileSender appends messages to a file and the log instead of sending them. It is used in test
+// mode, so templates and the schedule can be checked before real messages go out.
+type FileSender struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// Send records msg
+func (s *FileSender) Send(ctx context.Context, msg Message) error {
+	log.Printf("[Reminders] Test mode: %s to %s recorded in %s", msg.Channel, msg.Recipient, s.Path)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", s.Path, err)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "=== %s %s to %s\n", time.Now().Format("2006-01-02 15:04:05"), msg.Channel, msg.Recipient)
+	if msg.Subject != "" {
+		fmt.Fprintf(&b, "Subject: %s\n", msg.Subject)
+	}
+	fmt.Fprintf(&b, "%s\n\n", msg.Body)
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %v", s.Path, err)
+	}
+	return f.Close()
+}
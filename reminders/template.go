@@ -0,0 +1,62 @@
+package reminders
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Default templates, used while the clinic has not written its own
+const (
+	DefaultSMSTemplate   = "Hi {patient}, this is a reminder of your dental appointment on {date} at {time} with {dentist}. {clinic}"
+	DefaultEmailSubject  = "Appointment reminder for {date}"
+	DefaultEmailTemplate = "Dear {patient},\n\nThis is a reminder of your appointment on {date} at {time} with {dentist}.\n\nIf you cannot attend, please let us know as soon as possible.\n\n{clinic}"
+)
+
+// TemplateData fills the placeholders of a reminder template
+type TemplateData struct {
+	Patient string
+	Dentist string
+	Clinic  string
+	Start   time.Time
+}
+
+var placeholderPattern = regexp.MustCompile(`\{[a-z_]+\}`)
+
+var placeholders = map[string]func(TemplateData) string{
+	"{patient}": func(d TemplateData) string { return d.Patient },
+	"{date}":    func(d TemplateData) string { return d.Start.Format("Mon 2 Jan 2006") },
+	"{time}":    func(d TemplateData) string { return d.Start.Format("15:04") },
+	"{dentist}": func(d TemplateData) string {
+		if d.Dentist == "" {
+			return "your dentist"
+		}
+		return d.Dentist
+	},
+	"{clinic}": func(d TemplateData) string { return d.Clinic },
+}
+
+// Render replaces {patient}, {date}, {time}, {dentist} and {clinic} in tmpl
+func Render(tmpl string, data TemplateData) string {
+	out := placeholderPattern.ReplaceAllStringFunc(tmpl, func(p string) string {
+		if fill, ok := placeholders[p]; ok {
+			return fill(data)
+		}
+		return p
+	})
+	return strings.TrimSpace(out)
+}
+
+// ValidateTemplate rejects empty templates and unknown placeholders
+func ValidateTemplate(tmpl string) error {
+	if strings.TrimSpace(tmpl) == "" {
+		return fmt.Errorf("reminder template is empty")
+	}
+	for _, p := range placeholderPattern.FindAllString(tmpl, -1) {
+		if _, ok := placeholders[p]; !ok {
+			return fmt.Errorf("unknown placeholder %s; use {patient}, {date}, {time}, {dentist} or {clinic}", p)
+		}
+	}
+	return nil
+}